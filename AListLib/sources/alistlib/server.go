@@ -8,8 +8,13 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/cmd"
 	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
 	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap"
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/health"
+	"github.com/OpenListTeam/OpenList/v4/internal/lifecycle"
+	"github.com/OpenListTeam/OpenList/v4/internal/music"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/feed"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server"
 	"github.com/gin-gonic/gin"
@@ -18,6 +23,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -30,8 +36,28 @@ type Event interface {
 	OnShutdown(t string)
 }
 
+// StorageHealthCallback receives the changes of the health of the storages,
+// event is one of down, up and retry_failed
+type StorageHealthCallback interface {
+	OnStorageHealth(mountPath string, event string, message string)
+}
+
 var event Event
 var logFormatter *internal.MyFormatter
+var storageHealthCallback StorageHealthCallback
+var storageHealthOnce sync.Once
+
+// SetStorageHealthCallback forwards the events of the storage health monitor to cb
+func SetStorageHealthCallback(cb StorageHealthCallback) {
+	storageHealthCallback = cb
+	storageHealthOnce.Do(func() {
+		health.OnEvent(func(e health.Event) {
+			if cb := storageHealthCallback; cb != nil {
+				cb.OnStorageHealth(e.MountPath, e.Type, e.Message)
+			}
+		})
+	})
+}
 
 func Init(e Event, cb LogCallback) error {
 	event = e
@@ -79,6 +105,10 @@ func Start() {
 	bootstrap.InitOfflineDownloadTools()
 	bootstrap.LoadStorages()
 	bootstrap.InitTaskManager()
+	feed.Start()
+	lifecycle.Start()
+	music.Start()
+	health.Start()
 	if !flags.Debug && !flags.Dev {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -141,6 +171,11 @@ func Release() {
 func Shutdown(timeout int64) (err error) {
 	timeoutDuration := time.Duration(timeout) * time.Millisecond
 	utils.Log.Println("Shutdown server...")
+	feed.Stop()
+	lifecycle.Stop()
+	music.Stop()
+	health.Stop()
+	cache.ReleaseAll()
 	if conf.Conf.Scheme.HttpPort != -1 {
 		err := shutdown(httpSrv, timeoutDuration)
 		if err != nil {
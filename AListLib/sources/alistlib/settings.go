@@ -6,6 +6,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/secret"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 )
 
@@ -25,6 +26,11 @@ func SetConfigNoPrefix(b bool) {
 	flags.NoPrefix = b
 }
 
+// SetMasterKey 设置由系统密钥库保管的主密钥，用于加密存储的敏感配置，需在 Init 前调用
+func SetMasterKey(key string) {
+	secret.SetKeystoreKey(key)
+}
+
 func GetAllStorages() int {
 	var drivers = op.GetAllStorages()
 	return len(drivers)
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/backup"
+	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Export or import storages, users, metas, settings, sharings and ssh keys",
+}
+
+var exportBackupCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export the configuration to a backup file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return fmt.Errorf("file is required")
+		}
+		password, _ := cmd.Flags().GetString("password")
+		bootstrap.Init()
+		defer bootstrap.Release()
+		data, err := backup.Export()
+		if err != nil {
+			return fmt.Errorf("failed to export: %+v", err)
+		}
+		f, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create backup file: %+v", err)
+		}
+		defer f.Close()
+		if err := backup.Encode(f, data, password); err != nil {
+			return fmt.Errorf("failed to write backup file: %+v", err)
+		}
+		utils.Log.Infof("Backup has been exported to [%s] from CLI", args[0])
+		fmt.Printf("Backup has been exported to [%s]\n", args[0])
+		return nil
+	},
+}
+
+var importBackupCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import the configuration from a backup file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return fmt.Errorf("file is required")
+		}
+		password, _ := cmd.Flags().GetString("password")
+		mode, _ := cmd.Flags().GetString("mode")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open backup file: %+v", err)
+		}
+		defer f.Close()
+		data, err := backup.Decode(f, password)
+		if err != nil {
+			return fmt.Errorf("failed to read backup file: %+v", err)
+		}
+		bootstrap.Init()
+		defer bootstrap.Release()
+		report, err := backup.Import(context.Background(), data, backup.ImportOptions{
+			Mode:   backup.Mode(mode),
+			DryRun: dryRun,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to import: %+v", err)
+		}
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+		if !dryRun {
+			utils.Log.Infof("Backup [%s] has been imported from CLI with mode [%s]", args[0], report.Mode)
+		}
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(exportBackupCmd)
+	backupCmd.AddCommand(importBackupCmd)
+	backupCmd.PersistentFlags().StringP("password", "p", "", "Password to encrypt or decrypt the backup file")
+	importBackupCmd.Flags().StringP("mode", "m", string(backup.ModeMerge), "Import mode: merge only adds missing entries, overwrite also replaces existing ones")
+	importBackupCmd.Flags().Bool("dry-run", false, "Validate the backup without writing anything")
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/secret"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// RotateKeyCmd re-encrypts the confidential fields of all storages with a new master key
+var RotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Re-encrypt storage credentials with a new master key",
+	Long: `Re-encrypt storage credentials with a new master key.
+The new key is read from --new-key or --new-key-file, a random one is generated if neither is given.
+If the current key comes from the key file, the file is replaced with the new key,
+otherwise the new key has to be supplied by the env or the keystore from the next launch.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		newKey, _ := cmd.Flags().GetString("new-key")
+		if newKeyFile, _ := cmd.Flags().GetString("new-key-file"); newKeyFile != "" {
+			data, err := os.ReadFile(newKeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to read new key file: %+v", err)
+			}
+			newKey = strings.TrimSpace(string(data))
+		}
+		generated := false
+		if newKey == "" {
+			var err error
+			if newKey, err = secret.GenerateKey(); err != nil {
+				return fmt.Errorf("failed to generate new key: %+v", err)
+			}
+			generated = true
+		}
+		bootstrap.Init()
+		defer bootstrap.Release()
+
+		storages, _, err := db.GetStorages(1, -1)
+		if err != nil {
+			return fmt.Errorf("failed to get storages: %+v", err)
+		}
+		for _, s := range storages {
+			if secret.HasEncrypted(s.Driver, s.Addition) {
+				return fmt.Errorf("storage [%s] can not be decrypted with the current master key, nothing is changed", s.MountPath)
+			}
+		}
+		source := secret.KeySource()
+		keyFile := secret.KeyFile()
+		// keep the new key on disk before the storages are re-encrypted with it
+		pendingFile := keyFile + ".new"
+		if source == secret.SourceFile {
+			if err := secret.WriteKeyFile(pendingFile, newKey); err != nil {
+				return fmt.Errorf("failed to write new key file: %+v", err)
+			}
+		}
+		if err := secret.SetMasterKey(newKey, source); err != nil {
+			return err
+		}
+		if err := db.SaveStorages(storages); err != nil {
+			_ = os.Remove(pendingFile)
+			return fmt.Errorf("failed to re-encrypt storages, nothing is changed: %+v", err)
+		}
+		if source == secret.SourceFile {
+			if err := os.Rename(pendingFile, keyFile); err != nil {
+				return fmt.Errorf("storages are re-encrypted but failed to replace the key file, move %s to %s manually: %+v", pendingFile, keyFile, err)
+			}
+		}
+		utils.Log.Infof("master key has been rotated from CLI, %d storages re-encrypted", len(storages))
+		fmt.Printf("Master key has been rotated, %d storages re-encrypted\n", len(storages))
+		if source != secret.SourceFile {
+			fmt.Printf("The current key comes from the %s, replace it with the new key before the next launch\n", source)
+			if generated {
+				fmt.Printf("New key: %s\n", newKey)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(RotateKeyCmd)
+	RotateKeyCmd.Flags().String("new-key", "", "The new master key")
+	RotateKeyCmd.Flags().String("new-key-file", "", "Read the new master key from a file")
+}
@@ -13,8 +13,8 @@ type Addition struct {
 	OrderDirection string  `json:"order_direction" type:"select" options:"asc,desc"`
 	LimitRate      float64 `json:"limit_rate" type:"float" default:"1" help:"limit all api request rate ([limit]r/1s)"`
 	PageSize       int64   `json:"page_size" type:"number" default:"200" help:"list api per page size of 115open driver"`
-	AccessToken    string  `json:"access_token" required:"true"`
-	RefreshToken   string  `json:"refresh_token" required:"true"`
+	AccessToken    string  `json:"access_token" required:"true" confidential:"true"`
+	RefreshToken   string  `json:"refresh_token" required:"true" confidential:"true"`
 }
 
 var config = driver.Config{
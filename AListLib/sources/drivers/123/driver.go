@@ -247,6 +247,40 @@ func (d *Pan123) Put(ctx context.Context, dstDir model.Obj, file model.FileStrea
 	return err
 }
 
+func (d *Pan123) RapidPut(ctx context.Context, dstDir model.Obj, name string, size int64, hash utils.HashInfo) (model.Obj, error) {
+	etag := hash.GetHash(utils.MD5)
+	if len(etag) != utils.MD5.Width {
+		return nil, errs.NotSupport
+	}
+	data := base.Json{
+		"driveId":      0,
+		"duplicate":    2, // 2->覆盖 1->重命名 0->默认
+		"etag":         strings.ToLower(etag),
+		"fileName":     name,
+		"parentFileId": dstDir.GetID(),
+		"size":         size,
+		"type":         0,
+	}
+	var resp UploadResp
+	_, err := d.Request(UploadRequest, http.MethodPost, func(req *resty.Request) {
+		req.SetBody(data).SetContext(ctx)
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Data.Reuse {
+		return nil, errs.RapidUploadMiss
+	}
+	return File{
+		FileName: name,
+		Size:     size,
+		UpdateAt: time.Now(),
+		FileId:   resp.Data.FileId,
+		Type:     0,
+		Etag:     strings.ToLower(etag),
+	}, nil
+}
+
 func (d *Pan123) APIRateLimit(ctx context.Context, api string) error {
 	value, _ := d.apiRateLimit.LoadOrStore(api,
 		rate.NewLimiter(rate.Every(700*time.Millisecond), 1))
@@ -268,4 +302,7 @@ func (d *Pan123) GetDetails(ctx context.Context) (*model.StorageDetails, error)
 	}, nil
 }
 
-var _ driver.Driver = (*Pan123)(nil)
+var (
+	_ driver.Driver   = (*Pan123)(nil)
+	_ driver.RapidPut = (*Pan123)(nil)
+)
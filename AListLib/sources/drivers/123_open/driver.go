@@ -249,6 +249,48 @@ func (d *Open123) Put(ctx context.Context, dstDir model.Obj, file model.FileStre
 	return nil, fmt.Errorf("upload complete timeout")
 }
 
+func (d *Open123) RapidPut(ctx context.Context, dstDir model.Obj, name string, size int64, hash utils.HashInfo) (model.Obj, error) {
+	parentFileId, err := strconv.ParseInt(dstDir.GetID(), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse parentFileID error: %v", err)
+	}
+	sha1Hash := hash.GetHash(utils.SHA1)
+	etag := hash.GetHash(utils.MD5)
+	if len(sha1Hash) != utils.SHA1.Width && len(etag) != utils.MD5.Width {
+		return nil, errs.NotSupport
+	}
+	// 尝试 SHA1 秒传
+	if len(sha1Hash) == utils.SHA1.Width {
+		resp, err := d.sha1Reuse(parentFileId, name, sha1Hash, size, 2)
+		if err == nil && resp.Data.Reuse {
+			return File{
+				FileName: name,
+				Size:     size,
+				FileId:   resp.Data.FileID,
+				Type:     2,
+				SHA1:     sha1Hash,
+			}, nil
+		}
+	}
+	// 尝试 MD5 秒传
+	if len(etag) == utils.MD5.Width {
+		createResp, err := d.create(parentFileId, name, etag, size, 2, false)
+		if err != nil {
+			return nil, err
+		}
+		if createResp.Data.Reuse && createResp.Data.FileID != 0 {
+			return File{
+				FileName: name,
+				Size:     size,
+				FileId:   createResp.Data.FileID,
+				Type:     2,
+				Etag:     etag,
+			}, nil
+		}
+	}
+	return nil, errs.RapidUploadMiss
+}
+
 func (d *Open123) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 	userInfo, err := d.getUserInfo(ctx)
 	if err != nil {
@@ -273,4 +315,5 @@ func (d *Open123) OfflineDownloadProcess(ctx context.Context, taskID int) (float
 var (
 	_ driver.Driver    = (*Open123)(nil)
 	_ driver.PutResult = (*Open123)(nil)
+	_ driver.RapidPut  = (*Open123)(nil)
 )
@@ -7,11 +7,11 @@ import (
 
 type Addition struct {
 	ShareKey string `json:"sharekey" required:"true"`
-	SharePwd string `json:"sharepassword"`
+	SharePwd string `json:"sharepassword" confidential:"true"`
 	driver.RootID
 	//OrderBy        string `json:"order_by" type:"select" options:"file_name,size,update_at" default:"file_name"`
 	//OrderDirection string `json:"order_direction" type:"select" options:"asc,desc" default:"asc"`
-	AccessToken string `json:"accesstoken" type:"text"`
+	AccessToken string `json:"accesstoken" type:"text" confidential:"true"`
 }
 
 var config = driver.Config{
@@ -8,10 +8,10 @@ import (
 type Addition struct {
 	LoginType    string `json:"login_type" type:"select" options:"password,qrcode" default:"password" required:"true"`
 	Username     string `json:"username" required:"true"`
-	Password     string `json:"password" required:"true"`
+	Password     string `json:"password" required:"true" confidential:"true"`
 	VCode        string `json:"validate_code"`
-	AccessToken  string `json:"access_token" required:"false"`
-	RefreshToken string `json:"refresh_token" help:"To switch accounts, please clear this field"`
+	AccessToken  string `json:"access_token" required:"false" confidential:"true"`
+	RefreshToken string `json:"refresh_token" help:"To switch accounts, please clear this field" confidential:"true"`
 	driver.RootID
 	OrderBy         string `json:"order_by" type:"select" options:"filename,filesize,lastOpTime" default:"filename"`
 	OrderDirection  string `json:"order_direction" type:"select" options:"asc,desc" default:"asc"`
@@ -13,6 +13,7 @@ import (
 
 	"github.com/go-resty/resty/v2"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
@@ -93,7 +94,48 @@ func (y *Cloud189PC) RapidUploadFromTorrent(ctx context.Context, dstDir model.Ob
 		sliceMd5Hex = strings.ToUpper(utils.GetMD5EncodeStr(strings.Join(upperSliceMD5s, "\n")))
 	}
 
-	// 使用与 Web 端一致的三步秒传流程
+	resp, err := y.rapidUpload(ctx, dstDir, fileName, fileSize, fileMD5Upper, sliceMd5Hex, sliceSize, overwrite)
+	if err != nil {
+		return nil, err
+	}
+
+	// 秒传成功后，将 torrent 文件上传到目标目录（异步，不影响秒传结果）
+	if y.Addition.GenerateTorrent {
+		capturedDstDir := dstDir
+		capturedIsFamily := isFamily
+		go func() {
+			torrentName := fileName + ".cas.torrent"
+			infoHash, _ := GetInfoHashHex(torrentData)
+			utils.Log.Infof("秒传成功，上传 torrent: %s (info_hash: %s, size: %d bytes)",
+				torrentName, infoHash, len(torrentData))
+
+			torrentFileStream := &stream.FileStream{
+				Ctx: context.Background(),
+				Obj: &model.Object{
+					Name:     torrentName,
+					Size:     int64(len(torrentData)),
+					IsFolder: false,
+				},
+				Reader:   bytes.NewReader(torrentData),
+				Mimetype: "application/x-bittorrent",
+			}
+			_, uploadErr := y.fastUpload(context.Background(), capturedDstDir, torrentFileStream, func(p float64) {}, capturedIsFamily, false, false)
+			if uploadErr != nil {
+				utils.Log.Warnf("上传 torrent 文件失败: %v", uploadErr)
+			} else {
+				utils.Log.Infof("torrent 文件已上传: %s", torrentName)
+				op.Cache.DeleteDirectory(y, capturedDstDir.GetPath())
+			}
+		}()
+	}
+
+	return resp.toFile(), nil
+}
+
+// rapidUpload 使用与 Web 端一致的三步秒传流程
+// fileMD5、sliceMD5 均为大写十六进制，云端不存在该文件时返回 errs.RapidUploadMiss
+func (y *Cloud189PC) rapidUpload(ctx context.Context, dstDir model.Obj, fileName string, fileSize int64, fileMD5, sliceMD5 string, sliceSize int64, overwrite bool) (*CommitMultiUploadFileResp, error) {
+	isFamily := y.isFamily()
 	fullUrl := "https://upload.cloud.189.cn"
 	if isFamily {
 		fullUrl += "/family"
@@ -114,7 +156,7 @@ func (y *Cloud189PC) RapidUploadFromTorrent(ctx context.Context, dstDir model.Ob
 	}
 
 	var uploadInfo InitMultiUploadResp
-	_, err = y.request(fullUrl+"/initMultiUpload", "GET", func(req *resty.Request) {
+	_, err := y.request(fullUrl+"/initMultiUpload", "GET", func(req *resty.Request) {
 		req.SetContext(ctx)
 	}, initParams, &uploadInfo, isFamily)
 	if err != nil {
@@ -125,8 +167,8 @@ func (y *Cloud189PC) RapidUploadFromTorrent(ctx context.Context, dstDir model.Ob
 
 	// Step 2: checkTransSecond（用 fileMd5 + sliceMd5 + uploadFileId 检查秒传）
 	checkParams := Params{
-		"fileMd5":      fileMD5Upper,
-		"sliceMd5":     sliceMd5Hex,
+		"fileMd5":      fileMD5,
+		"sliceMd5":     sliceMD5,
 		"uploadFileId": uploadFileId,
 	}
 
@@ -144,7 +186,7 @@ func (y *Cloud189PC) RapidUploadFromTorrent(ctx context.Context, dstDir model.Ob
 	}
 
 	if checkResp.Data.FileDataExists != 1 {
-		return nil, fmt.Errorf("秒传失败：云端不存在该文件（fileMD5=%s, sliceMD5=%s, size=%d）", fileMD5Upper, sliceMd5Hex, fileSize)
+		return nil, errs.NewErr(errs.RapidUploadMiss, "云端不存在该文件（fileMD5=%s, sliceMD5=%s, size=%d）", fileMD5, sliceMD5, fileSize)
 	}
 
 	// Step 3: commitMultiUploadFile（传 fileMd5 + sliceMd5）
@@ -152,8 +194,8 @@ func (y *Cloud189PC) RapidUploadFromTorrent(ctx context.Context, dstDir model.Ob
 	var resp CommitMultiUploadFileResp
 	commitParams := Params{
 		"uploadFileId": uploadFileId,
-		"fileMd5":      fileMD5Upper,
-		"sliceMd5":     sliceMd5Hex,
+		"fileMd5":      fileMD5,
+		"sliceMd5":     sliceMD5,
 		"lazyCheck":    "1",
 		"opertype":     IF(overwrite, "3", "1"),
 	}
@@ -165,38 +207,7 @@ func (y *Cloud189PC) RapidUploadFromTorrent(ctx context.Context, dstDir model.Ob
 		utils.Log.Errorf("[RapidUpload] commitMultiUploadFile 失败: uploadFileId=%s, err=%v", uploadFileId, err)
 		return nil, fmt.Errorf("提交上传失败: %w", err)
 	}
-
-	// 秒传成功后，将 torrent 文件上传到目标目录（异步，不影响秒传结果）
-	if y.Addition.GenerateTorrent {
-		capturedDstDir := dstDir
-		capturedIsFamily := isFamily
-		go func() {
-			torrentName := fileName + ".cas.torrent"
-			infoHash, _ := GetInfoHashHex(torrentData)
-			utils.Log.Infof("秒传成功，上传 torrent: %s (info_hash: %s, size: %d bytes)",
-				torrentName, infoHash, len(torrentData))
-
-			torrentFileStream := &stream.FileStream{
-				Ctx: context.Background(),
-				Obj: &model.Object{
-					Name:     torrentName,
-					Size:     int64(len(torrentData)),
-					IsFolder: false,
-				},
-				Reader:   bytes.NewReader(torrentData),
-				Mimetype: "application/x-bittorrent",
-			}
-			_, uploadErr := y.fastUpload(context.Background(), capturedDstDir, torrentFileStream, func(p float64) {}, capturedIsFamily, false, false)
-			if uploadErr != nil {
-				utils.Log.Warnf("上传 torrent 文件失败: %v", uploadErr)
-			} else {
-				utils.Log.Infof("torrent 文件已上传: %s", torrentName)
-				op.Cache.DeleteDirectory(y, capturedDstDir.GetPath())
-			}
-		}()
-	}
-
-	return resp.toFile(), nil
+	return &resp, nil
 }
 
 // ComputeTorrentFromReader 从 io.Reader 计算并生成 torrent 文件
@@ -896,19 +896,42 @@ func (y *Cloud189PC) RapidUpload(ctx context.Context, dstDir model.Obj, stream m
 	if len(fileMd5) < utils.MD5.Width {
 		return nil, errors.New("invalid hash")
 	}
+	return y.oldRapidUpload(ctx, dstDir, stream.GetName(), stream.GetSize(), fileMd5, isFamily, overwrite)
+}
 
-	uploadInfo, err := y.OldUploadCreate(ctx, dstDir.GetID(), fileMd5, stream.GetName(), fmt.Sprint(stream.GetSize()), isFamily)
+// oldRapidUpload 使用旧版上传接口仅凭整文件 MD5 秒传
+func (y *Cloud189PC) oldRapidUpload(ctx context.Context, dstDir model.Obj, name string, size int64, fileMd5 string, isFamily bool, overwrite bool) (model.Obj, error) {
+	uploadInfo, err := y.OldUploadCreate(ctx, dstDir.GetID(), fileMd5, name, fmt.Sprint(size), isFamily)
 	if err != nil {
 		return nil, err
 	}
 
 	if uploadInfo.FileDataExists != 1 {
-		return nil, errors.New("rapid upload fail")
+		return nil, errs.RapidUploadMiss
 	}
 
 	return y.OldUploadCommit(ctx, uploadInfo.FileCommitUrl, uploadInfo.UploadFileId, isFamily, overwrite)
 }
 
+// RapidPut 仅凭 MD5 秒传。单分片文件的 sliceMd5 与整文件 MD5 相同，走分片上传接口；
+// 多分片文件需要每个分片的 MD5，只能走旧版上传接口
+func (y *Cloud189PC) RapidPut(ctx context.Context, dstDir model.Obj, name string, size int64, hash utils.HashInfo) (model.Obj, error) {
+	fileMD5 := hash.GetHash(utils.MD5)
+	if len(fileMD5) != utils.MD5.Width {
+		return nil, errs.NotSupport
+	}
+	sliceSize := partSize(size)
+	if size > sliceSize {
+		return y.oldRapidUpload(ctx, dstDir, name, size, strings.ToLower(fileMD5), y.isFamily(), true)
+	}
+	fileMD5 = strings.ToUpper(fileMD5)
+	resp, err := y.rapidUpload(ctx, dstDir, name, size, fileMD5, fileMD5, sliceSize, true)
+	if err != nil {
+		return nil, err
+	}
+	return resp.toFile(), nil
+}
+
 // 快传
 func (y *Cloud189PC) FastUpload(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up driver.UpdateProgress, isFamily bool, overwrite bool) (model.Obj, error) {
 	generateTorrent := y.Addition.GenerateTorrent && !isCASTorrentFile(file.GetName())
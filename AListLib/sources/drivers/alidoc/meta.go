@@ -7,7 +7,7 @@ import (
 
 type Addition struct {
 	driver.RootID
-	Cookie string `json:"cookie" type:"text" required:"true" help:"钉钉文档网页 Cookie"`
+	Cookie string `json:"cookie" type:"text" required:"true" help:"钉钉文档网页 Cookie" confidential:"true"`
 }
 
 var config = driver.Config{
@@ -8,10 +8,10 @@ import (
 type Addition struct {
 	driver.RootPath
 	Address           string `json:"url" required:"true"`
-	MetaPassword      string `json:"meta_password"`
+	MetaPassword      string `json:"meta_password" confidential:"true"`
 	Username          string `json:"username"`
-	Password          string `json:"password"`
-	Token             string `json:"token"`
+	Password          string `json:"password" confidential:"true"`
+	Token             string `json:"token" confidential:"true"`
 	PassIPToUpsteam   bool   `json:"pass_ip_to_upsteam" default:"true"`
 	PassUAToUpsteam   bool   `json:"pass_ua_to_upsteam" default:"true"`
 	ForwardArchiveReq bool   `json:"forward_archive_requests" default:"true"`
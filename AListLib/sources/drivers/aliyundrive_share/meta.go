@@ -6,9 +6,9 @@ import (
 )
 
 type Addition struct {
-	RefreshToken string `json:"refresh_token" required:"true"`
+	RefreshToken string `json:"refresh_token" required:"true" confidential:"true"`
 	ShareId      string `json:"share_id" required:"true"`
-	SharePwd     string `json:"share_pwd"`
+	SharePwd     string `json:"share_pwd" confidential:"true"`
 	driver.RootID
 	OrderBy        string `json:"order_by" type:"select" options:"name,size,updated_at,created_at"`
 	OrderDirection string `json:"order_direction" type:"select" options:"ASC,DESC"`
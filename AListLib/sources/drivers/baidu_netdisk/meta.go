@@ -15,10 +15,10 @@ type Addition struct {
 	UseOnlineAPI          bool   `json:"use_online_api" default:"true"`
 	APIAddress            string `json:"api_url_address" default:"https://api.oplist.org/baiduyun/renewapi"`
 	ClientID              string `json:"client_id"`
-	ClientSecret          string `json:"client_secret"`
+	ClientSecret          string `json:"client_secret" confidential:"true"`
 	CustomCrackUA         string `json:"custom_crack_ua" required:"true" default:"netdisk"`
 	AccessToken           string
-	RefreshToken          string `json:"refresh_token" required:"true"`
+	RefreshToken          string `json:"refresh_token" required:"true" confidential:"true"`
 	UploadThread          string `json:"upload_thread" default:"3" help:"1<=thread<=32"`
 	UploadSliceTimeout    int    `json:"upload_timeout" type:"number" default:"60" help:"per-slice upload timeout in seconds"`
 	UploadAPI             string `json:"upload_api" default:"https://d.pcs.baidu.com"`
@@ -7,7 +7,7 @@ import (
 
 type Addition struct {
 	// RefreshToken string `json:"refresh_token" required:"true"`
-	Cookie   string `json:"cookie" required:"true"`
+	Cookie   string `json:"cookie" required:"true" confidential:"true"`
 	ShowType string `json:"show_type" type:"select" options:"root,root_only_album,root_only_file" default:"root"`
 	AlbumID  string `json:"album_id"`
 	//AlbumPassword string `json:"album_password"`
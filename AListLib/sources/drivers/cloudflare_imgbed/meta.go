@@ -8,7 +8,7 @@ import (
 type Addition struct {
 	driver.RootPath
 	Address          string `json:"address" required:"true" help:"Backend API address of the image hosting service, e.g., https://img.example.com"`
-	Token            string `json:"token" required:"true" help:"Authentication Token"`
+	Token            string `json:"token" required:"true" help:"Authentication Token" confidential:"true"`
 	SmallChannelName string `json:"smallChannelName" help:"Channel name for regular files (typically <20MB)"`
 	LargeChannelName string `json:"largeChannelName" help:"Channel name for large files"`
 	LargeChannelType string `json:"largeChannelType" type:"select" options:",huggingface,telegram,cfr2,s3,discord" help:"Large File Channel Type: Hugging Face (Direct Upload)、telegram/cfr2/s3/discord(Multipart Upload)"`
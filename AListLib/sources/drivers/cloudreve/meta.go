@@ -11,8 +11,8 @@ type Addition struct {
 	// define other
 	Address                  string `json:"address" required:"true"`
 	Username                 string `json:"username"`
-	Password                 string `json:"password"`
-	Cookie                   string `json:"cookie"`
+	Password                 string `json:"password" confidential:"true"`
+	Cookie                   string `json:"cookie" confidential:"true"`
 	CustomUA                 string `json:"custom_ua"`
 	EnableThumbAndFolderSize bool   `json:"enable_thumb_and_folder_size"`
 }
@@ -8,7 +8,7 @@ import (
 type Addition struct {
 	driver.RootID
 	Repo          string `json:"repo" type:"string" required:"true"`
-	Token         string `json:"token" type:"string" required:"true"`
+	Token         string `json:"token" type:"string" required:"true" confidential:"true"`
 	UseTagName    bool   `json:"use_tag_name" type:"bool" default:"false" help:"Use tag name instead of release name"`
 	DefaultBranch string `json:"default_branch" type:"string" default:"main" help:"Default branch for new releases"`
 }
@@ -8,9 +8,9 @@ import (
 type Addition struct {
 	driver.RootID
 	Username     string `json:"username" help:"Your Degoo account email"`
-	Password     string `json:"password" help:"Your Degoo account password"`
-	RefreshToken string `json:"refresh_token" help:"Refresh token for automatic token renewal, obtained automatically"`
-	AccessToken  string `json:"access_token" help:"Access token for Degoo API, obtained automatically"`
+	Password     string `json:"password" help:"Your Degoo account password" confidential:"true"`
+	RefreshToken string `json:"refresh_token" help:"Refresh token for automatic token renewal, obtained automatically" confidential:"true"`
+	AccessToken  string `json:"access_token" help:"Access token for Degoo API, obtained automatically" confidential:"true"`
 }
 
 var config = driver.Config{
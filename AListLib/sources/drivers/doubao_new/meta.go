@@ -9,9 +9,9 @@ type Addition struct {
 	// Usually one of two
 	driver.RootID
 	// define other
-	Cookie         string `json:"cookie" required:"true" help:"Web Cookie"`
+	Cookie         string `json:"cookie" required:"true" help:"Web Cookie" confidential:"true"`
 	AppID          string `json:"app_id" required:"true" default:"497858" help:"Doubao App ID"`
-	DPoPKeySecret  string `json:"dpop_key_secret" help:"DPoP Key Secret for generating DPoP token"`
+	DPoPKeySecret  string `json:"dpop_key_secret" help:"DPoP Key Secret for generating DPoP token" confidential:"true"`
 	AuthClientID   string `json:"auth_client_id" help:"Doubao Biz Auth Client ID"`
 	AuthClientType string `json:"auth_client_type" help:"Doubao Biz Auth Client Type"`
 	AuthScope      string `json:"auth_scope" help:"Doubao Biz Auth Scope"`
@@ -8,10 +8,10 @@ import (
 type Addition struct {
 	driver.RootID
 	URL        string `json:"url" required:"true"`
-	ApiKey     string `json:"api_key"`
+	ApiKey     string `json:"api_key" confidential:"true"`
 	UserID     string `json:"user_id"`
 	Username   string `json:"username"`
-	Password   string `json:"password"`
+	Password   string `json:"password" confidential:"true"`
 	LinkMethod string `json:"link_method" type:"select" options:"stream,download" default:"stream"`
 }
 
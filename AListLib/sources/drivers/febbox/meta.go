@@ -8,7 +8,7 @@ import (
 type Addition struct {
 	driver.RootID
 	ClientID     string `json:"client_id" required:"true" default:""`
-	ClientSecret string `json:"client_secret" required:"true" default:""`
+	ClientSecret string `json:"client_secret" required:"true" default:"" confidential:"true"`
 	RefreshToken string
 	SortRule     string `json:"sort_rule" required:"true" type:"select" options:"size_asc,size_desc,name_asc,name_desc,update_asc,update_desc,ext_asc,ext_desc" default:"name_asc"`
 	PageSize     int64  `json:"page_size" required:"true" type:"number" default:"100" help:"list api per page size of FebBox driver"`
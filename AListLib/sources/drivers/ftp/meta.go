@@ -26,7 +26,7 @@ type Addition struct {
 	Address  string `json:"address" required:"true"`
 	Encoding string `json:"encoding" required:"true"`
 	Username string `json:"username" required:"true"`
-	Password string `json:"password" required:"true"`
+	Password string `json:"password" required:"true" confidential:"true"`
 	CwdList  bool   `json:"cwd_list" type:"bool" default:"false" help:"enter directory before listing"`
 	driver.RootPath
 }
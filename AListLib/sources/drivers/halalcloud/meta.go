@@ -9,12 +9,12 @@ type Addition struct {
 	// Usually one of two
 	driver.RootPath
 	// define other
-	RefreshToken string `json:"refresh_token" required:"true" help:"login type is refresh_token,this is required"`
+	RefreshToken string `json:"refresh_token" required:"true" help:"login type is refresh_token,this is required" confidential:"true"`
 	UploadThread string `json:"upload_thread" default:"3" help:"1 <= thread <= 32"`
 
 	AppID      string `json:"app_id" required:"true" default:"openlist/10001"`
 	AppVersion string `json:"app_version" required:"true" default:"1.0.0"`
-	AppSecret  string `json:"app_secret" required:"true" default:"bR4SJwOkvnG5WvVJ"`
+	AppSecret  string `json:"app_secret" required:"true" default:"bR4SJwOkvnG5WvVJ" confidential:"true"`
 }
 
 var config = driver.Config{
@@ -10,7 +10,7 @@ type Addition struct {
 
 	Address  string `json:"address" required:"true"`
 	UserName string `json:"username" required:"false"`
-	Password string `json:"password" required:"false"`
+	Password string `json:"password" required:"false" confidential:"true"`
 }
 
 var config = driver.Config{
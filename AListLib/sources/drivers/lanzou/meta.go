@@ -9,12 +9,12 @@ type Addition struct {
 	Type string `json:"type" type:"select" options:"account,cookie,url" default:"cookie"`
 
 	Account  string `json:"account"`
-	Password string `json:"password"`
+	Password string `json:"password" confidential:"true"`
 
-	Cookie string `json:"cookie" help:"about 15 days valid, ignore if shareUrl is used"`
+	Cookie string `json:"cookie" help:"about 15 days valid, ignore if shareUrl is used" confidential:"true"`
 
 	driver.RootID
-	SharePassword  string `json:"share_password"`
+	SharePassword  string `json:"share_password" confidential:"true"`
 	BaseUrl        string `json:"baseUrl" required:"true" default:"https://pc.woozooo.com" help:"basic URL for file operation"`
 	ShareUrl       string `json:"shareUrl" required:"true" default:"https://pan.lanzoui.com" help:"used to get the sharing page"`
 	UserAgent      string `json:"user_agent" required:"true" default:"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.39 (KHTML, like Gecko) Chrome/142.0.0.0 Safari/537.39"`
@@ -6,7 +6,7 @@ import (
 )
 
 type Addition struct {
-	AccessToken string `json:"access_token" required:"true"`
+	AccessToken string `json:"access_token" required:"true" confidential:"true"`
 	ProjectID   string `json:"project_id"`
 	driver.RootID
 	OrderBy   string `json:"order_by" type:"select" options:"updated_at,title,size" default:"title"`
@@ -10,9 +10,9 @@ type Addition struct {
 	//driver.RootPath
 	//driver.RootID
 	Email       string `json:"email" required:"true"`
-	Password    string `json:"password" required:"true"`
+	Password    string `json:"password" required:"true" confidential:"true"`
 	TwoFACode   string `json:"two_fa_code" required:"false" help:"2FA 6-digit code, filling in the 2FA code alone will not support reloading driver"`
-	TwoFASecret string `json:"two_fa_secret" required:"false" help:"2FA secret"`
+	TwoFASecret string `json:"two_fa_secret" required:"false" help:"2FA secret" confidential:"true"`
 	MoveToTrash bool   `json:"move_to_trash" default:"true" help:"move to trash when deleting files"`
 }
 
@@ -10,7 +10,7 @@ import (
 type Addition struct {
 	driver.RootPath
 	ShareLinkURL       string `json:"url" required:"true"`
-	ShareLinkPassword  string `json:"password"`
+	ShareLinkPassword  string `json:"password" confidential:"true"`
 	DisableDiskUsage   bool   `json:"disable_disk_usage" default:"false"`
 	EnableDirectUpload bool   `json:"enable_direct_upload" default:"false" help:"Allow uploading directly to OneDrive without going through OpenList"`
 	IsSharepoint       bool
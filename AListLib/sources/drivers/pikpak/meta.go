@@ -8,9 +8,9 @@ import (
 type Addition struct {
 	driver.RootID
 	Username         string `json:"username" required:"true"`
-	Password         string `json:"password" required:"true"`
+	Password         string `json:"password" required:"true" confidential:"true"`
 	Platform         string `json:"platform" required:"true" default:"web" type:"select" options:"android,web,pc"`
-	RefreshToken     string `json:"refresh_token" required:"true" default:""`
+	RefreshToken     string `json:"refresh_token" required:"true" default:"" confidential:"true"`
 	CaptchaToken     string `json:"captcha_token" default:""`
 	DeviceID         string `json:"device_id"  required:"false" default:""`
 	DisableMediaLink bool   `json:"disable_media_link" default:"true"`
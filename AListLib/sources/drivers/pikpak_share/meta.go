@@ -8,7 +8,7 @@ import (
 type Addition struct {
 	driver.RootID
 	ShareId               string `json:"share_id" required:"true"`
-	SharePwd              string `json:"share_pwd"`
+	SharePwd              string `json:"share_pwd" confidential:"true"`
 	Platform              string `json:"platform" default:"web" required:"true" type:"select" options:"android,web,pc"`
 	DeviceID              string `json:"device_id"  required:"false" default:""`
 	UseTransCodingAddress bool   `json:"use_transcoding_address" required:"true" default:"false"`
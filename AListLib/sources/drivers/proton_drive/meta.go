@@ -24,7 +24,7 @@ import (
 type Addition struct {
 	driver.RootID
 	Email              string `json:"email" required:"true" type:"string"`
-	Password           string `json:"password" required:"true" type:"string"`
+	Password           string `json:"password" required:"true" type:"string" confidential:"true"`
 	TwoFACode          string `json:"two_fa_code" type:"string"`
 	ChunkSize          int64  `json:"chunk_size" type:"number" default:"100"`
 	UseReusableLogin   bool   `json:"use_reusable_login" type:"bool" default:"true" help:"Use reusable login credentials instead of username/password"`
@@ -158,16 +158,16 @@ func (d *QuarkOrUC) Put(ctx context.Context, dstDir model.Obj, stream model.File
 		}
 	}
 	// pre
-	pre, err := d.upPre(stream, dstDir.GetID())
+	pre, err := d.upPre(stream.GetName(), stream.GetMimetype(), stream.GetSize(), dstDir.GetID())
 	if err != nil {
 		return err
 	}
 	// hash
-	finish, err := d.upHash(md5Str, sha1Str, pre.Data.TaskId)
+	hashResp, err := d.upHash(md5Str, sha1Str, pre.Data.TaskId)
 	if err != nil {
 		return err
 	}
-	if finish {
+	if hashResp.Data.Finish {
 		up(100)
 		return nil
 	}
@@ -222,6 +222,34 @@ func (d *QuarkOrUC) Put(ctx context.Context, dstDir model.Obj, stream model.File
 	return d.upFinish(pre)
 }
 
+// RapidPut 秒传只需要整个文件的 MD5 和 SHA1
+func (d *QuarkOrUC) RapidPut(ctx context.Context, dstDir model.Obj, name string, size int64, hash utils.HashInfo) (model.Obj, error) {
+	md5Str, sha1Str := hash.GetHash(utils.MD5), hash.GetHash(utils.SHA1)
+	if len(md5Str) != utils.MD5.Width || len(sha1Str) != utils.SHA1.Width {
+		return nil, errs.NotSupport
+	}
+	pre, err := d.upPre(name, utils.GetMimeType(name), size, dstDir.GetID())
+	if err != nil {
+		return nil, err
+	}
+	hashResp, err := d.upHash(md5Str, sha1Str, pre.Data.TaskId)
+	if err != nil {
+		return nil, err
+	}
+	if !hashResp.Data.Finish {
+		return nil, errs.RapidUploadMiss
+	}
+	now := time.Now()
+	return &model.Object{
+		ID:       hashResp.Data.Fid,
+		Name:     name,
+		Size:     size,
+		Modified: now,
+		Ctime:    now,
+		HashInfo: hash,
+	}, nil
+}
+
 func (d *QuarkOrUC) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 	memberInfo, err := d.memberInfo(ctx)
 	if err != nil {
@@ -235,4 +263,7 @@ func (d *QuarkOrUC) GetDetails(ctx context.Context) (*model.StorageDetails, erro
 	}, nil
 }
 
-var _ driver.Driver = (*QuarkOrUC)(nil)
+var (
+	_ driver.Driver   = (*QuarkOrUC)(nil)
+	_ driver.RapidPut = (*QuarkOrUC)(nil)
+)
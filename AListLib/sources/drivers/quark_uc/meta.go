@@ -6,7 +6,7 @@ import (
 )
 
 type Addition struct {
-	Cookie string `json:"cookie" required:"true"`
+	Cookie string `json:"cookie" required:"true" confidential:"true"`
 	driver.RootID
 	OrderBy               string `json:"order_by" type:"select" options:"none,file_type,file_name,updated_at" default:"none"`
 	OrderDirection        string `json:"order_direction" type:"select" options:"asc,desc" default:"asc"`
@@ -167,17 +167,17 @@ func (d *QuarkOrUC) getTranscodingLink(file model.Obj) (*model.Link, error) {
 	return nil, errors.New("no link found")
 }
 
-func (d *QuarkOrUC) upPre(file model.FileStreamer, parentId string) (UpPreResp, error) {
+func (d *QuarkOrUC) upPre(name, mimetype string, size int64, parentId string) (UpPreResp, error) {
 	now := time.Now()
 	data := base.Json{
 		"ccp_hash_update": true,
 		"dir_name":        "",
-		"file_name":       file.GetName(),
-		"format_type":     file.GetMimetype(),
+		"file_name":       name,
+		"format_type":     mimetype,
 		"l_created_at":    now.UnixMilli(),
 		"l_updated_at":    now.UnixMilli(),
 		"pdir_fid":        parentId,
-		"size":            file.GetSize(),
+		"size":            size,
 		//"same_path_reuse": true,
 	}
 	var resp UpPreResp
@@ -187,7 +187,7 @@ func (d *QuarkOrUC) upPre(file model.FileStreamer, parentId string) (UpPreResp,
 	return resp, err
 }
 
-func (d *QuarkOrUC) upHash(md5, sha1, taskId string) (bool, error) {
+func (d *QuarkOrUC) upHash(md5, sha1, taskId string) (HashResp, error) {
 	data := base.Json{
 		"md5":     md5,
 		"sha1":    sha1,
@@ -198,7 +198,7 @@ func (d *QuarkOrUC) upHash(md5, sha1, taskId string) (bool, error) {
 	_, err := d.request("/file/update/hash", http.MethodPost, func(req *resty.Request) {
 		req.SetBody(data)
 	}, &resp)
-	return resp.Data.Finish, err
+	return resp, err
 }
 
 func (d *QuarkOrUC) upPart(ctx context.Context, pre UpPreResp, mineType string, partNumber int, bytes io.Reader) (string, error) {
@@ -11,7 +11,7 @@ type Addition struct {
 	OrderBy        string `json:"order_by" type:"select" options:"file_name,updated_at" default:"updated_at"`
 	OrderDirection string `json:"order_direction" type:"select" options:"asc,desc" default:"desc"`
 	// define other
-	RefreshToken string `json:"refresh_token" required:"false" default:""`
+	RefreshToken string `json:"refresh_token" required:"false" default:"" confidential:"true"`
 	// 必要且影响登录,由签名决定
 	DeviceID string `json:"device_id"  required:"false" default:""`
 	// 登陆所用的数据 无需手动填写
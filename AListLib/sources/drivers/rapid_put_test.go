@@ -0,0 +1,56 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	_115 "github.com/OpenListTeam/OpenList/v4/drivers/115"
+	_123 "github.com/OpenListTeam/OpenList/v4/drivers/123"
+	_123_open "github.com/OpenListTeam/OpenList/v4/drivers/123_open"
+	_189pc "github.com/OpenListTeam/OpenList/v4/drivers/189pc"
+	"github.com/OpenListTeam/OpenList/v4/drivers/aliyundrive"
+	"github.com/OpenListTeam/OpenList/v4/drivers/baidu_netdisk"
+	"github.com/OpenListTeam/OpenList/v4/drivers/quark_open"
+	quark "github.com/OpenListTeam/OpenList/v4/drivers/quark_uc"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+func TestRapidPut(t *testing.T) {
+	for name, d := range map[string]driver.Driver{
+		"123":      &_123.Pan123{},
+		"123 Open": &_123_open.Open123{},
+		"189 PC":   &_189pc.Cloud189PC{},
+		"Quark":    &quark.QuarkOrUC{},
+	} {
+		if _, ok := d.(driver.RapidPut); !ok {
+			t.Errorf("%s does not implement the rapid put", name)
+		}
+	}
+	// the rapid uploads of these backends prove the content besides its hash,
+	// so they can not be done from a hash manifest
+	for name, d := range map[string]driver.Driver{
+		// the sha1 of the first 128KB and of a range chosen by the server
+		"115": &_115.Pan115{},
+		// the proof code read from the content at an offset derived from the token
+		"Aliyundrive": &aliyundrive.AliDrive{},
+		// the md5 of each 4MB block and of the first 256KB
+		"Baidu Netdisk": &baidu_netdisk.BaiduNetdisk{},
+		// the proof codes read from the content
+		"Quark Open": &quark_open.QuarkOpen{},
+	} {
+		if _, ok := d.(driver.RapidPut); ok {
+			t.Errorf("%s can not rapid put from the hash only", name)
+		}
+	}
+
+	// both the md5 and the sha1 are required by quark
+	_, err := (&quark.QuarkOrUC{}).RapidPut(context.Background(), &model.Object{ID: "0", IsFolder: true}, "a.txt", 5,
+		utils.NewHashInfo(utils.MD5, "5d41402abc4b2a76b9719d911017c592"))
+	if !errors.Is(err, errs.NotSupport) {
+		t.Errorf("expect the missing sha1 to be refused, got %v", err)
+	}
+}
@@ -11,8 +11,8 @@ type Addition struct {
 	Endpoint                 string `json:"endpoint" required:"true"`
 	Region                   string `json:"region"`
 	AccessKeyID              string `json:"access_key_id" required:"true"`
-	SecretAccessKey          string `json:"secret_access_key" required:"true"`
-	SessionToken             string `json:"session_token"`
+	SecretAccessKey          string `json:"secret_access_key" required:"true" confidential:"true"`
+	SessionToken             string `json:"session_token" confidential:"true"`
 	CustomHost               string `json:"custom_host"`
 	EnableCustomHostPresign  bool   `json:"enable_custom_host_presign"`
 	SignURLExpire            int    `json:"sign_url_expire" type:"number" default:"4"`
@@ -10,8 +10,8 @@ type Addition struct {
 
 	Address  string `json:"address" required:"true"`
 	UserName string `json:"username" required:"false"`
-	Password string `json:"password" required:"false"`
-	Token    string `json:"token" required:"false"`
+	Password string `json:"password" required:"false" confidential:"true"`
+	Token    string `json:"token" required:"false" confidential:"true"`
 	RepoId   string `json:"repoId" required:"false"`
 	RepoPwd  string `json:"repoPwd" required:"false"`
 }
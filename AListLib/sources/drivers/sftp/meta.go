@@ -8,9 +8,9 @@ import (
 type Addition struct {
 	Address    string `json:"address" required:"true"`
 	Username   string `json:"username" required:"true"`
-	PrivateKey string `json:"private_key" type:"text"`
-	Password   string `json:"password"`
-	Passphrase string `json:"passphrase"`
+	PrivateKey string `json:"private_key" type:"text" confidential:"true"`
+	Password   string `json:"password" confidential:"true"`
+	Passphrase string `json:"passphrase" confidential:"true"`
 	driver.RootPath
 	IgnoreSymlinkError bool `json:"ignore_symlink_error" default:"false" info:"Ignore symlink error"`
 }
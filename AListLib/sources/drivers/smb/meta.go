@@ -9,7 +9,7 @@ type Addition struct {
 	driver.RootPath
 	Address   string `json:"address" required:"true"`
 	Username  string `json:"username" required:"true"`
-	Password  string `json:"password"`
+	Password  string `json:"password" confidential:"true"`
 	ShareName string `json:"share_name" required:"true"`
 }
 
@@ -8,7 +8,7 @@ import (
 type Addition struct {
 	driver.RootPath
 	Address           string `json:"url" required:"true"`
-	Cookie            string `json:"cookie" type:"string" required:"true" help:"access_token=xxx"`
+	Cookie            string `json:"cookie" type:"string" required:"true" help:"access_token=xxx" confidential:"true"`
 	UseShareLink      bool   `json:"use_share_link" type:"bool" default:"false" help:"Create share link when getting link to support 302. If disabled, you need to enable web proxy."`
 	ChunkSize         int64  `json:"chunk_size" type:"number" default:"10" help:"Chunk size in MiB"`
 	RandomChunkName   bool   `json:"random_chunk_name" type:"bool" default:"true" help:"Random chunk name"`
@@ -7,7 +7,7 @@ import (
 
 type Addition struct {
 	driver.RootPath
-	Cookie string `json:"cookie" required:"true"`
+	Cookie string `json:"cookie" required:"true" confidential:"true"`
 	//JsToken        string `json:"js_token" type:"string" required:"true"`
 	DownloadAPI    string `json:"download_api" type:"select" options:"official,crack" default:"official"`
 	OrderBy        string `json:"order_by" type:"select" options:"name,time,size" default:"name"`
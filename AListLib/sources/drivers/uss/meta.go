@@ -10,7 +10,7 @@ type Addition struct {
 	Bucket              string `json:"bucket" required:"true"`
 	Endpoint            string `json:"endpoint" required:"true"`
 	OperatorName        string `json:"operator_name" required:"true"`
-	OperatorPassword    string `json:"operator_password" required:"true"`
+	OperatorPassword    string `json:"operator_password" required:"true" confidential:"true"`
 	AntiTheftChainToken string `json:"anti_theft_chain_token" required:"false" default:""`
 	//CustomHost       string `json:"custom_host"`	//Endpoint与CustomHost作用相同，去除
 	SignURLExpire int `json:"sign_url_expire" type:"number" default:"4"`
@@ -9,11 +9,11 @@ type Addition struct {
 	// Usually one of two
 	driver.RootID
 	// define other
-	RefreshToken string `json:"refresh_token" required:"true"`
+	RefreshToken string `json:"refresh_token" required:"true" confidential:"true"`
 	FamilyID     string `json:"family_id" help:"Keep it empty if you want to use your personal drive"`
 	SortRule     string `json:"sort_rule" type:"select" options:"name_asc,name_desc,time_asc,time_desc,size_asc,size_desc" default:"name_asc"`
 
-	AccessToken string `json:"access_token"`
+	AccessToken string `json:"access_token" confidential:"true"`
 }
 
 var config = driver.Config{
@@ -7,7 +7,7 @@ import (
 
 type Addition struct {
 	driver.RootPath
-	Cookie   string `json:"cookie" required:"true"`
+	Cookie   string `json:"cookie" required:"true" confidential:"true"`
 	Mode     string `json:"mode" type:"select" options:"Personal,Business" default:"Personal"`
 	CustomUA string `json:"custom_ua"`
 }
@@ -0,0 +1,246 @@
+// Package backup exports and imports the configuration stored in the database:
+// storages, users, metas, settings, sharings and ssh public keys.
+//
+// An archive starts with the magic "OLBK", a format version and a flag byte,
+// followed by the gzip compressed JSON of Data. When a password is given the
+// compressed data is sealed with AES-256-GCM using a key derived by scrypt.
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// Version is the version of Data, increase it when the layout changes
+	Version = 1
+
+	magic         = "OLBK"
+	formatVersion = 1
+	flagEncrypted = 1 << 0
+
+	saltSize = 16
+	keySize  = 32
+	// maxArchiveSize limits the decompressed size of an archive
+	maxArchiveSize = 256 * 1024 * 1024
+)
+
+var (
+	ErrInvalidArchive   = errors.New("not a valid backup archive")
+	ErrPasswordRequired = errors.New("backup is encrypted, password is required")
+	ErrWrongPassword    = errors.New("wrong password or corrupted backup")
+)
+
+type Data struct {
+	Version       int                 `json:"version"`
+	Created       time.Time           `json:"created"`
+	AppVersion    string              `json:"app_version"`
+	Storages      []model.Storage     `json:"storages"`
+	Users         []User              `json:"users"`
+	Metas         []model.Meta        `json:"metas"`
+	Settings      []model.SettingItem `json:"settings"`
+	Sharings      []Sharing           `json:"sharings"`
+	SSHPublicKeys []SSHPublicKey      `json:"ssh_public_keys"`
+}
+
+// User carries the fields of model.User that are hidden from json
+type User struct {
+	model.User
+	PwdHash   string `json:"pwd_hash"`
+	PwdTS     int64  `json:"pwd_ts"`
+	Salt      string `json:"salt"`
+	OtpSecret string `json:"otp_secret"`
+	Authn     string `json:"authn"`
+}
+
+type Sharing struct {
+	model.SharingDB
+	FilesRaw  string `json:"files_raw"`
+	CreatorId uint   `json:"creator_id"`
+}
+
+type SSHPublicKey struct {
+	model.SSHPublicKey
+	UserId uint   `json:"user_id"`
+	KeyStr string `json:"key_str"`
+}
+
+func (u User) toModel() model.User {
+	m := u.User
+	m.PwdHash, m.PwdTS, m.Salt, m.OtpSecret, m.Authn = u.PwdHash, u.PwdTS, u.Salt, u.OtpSecret, u.Authn
+	m.Password = ""
+	return m
+}
+
+func (s Sharing) toModel() model.SharingDB {
+	m := s.SharingDB
+	m.FilesRaw, m.CreatorId = s.FilesRaw, s.CreatorId
+	return m
+}
+
+func (k SSHPublicKey) toModel() model.SSHPublicKey {
+	m := k.SSHPublicKey
+	m.UserId, m.KeyStr = k.UserId, k.KeyStr
+	return m
+}
+
+// Export reads the configuration from the database
+func Export() (*Data, error) {
+	data := &Data{
+		Version:    Version,
+		Created:    time.Now(),
+		AppVersion: conf.Version,
+	}
+	var err error
+	if data.Storages, _, err = db.GetStorages(1, model.MaxInt); err != nil {
+		return nil, errors.WithMessage(err, "failed get storages")
+	}
+	users, _, err := db.GetUsers(1, model.MaxInt)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get users")
+	}
+	for _, u := range users {
+		data.Users = append(data.Users, User{
+			User:      u,
+			PwdHash:   u.PwdHash,
+			PwdTS:     u.PwdTS,
+			Salt:      u.Salt,
+			OtpSecret: u.OtpSecret,
+			Authn:     u.Authn,
+		})
+	}
+	if data.Metas, _, err = db.GetMetas(1, model.MaxInt); err != nil {
+		return nil, errors.WithMessage(err, "failed get metas")
+	}
+	if data.Settings, err = db.GetSettingItems(); err != nil {
+		return nil, errors.WithMessage(err, "failed get settings")
+	}
+	sharings, _, err := db.GetSharings(1, model.MaxInt)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get sharings")
+	}
+	for _, s := range sharings {
+		data.Sharings = append(data.Sharings, Sharing{SharingDB: s, FilesRaw: s.FilesRaw, CreatorId: s.CreatorId})
+	}
+	keys, _, err := db.GetSSHPublicKeys(1, model.MaxInt)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get ssh public keys")
+	}
+	for _, k := range keys {
+		data.SSHPublicKeys = append(data.SSHPublicKeys, SSHPublicKey{SSHPublicKey: k, UserId: k.UserId, KeyStr: k.KeyStr})
+	}
+	return data, nil
+}
+
+// Encode writes data as an archive, it is encrypted if password is not empty
+func Encode(w io.Writer, data *Data, password string) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(data); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := gz.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	header := []byte{magic[0], magic[1], magic[2], magic[3], formatVersion, 0}
+	payload := buf.Bytes()
+	if password != "" {
+		header[len(header)-1] |= flagEncrypted
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return errors.WithStack(err)
+		}
+		gcm, err := newGCM(password, salt)
+		if err != nil {
+			return err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return errors.WithStack(err)
+		}
+		// the header is authenticated so that the flags can not be altered
+		payload = gcm.Seal(append(salt, nonce...), nonce, payload, header)
+	}
+	if _, err := w.Write(header); err != nil {
+		return errors.WithStack(err)
+	}
+	_, err := w.Write(payload)
+	return errors.WithStack(err)
+}
+
+// Decode reads an archive written by Encode
+func Decode(r io.Reader, password string) (*Data, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic)+2)
+	if _, err := io.ReadFull(br, header); err != nil || string(header[:len(magic)]) != magic {
+		return nil, ErrInvalidArchive
+	}
+	if header[len(magic)] != formatVersion {
+		return nil, errors.Errorf("unsupported backup format version %d", header[len(magic)])
+	}
+	var payload io.Reader = br
+	if header[len(magic)+1]&flagEncrypted != 0 {
+		if password == "" {
+			return nil, ErrPasswordRequired
+		}
+		raw, err := io.ReadAll(io.LimitReader(br, maxArchiveSize))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if len(raw) < saltSize {
+			return nil, ErrInvalidArchive
+		}
+		gcm, err := newGCM(password, raw[:saltSize])
+		if err != nil {
+			return nil, err
+		}
+		raw = raw[saltSize:]
+		if len(raw) < gcm.NonceSize() {
+			return nil, ErrInvalidArchive
+		}
+		plain, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], header)
+		if err != nil {
+			return nil, ErrWrongPassword
+		}
+		payload = bytes.NewReader(plain)
+	}
+	gz, err := gzip.NewReader(payload)
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+	defer gz.Close()
+	var data Data
+	if err := json.NewDecoder(io.LimitReader(gz, maxArchiveSize)).Decode(&data); err != nil {
+		return nil, errors.Wrap(err, "failed decode backup")
+	}
+	if data.Version < 1 || data.Version > Version {
+		return nil, errors.Errorf("unsupported backup version %d", data.Version)
+	}
+	return &data, nil
+}
+
+func newGCM(password string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(password), salt, 1<<15, 8, 1, keySize)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	return gcm, errors.WithStack(err)
+}
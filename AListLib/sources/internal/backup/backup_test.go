@@ -0,0 +1,121 @@
+package backup_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/backup"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestExportImport(t *testing.T) {
+	admin := &model.User{Username: "admin", Role: model.ADMIN, Permission: 0xFFFF}
+	admin.SetPassword("admin")
+	bob := &model.User{Username: "bob", Role: model.GENERAL, BasePath: "/", OtpSecret: "otp"}
+	bob.SetPassword("bob")
+	for _, u := range []*model.User{admin, bob} {
+		if err := db.CreateUser(u); err != nil {
+			t.Fatalf("failed create user: %+v", err)
+		}
+	}
+	if err := db.CreateStorage(&model.Storage{Driver: "Local", MountPath: "/local", Addition: `{"root_folder_path":"."}`}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	if err := db.CreateMeta(&model.Meta{Path: "/local", ReadUsers: []uint{bob.ID}}); err != nil {
+		t.Fatalf("failed create meta: %+v", err)
+	}
+	if _, err := db.CreateSharing(&model.SharingDB{ID: "share1", FilesRaw: `["/local/a"]`, CreatorId: bob.ID}); err != nil {
+		t.Fatalf("failed create sharing: %+v", err)
+	}
+
+	data, err := backup.Export()
+	if err != nil {
+		t.Fatalf("failed export: %+v", err)
+	}
+	var buf bytes.Buffer
+	if err := backup.Encode(&buf, data, "secret"); err != nil {
+		t.Fatalf("failed encode: %+v", err)
+	}
+	archive := buf.Bytes()
+	if _, err := backup.Decode(bytes.NewReader(archive), ""); !errors.Is(err, backup.ErrPasswordRequired) {
+		t.Fatalf("expected password required, got %v", err)
+	}
+	if _, err := backup.Decode(bytes.NewReader(archive), "wrong"); !errors.Is(err, backup.ErrWrongPassword) {
+		t.Fatalf("expected wrong password, got %v", err)
+	}
+	data, err = backup.Decode(bytes.NewReader(archive), "secret")
+	if err != nil {
+		t.Fatalf("failed decode: %+v", err)
+	}
+
+	// lose bob and everything that refers to him
+	meta, _ := db.GetMetaByPath("/local")
+	_ = db.DeleteMetaById(meta.ID)
+	_ = db.DeleteSharingById("share1")
+	_ = db.DeleteUserById(bob.ID)
+	// keep bob's id from being reused
+	_ = db.CreateUser(&model.User{Username: "carol", Role: model.GENERAL})
+
+	report, err := backup.Import(context.Background(), data, backup.ImportOptions{Mode: backup.ModeMerge, DryRun: true})
+	if err != nil {
+		t.Fatalf("failed dry run: %+v", err)
+	}
+	if report.Users.Created != 1 || report.Metas.Created != 1 || report.Sharings.Created != 1 {
+		t.Fatalf("unexpected dry run report: %+v", report)
+	}
+	if _, err := db.GetUserByName("bob"); err == nil {
+		t.Fatalf("dry run should not create users")
+	}
+
+	report, err = backup.Import(context.Background(), data, backup.ImportOptions{Mode: backup.ModeMerge})
+	if err != nil {
+		t.Fatalf("failed import: %+v", err)
+	}
+	if report.Users.Created != 1 || report.Users.Skipped != 1 || report.Storages.Skipped != 1 {
+		t.Fatalf("unexpected import report: %+v", report)
+	}
+	restored, err := db.GetUserByName("bob")
+	if err != nil {
+		t.Fatalf("bob is not restored: %+v", err)
+	}
+	if restored.ID == bob.ID || restored.PwdHash != bob.PwdHash || restored.Salt != bob.Salt || restored.OtpSecret != "otp" {
+		t.Fatalf("bob is not restored with his secrets: %+v", restored)
+	}
+	if err := restored.ValidateRawPassword("bob"); err != nil {
+		t.Fatalf("bob can not log in: %+v", err)
+	}
+	meta, err = db.GetMetaByPath("/local")
+	if err != nil || len(meta.ReadUsers) != 1 || meta.ReadUsers[0] != restored.ID {
+		t.Fatalf("meta users are not remapped: %+v, %v", meta, err)
+	}
+	sharing, err := db.GetSharingById("share1")
+	if err != nil || sharing.CreatorId != restored.ID || sharing.FilesRaw != `["/local/a"]` {
+		t.Fatalf("sharing is not restored: %+v, %v", sharing, err)
+	}
+
+	storage, _ := db.GetStorageByMountPath("/local")
+	data.Storages[0].Remark = "restored"
+	if _, err := backup.Import(context.Background(), data, backup.ImportOptions{Mode: backup.ModeOverwrite}); err != nil {
+		t.Fatalf("failed overwrite: %+v", err)
+	}
+	updated, _ := db.GetStorageByMountPath("/local")
+	if updated.ID != storage.ID || updated.Remark != "restored" {
+		t.Fatalf("storage is not overwritten: %+v", updated)
+	}
+}
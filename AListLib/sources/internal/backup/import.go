@@ -0,0 +1,390 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+type Mode string
+
+const (
+	// ModeMerge only adds the entries that do not exist yet
+	ModeMerge Mode = "merge"
+	// ModeOverwrite also replaces the existing entries with the ones in the backup
+	ModeOverwrite Mode = "overwrite"
+)
+
+type ImportOptions struct {
+	Mode Mode
+	// DryRun validates the backup against the database without writing anything
+	DryRun bool
+	// LoadStorages reloads the imported storages, it should only be set when the server is running
+	LoadStorages bool
+}
+
+type SectionReport struct {
+	Created int      `json:"created"`
+	Updated int      `json:"updated"`
+	Skipped int      `json:"skipped"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+func (r *SectionReport) fail(format string, args ...any) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+type Report struct {
+	Mode          Mode          `json:"mode"`
+	DryRun        bool          `json:"dry_run"`
+	Storages      SectionReport `json:"storages"`
+	Users         SectionReport `json:"users"`
+	Metas         SectionReport `json:"metas"`
+	Settings      SectionReport `json:"settings"`
+	Sharings      SectionReport `json:"sharings"`
+	SSHPublicKeys SectionReport `json:"ssh_public_keys"`
+}
+
+type importer struct {
+	ctx    context.Context
+	data   *Data
+	opts   ImportOptions
+	report *Report
+	// userIDs maps the user ids in the backup to the ids in the database,
+	// users that would be created in a dry run are mapped to 0
+	userIDs map[uint]uint
+	// toLoad are the storages to be loaded after the import
+	toLoad []model.Storage
+}
+
+// Import writes data to the database, entries are matched by their natural keys:
+// storages by mount path, users by name (admin and guest by role), metas by path,
+// settings by key, sharings by id and ssh public keys by user and fingerprint.
+// Entries that are not in the backup are always kept.
+func Import(ctx context.Context, data *Data, opts ImportOptions) (*Report, error) {
+	if opts.Mode == "" {
+		opts.Mode = ModeMerge
+	}
+	if opts.Mode != ModeMerge && opts.Mode != ModeOverwrite {
+		return nil, errors.Errorf("invalid import mode: %s", opts.Mode)
+	}
+	if data.Version < 1 || data.Version > Version {
+		return nil, errors.Errorf("unsupported backup version %d", data.Version)
+	}
+	im := &importer{
+		ctx:     ctx,
+		data:    data,
+		opts:    opts,
+		report:  &Report{Mode: opts.Mode, DryRun: opts.DryRun},
+		userIDs: map[uint]uint{},
+	}
+	// users go first, the other sections refer to them by id
+	im.importUsers()
+	im.importStorages()
+	im.importMetas()
+	im.importSettings()
+	im.importSharings()
+	im.importSSHPublicKeys()
+	if len(im.toLoad) > 0 {
+		go func(storages []model.Storage) {
+			for _, s := range storages {
+				if err := op.LoadStorage(context.Background(), s); err != nil {
+					utils.Log.Errorf("failed load imported storage [%s]: %+v", s.MountPath, err)
+				}
+			}
+		}(im.toLoad)
+	}
+	return im.report, nil
+}
+
+func (im *importer) overwrite() bool {
+	return im.opts.Mode == ModeOverwrite
+}
+
+func (im *importer) importUsers() {
+	r := &im.report.Users
+	for _, bu := range im.data.Users {
+		u := bu.toModel()
+		if u.Username == "" {
+			r.fail("user [%d]: username is empty", bu.ID)
+			continue
+		}
+		var (
+			old *model.User
+			err error
+		)
+		if u.IsAdmin() || u.IsGuest() {
+			old, err = db.GetUserByRole(u.Role)
+		} else {
+			old, err = db.GetUserByName(u.Username)
+			if err == nil && old.Role != u.Role {
+				r.fail("user [%s]: username is taken by a user with another role", u.Username)
+				continue
+			}
+		}
+		if err != nil {
+			old = nil
+		}
+		if old != nil {
+			im.userIDs[bu.ID] = old.ID
+			if !im.overwrite() {
+				r.Skipped++
+				continue
+			}
+			u.ID = old.ID
+			if !im.opts.DryRun {
+				if err := op.UpdateUser(&u); err != nil {
+					r.fail("user [%s]: %v", u.Username, err)
+					continue
+				}
+			}
+			r.Updated++
+			continue
+		}
+		u.ID = 0
+		if !im.opts.DryRun {
+			if err := op.CreateUser(&u); err != nil {
+				r.fail("user [%s]: %v", u.Username, err)
+				continue
+			}
+		}
+		im.userIDs[bu.ID] = u.ID
+		r.Created++
+	}
+}
+
+func (im *importer) mapUserIDs(ids []uint) []uint {
+	if ids == nil {
+		return nil
+	}
+	res := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if newID, ok := im.userIDs[id]; ok {
+			res = append(res, newID)
+		}
+	}
+	return res
+}
+
+func (im *importer) importStorages() {
+	r := &im.report.Storages
+	for _, s := range im.data.Storages {
+		s.MountPath = utils.FixAndCleanPath(s.MountPath)
+		if _, err := op.GetDriver(s.Driver); err != nil {
+			r.fail("storage [%s]: %v", s.MountPath, err)
+			continue
+		}
+		if !utils.Json.Valid([]byte(s.Addition)) {
+			r.fail("storage [%s]: addition is not valid json", s.MountPath)
+			continue
+		}
+		old, err := db.GetStorageByMountPath(s.MountPath)
+		if err != nil {
+			old = nil
+		}
+		if old != nil && !im.overwrite() {
+			r.Skipped++
+			continue
+		}
+		if im.opts.DryRun {
+			if old != nil {
+				r.Updated++
+			} else {
+				r.Created++
+			}
+			continue
+		}
+		if old != nil {
+			s.ID = old.ID
+			if im.opts.LoadStorages && !old.Disabled && op.HasStorage(old.MountPath) {
+				if err := op.DisableStorage(im.ctx, old.ID); err != nil {
+					r.fail("storage [%s]: failed drop the old storage: %v", s.MountPath, err)
+					continue
+				}
+			}
+			err = db.UpdateStorage(&s)
+		} else {
+			s.ID = 0
+			err = db.CreateStorage(&s)
+		}
+		if err != nil {
+			r.fail("storage [%s]: %v", s.MountPath, err)
+			continue
+		}
+		if old != nil {
+			r.Updated++
+		} else {
+			r.Created++
+		}
+		if im.opts.LoadStorages && !s.Disabled {
+			im.toLoad = append(im.toLoad, s)
+		}
+	}
+}
+
+func (im *importer) importMetas() {
+	r := &im.report.Metas
+	for _, m := range im.data.Metas {
+		m.Path = utils.FixAndCleanPath(m.Path)
+		m.ReadUsers = im.mapUserIDs(m.ReadUsers)
+		m.WriteUsers = im.mapUserIDs(m.WriteUsers)
+		old, err := db.GetMetaByPath(m.Path)
+		if err != nil {
+			old = nil
+		}
+		if old != nil && !im.overwrite() {
+			r.Skipped++
+			continue
+		}
+		if old != nil {
+			m.ID = old.ID
+			if !im.opts.DryRun {
+				if err := op.UpdateMeta(&m); err != nil {
+					r.fail("meta [%s]: %v", m.Path, err)
+					continue
+				}
+			}
+			r.Updated++
+			continue
+		}
+		m.ID = 0
+		if !im.opts.DryRun {
+			if err := op.CreateMeta(&m); err != nil {
+				r.fail("meta [%s]: %v", m.Path, err)
+				continue
+			}
+		}
+		r.Created++
+	}
+}
+
+// importSettings only restores the values of the settings known by this version,
+// all settings exist in the database so nothing is imported in merge mode
+func (im *importer) importSettings() {
+	r := &im.report.Settings
+	var items []model.SettingItem
+	for _, item := range im.data.Settings {
+		old, err := db.GetSettingItemByKey(item.Key)
+		if err != nil || old.Flag == model.READONLY || old.IsDeprecated() {
+			r.Skipped++
+			continue
+		}
+		if !im.overwrite() || old.Value == item.Value {
+			r.Skipped++
+			continue
+		}
+		old.Value = item.Value
+		items = append(items, *old)
+	}
+	if len(items) == 0 {
+		return
+	}
+	if !im.opts.DryRun {
+		if err := op.SaveSettingItems(items); err != nil {
+			r.fail("%v", err)
+			return
+		}
+	}
+	r.Updated += len(items)
+}
+
+func (im *importer) importSharings() {
+	r := &im.report.Sharings
+	for _, bs := range im.data.Sharings {
+		s := bs.toModel()
+		if s.ID == "" {
+			r.fail("sharing: id is empty")
+			continue
+		}
+		creatorID, ok := im.userIDs[s.CreatorId]
+		if !ok {
+			r.fail("sharing [%s]: creator is not imported", s.ID)
+			continue
+		}
+		s.CreatorId = creatorID
+		old, err := db.GetSharingById(s.ID)
+		if err != nil {
+			old = nil
+		}
+		if old != nil && !im.overwrite() {
+			r.Skipped++
+			continue
+		}
+		if old != nil {
+			if !im.opts.DryRun {
+				if err := op.UpdateSharing(&model.Sharing{SharingDB: &s}, true); err != nil {
+					r.fail("sharing [%s]: %v", s.ID, err)
+					continue
+				}
+			}
+			r.Updated++
+			continue
+		}
+		if !im.opts.DryRun {
+			if _, err := db.CreateSharing(&s); err != nil {
+				r.fail("sharing [%s]: %v", s.ID, err)
+				continue
+			}
+		}
+		r.Created++
+	}
+}
+
+func (im *importer) importSSHPublicKeys() {
+	r := &im.report.SSHPublicKeys
+	for _, bk := range im.data.SSHPublicKeys {
+		k := bk.toModel()
+		if _, err := k.GetKey(); err != nil {
+			r.fail("ssh public key [%s]: %v", k.Title, err)
+			continue
+		}
+		userID, ok := im.userIDs[k.UserId]
+		if !ok {
+			r.fail("ssh public key [%s]: user is not imported", k.Title)
+			continue
+		}
+		k.UserId = userID
+		var old *model.SSHPublicKey
+		if userID != 0 {
+			keys, _, err := db.GetSSHPublicKeyByUserId(userID, 1, model.MaxInt)
+			if err != nil {
+				r.fail("ssh public key [%s]: %v", k.Title, err)
+				continue
+			}
+			for i := range keys {
+				if keys[i].Fingerprint == k.Fingerprint {
+					old = &keys[i]
+					break
+				}
+			}
+		}
+		if old != nil && !im.overwrite() {
+			r.Skipped++
+			continue
+		}
+		if old != nil {
+			k.ID = old.ID
+			if !im.opts.DryRun {
+				if err := db.UpdateSSHPublicKey(&k); err != nil {
+					r.fail("ssh public key [%s]: %v", k.Title, err)
+					continue
+				}
+			}
+			r.Updated++
+			continue
+		}
+		k.ID = 0
+		if !im.opts.DryRun {
+			if err := db.CreateSSHPublicKey(&k); err != nil {
+				r.fail("ssh public key [%s]: %v", k.Title, err)
+				continue
+			}
+		}
+		r.Created++
+	}
+}
@@ -0,0 +1,337 @@
+// Package block_cache caches the proxied reads on the local disk in blocks of
+// a fixed size. The blocks of a file are kept under a folder named by the
+// storage and the path, and named by the version of the file, so a changed
+// file never reads the stale blocks. The least recently used blocks are
+// evicted when the size cap is exceeded.
+package block_cache
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
+	log "github.com/sirupsen/logrus"
+)
+
+// metaName is the file storing the path of the cached file in its folder
+const metaName = "path"
+
+type entry struct {
+	storageID uint
+	path      string
+	// dir is the folder of the blocks relative to the cache dir
+	dir     string
+	version string
+	blocks  map[int64]*block
+}
+
+type block struct {
+	entry *entry
+	index int64
+	size  int64
+	elem  *list.Element
+}
+
+type Cache struct {
+	dir       string
+	blockSize int64
+	maxSize   int64
+
+	mu      sync.Mutex
+	used    int64
+	lru     *list.List // of *block, the front is the most recently used
+	entries map[string]*entry
+
+	hits   atomic.Int64
+	misses atomic.Int64
+	fetchG singleflight.Group[struct{}]
+}
+
+// New creates a cache in dir and loads the blocks cached before
+func New(dir string, blockSize, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return nil, err
+	}
+	c := &Cache{
+		dir:       dir,
+		blockSize: blockSize,
+		maxSize:   maxSize,
+		lru:       list.New(),
+		entries:   make(map[string]*entry),
+	}
+	c.load()
+	return c, nil
+}
+
+func entryDir(storageID uint, path string) string {
+	sum := sha1.Sum([]byte(path))
+	return filepath.Join(strconv.FormatUint(uint64(storageID), 10), hex.EncodeToString(sum[:]))
+}
+
+func (c *Cache) blockPath(e *entry, index int64) string {
+	return filepath.Join(c.dir, e.dir, fmt.Sprintf("%s-%d", e.version, index))
+}
+
+type loadedBlock struct {
+	b       *block
+	modTime time.Time
+}
+
+// load rebuilds the index from the disk, the modified times of the blocks
+// are the approximate order of the last uses
+func (c *Cache) load() {
+	var loaded []loadedBlock
+	storages, _ := os.ReadDir(c.dir)
+	for _, s := range storages {
+		storageID, err := strconv.ParseUint(s.Name(), 10, 64)
+		if err != nil || !s.IsDir() {
+			// the temp files of the unfinished downloads
+			_ = os.RemoveAll(filepath.Join(c.dir, s.Name()))
+			continue
+		}
+		files, _ := os.ReadDir(filepath.Join(c.dir, s.Name()))
+		for _, f := range files {
+			dir := filepath.Join(s.Name(), f.Name())
+			meta, err := os.ReadFile(filepath.Join(c.dir, dir, metaName))
+			if err != nil {
+				_ = os.RemoveAll(filepath.Join(c.dir, dir))
+				continue
+			}
+			e := &entry{storageID: uint(storageID), path: string(meta), dir: dir, blocks: make(map[int64]*block)}
+			items, _ := os.ReadDir(filepath.Join(c.dir, dir))
+			var newest time.Time
+			for _, item := range items {
+				info, err := item.Info()
+				if version, _, ok := parseBlockName(item.Name()); ok && err == nil && info.ModTime().After(newest) {
+					newest, e.version = info.ModTime(), version
+				}
+			}
+			// only the blocks of the latest version are kept
+			for _, item := range items {
+				if item.Name() == metaName {
+					continue
+				}
+				version, index, ok := parseBlockName(item.Name())
+				info, err := item.Info()
+				if !ok || err != nil || version != e.version {
+					_ = os.Remove(filepath.Join(c.dir, dir, item.Name()))
+					continue
+				}
+				b := &block{entry: e, index: index, size: info.Size()}
+				e.blocks[index] = b
+				loaded = append(loaded, loadedBlock{b: b, modTime: info.ModTime()})
+			}
+			if len(e.blocks) == 0 {
+				_ = os.RemoveAll(filepath.Join(c.dir, dir))
+				continue
+			}
+			c.entries[dir] = e
+		}
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].modTime.After(loaded[j].modTime) })
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, lb := range loaded {
+		lb.b.elem = c.lru.PushBack(lb.b)
+		c.used += lb.b.size
+	}
+	c.evict()
+}
+
+// parseBlockName splits the name of a block file into the version and the index
+func parseBlockName(name string) (string, int64, bool) {
+	i := strings.LastIndexByte(name, '-')
+	if i <= 0 {
+		return "", 0, false
+	}
+	index, err := strconv.ParseInt(name[i+1:], 10, 64)
+	if err != nil || index < 0 {
+		return "", 0, false
+	}
+	return name[:i], index, true
+}
+
+// open returns the entry of the file, the blocks of the other versions are dropped
+func (c *Cache) open(storageID uint, path, version string) (*entry, error) {
+	dir := entryDir(storageID, path)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[dir]
+	if ok && e.version == version {
+		return e, nil
+	}
+	if ok {
+		c.dropEntry(e)
+	}
+	if err := os.MkdirAll(filepath.Join(c.dir, dir), 0o777); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, dir, metaName), []byte(path), 0o666); err != nil {
+		return nil, err
+	}
+	e = &entry{storageID: storageID, path: path, dir: dir, version: version, blocks: make(map[int64]*block)}
+	c.entries[dir] = e
+	return e, nil
+}
+
+// openBlock opens the cached block, nil if it is not cached
+func (c *Cache) openBlock(e *entry, index int64) *os.File {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := e.blocks[index]
+	if !ok || c.entries[e.dir] != e {
+		return nil
+	}
+	f, err := os.Open(c.blockPath(e, index))
+	if err != nil {
+		c.removeBlock(b)
+		return nil
+	}
+	c.lru.MoveToFront(b.elem)
+	return f
+}
+
+// add moves the downloaded temp file into the cache, it is discarded if the
+// entry is dropped during the download
+func (c *Cache) add(e *entry, index int64, tmp string, size int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[e.dir] != e {
+		return os.Remove(tmp)
+	}
+	if old, ok := e.blocks[index]; ok {
+		c.removeBlock(old)
+	}
+	if err := os.Rename(tmp, c.blockPath(e, index)); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	b := &block{entry: e, index: index, size: size}
+	b.elem = c.lru.PushFront(b)
+	e.blocks[index] = b
+	c.used += size
+	c.evict()
+	return nil
+}
+
+func (c *Cache) evict() {
+	for c.used > c.maxSize && c.lru.Len() > 0 {
+		b := c.lru.Back().Value.(*block)
+		c.removeBlock(b)
+		if len(b.entry.blocks) == 0 {
+			c.dropEntry(b.entry)
+		}
+	}
+}
+
+func (c *Cache) removeBlock(b *block) {
+	if err := os.Remove(c.blockPath(b.entry, b.index)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("failed remove cached block: %+v", err)
+	}
+	c.lru.Remove(b.elem)
+	delete(b.entry.blocks, b.index)
+	c.used -= b.size
+}
+
+func (c *Cache) dropEntry(e *entry) {
+	for _, b := range e.blocks {
+		c.lru.Remove(b.elem)
+		c.used -= b.size
+	}
+	e.blocks = make(map[int64]*block)
+	delete(c.entries, e.dir)
+	if err := os.RemoveAll(filepath.Join(c.dir, e.dir)); err != nil {
+		log.Warnf("failed remove cached blocks: %+v", err)
+	}
+}
+
+// Invalidate drops the cached blocks of the path and the files under it
+func (c *Cache) Invalidate(storageID uint, path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.entries {
+		if e.storageID == storageID && (e.path == path || strings.HasPrefix(e.path, strings.TrimSuffix(path, "/")+"/")) {
+			c.dropEntry(e)
+		}
+	}
+}
+
+// DropStorage drops all the cached blocks of the storage
+func (c *Cache) DropStorage(storageID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.entries {
+		if e.storageID == storageID {
+			c.dropEntry(e)
+		}
+	}
+	_ = os.RemoveAll(filepath.Join(c.dir, strconv.FormatUint(uint64(storageID), 10)))
+}
+
+// Clear drops all the cached blocks
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.entries {
+		c.dropEntry(e)
+	}
+}
+
+type StorageStats struct {
+	StorageID uint  `json:"storage_id"`
+	Files     int   `json:"files"`
+	Blocks    int   `json:"blocks"`
+	Size      int64 `json:"size"`
+}
+
+type Stats struct {
+	Size      int64          `json:"size"`
+	MaxSize   int64          `json:"max_size"`
+	BlockSize int64          `json:"block_size"`
+	Blocks    int            `json:"blocks"`
+	Hits      int64          `json:"hits"`
+	Misses    int64          `json:"misses"`
+	Storages  []StorageStats `json:"storages"`
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := Stats{
+		Size:      c.used,
+		MaxSize:   c.maxSize,
+		BlockSize: c.blockSize,
+		Blocks:    c.lru.Len(),
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Storages:  []StorageStats{},
+	}
+	byStorage := make(map[uint]*StorageStats)
+	for _, e := range c.entries {
+		ss, ok := byStorage[e.storageID]
+		if !ok {
+			ss = &StorageStats{StorageID: e.storageID}
+			byStorage[e.storageID] = ss
+		}
+		ss.Files++
+		ss.Blocks += len(e.blocks)
+		for _, b := range e.blocks {
+			ss.Size += b.size
+		}
+	}
+	for _, ss := range byStorage {
+		s.Storages = append(s.Storages, *ss)
+	}
+	sort.Slice(s.Storages, func(i, j int) bool { return s.Storages[i].StorageID < s.Storages[j].StorageID })
+	return s
+}
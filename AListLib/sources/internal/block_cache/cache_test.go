@@ -0,0 +1,149 @@
+package block_cache
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+)
+
+const content = "0123456789abcdef"
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 4, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Instance = c
+	defer func() { Instance = nil }()
+
+	var requests int
+	storage := &model.Storage{ID: 1, Proxy: model.Proxy{BlockCache: true}}
+	obj := &model.Object{Name: "a.bin", Size: int64(len(content)), Modified: time.Unix(1, 0)}
+	read := func(obj model.Obj, start, length int64) string {
+		t.Helper()
+		link := Wrap(storage, "/dir/a.bin", obj, &model.Link{
+			RangeReader: stream.RangeReaderFunc(func(ctx context.Context, r http_range.Range) (io.ReadCloser, error) {
+				requests++
+				return io.NopCloser(strings.NewReader(content[r.Start : r.Start+r.Length])), nil
+			}),
+		})
+		rc, err := link.RangeReader.RangeRead(context.Background(), http_range.Range{Start: start, Length: length})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	if data := read(obj, 2, 6); data != "234567" || requests != 2 {
+		t.Fatalf("unexpected read %q with %d requests", data, requests)
+	}
+	if data := read(obj, 5, 3); data != "567" || requests != 2 {
+		t.Fatalf("expect the cached block, got %q with %d requests", data, requests)
+	}
+	// the least recently used block 0 is evicted beyond the size cap
+	if data := read(obj, 8, -1); data != "89abcdef" || requests != 4 {
+		t.Fatalf("unexpected read %q with %d requests", data, requests)
+	}
+	if s := c.Stats(); s.Size != 8 || s.Blocks != 2 || s.Hits != 1 || s.Misses != 4 ||
+		len(s.Storages) != 1 || s.Storages[0].Files != 1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+
+	// the blocks are kept across restarts
+	if c, err = New(dir, 4, 10); err != nil {
+		t.Fatal(err)
+	}
+	Instance = c
+	if data := read(obj, 12, 4); data != "cdef" || requests != 4 {
+		t.Fatalf("expect the reloaded block, got %q with %d requests", data, requests)
+	}
+
+	// a changed file never reads the stale blocks
+	changed := &model.Object{Name: "a.bin", Size: int64(len(content)), Modified: time.Unix(2, 0)}
+	if data := read(changed, 12, 4); data != "cdef" || requests != 5 {
+		t.Fatalf("expect the new version to be downloaded, got %q with %d requests", data, requests)
+	}
+	c.Invalidate(1, "/dir")
+	if s := c.Stats(); s.Size != 0 || s.Blocks != 0 {
+		t.Fatalf("unexpected stats after the invalidation: %+v", s)
+	}
+
+	storage.BlockCache = false
+	if link := Wrap(storage, "/dir/a.bin", obj, &model.Link{URL: "http://example.com/a.bin"}); link.RangeReader != nil {
+		t.Fatal("the link of the storage without the cache is wrapped")
+	}
+}
+
+func TestCanceledRead(t *testing.T) {
+	c, err := New(t.TempDir(), 4, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Instance = c
+	defer func() { Instance = nil }()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var requests int
+	storage := &model.Storage{ID: 1, Proxy: model.Proxy{BlockCache: true}}
+	obj := &model.Object{Name: "a.bin", Size: int64(len(content)), Modified: time.Unix(1, 0)}
+	link := &model.Link{
+		RangeReader: stream.RangeReaderFunc(func(ctx context.Context, r http_range.Range) (io.ReadCloser, error) {
+			requests++
+			close(started)
+			<-release
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return io.NopCloser(strings.NewReader(content[r.Start : r.Start+r.Length])), nil
+		}),
+		Concurrency: 2,
+		PartSize:    4,
+	}
+	wrapped := Wrap(storage, "/a.bin", obj, link)
+	if link.Concurrency != 2 || link.PartSize != 4 || wrapped.Concurrency != 0 {
+		t.Fatalf("the link is changed: %+v", link)
+	}
+	// the upstream is read as is, so the requests are counted exactly
+	wrapped.RangeReader.(*rangeReader).upstream = link.RangeReader
+
+	// the fetch started by a canceled reader is kept for the other readers
+	ctx, cancel := context.WithCancel(context.Background())
+	rc, err := wrapped.RangeReader.RangeRead(ctx, http_range.Range{Start: 0, Length: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := io.ReadAll(rc)
+		errCh <- err
+	}()
+	<-started
+	cancel()
+	if err := <-errCh; err != context.Canceled {
+		t.Fatalf("expect the read to be canceled, got %v", err)
+	}
+	close(release)
+	for i := 0; i < 50 && c.Stats().Blocks == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	rc, err = wrapped.RangeReader.RangeRead(context.Background(), http_range.Range{Start: 0, Length: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if data, err := io.ReadAll(rc); string(data) != "0123" || err != nil || requests != 1 {
+		t.Fatalf("expect the block fetched once, got %q %v with %d requests", data, err, requests)
+	}
+}
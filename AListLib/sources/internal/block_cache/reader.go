@@ -0,0 +1,204 @@
+package block_cache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Instance is the cache of the proxied reads, nil if it is disabled
+var Instance *Cache
+
+// version identifies the content of obj by the size and the hash, or the
+// modified time if there is no hash
+func version(obj model.Obj) string {
+	id := obj.ModTime().UTC().String()
+	if len(obj.GetHash().Export()) > 0 {
+		id = obj.GetHash().String()
+	}
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d:%s", obj.GetSize(), id)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Wrap returns a clone of the link reading through the cache if the storage
+// enables it, the links of the local files and the transcoded contents are
+// kept as is. The link itself is not changed, so it still reads the upstream
+// concurrently when it is not read through the cache
+func Wrap(storage *model.Storage, path string, obj model.Obj, link *model.Link) *model.Link {
+	c := Instance
+	if c == nil || !storage.BlockCache || obj.IsDir() || obj.GetSize() <= 0 || link.ContentLength > 0 {
+		return link
+	}
+	if _, ok := link.RangeReader.(*model.FileRangeReader); ok {
+		return link
+	}
+	rr, err := stream.GetRangeReaderFromLink(obj.GetSize(), link)
+	if err != nil {
+		return link
+	}
+	wrapped := link.Clone()
+	wrapped.RangeReader = &rangeReader{
+		c:         c,
+		storageID: storage.ID,
+		path:      path,
+		version:   version(obj),
+		size:      obj.GetSize(),
+		upstream:  rr,
+	}
+	// the upstream is already read concurrently if it is required
+	wrapped.Concurrency, wrapped.PartSize = 0, 0
+	return wrapped
+}
+
+type rangeReader struct {
+	c         *Cache
+	storageID uint
+	path      string
+	version   string
+	size      int64
+	upstream  model.RangeReaderIF
+}
+
+func (r *rangeReader) RangeRead(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+	if httpRange.Length < 0 || httpRange.Start+httpRange.Length > r.size {
+		httpRange.Length = r.size - httpRange.Start
+	}
+	e, err := r.c.open(r.storageID, r.path, r.version)
+	if err != nil {
+		log.Warnf("failed open block cache, read from upstream: %+v", err)
+		return r.upstream.RangeRead(ctx, httpRange)
+	}
+	return &blockReader{ctx: ctx, r: r, e: e, pos: httpRange.Start, end: httpRange.Start + httpRange.Length}, nil
+}
+
+// blockReader reads the range block by block, the missing blocks are
+// downloaded into the cache first
+type blockReader struct {
+	ctx context.Context
+	r   *rangeReader
+	e   *entry
+	pos int64
+	end int64
+	// cur reads the current block until curEnd
+	cur    io.ReadCloser
+	curEnd int64
+}
+
+func (b *blockReader) Read(p []byte) (int, error) {
+	if b.cur == nil || b.pos >= b.curEnd {
+		if b.pos >= b.end {
+			return 0, io.EOF
+		}
+		if err := b.next(); err != nil {
+			return 0, err
+		}
+	}
+	if remain := b.curEnd - b.pos; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+	n, err := b.cur.Read(p)
+	b.pos += int64(n)
+	if err == io.EOF && b.pos < b.curEnd {
+		err = io.ErrUnexpectedEOF
+	} else if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// next opens the block containing pos
+func (b *blockReader) next() error {
+	if b.cur != nil {
+		_ = b.cur.Close()
+		b.cur = nil
+	}
+	c := b.r.c
+	index := b.pos / c.blockSize
+	blockStart := index * c.blockSize
+	b.curEnd = min(blockStart+c.blockSize, b.end)
+	f := c.openBlock(b.e, index)
+	if f != nil {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+		key := fmt.Sprintf("%s/%s-%d", b.e.dir, b.e.version, index)
+		// the fetch is shared by the readers of the block, so it is not
+		// canceled with the reader starting it and each reader waits on its own
+		ctx := context.WithoutCancel(b.ctx)
+		select {
+		case res := <-c.fetchG.DoChan(key, func() (struct{}, error) {
+			return struct{}{}, b.fetch(ctx, index)
+		}):
+			if res.Err != nil {
+				return res.Err
+			}
+		case <-b.ctx.Done():
+			return b.ctx.Err()
+		}
+		f = c.openBlock(b.e, index)
+	}
+	if f == nil {
+		// the block is evicted or invalidated at once, read it from the upstream
+		rc, err := b.r.upstream.RangeRead(b.ctx, http_range.Range{Start: b.pos, Length: b.curEnd - b.pos})
+		if err != nil {
+			return err
+		}
+		b.cur = rc
+		return nil
+	}
+	if _, err := f.Seek(b.pos-blockStart, io.SeekStart); err != nil {
+		_ = f.Close()
+		return err
+	}
+	b.cur = f
+	return nil
+}
+
+// fetch downloads the whole block into the cache
+func (b *blockReader) fetch(ctx context.Context, index int64) error {
+	c := b.r.c
+	start := index * c.blockSize
+	length := min(c.blockSize, b.r.size-start)
+	rc, err := b.r.upstream.RangeRead(ctx, http_range.Range{Start: start, Length: length})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	tmp, err := os.CreateTemp(c.dir, "*.tmp")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	n, err := utils.CopyWithBuffer(tmp, io.LimitReader(rc, length))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n != length {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = c.add(b.e, index, tmp.Name(), n); err != nil {
+		// the block is read from the upstream instead
+		log.Warnf("failed add the block to the cache: %+v", err)
+	}
+	return nil
+}
+
+func (b *blockReader) Close() error {
+	if b.cur != nil {
+		return b.cur.Close()
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package bootstrap
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/block_cache"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+func InitBlockCache() {
+	cfg := conf.Conf.BlockCache
+	if cfg.MaxSize <= 0 || cfg.BlockSize <= 0 {
+		return
+	}
+	c, err := block_cache.New(cfg.Dir, int64(cfg.BlockSize)<<20, int64(cfg.MaxSize)<<20)
+	if err != nil {
+		utils.Log.Errorf("failed init block cache: %+v", err)
+		return
+	}
+	block_cache.Instance = c
+	op.RegisterObjWriteHook(func(storage driver.Driver, path string) {
+		c.Invalidate(storage.GetStorage().ID, path)
+	})
+	op.RegisterStorageHook(func(typ string, storage driver.Driver) {
+		if typ == "del" || !storage.GetStorage().BlockCache {
+			c.DropStorage(storage.GetStorage().ID)
+		}
+	})
+}
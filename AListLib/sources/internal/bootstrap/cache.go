@@ -0,0 +1,39 @@
+package bootstrap
+
+import (
+	"path/filepath"
+
+	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// InitCache connects the shared cache backend, the instances of a
+// deployment must use the same one
+func InitCache() {
+	cfg := conf.Conf.Cache
+	switch cfg.Backend {
+	case "", "memory":
+		return
+	case "redis":
+		if err := cache.LoadInstanceID(filepath.Join(flags.DataDir, "instance_id")); err != nil {
+			utils.Log.Fatalf("failed load the instance id: %+v", err)
+		}
+		b, err := cache.NewRedisBackend(cache.RedisOptions{
+			Address:  cfg.Redis.Address,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+			Prefix:   cfg.Redis.Prefix,
+		})
+		if err != nil {
+			utils.Log.Fatalf("failed connect redis: %+v", err)
+		}
+		if err = cache.SetBackend(b); err != nil {
+			utils.Log.Fatalf("failed init redis cache backend: %+v", err)
+		}
+		utils.Log.Infof("use redis cache backend %s as instance %s", cfg.Redis.Address, cache.InstanceID)
+	default:
+		utils.Log.Fatalf("unknown cache backend: %s", cfg.Backend)
+	}
+}
@@ -155,11 +155,17 @@ func InitConfig() {
 	convertAbsPath(&conf.Conf.TempDir)
 	convertAbsPath(&conf.Conf.BleveDir)
 	convertAbsPath(&conf.Conf.DistDir)
+	convertAbsPath(&conf.Conf.Tus.Dir)
+	convertAbsPath(&conf.Conf.BlockCache.Dir)
 
 	err := os.MkdirAll(conf.Conf.TempDir, 0o777)
 	if err != nil {
 		log.Fatalf("create temp dir error: %+v", err)
 	}
+	err = os.MkdirAll(conf.Conf.Tus.Dir, 0o777)
+	if err != nil {
+		log.Fatalf("create tus dir error: %+v", err)
+	}
 	log.Debugf("config: %+v", conf.Conf)
 
 	// Validate and display proxy configuration status
@@ -0,0 +1,79 @@
+package bootstrap
+
+import (
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/dedupe"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/tache"
+)
+
+var taskStateNames = map[tache.State]string{
+	tache.StatePending:      "pending",
+	tache.StateRunning:      "running",
+	tache.StateSucceeded:    "succeeded",
+	tache.StateCanceling:    "canceling",
+	tache.StateCanceled:     "canceled",
+	tache.StateErrored:      "errored",
+	tache.StateFailing:      "failing",
+	tache.StateFailed:       "failed",
+	tache.StateWaitingRetry: "waiting_retry",
+	tache.StateBeforeRetry:  "before_retry",
+}
+
+func collectTaskStates[T tache.Task](emit func(float64, ...string), typ string, m task.Manager[T]) {
+	for state, name := range taskStateNames {
+		emit(float64(len(m.GetByState(state))), typ, name)
+	}
+}
+
+var initMetricsOnce sync.Once
+
+// InitMetrics registers the collectors of the tasks and the storages, the
+// task managers are read on every scrape as they are created by Start.
+func InitMetrics() {
+	initMetricsOnce.Do(func() {
+		metrics.Register(metrics.NewGaugeFunc("tasks", "Number of the tasks, by type and state.",
+			[]string{"type", "state"}, func(emit func(float64, ...string)) {
+				if fs.UploadTaskManager != nil {
+					collectTaskStates(emit, "upload", fs.UploadTaskManager)
+				}
+				if fs.CopyTaskManager != nil {
+					collectTaskStates(emit, "copy", fs.CopyTaskManager)
+				}
+				if fs.MoveTaskManager != nil {
+					collectTaskStates(emit, "move", fs.MoveTaskManager)
+				}
+				if fs.ArchiveDownloadTaskManager != nil {
+					collectTaskStates(emit, "decompress", fs.ArchiveDownloadTaskManager)
+				}
+				if fs.ArchiveContentUploadTaskManager.Manager != nil {
+					collectTaskStates(emit, "decompress_upload", fs.ArchiveContentUploadTaskManager)
+				}
+				if dedupe.TaskManager != nil {
+					collectTaskStates(emit, "dedupe", dedupe.TaskManager)
+				}
+				if tool.DownloadTaskManager != nil {
+					collectTaskStates(emit, "offline_download", tool.DownloadTaskManager)
+				}
+				if tool.TransferTaskManager != nil {
+					collectTaskStates(emit, "offline_download_transfer", tool.TransferTaskManager)
+				}
+			}))
+		metrics.Register(metrics.NewGaugeFunc("storage_up", "Whether the storage works, 1 if its status is work.",
+			[]string{"mount_path", "driver"}, func(emit func(float64, ...string)) {
+				for _, storage := range op.GetAllStorages() {
+					s := storage.GetStorage()
+					up := 0.0
+					if s.Status == op.WORK {
+						up = 1
+					}
+					emit(up, s.MountPath, s.Driver)
+				}
+			}))
+	})
+}
@@ -0,0 +1,27 @@
+package v4_1_10
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/secret"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// EncryptStorageAdditions encrypts the confidential fields of the existing storages,
+// they are stored in plain text by older versions
+func EncryptStorageAdditions() {
+	storages, _, err := db.GetStorages(1, -1)
+	if err != nil {
+		utils.Log.Errorf("[EncryptStorageAdditions] failed to get storages: %s", err.Error())
+		return
+	}
+	for _, s := range storages {
+		if len(secret.ConfidentialFields(s.Driver)) == 0 || secret.HasEncrypted(s.Driver, s.Addition) {
+			continue
+		}
+		// UpdateStorage encrypts the plain values on the way
+		err = db.UpdateStorage(&s)
+		if err != nil {
+			utils.Log.Errorf("[EncryptStorageAdditions] failed to update storage [%d]%s: %s", s.ID, s.MountPath, err.Error())
+		}
+	}
+}
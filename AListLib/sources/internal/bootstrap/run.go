@@ -12,12 +12,19 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
 	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap/data"
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/health"
+	"github.com/OpenListTeam/OpenList/v4/internal/lifecycle"
+	"github.com/OpenListTeam/OpenList/v4/internal/music"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/feed"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server"
+	"github.com/OpenListTeam/OpenList/v4/server/dlna"
 	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
+	"github.com/OpenListTeam/OpenList/v4/server/nfs"
 	"github.com/OpenListTeam/sftpd-openlist"
 	ftpserver "github.com/fclairamb/ftpserverlib"
 	"github.com/gin-gonic/gin"
@@ -32,35 +39,48 @@ import (
 func Init() {
 	InitConfig()
 	Log()
+	InitTracing()
+	InitCache()
+	InitSecret()
 	InitDB()
 	data.InitData()
 	InitStreamLimit()
+	InitBlockCache()
 	InitIndex()
 	InitUpgradePatch()
 }
 
 func Release() {
 	db.Close()
+	cache.ReleaseAll()
+	_ = cache.Shared().Close()
+	_ = shutdownTracing(context.Background())
 }
 
 var (
-	running      bool
-	httpSrv      *http.Server
-	httpRunning  bool
-	httpsSrv     *http.Server
-	httpsRunning bool
-	unixSrv      *http.Server
-	unixRunning  bool
-	quicSrv      *http3.Server
-	quicRunning  bool
-	s3Srv        *http.Server
-	s3Running    bool
-	ftpDriver    *server.FtpMainDriver
-	ftpServer    *ftpserver.FtpServer
-	ftpRunning   bool
-	sftpDriver   *server.SftpDriver
-	sftpServer   *sftpd.SftpServer
-	sftpRunning  bool
+	running        bool
+	httpSrv        *http.Server
+	httpRunning    bool
+	httpsSrv       *http.Server
+	httpsRunning   bool
+	unixSrv        *http.Server
+	unixRunning    bool
+	quicSrv        *http3.Server
+	quicRunning    bool
+	s3Srv          *http.Server
+	s3Running      bool
+	ftpDriver      *server.FtpMainDriver
+	ftpServer      *ftpserver.FtpServer
+	ftpRunning     bool
+	sftpDriver     *server.SftpDriver
+	sftpServer     *sftpd.SftpServer
+	sftpRunning    bool
+	dlnaServer     *server.DLNAServer
+	dlnaRunning    bool
+	nfsServer      *nfs.Server
+	nfsRunning     bool
+	metricsSrv     *http.Server
+	metricsRunning bool
 )
 
 // Called by OpenList-Mobile
@@ -80,6 +100,12 @@ func IsRunning(t string) bool {
 		return sftpRunning
 	case "ftp":
 		return ftpRunning
+	case "dlna":
+		return dlnaRunning
+	case "nfs":
+		return nfsRunning
+	case "metrics":
+		return metricsRunning
 	}
 	return running
 }
@@ -92,6 +118,13 @@ func Start() {
 	InitOfflineDownloadTools()
 	LoadStorages()
 	InitTaskManager()
+	feed.Start()
+	lifecycle.Start()
+	music.Start()
+	health.Start()
+	if conf.Conf.Metrics.Enable {
+		InitMetrics()
+	}
 	if !flags.Debug && !flags.Dev {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -268,12 +301,73 @@ func Start() {
 			}()
 		}
 	}
+	if conf.Conf.DLNA.Listen != "" && conf.Conf.DLNA.Enable {
+		dr := gin.New()
+		dr.Use(gin.LoggerWithWriter(log.StandardLogger().Out), gin.RecoveryWithWriter(log.StandardLogger().Out))
+		mediaServer := dlna.NewServer(conf.Conf.DLNA.FriendlyName, conf.Conf.DLNA.User)
+		server.InitDLNA(dr, mediaServer)
+		dlnaServer = server.NewDLNAServer(dr, mediaServer.UUID)
+		fmt.Printf("start dlna server on %s\n", conf.Conf.DLNA.Listen)
+		utils.Log.Infof("start dlna server on %s", conf.Conf.DLNA.Listen)
+		go func() {
+			dlnaRunning = true
+			err := dlnaServer.ListenAndServe()
+			dlnaRunning = false
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				handleEndpointStartFailedHooks("dlna", err)
+				utils.Log.Errorf("failed to start dlna server: %s", err.Error())
+			} else {
+				handleEndpointShutdownHooks("dlna")
+			}
+		}()
+	}
+	if conf.Conf.NFS.Listen != "" && conf.Conf.NFS.Enable {
+		var err error
+		nfsServer, err = server.NewNFSServer()
+		if err != nil {
+			utils.Log.Errorf("failed to start nfs server: %s", err.Error())
+		} else {
+			fmt.Printf("start nfs server on %s\n", conf.Conf.NFS.Listen)
+			utils.Log.Infof("start nfs server on %s", conf.Conf.NFS.Listen)
+			go func() {
+				nfsRunning = true
+				err := nfsServer.ListenAndServe(conf.Conf.NFS.Listen)
+				nfsRunning = false
+				if err != nil {
+					handleEndpointStartFailedHooks("nfs", err)
+					utils.Log.Errorf("problem nfs server listening: %s", err.Error())
+				} else {
+					handleEndpointShutdownHooks("nfs")
+				}
+			}()
+		}
+	}
+	if conf.Conf.Metrics.Listen != "" && conf.Conf.Metrics.Enable {
+		metricsSrv = server.NewMetricsServer()
+		fmt.Printf("start metrics server on %s\n", conf.Conf.Metrics.Listen)
+		utils.Log.Infof("start metrics server on %s", conf.Conf.Metrics.Listen)
+		go func() {
+			metricsRunning = true
+			err := metricsSrv.ListenAndServe()
+			metricsRunning = false
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				handleEndpointStartFailedHooks("metrics", err)
+				utils.Log.Errorf("failed to start metrics server: %s", err.Error())
+			} else {
+				handleEndpointShutdownHooks("metrics")
+			}
+		}()
+	}
 	running = true
 }
 
 func Shutdown(timeout time.Duration) {
 	utils.Log.Println("Shutdown server...")
 	fs.ArchiveContentUploadTaskManager.RemoveAll()
+	feed.Stop()
+	lifecycle.Stop()
+	music.Stop()
+	health.Stop()
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 	var wg sync.WaitGroup
@@ -352,6 +446,36 @@ func Shutdown(timeout time.Duration) {
 			sftpDriver = nil
 		}()
 	}
+	if conf.Conf.DLNA.Listen != "" && conf.Conf.DLNA.Enable && dlnaServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := dlnaServer.Shutdown(ctx); err != nil {
+				utils.Log.Error("DLNA server shutdown err: ", err)
+			}
+			dlnaServer = nil
+		}()
+	}
+	if conf.Conf.NFS.Listen != "" && conf.Conf.NFS.Enable && nfsServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := nfsServer.Close(); err != nil {
+				utils.Log.Error("NFS server shutdown err: ", err)
+			}
+			nfsServer = nil
+		}()
+	}
+	if metricsSrv != nil && conf.Conf.Metrics.Listen != "" && conf.Conf.Metrics.Enable {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := metricsSrv.Shutdown(ctx); err != nil {
+				utils.Log.Error("metrics server shutdown err: ", err)
+			}
+			metricsSrv = nil
+		}()
+	}
 	wg.Wait()
 	utils.Log.Println("Server exit")
 	running = false
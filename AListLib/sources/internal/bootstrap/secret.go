@@ -0,0 +1,13 @@
+package bootstrap
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/secret"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+func InitSecret() {
+	if err := secret.Init(); err != nil {
+		utils.Log.Fatalf("failed load master key: %+v", err)
+	}
+	utils.Log.Infof("master key is loaded from %s", secret.KeySource())
+}
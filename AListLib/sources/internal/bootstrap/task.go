@@ -3,6 +3,7 @@ package bootstrap
 import (
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/dedupe"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
@@ -49,4 +50,5 @@ func InitTaskManager() {
 	op.RegisterSettingChangingCallback(func() {
 		fs.ArchiveContentUploadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers)))
 	})
+	dedupe.TaskManager = tache.NewManager[*dedupe.ScanTask](tache.WithWorks(conf.Conf.Tasks.Dedupe.Workers), tache.WithMaxRetry(conf.Conf.Tasks.Dedupe.MaxRetry)) //the reports are kept in memory, dedupe will not support persist
 }
@@ -0,0 +1,32 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+)
+
+var shutdownTracing = func(context.Context) error { return nil }
+
+// InitTracing exports the spans to the OTLP collector, they are dropped if
+// the tracing is not enabled
+func InitTracing() {
+	cfg := conf.Conf.Tracing
+	if !cfg.Enable {
+		return
+	}
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	}
+	exp, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		utils.Log.Errorf("failed init the tracing exporter: %+v", err)
+		return
+	}
+	shutdownTracing = tracing.Setup(exp, cfg.ServiceName, cfg.SampleRatio)
+	utils.Log.Infof("export the traces of %s over OTLP", cfg.ServiceName)
+}
@@ -0,0 +1,234 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	log "github.com/sirupsen/logrus"
+)
+
+// Backend is the store shared by the instances of a deployment. The memory
+// backend keeps the behaviour of a single instance, the redis backend shares
+// the entries and the broadcasts between the instances.
+type Backend interface {
+	Get(key string) ([]byte, bool, error)
+	// Set stores the value, it never expires if ttl is 0
+	Set(key string, value []byte, ttl time.Duration) error
+	// SetNX stores the value only if the key does not exist
+	SetNX(key string, value []byte, ttl time.Duration) (bool, error)
+	Del(keys ...string) error
+	// DelPrefix removes all the keys starting with prefix
+	DelPrefix(prefix string) error
+	Expire(key string, ttl time.Duration) (bool, error)
+	// TTL returns the remaining time to live, false if the key does not exist or never expires
+	TTL(key string) (time.Duration, bool, error)
+	Publish(channel, message string) error
+	// Subscribe delivers the messages of the channel to handler until the
+	// backend is closed. An empty message means that the subscription was
+	// interrupted and the messages in between may be lost.
+	Subscribe(channel string, handler func(message string)) error
+	Close() error
+}
+
+// broadcastChannel carries the broadcasts of all the topics
+const broadcastChannel = "broadcast"
+
+var (
+	backendMu sync.RWMutex
+	backend   Backend = NewMemoryBackend()
+
+	// InstanceID identifies this instance among the ones sharing the backend
+	InstanceID = random.String(16)
+
+	broadcastMu       sync.RWMutex
+	broadcastHandlers = map[string][]func(message string){}
+)
+
+// Shared returns the backend shared by the instances
+func Shared() Backend {
+	backendMu.RLock()
+	defer backendMu.RUnlock()
+	return backend
+}
+
+// Distributed reports whether the backend is shared with other instances
+func Distributed() bool {
+	_, ok := Shared().(*MemoryBackend)
+	return !ok
+}
+
+// SetBackend replaces the shared backend and closes the previous one
+func SetBackend(b Backend) error {
+	if err := b.Subscribe(broadcastChannel, dispatchBroadcast); err != nil {
+		return err
+	}
+	backendMu.Lock()
+	old := backend
+	backend = b
+	backendMu.Unlock()
+	if old != nil {
+		_ = old.Close()
+	}
+	return nil
+}
+
+// OnBroadcast registers the handler of the broadcasts of the topic sent by
+// the other instances. An empty message means that some broadcasts may be
+// lost, the handler should drop everything it caches.
+func OnBroadcast(topic string, handler func(message string)) {
+	broadcastMu.Lock()
+	defer broadcastMu.Unlock()
+	broadcastHandlers[topic] = append(broadcastHandlers[topic], handler)
+}
+
+// Broadcast sends the message of the topic to the other instances
+func Broadcast(topic, message string) {
+	if !Distributed() {
+		return
+	}
+	if err := Shared().Publish(broadcastChannel, encodeBroadcast(InstanceID, topic, message)); err != nil {
+		log.Warnf("failed broadcast %s: %+v", topic, err)
+	}
+}
+
+func encodeBroadcast(instanceID, topic, message string) string {
+	return instanceID + "\t" + topic + "\t" + message
+}
+
+func dispatchBroadcast(raw string) {
+	broadcastMu.RLock()
+	defer broadcastMu.RUnlock()
+	if raw == "" {
+		for _, handlers := range broadcastHandlers {
+			for _, h := range handlers {
+				h("")
+			}
+		}
+		return
+	}
+	parts := strings.SplitN(raw, "\t", 3)
+	if len(parts) != 3 || parts[0] == InstanceID || parts[2] == "" {
+		return
+	}
+	for _, h := range broadcastHandlers[parts[1]] {
+		h(parts[2])
+	}
+}
+
+const ownerPrefix = "owner:"
+
+// Own acquires or renews the lease of key for this instance, only the owner
+// of the lease runs the job of key so the instances never run it twice
+func Own(key string, ttl time.Duration) bool {
+	return own(Shared(), InstanceID, key, ttl)
+}
+
+func own(b Backend, owner, key string, ttl time.Duration) bool {
+	key = ownerPrefix + key
+	ok, err := b.SetNX(key, []byte(owner), ttl)
+	if err != nil {
+		log.Warnf("failed acquire the lease of %s: %+v", key, err)
+		return false
+	}
+	if ok {
+		return true
+	}
+	current, exists, err := b.Get(key)
+	if err != nil || !exists || string(current) != owner {
+		return false
+	}
+	ok, err = b.Expire(key, ttl)
+	return err == nil && ok
+}
+
+// lease is a lease held by this instance until it is released
+type lease struct {
+	owned  atomic.Bool
+	cancel context.CancelFunc
+}
+
+var (
+	heldMu sync.Mutex
+	held   = map[string]*lease{}
+)
+
+// Hold acquires the lease of key and keeps renewing it until it is released.
+// A lease lost while this instance could not reach the backend is acquired
+// again once it expires, Holds tells whether it is owned meanwhile.
+func Hold(key string, ttl time.Duration) bool {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	if l, ok := held[key]; ok {
+		return l.owned.Load()
+	}
+	if !Own(key, ttl) {
+		return false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &lease{cancel: cancel}
+	l.owned.Store(true)
+	held[key] = l
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				owned := Own(key, ttl)
+				if l.owned.Swap(owned) != owned {
+					if owned {
+						log.Infof("acquired the lease of %s again", key)
+					} else {
+						log.Warnf("lost the lease of %s", key)
+					}
+				}
+			}
+		}
+	}()
+	return true
+}
+
+// Holds reports whether this instance owns the lease of key held by Hold
+func Holds(key string) bool {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	l, ok := held[key]
+	return ok && l.owned.Load()
+}
+
+// ReleaseAll gives up the leases held by this instance, so the other
+// instances take them over at once instead of waiting for them to expire
+func ReleaseAll() {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	for key, l := range held {
+		l.cancel()
+		delete(held, key)
+		if current, ok, _ := Shared().Get(ownerPrefix + key); ok && string(current) == InstanceID {
+			if err := Shared().Del(ownerPrefix + key); err != nil {
+				log.Warnf("failed release the lease of %s: %+v", key, err)
+			}
+		}
+	}
+}
+
+// LoadInstanceID keeps the id of this instance in the file, so a restarted
+// instance still owns the leases it held
+func LoadInstanceID(path string) error {
+	data, err := os.ReadFile(path)
+	if err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		InstanceID = strings.TrimSpace(string(data))
+		return nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.WriteFile(path, []byte(InstanceID), 0o600)
+}
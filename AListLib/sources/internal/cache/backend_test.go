@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/cache/redistest"
+	gocache "github.com/OpenListTeam/go-cache"
+)
+
+func newRedisBackends(t *testing.T) (*redistest.Server, *RedisBackend, *RedisBackend) {
+	t.Helper()
+	srv, err := redistest.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	var backends [2]*RedisBackend
+	for i := range backends {
+		if backends[i], err = NewRedisBackend(RedisOptions{Address: srv.Addr(), Prefix: "test:"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return srv, backends[0], backends[1]
+}
+
+func TestRedisBackend(t *testing.T) {
+	srv, a, b := newRedisBackends(t)
+	defer a.Close()
+	defer b.Close()
+
+	if err := a.Set("k", []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, err := b.Get("k"); err != nil || !ok || string(v) != "v" {
+		t.Fatalf("expect the value set by the other instance, got %q %v %v", v, ok, err)
+	}
+	if ok, _ := b.SetNX("k", []byte("w"), 0); ok {
+		t.Fatal("expect the existing key to be kept")
+	}
+	if ok, _ := a.Expire("k", time.Minute); !ok {
+		t.Fatal("failed expire")
+	}
+	if ttl, ok, _ := b.TTL("k"); !ok || ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("unexpected ttl %v %v", ttl, ok)
+	}
+	if ok, _ := a.Expire("k", 0); !ok {
+		t.Fatal("failed persist")
+	}
+	if _, ok, _ := b.TTL("k"); ok {
+		t.Fatal("expect the key to never expire")
+	}
+	_ = a.Set("ns:1", []byte("1"), 0)
+	_ = a.Set("ns:2", []byte("2"), 0)
+	if err := b.DelPrefix("ns:"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := srv.Keys(); len(keys) != 1 || keys[0] != "test:k" {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	srv, a, b := newRedisBackends(t)
+	defer b.Close()
+	if err := SetBackend(a); err != nil {
+		t.Fatal(err)
+	}
+	defer SetBackend(NewMemoryBackend())
+
+	received := make(chan string, 8)
+	OnBroadcast("test", func(message string) { received <- message })
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("expect %q, got %q", want, got)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("expect %q, got nothing", want)
+		}
+	}
+
+	// the broadcasts of this instance are not delivered to itself
+	Broadcast("test", "self")
+	if err := b.Publish(broadcastChannel, encodeBroadcast("peer", "test", "hello")); err != nil {
+		t.Fatal(err)
+	}
+	expect("hello")
+
+	// the handlers are told to drop everything after a reconnection
+	srv.DropConnections()
+	expect("")
+	for i := 0; i < 50; i++ {
+		if err := b.Publish(broadcastChannel, encodeBroadcast("peer", "test", "again")); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	expect("again")
+}
+
+func TestOwn(t *testing.T) {
+	_, a, b := newRedisBackends(t)
+	defer a.Close()
+	defer b.Close()
+
+	if !own(a, "one", "job", 100*time.Millisecond) {
+		t.Fatal("failed acquire the lease")
+	}
+	if own(b, "two", "job", 100*time.Millisecond) {
+		t.Fatal("the lease is acquired twice")
+	}
+	if !own(a, "one", "job", 100*time.Millisecond) {
+		t.Fatal("failed renew the lease")
+	}
+	time.Sleep(150 * time.Millisecond)
+	if !own(b, "two", "job", time.Minute) {
+		t.Fatal("the expired lease is not taken over")
+	}
+}
+
+func TestHold(t *testing.T) {
+	_, a, b := newRedisBackends(t)
+	defer b.Close()
+	if err := SetBackend(a); err != nil {
+		t.Fatal(err)
+	}
+	defer SetBackend(NewMemoryBackend())
+
+	if !Hold("tasks", time.Minute) || !Holds("tasks") {
+		t.Fatal("failed hold the lease")
+	}
+	if own(b, "peer", "tasks", time.Minute) {
+		t.Fatal("the held lease is acquired by another instance")
+	}
+	// the lease is given up at once
+	ReleaseAll()
+	if Holds("tasks") {
+		t.Fatal("the released lease is still held")
+	}
+	if !own(b, "peer", "tasks", time.Minute) {
+		t.Fatal("the released lease is not taken over")
+	}
+	if Hold("tasks", time.Minute) {
+		t.Fatal("the lease of another instance is held")
+	}
+}
+
+func TestLoadInstanceID(t *testing.T) {
+	defer func(id string) { InstanceID = id }(InstanceID)
+	path := filepath.Join(t.TempDir(), "instance_id")
+	if err := LoadInstanceID(path); err != nil {
+		t.Fatal(err)
+	}
+	id := InstanceID
+	// a restarted instance keeps its id
+	InstanceID = "random"
+	if err := LoadInstanceID(path); err != nil {
+		t.Fatal(err)
+	}
+	if InstanceID != id {
+		t.Fatalf("expect the id %q to be kept, got %q", id, InstanceID)
+	}
+}
+
+func TestSharedCache(t *testing.T) {
+	_, a, b := newRedisBackends(t)
+	defer b.Close()
+	if err := SetBackend(a); err != nil {
+		t.Fatal(err)
+	}
+	defer SetBackend(NewMemoryBackend())
+
+	c := NewSharedCache[int]("login")
+	c.Set("1.2.3.4", 3)
+	if v, ok, _ := b.Get("login:1.2.3.4"); !ok || string(v) != "3" {
+		t.Fatalf("the entry is not shared: %q %v", v, ok)
+	}
+	if !c.Expire("1.2.3.4", time.Minute) {
+		t.Fatal("failed expire")
+	}
+	if ttl, ok := c.Ttl("1.2.3.4"); !ok || ttl <= 0 {
+		t.Fatalf("unexpected ttl %v %v", ttl, ok)
+	}
+	if !c.Set("5.6.7.8", 1, gocache.WithEx[int](time.Minute)) {
+		t.Fatal("failed set")
+	}
+	if _, ok, _ := b.TTL("login:5.6.7.8"); !ok {
+		t.Fatal("the expiration of the option is lost")
+	}
+	if c.Del("1.2.3.4") != 1 {
+		t.Fatal("failed delete")
+	}
+	if _, ok := c.Get("1.2.3.4"); ok {
+		t.Fatal("the deleted entry is kept")
+	}
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	value    []byte
+	expireAt time.Time
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
+
+// MemoryBackend keeps the entries in the memory of this instance
+type MemoryBackend struct {
+	mu          sync.Mutex
+	entries     map[string]memoryEntry
+	subscribers map[string][]func(message string)
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	b := &MemoryBackend{
+		entries:     map[string]memoryEntry{},
+		subscribers: map[string][]func(message string){},
+	}
+	gcFuncs = append(gcFuncs, b.gc)
+	return b
+}
+
+func expireAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// getLocked returns the entry if it is not expired
+func (b *MemoryBackend) getLocked(key string) (memoryEntry, bool) {
+	e, ok := b.entries[key]
+	if ok && e.expired(time.Now()) {
+		delete(b.entries, key)
+		return memoryEntry{}, false
+	}
+	return e, ok
+}
+
+func (b *MemoryBackend) Get(key string) ([]byte, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.getLocked(key)
+	return e.value, ok, nil
+}
+
+func (b *MemoryBackend) Set(key string, value []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[key] = memoryEntry{value: value, expireAt: expireAt(ttl)}
+	return nil
+}
+
+func (b *MemoryBackend) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.getLocked(key); ok {
+		return false, nil
+	}
+	b.entries[key] = memoryEntry{value: value, expireAt: expireAt(ttl)}
+	return true, nil
+}
+
+func (b *MemoryBackend) Del(keys ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, key := range keys {
+		delete(b.entries, key)
+	}
+	return nil
+}
+
+func (b *MemoryBackend) DelPrefix(prefix string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key := range b.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(b.entries, key)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBackend) Expire(key string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.getLocked(key)
+	if !ok {
+		return false, nil
+	}
+	e.expireAt = expireAt(ttl)
+	b.entries[key] = e
+	return true, nil
+}
+
+func (b *MemoryBackend) TTL(key string) (time.Duration, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.getLocked(key)
+	if !ok || e.expireAt.IsZero() {
+		return 0, false, nil
+	}
+	return time.Until(e.expireAt), true, nil
+}
+
+func (b *MemoryBackend) Publish(channel, message string) error {
+	b.mu.Lock()
+	handlers := b.subscribers[channel]
+	b.mu.Unlock()
+	for _, h := range handlers {
+		h(message)
+	}
+	return nil
+}
+
+func (b *MemoryBackend) Subscribe(channel string, handler func(message string)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[channel] = append(b.subscribers[channel], handler)
+	return nil
+}
+
+func (b *MemoryBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = map[string][]func(message string){}
+	return nil
+}
+
+func (b *MemoryBackend) gc() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	for key, e := range b.entries {
+		if e.expired(now) {
+			delete(b.entries, key)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	redisDialTimeout = 5 * time.Second
+	redisIOTimeout   = 10 * time.Second
+	redisMaxIdle     = 8
+)
+
+type RedisOptions struct {
+	Address  string
+	Password string
+	DB       int
+	// Prefix is prepended to the keys and the channels
+	Prefix string
+}
+
+// RedisBackend shares the entries and the broadcasts between the instances
+// by a redis server, it speaks the RESP protocol directly
+type RedisBackend struct {
+	opts RedisOptions
+	idle chan *redisConn
+
+	mu     sync.Mutex
+	closed bool
+	subs   []*redisConn
+}
+
+// NewRedisBackend connects to the redis server and checks it by a PING
+func NewRedisBackend(opts RedisOptions) (*RedisBackend, error) {
+	b := &RedisBackend{opts: opts, idle: make(chan *redisConn, redisMaxIdle)}
+	if _, err := b.do("PING"); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func (b *RedisBackend) dial() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", b.opts.Address, redisDialTimeout)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	if b.opts.Password != "" {
+		if _, err = c.do("AUTH", b.opts.Password); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	if b.opts.DB != 0 {
+		if _, err = c.do("SELECT", strconv.Itoa(b.opts.DB)); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *redisConn) write(args ...string) error {
+	_ = c.conn.SetDeadline(time.Now().Add(redisIOTimeout))
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return errors.WithStack(c.w.Flush())
+}
+
+// read parses a reply, the bulk strings are string, the nil ones are nil
+func (c *redisConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		return n, errors.WithStack(err)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, errors.WithStack(err)
+		}
+		buf := make([]byte, n+2)
+		if _, err = io.ReadFull(c.r, buf); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, errors.WithStack(err)
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, errors.Errorf("redis: unexpected reply %q", line)
+}
+
+func (c *redisConn) do(args ...string) (any, error) {
+	if err := c.write(args...); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// do runs the command on an idle connection, the connection is discarded
+// on the network errors
+func (b *RedisBackend) do(args ...string) (any, error) {
+	var c *redisConn
+	select {
+	case c = <-b.idle:
+	default:
+		var err error
+		if c, err = b.dial(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := c.do(args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		_ = c.conn.Close()
+		return nil, err
+	}
+	select {
+	case b.idle <- c:
+	default:
+		_ = c.conn.Close()
+	}
+	return reply, err
+}
+
+func (b *RedisBackend) key(key string) string {
+	return b.opts.Prefix + key
+}
+
+func millis(ttl time.Duration) string {
+	return strconv.FormatInt(max(ttl.Milliseconds(), 1), 10)
+}
+
+func (b *RedisBackend) Get(key string) ([]byte, bool, error) {
+	reply, err := b.do("GET", b.key(key))
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	s, _ := reply.(string)
+	return []byte(s), true, nil
+}
+
+func (b *RedisBackend) Set(key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", b.key(key), string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", millis(ttl))
+	}
+	_, err := b.do(args...)
+	return err
+}
+
+func (b *RedisBackend) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	args := []string{"SET", b.key(key), string(value), "NX"}
+	if ttl > 0 {
+		args = append(args, "PX", millis(ttl))
+	}
+	reply, err := b.do(args...)
+	return err == nil && reply != nil, err
+}
+
+func (b *RedisBackend) Del(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := []string{"DEL"}
+	for _, key := range keys {
+		args = append(args, b.key(key))
+	}
+	_, err := b.do(args...)
+	return err
+}
+
+// DelPrefix scans the keys by the prefix, the glob characters in the prefix are escaped
+func (b *RedisBackend) DelPrefix(prefix string) error {
+	pattern := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).
+		Replace(b.key(prefix)) + "*"
+	cursor := "0"
+	for {
+		reply, err := b.do("SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+		if err != nil {
+			return err
+		}
+		items, ok := reply.([]any)
+		if !ok || len(items) != 2 {
+			return errors.Errorf("redis: unexpected scan reply %v", reply)
+		}
+		cursor, _ = items[0].(string)
+		keys, _ := items[1].([]any)
+		if len(keys) > 0 {
+			args := []string{"DEL"}
+			for _, key := range keys {
+				s, _ := key.(string)
+				args = append(args, s)
+			}
+			if _, err = b.do(args...); err != nil {
+				return err
+			}
+		}
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+func (b *RedisBackend) Expire(key string, ttl time.Duration) (bool, error) {
+	if ttl > 0 {
+		reply, err := b.do("PEXPIRE", b.key(key), millis(ttl))
+		return reply == int64(1), err
+	}
+	// PERSIST does not tell the key without a timeout from the missing one
+	reply, err := b.do("EXISTS", b.key(key))
+	if err != nil || reply != int64(1) {
+		return false, err
+	}
+	_, err = b.do("PERSIST", b.key(key))
+	return err == nil, err
+}
+
+func (b *RedisBackend) TTL(key string) (time.Duration, bool, error) {
+	reply, err := b.do("PTTL", b.key(key))
+	if err != nil {
+		return 0, false, err
+	}
+	ms, _ := reply.(int64)
+	if ms < 0 {
+		return 0, false, nil
+	}
+	return time.Duration(ms) * time.Millisecond, true, nil
+}
+
+func (b *RedisBackend) Publish(channel, message string) error {
+	_, err := b.do("PUBLISH", b.key(channel), message)
+	return err
+}
+
+// Subscribe keeps a dedicated connection for the channel, it reconnects
+// after the failures until the backend is closed
+func (b *RedisBackend) Subscribe(channel string, handler func(message string)) error {
+	c, err := b.subscribe(channel)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			b.receive(c, handler)
+			for {
+				if b.isClosed() {
+					return
+				}
+				if c, err = b.subscribe(channel); err == nil {
+					break
+				}
+				log.Warnf("failed subscribe redis channel %s: %+v", channel, err)
+				time.Sleep(time.Second)
+			}
+			// the messages are lost while the connection is down
+			handler("")
+		}
+	}()
+	return nil
+}
+
+func (b *RedisBackend) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+func (b *RedisBackend) subscribe(channel string) (*redisConn, error) {
+	c, err := b.dial()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = c.do("SUBSCRIBE", b.key(channel)); err != nil {
+		_ = c.conn.Close()
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		_ = c.conn.Close()
+		return nil, errors.New("redis: backend is closed")
+	}
+	b.subs = append(b.subs, c)
+	return c, nil
+}
+
+func (b *RedisBackend) receive(c *redisConn, handler func(message string)) {
+	defer func() {
+		_ = c.conn.Close()
+		b.mu.Lock()
+		for i, sub := range b.subs {
+			if sub == c {
+				b.subs = append(b.subs[:i], b.subs[i+1:]...)
+				break
+			}
+		}
+		b.mu.Unlock()
+	}()
+	for {
+		_ = c.conn.SetDeadline(time.Time{})
+		reply, err := c.read()
+		if err != nil {
+			return
+		}
+		items, ok := reply.([]any)
+		if !ok || len(items) != 3 || items[0] != "message" {
+			continue
+		}
+		if message, ok := items[2].(string); ok && message != "" {
+			handler(message)
+		}
+	}
+}
+
+func (b *RedisBackend) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	for _, c := range b.subs {
+		_ = c.conn.Close()
+	}
+	b.subs = nil
+	b.mu.Unlock()
+	for {
+		select {
+		case c := <-b.idle:
+			_ = c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
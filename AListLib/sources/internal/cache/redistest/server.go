@@ -0,0 +1,303 @@
+// Package redistest runs an in-process stand-in of a redis server for the
+// tests. It speaks the RESP protocol and supports the commands used by the
+// redis backend of the caches.
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	value    string
+	expireAt time.Time
+}
+
+type Server struct {
+	ln net.Listener
+
+	mu          sync.Mutex
+	data        map[string]entry
+	subscribers map[string]map[*client]struct{}
+	conns       map[net.Conn]struct{}
+}
+
+type client struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+// Start listens on a random local port, the server is stopped by Close
+func Start() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		ln:          ln,
+		data:        map[string]entry{},
+		subscribers: map[string]map[*client]struct{}{},
+		conns:       map[net.Conn]struct{}{},
+	}
+	go s.serve()
+	return s, nil
+}
+
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *Server) Close() {
+	_ = s.ln.Close()
+	s.DropConnections()
+}
+
+// DropConnections closes the connections of the clients as a restart of the server
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+}
+
+// Keys returns the keys not expired
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for k := range s.data {
+		if _, ok := s.getLocked(k); ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	c := &client{w: bufio.NewWriter(conn)}
+	defer func() {
+		_ = conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		for _, subs := range s.subscribers {
+			delete(subs, c)
+		}
+		s.mu.Unlock()
+	}()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		reply := s.exec(c, args)
+		c.mu.Lock()
+		_, err = c.w.WriteString(reply)
+		if err == nil {
+			err = c.w.Flush()
+		}
+		c.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "*"), "\r\n"))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "$"), "\r\n"))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func integer(n int64) string {
+	return fmt.Sprintf(":%d\r\n", n)
+}
+
+func array(items ...string) string {
+	return fmt.Sprintf("*%d\r\n%s", len(items), strings.Join(items, ""))
+}
+
+const (
+	ok      = "+OK\r\n"
+	nilBulk = "$-1\r\n"
+)
+
+func (s *Server) getLocked(key string) (entry, bool) {
+	e, exists := s.data[key]
+	if exists && !e.expireAt.IsZero() && !time.Now().Before(e.expireAt) {
+		delete(s.data, key)
+		return entry{}, false
+	}
+	return e, exists
+}
+
+func (s *Server) exec(c *client, args []string) string {
+	if len(args) == 0 {
+		return "-ERR empty command\r\n"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "AUTH", "SELECT":
+		return ok
+	case "GET":
+		if e, exists := s.getLocked(args[1]); exists {
+			return bulk(e.value)
+		}
+		return nilBulk
+	case "SET":
+		e := entry{value: args[2]}
+		nx := false
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				nx = true
+			case "PX":
+				i++
+				ms, _ := strconv.ParseInt(args[i], 10, 64)
+				e.expireAt = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+		}
+		if _, exists := s.getLocked(args[1]); exists && nx {
+			return nilBulk
+		}
+		s.data[args[1]] = e
+		return ok
+	case "DEL":
+		var n int64
+		for _, key := range args[1:] {
+			if _, exists := s.getLocked(key); exists {
+				delete(s.data, key)
+				n++
+			}
+		}
+		return integer(n)
+	case "EXISTS":
+		var n int64
+		for _, key := range args[1:] {
+			if _, exists := s.getLocked(key); exists {
+				n++
+			}
+		}
+		return integer(n)
+	case "PEXPIRE":
+		e, exists := s.getLocked(args[1])
+		if !exists {
+			return integer(0)
+		}
+		ms, _ := strconv.ParseInt(args[2], 10, 64)
+		e.expireAt = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		s.data[args[1]] = e
+		return integer(1)
+	case "PERSIST":
+		e, exists := s.getLocked(args[1])
+		if !exists || e.expireAt.IsZero() {
+			return integer(0)
+		}
+		e.expireAt = time.Time{}
+		s.data[args[1]] = e
+		return integer(1)
+	case "PTTL":
+		e, exists := s.getLocked(args[1])
+		if !exists {
+			return integer(-2)
+		}
+		if e.expireAt.IsZero() {
+			return integer(-1)
+		}
+		return integer(time.Until(e.expireAt).Milliseconds())
+	case "SCAN":
+		// all the keys are returned at once
+		pattern := "*"
+		for i := 2; i+1 < len(args); i += 2 {
+			if strings.ToUpper(args[i]) == "MATCH" {
+				pattern = args[i+1]
+			}
+		}
+		var keys []string
+		for key := range s.data {
+			if _, exists := s.getLocked(key); !exists {
+				continue
+			}
+			if matched, _ := path.Match(pattern, key); matched {
+				keys = append(keys, bulk(key))
+			}
+		}
+		return array(bulk("0"), array(keys...))
+	case "PUBLISH":
+		subs := s.subscribers[args[1]]
+		for sub := range subs {
+			sub.send(array(bulk("message"), bulk(args[1]), bulk(args[2])))
+		}
+		return integer(int64(len(subs)))
+	case "SUBSCRIBE":
+		// the replies are sent before any message of the channels
+		for i, channel := range args[1:] {
+			if s.subscribers[channel] == nil {
+				s.subscribers[channel] = map[*client]struct{}{}
+			}
+			s.subscribers[channel][c] = struct{}{}
+			c.send(array(bulk("subscribe"), bulk(channel), integer(int64(i+1))))
+		}
+		return ""
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+}
+
+func (c *client) send(msg string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.w.WriteString(msg); err == nil {
+		_ = c.w.Flush()
+	}
+}
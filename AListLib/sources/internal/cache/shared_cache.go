@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	gocache "github.com/OpenListTeam/go-cache"
+	log "github.com/sirupsen/logrus"
+)
+
+// SharedCache stores the values in the shared backend under its namespace,
+// so the instances of a deployment see the same entries. The values are
+// encoded in json.
+type SharedCache[V any] struct {
+	namespace string
+}
+
+var _ gocache.ICache[int] = (*SharedCache[int])(nil)
+
+func NewSharedCache[V any](namespace string) *SharedCache[V] {
+	return &SharedCache[V]{namespace: namespace + ":"}
+}
+
+func (c *SharedCache[V]) key(k string) string {
+	return c.namespace + k
+}
+
+// sharedItem records the expiration set by the options
+type sharedItem struct {
+	expireAt time.Time
+}
+
+func (i *sharedItem) Expired() bool {
+	return i.CanExpire() && !time.Now().Before(i.expireAt)
+}
+
+func (i *sharedItem) CanExpire() bool {
+	return !i.expireAt.IsZero()
+}
+
+func (i *sharedItem) SetExpireAt(t time.Time) {
+	i.expireAt = t
+}
+
+func (c *SharedCache[V]) Set(k string, v V, opts ...gocache.SetIOption[V]) bool {
+	var item sharedItem
+	for _, opt := range opts {
+		if !opt(c, k, &item) {
+			return false
+		}
+	}
+	var ttl time.Duration
+	if item.CanExpire() {
+		if ttl = time.Until(item.expireAt); ttl <= 0 {
+			c.Del(k)
+			return true
+		}
+	}
+	data, err := utils.Json.Marshal(v)
+	if err != nil {
+		log.Warnf("failed encode the value of %s: %+v", c.key(k), err)
+		return false
+	}
+	if err = Shared().Set(c.key(k), data, ttl); err != nil {
+		log.Warnf("failed set %s: %+v", c.key(k), err)
+		return false
+	}
+	return true
+}
+
+func (c *SharedCache[V]) Get(k string) (V, bool) {
+	var v V
+	data, ok, err := Shared().Get(c.key(k))
+	if err != nil {
+		log.Warnf("failed get %s: %+v", c.key(k), err)
+		return v, false
+	}
+	if !ok {
+		return v, false
+	}
+	if err = utils.Json.Unmarshal(data, &v); err != nil {
+		log.Warnf("failed decode the value of %s: %+v", c.key(k), err)
+		return v, false
+	}
+	return v, true
+}
+
+func (c *SharedCache[V]) GetSet(k string, v V, opts ...gocache.SetIOption[V]) (V, bool) {
+	defer c.Set(k, v, opts...)
+	return c.Get(k)
+}
+
+func (c *SharedCache[V]) GetDel(k string) (V, bool) {
+	defer c.Del(k)
+	return c.Get(k)
+}
+
+func (c *SharedCache[V]) Del(ks ...string) int {
+	count := 0
+	keys := make([]string, 0, len(ks))
+	for _, k := range ks {
+		if c.Exists(k) {
+			count++
+		}
+		keys = append(keys, c.key(k))
+	}
+	if err := Shared().Del(keys...); err != nil {
+		log.Warnf("failed delete %v: %+v", keys, err)
+	}
+	return count
+}
+
+// DelExpired is a no-op as the backend removes the expired keys itself
+func (c *SharedCache[V]) DelExpired(k string) bool {
+	return false
+}
+
+func (c *SharedCache[V]) Exists(ks ...string) bool {
+	for _, k := range ks {
+		if _, ok, err := Shared().Get(c.key(k)); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *SharedCache[V]) Expire(k string, d time.Duration) bool {
+	if d <= 0 {
+		c.Del(k)
+		return false
+	}
+	ok, err := Shared().Expire(c.key(k), d)
+	if err != nil {
+		log.Warnf("failed expire %s: %+v", c.key(k), err)
+	}
+	return ok
+}
+
+func (c *SharedCache[V]) ExpireAt(k string, t time.Time) bool {
+	return c.Expire(k, time.Until(t))
+}
+
+func (c *SharedCache[V]) Persist(k string) bool {
+	ok, err := Shared().Expire(c.key(k), 0)
+	if err != nil {
+		log.Warnf("failed persist %s: %+v", c.key(k), err)
+	}
+	return ok
+}
+
+func (c *SharedCache[V]) Ttl(k string) (time.Duration, bool) {
+	ttl, ok, err := Shared().TTL(c.key(k))
+	if err != nil {
+		log.Warnf("failed get the ttl of %s: %+v", c.key(k), err)
+	}
+	return ttl, ok
+}
+
+func (c *SharedCache[V]) Clear() {
+	if err := Shared().DelPrefix(c.namespace); err != nil {
+		log.Warnf("failed clear %s: %+v", c.namespace, err)
+	}
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"strings"
 	"sync"
 	"time"
 )
@@ -81,6 +82,17 @@ func (c *TypedCache[T]) DeleteKey(key string) {
 	delete(c.entries, key)
 }
 
+// DeletePrefix deletes the keys starting with prefix
+func (c *TypedCache[T]) DeletePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
 func (c *TypedCache[T]) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -73,6 +73,7 @@ type TasksConfig struct {
 	Move               TaskConfig `json:"move" envPrefix:"MOVE_"`
 	Decompress         TaskConfig `json:"decompress" envPrefix:"DECOMPRESS_"`
 	DecompressUpload   TaskConfig `json:"decompress_upload" envPrefix:"DECOMPRESS_UPLOAD_"`
+	Dedupe             TaskConfig `json:"dedupe" envPrefix:"DEDUPE_"`
 	AllowRetryCanceled bool       `json:"allow_retry_canceled" env:"ALLOW_RETRY_CANCELED"`
 }
 
@@ -106,10 +107,123 @@ type SFTP struct {
 	Listen string `json:"listen" env:"LISTEN"`
 }
 
+type DLNA struct {
+	Enable       bool   `json:"enable" env:"ENABLE"`
+	Listen       string `json:"listen" env:"LISTEN"`
+	FriendlyName string `json:"friendly_name" env:"FRIENDLY_NAME"`
+	// User browses the file system, the guest is used if it is empty
+	User string `json:"user" env:"USER"`
+	// NotifyInterval is the interval in seconds of the SSDP alive announcements
+	NotifyInterval int `json:"notify_interval" env:"NOTIFY_INTERVAL"`
+}
+
+type NFSExport struct {
+	// CIDR of the clients that match the rule, e.g. 192.168.1.0/24
+	CIDR string `json:"cidr"`
+	// User the clients act as, the guest is used if it is empty
+	User     string `json:"user"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+type NFS struct {
+	Enable bool   `json:"enable" env:"ENABLE"`
+	Listen string `json:"listen" env:"LISTEN"`
+	// Exports are matched in order, clients that match no rule are rejected
+	Exports []NFSExport `json:"exports"`
+}
+
 type MCP struct {
 	Enable bool `json:"enable" env:"ENABLE"`
 }
 
+type Subsonic struct {
+	Enable bool `json:"enable" env:"ENABLE"`
+	// MusicRoots are the paths scanned for the music library
+	MusicRoots []string `json:"music_roots" env:"MUSIC_ROOTS"`
+	// ScanInterval is the interval in minutes of the library scans, 0 only scans on demand
+	ScanInterval int `json:"scan_interval" env:"SCAN_INTERVAL"`
+}
+
+type OPDS struct {
+	Enable bool `json:"enable" env:"ENABLE"`
+	// Roots are the paths exposed as catalogs
+	Roots []string `json:"roots" env:"ROOTS"`
+}
+
+type Restic struct {
+	Enable bool `json:"enable" env:"ENABLE"`
+	// Path is the directory of the repositories under the base path of the user
+	Path string `json:"path" env:"PATH"`
+}
+
+type Tus struct {
+	// Dir stages the chunks of the resumable uploads, it is kept across restarts
+	Dir string `json:"dir" env:"DIR"`
+	// Expire is the hours an unfinished upload is kept after its last chunk
+	Expire int `json:"expire" env:"EXPIRE"`
+	// MaxSize is the size cap in MB of an upload, 0 is unlimited
+	MaxSize int64 `json:"max_size" env:"MAX_SIZE"`
+}
+
+type BlockCache struct {
+	// Dir stores the cached blocks of the proxied downloads, it is kept across restarts
+	Dir string `json:"dir" env:"DIR"`
+	// MaxSize is the size cap in MB, the least recently used blocks are evicted, 0 disables the cache
+	MaxSize int `json:"max_size" env:"MAX_SIZE"`
+	// BlockSize is the size in MB of the blocks read from the upstream
+	BlockSize int `json:"block_size" env:"BLOCK_SIZE"`
+}
+
+type Redis struct {
+	Address  string `json:"address" env:"ADDRESS"`
+	Password string `json:"password" env:"PASSWORD"`
+	DB       int    `json:"db" env:"DB"`
+	// Prefix is prepended to the keys, the deployments sharing a server need different prefixes
+	Prefix string `json:"prefix" env:"PREFIX"`
+}
+
+type CacheConfig struct {
+	// Backend is memory or redis, the redis backend shares the caches and the
+	// task ownership between the instances of a deployment
+	Backend string `json:"backend" env:"BACKEND"`
+	Redis   Redis  `json:"redis" envPrefix:"REDIS_"`
+}
+
+type Metrics struct {
+	Enable bool `json:"enable" env:"ENABLE"`
+	// Token is required as a bearer token by /metrics of the main server, the
+	// endpoint is not served there when it is empty
+	Token string `json:"token" env:"TOKEN"`
+	// Listen serves /metrics without the token on a separate address, e.g. 127.0.0.1:9090
+	Listen string `json:"listen" env:"LISTEN"`
+}
+
+type Tracing struct {
+	Enable bool `json:"enable" env:"ENABLE"`
+	// Endpoint is the url of the OTLP/HTTP collector, e.g. http://localhost:4318,
+	// the OTEL_EXPORTER_OTLP_* variables are used when it is empty
+	Endpoint    string `json:"endpoint" env:"ENDPOINT"`
+	ServiceName string `json:"service_name" env:"SERVICE_NAME"`
+	// SampleRatio is the ratio of the traces sampled, from 0 to 1
+	SampleRatio float64 `json:"sample_ratio" env:"SAMPLE_RATIO"`
+}
+
+type Health struct {
+	// Enable probes the storages periodically and initializes again the failed ones
+	Enable bool `json:"enable" env:"ENABLE"`
+	// Interval is the seconds between the probes of a storage
+	Interval int `json:"interval" env:"INTERVAL"`
+	// Timeout is the seconds a probe may take
+	Timeout int `json:"timeout" env:"TIMEOUT"`
+	// MaxBackoff is the max seconds between the initializations of a failed storage
+	MaxBackoff int `json:"max_backoff" env:"MAX_BACKOFF"`
+	// FailureThreshold is the number of the probes failed in a row before a
+	// working storage is initialized again
+	FailureThreshold int `json:"failure_threshold" env:"FAILURE_THRESHOLD"`
+	// History is the number of the checks kept for each storage
+	History int `json:"history" env:"HISTORY"`
+}
+
 type Config struct {
 	Force                 bool        `json:"force" env:"FORCE"`
 	SiteURL               string      `json:"site_url" env:"SITE_URL"`
@@ -135,16 +249,33 @@ type Config struct {
 	S3                    S3          `json:"s3" envPrefix:"S3_"`
 	FTP                   FTP         `json:"ftp" envPrefix:"FTP_"`
 	SFTP                  SFTP        `json:"sftp" envPrefix:"SFTP_"`
+	DLNA                  DLNA        `json:"dlna" envPrefix:"DLNA_"`
+	NFS                   NFS         `json:"nfs" envPrefix:"NFS_"`
 	MCP                   MCP         `json:"mcp" envPrefix:"MCP_"`
+	Subsonic              Subsonic    `json:"subsonic" envPrefix:"SUBSONIC_"`
+	OPDS                  OPDS        `json:"opds" envPrefix:"OPDS_"`
+	Restic                Restic      `json:"restic" envPrefix:"RESTIC_"`
+	Tus                   Tus         `json:"tus" envPrefix:"TUS_"`
+	BlockCache            BlockCache  `json:"block_cache" envPrefix:"BLOCK_CACHE_"`
+	PersistDirCache       bool        `json:"persist_dir_cache" env:"PERSIST_DIR_CACHE"`
+	Cache                 CacheConfig `json:"cache" envPrefix:"CACHE_"`
+	Metrics               Metrics     `json:"metrics" envPrefix:"METRICS_"`
+	Tracing               Tracing     `json:"tracing" envPrefix:"TRACING_"`
+	StorageHealth         Health      `json:"storage_health" envPrefix:"STORAGE_HEALTH_"`
 	LastLaunchedVersion   string      `json:"last_launched_version"`
 	ProxyAddress          string      `json:"proxy_address" env:"PROXY_ADDRESS"`
+	MasterKey             string      `json:"-" env:"MASTER_KEY"` // never written to the config file
+	MasterKeyFile         string      `json:"master_key_file" env:"MASTER_KEY_FILE"`
 }
 
 func DefaultConfig(dataDir string) *Config {
 	tempDir := filepath.Join(dataDir, "temp")
+	tusDir := filepath.Join(dataDir, "tus")
+	blockCacheDir := filepath.Join(dataDir, "block_cache")
 	indexDir := filepath.Join(dataDir, "bleve")
 	logPath := filepath.Join(dataDir, "log/log.log")
 	dbPath := filepath.Join(dataDir, "data.db")
+	masterKeyFile := filepath.Join(dataDir, "master.key")
 	return &Config{
 		Scheme: Scheme{
 			Address:    "0.0.0.0",
@@ -158,6 +289,7 @@ func DefaultConfig(dataDir string) *Config {
 		JwtSecret:      random.String(16),
 		TokenExpiresIn: 48,
 		TempDir:        tempDir,
+		MasterKeyFile:  masterKeyFile,
 		Database: Database{
 			Type:        "sqlite3",
 			Port:        0,
@@ -221,6 +353,9 @@ func DefaultConfig(dataDir string) *Config {
 				Workers:  5,
 				MaxRetry: 2,
 			},
+			Dedupe: TaskConfig{
+				Workers: 1,
+			},
 			AllowRetryCanceled: false,
 		},
 		Cors: Cors{
@@ -249,9 +384,68 @@ func DefaultConfig(dataDir string) *Config {
 			Enable: false,
 			Listen: ":5222",
 		},
+		DLNA: DLNA{
+			Enable:         false,
+			Listen:         ":5223",
+			FriendlyName:   "OpenList",
+			NotifyInterval: 900,
+		},
+		NFS: NFS{
+			Enable: false,
+			Listen: ":2049",
+			Exports: []NFSExport{{
+				CIDR:     "127.0.0.1/32",
+				ReadOnly: true,
+			}},
+		},
 		MCP: MCP{
 			Enable: false,
 		},
+		Subsonic: Subsonic{
+			Enable:       false,
+			MusicRoots:   []string{},
+			ScanInterval: 1440,
+		},
+		OPDS: OPDS{
+			Enable: false,
+			Roots:  []string{},
+		},
+		Restic: Restic{
+			Enable: false,
+			Path:   "/restic",
+		},
+		Tus: Tus{
+			Dir:     tusDir,
+			Expire:  24,
+			MaxSize: 10240,
+		},
+		BlockCache: BlockCache{
+			Dir:       blockCacheDir,
+			MaxSize:   1024,
+			BlockSize: 4,
+		},
+		Cache: CacheConfig{
+			Backend: "memory",
+			Redis: Redis{
+				Prefix: "openlist:",
+			},
+		},
+		Metrics: Metrics{
+			Enable: false,
+		},
+		Tracing: Tracing{
+			Enable:      false,
+			ServiceName: "openlist",
+			SampleRatio: 1,
+		},
+		StorageHealth: Health{
+			Enable:           false,
+			Interval:         300,
+			Timeout:          30,
+			MaxBackoff:       3600,
+			FailureThreshold: 3,
+			History:          20,
+		},
 		LastLaunchedVersion: "",
 		ProxyAddress:        "",
 	}
@@ -74,6 +74,13 @@ const (
 	TransmissionUri      = "transmission_uri"
 	TransmissionSeedtime = "transmission_seedtime"
 
+	// bittorrent
+	BitTorrentEnabled    = "bittorrent_enabled"
+	BitTorrentListenPort = "bittorrent_listen_port"
+	BitTorrentEnableDHT  = "bittorrent_enable_dht"
+	BitTorrentSeedtime   = "bittorrent_seedtime"
+	BitTorrentSeedRatio  = "bittorrent_seed_ratio"
+
 	// 115
 	Pan115TempDir = "115_temp_dir"
 
@@ -157,6 +164,7 @@ const (
 	TaskMoveThreadsNum                    = "move_task_threads_num"
 	TaskDecompressDownloadThreadsNum      = "decompress_download_task_threads_num"
 	TaskDecompressUploadThreadsNum        = "decompress_upload_task_threads_num"
+	TaskVerifyChecksum                    = "task_verify_checksum"
 	StreamMaxClientDownloadSpeed          = "max_client_download_speed"
 	StreamMaxClientUploadSpeed            = "max_client_upload_speed"
 	StreamMaxServerDownloadSpeed          = "max_server_download_speed"
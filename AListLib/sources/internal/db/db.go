@@ -12,7 +12,7 @@ var db *gorm.DB
 
 func Init(d *gorm.DB) {
 	db = d
-	err := AutoMigrate(new(model.Storage), new(model.User), new(model.Meta), new(model.SettingItem), new(model.SearchNode), new(model.TaskItem), new(model.SSHPublicKey), new(model.SharingDB))
+	err := AutoMigrate(new(model.Storage), new(model.User), new(model.Meta), new(model.SettingItem), new(model.SearchNode), new(model.TaskItem), new(model.SSHPublicKey), new(model.SharingDB), new(model.Feed), new(model.FeedItem), new(model.MusicArtist), new(model.MusicAlbum), new(model.MusicTrack), new(model.EbookMeta), new(model.TusUpload), new(model.DirCache), new(model.LifecycleRule), new(model.LifecycleRun), new(model.LifecycleItem), new(model.FileHash))
 	if err != nil {
 		log.Fatalf("failed migrate database: %s", err.Error())
 	}
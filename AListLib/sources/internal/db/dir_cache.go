@@ -0,0 +1,51 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// SaveDirCache replaces the persisted listing of the directory
+func SaveDirCache(c *model.DirCache) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("path")), c.Path).Delete(&model.DirCache{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(c).Error
+	}))
+}
+
+func DeleteDirCache(path string) error {
+	return errors.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("path")), path).Delete(&model.DirCache{}).Error)
+}
+
+// DeleteDirCacheTree deletes the persisted listings of the directory and the directories under it
+func DeleteDirCacheTree(path string) error {
+	return errors.WithStack(whereInDir("path", path).Delete(&model.DirCache{}).Error)
+}
+
+func DeleteDirCachesByStorage(storageID uint) error {
+	return errors.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("storage_id")), storageID).Delete(&model.DirCache{}).Error)
+}
+
+func ClearDirCaches() error {
+	return errors.WithStack(db.Where("1 = 1").Delete(&model.DirCache{}).Error)
+}
+
+// GetDirCachesByStorage returns the persisted listings of the storage not expired at t
+func GetDirCachesByStorage(storageID uint, t time.Time) ([]model.DirCache, error) {
+	var caches []model.DirCache
+	if err := db.Where(fmt.Sprintf("%s = ? AND %s > ?", columnName("storage_id"), columnName("expires_at")), storageID, t).
+		Find(&caches).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get dir caches")
+	}
+	return caches, nil
+}
+
+func DeleteExpiredDirCaches(t time.Time) error {
+	return errors.WithStack(db.Where(fmt.Sprintf("%s <= ?", columnName("expires_at")), t).Delete(&model.DirCache{}).Error)
+}
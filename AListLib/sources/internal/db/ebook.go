@@ -0,0 +1,21 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetEbookMeta(dir, name string) (*model.EbookMeta, error) {
+	var m model.EbookMeta
+	if err := db.Where(fmt.Sprintf("%s = ? AND %s = ?", columnName("dir"), columnName("name")), dir, name).
+		First(&m).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get ebook meta")
+	}
+	return &m, nil
+}
+
+func SaveEbookMeta(m *model.EbookMeta) error {
+	return errors.WithStack(db.Save(m).Error)
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetFeeds(pageIndex, pageSize int) (feeds []model.Feed, count int64, err error) {
+	feedDB := db.Model(&model.Feed{})
+	if err := feedDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get feeds count")
+	}
+	if err := feedDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&feeds).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find feeds")
+	}
+	return feeds, count, nil
+}
+
+func GetEnabledFeeds() ([]model.Feed, error) {
+	var feeds []model.Feed
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("disabled")), false).Find(&feeds).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find enabled feeds")
+	}
+	return feeds, nil
+}
+
+func GetFeedById(id uint) (*model.Feed, error) {
+	var f model.Feed
+	if err := db.First(&f, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get feed")
+	}
+	return &f, nil
+}
+
+func CreateFeed(f *model.Feed) error {
+	return errors.WithStack(db.Create(f).Error)
+}
+
+func UpdateFeed(f *model.Feed) error {
+	return errors.WithStack(db.Save(f).Error)
+}
+
+// UpdateFeedCheckResult only updates the check result, leave the rules untouched
+func UpdateFeedCheckResult(f *model.Feed) error {
+	return errors.WithStack(db.Model(f).Select("last_checked", "last_error").Updates(f).Error)
+}
+
+func DeleteFeedById(id uint) error {
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("feed_id")), id).Delete(&model.FeedItem{}).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(db.Delete(&model.Feed{}, id).Error)
+}
+
+func GetFeedItem(feedId uint, guid string) (*model.FeedItem, error) {
+	item := model.FeedItem{FeedId: feedId, Guid: guid}
+	if err := db.Where(item).First(&item).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get feed item")
+	}
+	return &item, nil
+}
+
+func SaveFeedItem(item *model.FeedItem) error {
+	return errors.WithStack(db.Save(item).Error)
+}
+
+func GetFeedItems(feedId uint, pageIndex, pageSize int) (items []model.FeedItem, count int64, err error) {
+	itemDB := db.Model(&model.FeedItem{}).Where(model.FeedItem{FeedId: feedId})
+	if err := itemDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get feed items count")
+	}
+	if err := itemDB.Order(columnName("id") + " DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find feed items")
+	}
+	return items, count, nil
+}
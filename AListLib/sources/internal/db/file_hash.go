@@ -0,0 +1,27 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+func fileHashKey(path string) string {
+	return utils.HashData(utils.SHA1, []byte(path))
+}
+
+func GetFileHash(path string) (*model.FileHash, error) {
+	h := model.FileHash{Key: fileHashKey(path)}
+	if err := db.Where(h).First(&h).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get file hash")
+	}
+	return &h, nil
+}
+
+func SaveFileHash(h *model.FileHash) error {
+	h.Key = fileHashKey(h.Path)
+	if old, err := GetFileHash(h.Path); err == nil {
+		h.ID = old.ID
+	}
+	return errors.WithStack(db.Save(h).Error)
+}
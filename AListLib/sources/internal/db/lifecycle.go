@@ -0,0 +1,96 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetLifecycleRules(pageIndex, pageSize int) (rules []model.LifecycleRule, count int64, err error) {
+	ruleDB := db.Model(&model.LifecycleRule{})
+	if err := ruleDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get lifecycle rules count")
+	}
+	if err := ruleDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&rules).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find lifecycle rules")
+	}
+	return rules, count, nil
+}
+
+func GetEnabledLifecycleRules() ([]model.LifecycleRule, error) {
+	var rules []model.LifecycleRule
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("disabled")), false).Find(&rules).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find enabled lifecycle rules")
+	}
+	return rules, nil
+}
+
+func GetLifecycleRuleById(id uint) (*model.LifecycleRule, error) {
+	var r model.LifecycleRule
+	if err := db.First(&r, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get lifecycle rule")
+	}
+	return &r, nil
+}
+
+func CreateLifecycleRule(r *model.LifecycleRule) error {
+	return errors.WithStack(db.Create(r).Error)
+}
+
+func UpdateLifecycleRule(r *model.LifecycleRule) error {
+	return errors.WithStack(db.Save(r).Error)
+}
+
+// UpdateLifecycleRuleRunResult only updates the run result, leave the rule untouched
+func UpdateLifecycleRuleRunResult(r *model.LifecycleRule) error {
+	return errors.WithStack(db.Model(r).Select("last_run", "last_error").Updates(r).Error)
+}
+
+func DeleteLifecycleRuleById(id uint) error {
+	runs := db.Model(&model.LifecycleRun{}).Select("id").Where(fmt.Sprintf("%s = ?", columnName("rule_id")), id)
+	if err := db.Where(fmt.Sprintf("%s IN (?)", columnName("run_id")), runs).Delete(&model.LifecycleItem{}).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("rule_id")), id).Delete(&model.LifecycleRun{}).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(db.Delete(&model.LifecycleRule{}, id).Error)
+}
+
+func CreateLifecycleRun(run *model.LifecycleRun) error {
+	return errors.WithStack(db.Create(run).Error)
+}
+
+func UpdateLifecycleRun(run *model.LifecycleRun) error {
+	return errors.WithStack(db.Save(run).Error)
+}
+
+func CreateLifecycleItems(items []model.LifecycleItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return errors.WithStack(db.CreateInBatches(items, 100).Error)
+}
+
+func GetLifecycleRuns(ruleId uint, pageIndex, pageSize int) (runs []model.LifecycleRun, count int64, err error) {
+	runDB := db.Model(&model.LifecycleRun{}).Where(model.LifecycleRun{RuleId: ruleId})
+	if err := runDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get lifecycle runs count")
+	}
+	if err := runDB.Order(columnName("id") + " DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&runs).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find lifecycle runs")
+	}
+	return runs, count, nil
+}
+
+func GetLifecycleItems(runId uint, pageIndex, pageSize int) (items []model.LifecycleItem, count int64, err error) {
+	itemDB := db.Model(&model.LifecycleItem{}).Where(model.LifecycleItem{RunId: runId})
+	if err := itemDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get lifecycle items count")
+	}
+	if err := itemDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find lifecycle items")
+	}
+	return items, count, nil
+}
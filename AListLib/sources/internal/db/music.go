@@ -0,0 +1,243 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// whereInDir matches the rows whose column is dir or a path under it
+func whereInDir(column, dir string) *gorm.DB {
+	if dir == "/" {
+		return db.Where("1 = 1")
+	}
+	return db.Where(fmt.Sprintf("%s LIKE ?", columnName(column)),
+		fmt.Sprintf("%s/%%", dir)).
+		Or(fmt.Sprintf("%s = ?", columnName(column)), dir)
+}
+
+func randomOrder() string {
+	if conf.Conf.Database.Type == "mysql" {
+		return "RAND()"
+	}
+	return "RANDOM()"
+}
+
+func GetAllMusicArtists() ([]model.MusicArtist, error) {
+	var artists []model.MusicArtist
+	if err := db.Find(&artists).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find music artists")
+	}
+	return artists, nil
+}
+
+func GetAllMusicAlbums() ([]model.MusicAlbum, error) {
+	var albums []model.MusicAlbum
+	if err := db.Find(&albums).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find music albums")
+	}
+	return albums, nil
+}
+
+func GetAllMusicTracks() ([]model.MusicTrack, error) {
+	var tracks []model.MusicTrack
+	if err := db.Find(&tracks).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find music tracks")
+	}
+	return tracks, nil
+}
+
+func SaveMusicArtist(a *model.MusicArtist) error {
+	return errors.WithStack(db.Save(a).Error)
+}
+
+func SaveMusicAlbum(a *model.MusicAlbum) error {
+	return errors.WithStack(db.Save(a).Error)
+}
+
+// SaveMusicTracks saves the tracks in a transaction
+func SaveMusicTracks(tracks []*model.MusicTrack) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		for _, t := range tracks {
+			if err := tx.Save(t).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+func DeleteMusicArtists(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return errors.WithStack(db.Delete(&model.MusicArtist{}, ids).Error)
+}
+
+func DeleteMusicAlbums(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return errors.WithStack(db.Delete(&model.MusicAlbum{}, ids).Error)
+}
+
+func DeleteMusicTracks(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return errors.WithStack(db.Delete(&model.MusicTrack{}, ids).Error)
+}
+
+func GetMusicArtistById(id uint) (*model.MusicArtist, error) {
+	var a model.MusicArtist
+	if err := db.First(&a, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get music artist")
+	}
+	return &a, nil
+}
+
+func GetMusicAlbumById(id uint) (*model.MusicAlbum, error) {
+	var a model.MusicAlbum
+	if err := db.First(&a, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get music album")
+	}
+	return &a, nil
+}
+
+func GetMusicTrackById(id uint) (*model.MusicTrack, error) {
+	var t model.MusicTrack
+	if err := db.First(&t, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get music track")
+	}
+	return &t, nil
+}
+
+// GetMusicArtists returns the artists that have albums in dir
+func GetMusicArtists(dir string) ([]model.MusicArtist, error) {
+	var artists []model.MusicArtist
+	albums := db.Model(&model.MusicAlbum{}).Select(columnName("artist_id")).Where(whereInDir("dir", dir))
+	if err := db.Where(fmt.Sprintf("%s IN (?)", columnName("id")), albums).
+		Order(columnName("name")).Find(&artists).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find music artists")
+	}
+	return artists, nil
+}
+
+func GetMusicAlbumsByArtist(artistId uint, dir string) ([]model.MusicAlbum, error) {
+	var albums []model.MusicAlbum
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("artist_id")), artistId).Where(whereInDir("dir", dir)).
+		Order(fmt.Sprintf("%s, %s", columnName("year"), columnName("name"))).Find(&albums).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find music albums")
+	}
+	return albums, nil
+}
+
+func GetMusicTracksByAlbum(albumId uint, dir string) ([]model.MusicTrack, error) {
+	var tracks []model.MusicTrack
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("album_id")), albumId).Where(whereInDir("dir", dir)).
+		Order(fmt.Sprintf("%s, %s, %s", columnName("disc"), columnName("track"), columnName("name"))).
+		Find(&tracks).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find music tracks")
+	}
+	return tracks, nil
+}
+
+// MusicAlbumListReq is the filter and the order of an album list
+type MusicAlbumListReq struct {
+	// Type is one of random, newest, alphabeticalByName, alphabeticalByArtist, byYear and byGenre
+	Type     string
+	Genre    string
+	FromYear int
+	ToYear   int
+	Offset   int
+	Size     int
+}
+
+func GetMusicAlbums(dir string, req MusicAlbumListReq) ([]model.MusicAlbum, error) {
+	albumDB := db.Model(&model.MusicAlbum{}).Where(whereInDir("dir", dir))
+	switch req.Type {
+	case "random":
+		albumDB = albumDB.Order(randomOrder())
+	case "newest":
+		albumDB = albumDB.Order(columnName("created") + " DESC")
+	case "alphabeticalByArtist":
+		albumDB = albumDB.Order(fmt.Sprintf("%s, %s", columnName("artist"), columnName("name")))
+	case "byYear":
+		from, to, order := req.FromYear, req.ToYear, ""
+		if from > to {
+			from, to, order = to, from, " DESC"
+		}
+		albumDB = albumDB.Where(fmt.Sprintf("%s BETWEEN ? AND ?", columnName("year")), from, to).
+			Order(columnName("year") + order)
+	case "byGenre":
+		albumDB = albumDB.Where(fmt.Sprintf("%s = ?", columnName("genre")), req.Genre).Order(columnName("name"))
+	default:
+		albumDB = albumDB.Order(columnName("name"))
+	}
+	var albums []model.MusicAlbum
+	if err := albumDB.Offset(req.Offset).Limit(req.Size).Find(&albums).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find music albums")
+	}
+	return albums, nil
+}
+
+func GetRandomMusicTracks(dir, genre string, size int) ([]model.MusicTrack, error) {
+	trackDB := db.Model(&model.MusicTrack{}).Where(whereInDir("dir", dir))
+	if genre != "" {
+		trackDB = trackDB.Where(fmt.Sprintf("%s = ?", columnName("genre")), genre)
+	}
+	var tracks []model.MusicTrack
+	if err := trackDB.Order(randomOrder()).Limit(size).Find(&tracks).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find music tracks")
+	}
+	return tracks, nil
+}
+
+// MusicSearchReq is a page of the matches of a search
+type MusicSearchReq struct {
+	Query  string
+	Offset int
+	Size   int
+}
+
+func SearchMusicArtists(dir string, req MusicSearchReq) ([]model.MusicArtist, error) {
+	var artists []model.MusicArtist
+	albums := db.Model(&model.MusicAlbum{}).Select(columnName("artist_id")).Where(whereInDir("dir", dir))
+	if err := db.Where(fmt.Sprintf("%s IN (?)", columnName("id")), albums).
+		Where(fmt.Sprintf("%s LIKE ?", columnName("name")), fmt.Sprintf("%%%s%%", req.Query)).
+		Order(columnName("name")).Offset(req.Offset).Limit(req.Size).Find(&artists).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed search music artists")
+	}
+	return artists, nil
+}
+
+func SearchMusicAlbums(dir string, req MusicSearchReq) ([]model.MusicAlbum, error) {
+	var albums []model.MusicAlbum
+	if err := db.Where(whereInDir("dir", dir)).
+		Where(fmt.Sprintf("%s LIKE ?", columnName("name")), fmt.Sprintf("%%%s%%", req.Query)).
+		Order(columnName("name")).Offset(req.Offset).Limit(req.Size).Find(&albums).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed search music albums")
+	}
+	return albums, nil
+}
+
+func SearchMusicTracks(dir string, req MusicSearchReq) ([]model.MusicTrack, error) {
+	var tracks []model.MusicTrack
+	if err := db.Where(whereInDir("dir", dir)).
+		Where(fmt.Sprintf("%s LIKE ?", columnName("title")), fmt.Sprintf("%%%s%%", req.Query)).
+		Order(columnName("title")).Offset(req.Offset).Limit(req.Size).Find(&tracks).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed search music tracks")
+	}
+	return tracks, nil
+}
+
+func GetMusicTracksByDir(dir string) ([]model.MusicTrack, error) {
+	var tracks []model.MusicTrack
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("dir")), dir).Find(&tracks).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find music tracks")
+	}
+	return tracks, nil
+}
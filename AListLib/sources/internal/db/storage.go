@@ -4,7 +4,10 @@ import (
 	"fmt"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/secret"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // why don't need `cache` for storage?
@@ -12,14 +15,73 @@ import (
 // the most of the read operation is from `op.storagesMap`
 // just for persistence in database
 
+// the confidential fields of the addition are encrypted when a storage is
+// written and decrypted when it is read, so callers always see plain text
+
+func encryptStorage(storage *model.Storage) (*model.Storage, error) {
+	addition, err := secret.EncryptAddition(storage.Driver, storage.Addition)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed encrypt addition of storage [%s]", storage.MountPath)
+	}
+	s := *storage
+	s.Addition = addition
+	return &s, nil
+}
+
+func decryptStorage(storage *model.Storage) {
+	addition, err := secret.DecryptAddition(storage.Driver, storage.Addition)
+	if err != nil {
+		// keep the encrypted values, they are written back untouched and
+		// the storage fails to load with the error of the decryption
+		log.Errorf("failed decrypt addition of storage [%s]: %+v", storage.MountPath, err)
+		return
+	}
+	storage.Addition = addition
+}
+
+func decryptStorages(storages []model.Storage) {
+	for i := range storages {
+		decryptStorage(&storages[i])
+	}
+}
+
 // CreateStorage just insert storage to database
 func CreateStorage(storage *model.Storage) error {
-	return errors.WithStack(db.Create(storage).Error)
+	s, err := encryptStorage(storage)
+	if err != nil {
+		return err
+	}
+	if err := db.Create(s).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	storage.ID = s.ID
+	return nil
 }
 
 // UpdateStorage just update storage in database
 func UpdateStorage(storage *model.Storage) error {
-	return errors.WithStack(db.Save(storage).Error)
+	s, err := encryptStorage(storage)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(db.Save(s).Error)
+}
+
+// SaveStorages updates storages in a transaction, it is used to re-encrypt
+// all storages after the master key is changed
+func SaveStorages(storages []model.Storage) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		for i := range storages {
+			s, err := encryptStorage(&storages[i])
+			if err != nil {
+				return err
+			}
+			if err := tx.Save(s).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
 }
 
 // DeleteStorageById just delete storage from database by id
@@ -38,6 +100,7 @@ func GetStorages(pageIndex, pageSize int) ([]model.Storage, int64, error) {
 	if err := addStorageOrder(storageDB).Order(columnName("order")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&storages).Error; err != nil {
 		return nil, 0, errors.WithStack(err)
 	}
+	decryptStorages(storages)
 	return storages, count, nil
 }
 
@@ -48,6 +111,7 @@ func GetStorageById(id uint) (*model.Storage, error) {
 	if err := db.First(&storage).Error; err != nil {
 		return nil, errors.WithStack(err)
 	}
+	decryptStorage(&storage)
 	return &storage, nil
 }
 
@@ -57,6 +121,7 @@ func GetStorageByMountPath(mountPath string) (*model.Storage, error) {
 	if err := db.Where("mount_path = ?", mountPath).First(&storage).Error; err != nil {
 		return nil, errors.WithStack(err)
 	}
+	decryptStorage(&storage)
 	return &storage, nil
 }
 
@@ -66,5 +131,6 @@ func GetEnabledStorages() ([]model.Storage, error) {
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	decryptStorages(storages)
 	return storages, nil
 }
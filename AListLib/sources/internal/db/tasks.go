@@ -1,9 +1,13 @@
 package db
 
 import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 func GetTaskDataByType(type_s string) (*model.TaskItem, error) {
@@ -22,8 +26,25 @@ func CreateTaskData(t *model.TaskItem) error {
 	return errors.WithStack(db.Create(t).Error)
 }
 
+// taskOwnerTTL is the lease of the persisted tasks of a type, the owner renews it while running
+const taskOwnerTTL = time.Minute
+
+func taskOwnerKey(type_s string) string {
+	return "task:" + type_s
+}
+
+// ownTaskData claims the persisted tasks of the type for this instance, so the
+// instances sharing the database never restore and run the same tasks twice
+func ownTaskData(type_s string) bool {
+	if cache.Hold(taskOwnerKey(type_s), taskOwnerTTL) {
+		return true
+	}
+	log.Warnf("the persisted %s tasks are owned by another instance, the %s tasks of this instance are not persisted", type_s, type_s)
+	return false
+}
+
 func GetTaskDataFunc(type_s string, enabled bool) func() ([]byte, error) {
-	if !enabled {
+	if !enabled || !ownTaskData(type_s) {
 		return nil
 	}
 	task, err := GetTaskDataByType(type_s)
@@ -37,10 +58,15 @@ func GetTaskDataFunc(type_s string, enabled bool) func() ([]byte, error) {
 }
 
 func UpdateTaskDataFunc(type_s string, enabled bool) func([]byte) error {
-	if !enabled {
+	if !enabled || !ownTaskData(type_s) {
 		return nil
 	}
 	return func(data []byte) error {
+		// the lease may be taken over while this instance could not renew it
+		if !cache.Holds(taskOwnerKey(type_s)) {
+			log.Warnf("the lease of the %s tasks is lost, they are not persisted", type_s)
+			return nil
+		}
 		s := string(data)
 		if s == "null" || s == "" {
 			s = "[]"
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func CreateTusUpload(u *model.TusUpload) error {
+	return errors.WithStack(db.Create(u).Error)
+}
+
+func GetTusUpload(id string) (*model.TusUpload, error) {
+	var u model.TusUpload
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("id")), id).First(&u).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get tus upload")
+	}
+	return &u, nil
+}
+
+func UpdateTusUpload(u *model.TusUpload) error {
+	return errors.WithStack(db.Save(u).Error)
+}
+
+func DeleteTusUpload(id string) error {
+	return errors.WithStack(db.Delete(&model.TusUpload{ID: id}).Error)
+}
+
+// GetExpiredTusUploads returns the uploads not finished before t, the uploads
+// handed to the tasks are left to them
+func GetExpiredTusUploads(t time.Time) ([]model.TusUpload, error) {
+	var uploads []model.TusUpload
+	if err := db.Where(fmt.Sprintf("%s < ? AND %s = ?", columnName("expires_at"), columnName("put")), t, false).Find(&uploads).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get expired tus uploads")
+	}
+	return uploads, nil
+}
@@ -0,0 +1,370 @@
+// Package dedupe finds the duplicated files across the storages. The files
+// are grouped by size first, the candidates are then confirmed by the hashes
+// given by the drivers, or by hashing their contents. The hashes of the
+// contents are cached in the database.
+package dedupe
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	stdpath "path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// sampleSize is the size of each range read to hash a file
+	sampleSize = 1024 * 1024
+	// samples is the number of the ranges read, a file not larger than
+	// samples*sampleSize is hashed whole
+	samples = 3
+)
+
+// hashTypes are the driver hashes trusted to confirm the duplicates, by preference
+var hashTypes = []*utils.HashType{utils.SHA256, utils.SHA1, utils.MD5}
+
+type File struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	hash     utils.HashInfo
+}
+
+// Group is a set of files with the same content
+type Group struct {
+	Size  int64  `json:"size"`
+	Hash  string `json:"hash"`
+	Files []File `json:"files"`
+}
+
+type Report struct {
+	// Scanned is the number of the files walked
+	Scanned int `json:"scanned"`
+	// Candidates is the number of the files sharing their size with another
+	Candidates int `json:"candidates"`
+	// Failed is the number of the candidates which could not be hashed
+	Failed int     `json:"failed"`
+	Groups []Group `json:"groups"`
+	// Wasted is the size taken by the duplicates besides one file per group
+	Wasted int64 `json:"wasted"`
+}
+
+// Scan walks the paths and returns the groups of the duplicated files not
+// smaller than minSize, progress is called with the percentage done
+func Scan(ctx context.Context, paths []string, minSize int64, progress func(float64)) (*Report, error) {
+	minSize = max(minSize, 1)
+	report := &Report{Groups: []Group{}}
+	seen := make(map[string]struct{})
+	bySize := make(map[int64][]File)
+	for _, root := range paths {
+		obj, err := fs.Get(ctx, root, &fs.GetArgs{})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed get %s", root)
+		}
+		err = fs.WalkFS(ctx, -1, root, obj, func(p string, obj model.Obj) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if obj.IsDir() {
+				return nil
+			}
+			// the paths may overlap
+			if _, ok := seen[p]; ok {
+				return nil
+			}
+			seen[p] = struct{}{}
+			report.Scanned++
+			if obj.GetSize() < minSize {
+				return nil
+			}
+			bySize[obj.GetSize()] = append(bySize[obj.GetSize()], File{
+				Path:     p,
+				Size:     obj.GetSize(),
+				Modified: obj.ModTime(),
+				hash:     obj.GetHash(),
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var sizes []int64
+	for size, files := range bySize {
+		if len(files) > 1 {
+			sizes = append(sizes, size)
+			report.Candidates += len(files)
+		}
+	}
+	slices.Sort(sizes)
+	for i, size := range sizes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for hash, files := range confirm(ctx, bySize[size], &report.Failed) {
+			if len(files) < 2 {
+				continue
+			}
+			sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+			report.Groups = append(report.Groups, Group{Size: size, Hash: hash, Files: files})
+			report.Wasted += size * int64(len(files)-1)
+		}
+		if progress != nil {
+			progress(float64(i+1) / float64(len(sizes)) * 100)
+		}
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		gi, gj := report.Groups[i], report.Groups[j]
+		wi, wj := gi.Size*int64(len(gi.Files)-1), gj.Size*int64(len(gj.Files)-1)
+		if wi != wj {
+			return wi > wj
+		}
+		return gi.Files[0].Path < gj.Files[0].Path
+	})
+	return report, nil
+}
+
+// confirm groups the files of the same size by their hashes, the files which
+// could not be hashed are left out and counted in failed. Without a driver
+// hash shared by all the files, the files are grouped by a hash of ranged
+// reads first, then the groups are confirmed by hashing the whole contents.
+func confirm(ctx context.Context, files []File, failed *int) map[string][]File {
+	res := make(map[string][]File)
+	for _, ht := range hashTypes {
+		if !slices.ContainsFunc(files, func(f File) bool { return f.hash.GetHash(ht) == "" }) {
+			for _, f := range files {
+				key := ht.Name + ":" + strings.ToLower(f.hash.GetHash(ht))
+				res[key] = append(res[key], f)
+			}
+			return res
+		}
+	}
+	samples := make(map[string][]File)
+	for _, f := range files {
+		key, err := sampleHash(ctx, f)
+		if err != nil {
+			log.Warnf("failed hash %s: %+v", f.Path, err)
+			*failed++
+			continue
+		}
+		samples[key] = append(samples[key], f)
+	}
+	for _, candidates := range samples {
+		if len(candidates) < 2 {
+			continue
+		}
+		for _, f := range candidates {
+			key, err := fullHash(ctx, f)
+			if err != nil {
+				log.Warnf("failed hash %s: %+v", f.Path, err)
+				*failed++
+				continue
+			}
+			res[key] = append(res[key], f)
+		}
+	}
+	return res
+}
+
+// sampleHash hashes the ranges at the start, the middle and the end of the
+// file, it only tells the files which are surely different
+func sampleHash(ctx context.Context, f File) (string, error) {
+	sum, err := hashRanges(ctx, f, sampleRanges(f.Size))
+	if err != nil {
+		return "", err
+	}
+	return "sample:" + sum, nil
+}
+
+// fullHash hashes the whole content of the file, it is kept in the database
+// until the file is modified
+func fullHash(ctx context.Context, f File) (string, error) {
+	if cached, err := db.GetFileHash(f.Path); err == nil && cached.Size == f.Size && cached.Modified.Equal(f.Modified) {
+		return cached.Hash, nil
+	}
+	sum, err := hashRanges(ctx, f, []http_range.Range{{Start: 0, Length: f.Size}})
+	if err != nil {
+		return "", err
+	}
+	hash := "content:" + sum
+	if err := db.SaveFileHash(&model.FileHash{
+		Path:     f.Path,
+		Size:     f.Size,
+		Modified: f.Modified,
+		Hash:     hash,
+		Updated:  time.Now(),
+	}); err != nil {
+		log.Warnf("failed save hash of %s: %+v", f.Path, err)
+	}
+	return hash, nil
+}
+
+// hashRanges hashes the size of the file and the content of the ranges
+func hashRanges(ctx context.Context, f File, ranges []http_range.Range) (string, error) {
+	link, obj, err := fs.Link(ctx, f.Path, model.LinkArgs{NoBlockCache: true})
+	if err != nil {
+		return "", err
+	}
+	defer link.Close()
+	rr, err := stream.GetRangeReaderFromLink(obj.GetSize(), link)
+	if err != nil {
+		return "", err
+	}
+	h := utils.SHA1.NewFunc()
+	h.Write([]byte(strconv.FormatInt(f.Size, 10)))
+	for _, r := range ranges {
+		rc, err := rr.RangeRead(ctx, r)
+		if err != nil {
+			return "", err
+		}
+		n, err := utils.CopyWithBuffer(h, io.LimitReader(rc, r.Length))
+		_ = rc.Close()
+		if err != nil {
+			return "", err
+		}
+		if n != r.Length {
+			return "", errors.Errorf("read %d bytes at %d, expect %d", n, r.Start, r.Length)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func sampleRanges(size int64) []http_range.Range {
+	if size <= samples*sampleSize {
+		return []http_range.Range{{Start: 0, Length: size}}
+	}
+	return []http_range.Range{
+		{Start: 0, Length: sampleSize},
+		{Start: size/2 - sampleSize/2, Length: sampleSize},
+		{Start: size - sampleSize, Length: sampleSize},
+	}
+}
+
+const (
+	ActionRemove = "remove"
+	ActionMove   = "move"
+)
+
+type Result struct {
+	Path   string `json:"path"`
+	TaskId string `json:"task_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Group returns the group of the report containing the path
+func (r *Report) Group(path string) (*Group, bool) {
+	for i := range r.Groups {
+		for _, f := range r.Groups[i].Files {
+			if f.Path == path {
+				return &r.Groups[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Resolve keeps the file at keep and removes the others of its group, or
+// moves them to dstDir. The others are checked to be in the same group.
+func (r *Report) Resolve(ctx context.Context, keep string, others []string, action, dstDir string) ([]Result, error) {
+	g, err := r.check(keep, others, action, dstDir)
+	if err != nil {
+		return nil, err
+	}
+	results, err := resolve(ctx, g, keep, others, action, dstDir)
+	if err != nil {
+		return nil, err
+	}
+	r.apply(keep, results)
+	return results, nil
+}
+
+// check validates the request against the report and returns a copy of the
+// group, so the files can be changed without holding the report
+func (r *Report) check(keep string, others []string, action, dstDir string) (Group, error) {
+	if action != ActionRemove && action != ActionMove {
+		return Group{}, errors.Errorf("invalid action: %s", action)
+	}
+	g, ok := r.Group(keep)
+	if !ok {
+		return Group{}, errors.Errorf("%s is not a duplicate in the report", keep)
+	}
+	for _, p := range others {
+		if p == keep || !slices.ContainsFunc(g.Files, func(f File) bool { return f.Path == p }) {
+			return Group{}, errors.Errorf("%s is not a duplicate of %s", p, keep)
+		}
+		if action == ActionMove && stdpath.Dir(p) == dstDir {
+			return Group{}, errors.Errorf("%s is already in %s", p, dstDir)
+		}
+	}
+	return Group{Size: g.Size, Hash: g.Hash, Files: slices.Clone(g.Files)}, nil
+}
+
+// resolve removes or moves the others of the group
+func resolve(ctx context.Context, g Group, keep string, others []string, action, dstDir string) ([]Result, error) {
+	// the files changed since the scan are not duplicates any more
+	for _, f := range g.Files {
+		if f.Path != keep && !slices.Contains(others, f.Path) {
+			continue
+		}
+		obj, err := fs.Get(ctx, f.Path, &fs.GetArgs{NoLog: true})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed get %s", f.Path)
+		}
+		if obj.GetSize() != f.Size || !obj.ModTime().Equal(f.Modified) {
+			return nil, errors.Errorf("%s is modified since the scan", f.Path)
+		}
+	}
+	results := make([]Result, 0, len(others))
+	for _, p := range others {
+		res := Result{Path: p}
+		var err error
+		if action == ActionRemove {
+			err = fs.Remove(ctx, p)
+		} else {
+			t, e := fs.Move(ctx, p, dstDir)
+			if err = e; err == nil && t != nil {
+				res.TaskId = t.GetID()
+			}
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// apply drops the resolved files from the report
+func (r *Report) apply(keep string, results []Result) {
+	g, ok := r.Group(keep)
+	if !ok {
+		return
+	}
+	for _, res := range results {
+		if res.Error == "" && slices.ContainsFunc(g.Files, func(f File) bool { return f.Path == res.Path }) {
+			g.remove(res.Path)
+			r.Wasted -= g.Size
+		}
+	}
+	if len(g.Files) < 2 {
+		r.Groups = slices.DeleteFunc(r.Groups, func(other Group) bool { return len(other.Files) < 2 })
+	}
+}
+
+// remove drops the resolved file from the group
+func (g *Group) remove(path string) {
+	g.Files = slices.DeleteFunc(g.Files, func(f File) bool { return f.Path == path })
+}
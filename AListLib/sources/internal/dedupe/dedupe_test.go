@@ -0,0 +1,133 @@
+package dedupe
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"a.bin":     "duplicated",
+		"sub/b.bin": "duplicated",
+		"c.bin":     "same size!",
+		"d.txt":     "unique",
+	} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/dedupe",
+		Addition:  `{"root_folder_path":"` + filepath.ToSlash(root) + `"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	// the overlapping paths are walked once
+	report, err := Scan(ctx, []string{"/dedupe", "/dedupe/sub"}, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned != 4 || report.Candidates != 3 || report.Failed != 0 {
+		t.Errorf("unexpected counts: %+v", report)
+	}
+	if len(report.Groups) != 1 || len(report.Groups[0].Files) != 2 {
+		t.Fatalf("expect 1 group of 2 files, got %+v", report.Groups)
+	}
+	g := report.Groups[0]
+	if g.Files[0].Path != "/dedupe/a.bin" || g.Files[1].Path != "/dedupe/sub/b.bin" || report.Wasted != 10 {
+		t.Errorf("unexpected group: %+v", g)
+	}
+	if h, err := db.GetFileHash("/dedupe/a.bin"); err != nil || h.Hash != g.Hash {
+		t.Errorf("the hash is not cached: %+v, %v", h, err)
+	}
+
+	if _, err := report.Resolve(ctx, "/dedupe/a.bin", []string{"/dedupe/c.bin"}, ActionRemove, ""); err == nil {
+		t.Error("expect a file out of the group to be refused")
+	}
+	if _, err := report.Resolve(ctx, "/dedupe/a.bin", []string{"/dedupe/sub/b.bin"}, ActionMove, "/dedupe/sub"); err == nil || report.Wasted != 10 {
+		t.Error("expect the move into the same folder to be refused")
+	}
+	results, err := report.Resolve(ctx, "/dedupe/a.bin", []string{"/dedupe/sub/b.bin"}, ActionRemove, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Error != "" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if _, err := os.Stat(filepath.Join(root, "sub", "b.bin")); !os.IsNotExist(err) {
+		t.Error("the duplicate is not removed")
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.bin")); err != nil {
+		t.Error("the kept file is removed")
+	}
+	if len(report.Groups) != 0 || report.Wasted != 0 {
+		t.Errorf("the resolved group is kept: %+v", report)
+	}
+}
+
+func TestScanLargeFiles(t *testing.T) {
+	root := t.TempDir()
+	content := make([]byte, 4*sampleSize)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	// the byte differs out of the sampled ranges
+	changed := append([]byte(nil), content...)
+	changed[sampleSize+100*1024] ^= 0xff
+	for name, data := range map[string][]byte{
+		"a.bin":       content,
+		"b.bin":       content,
+		"changed.bin": changed,
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/dedupe_large",
+		Addition:  `{"root_folder_path":"` + filepath.ToSlash(root) + `"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Scan(context.Background(), []string{"/dedupe_large"}, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Groups) != 1 || len(report.Groups[0].Files) != 2 {
+		t.Fatalf("expect 1 group of 2 files, got %+v", report.Groups)
+	}
+	for _, f := range report.Groups[0].Files {
+		if f.Path == "/dedupe_large/changed.bin" {
+			t.Errorf("the file differing out of the samples is grouped: %+v", report.Groups[0])
+		}
+	}
+}
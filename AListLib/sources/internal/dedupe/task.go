@@ -0,0 +1,104 @@
+package dedupe
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/tache"
+	"github.com/pkg/errors"
+)
+
+// TaskManager runs the scans, the reports are kept in the tasks so the scans
+// are not persisted
+var TaskManager *tache.Manager[*ScanTask]
+
+type ScanTask struct {
+	task.TaskExtension
+	Paths   []string `json:"paths"`
+	MinSize int64    `json:"min_size"`
+	mu      sync.Mutex
+	report  *Report
+}
+
+func (t *ScanTask) GetName() string {
+	return fmt.Sprintf("find duplicates in [%s]", strings.Join(t.Paths, ", "))
+}
+
+func (t *ScanTask) GetStatus() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.report != nil {
+		return fmt.Sprintf("found %d groups of duplicates", len(t.report.Groups))
+	}
+	return "scanning"
+}
+
+func (t *ScanTask) Run() error {
+	t.ClearEndTime()
+	t.SetStartTime(time.Now())
+	defer func() { t.SetEndTime(time.Now()) }()
+	report, err := Scan(t.Ctx(), t.Paths, t.MinSize, t.SetProgress)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.report = report
+	t.mu.Unlock()
+	return nil
+}
+
+// Report returns the report of the finished scan
+func (t *ScanTask) Report() (*Report, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.report, t.report != nil
+}
+
+// Resolve resolves a group of the report, see Report.Resolve. The files are
+// changed without holding the task, so its status is still got meanwhile
+func (t *ScanTask) Resolve(ctx context.Context, keep string, others []string, action, dstDir string) ([]Result, error) {
+	t.mu.Lock()
+	if t.report == nil {
+		t.mu.Unlock()
+		return nil, errors.New("the scan is not finished")
+	}
+	g, err := t.report.check(keep, others, action, dstDir)
+	t.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	results, err := resolve(ctx, g, keep, others, action, dstDir)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.report.apply(keep, results)
+	return results, nil
+}
+
+// Add adds the task scanning the paths
+func Add(ctx context.Context, paths []string, minSize int64) (*ScanTask, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no path to scan")
+	}
+	t := &ScanTask{
+		TaskExtension: task.TaskExtension{
+			Trace: tracing.Carrier(ctx),
+		},
+		Paths:   paths,
+		MinSize: minSize,
+	}
+	t.Creator, _ = ctx.Value(conf.UserKey).(*model.User)
+	t.ApiUrl = common.GetApiUrl(ctx)
+	TaskManager.Add(t)
+	return t, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 )
 
 type Driver interface {
@@ -110,6 +111,17 @@ type PutURL interface {
 	PutURL(ctx context.Context, dstDir model.Obj, name, url string) error
 }
 
+type RapidPut interface {
+	// RapidPut create a file from its hash without transferring the content ("instant upload")
+	// Implemented by the 123, 123 Open, 189 PC and Quark drivers, whose backends deduplicate files by hash.
+	// The 115, Aliyundrive, Baidu Netdisk and Quark Open backends also ask for proofs read from the
+	// content (the hash of the head, of the blocks or of a range chosen by the server), so they can not
+	// create a file from its hash only
+	// return errs.RapidUploadMiss if the backend does not hold the content
+	// return errs.NotSupport if none of the hashes in `hash` can be used by the driver
+	RapidPut(ctx context.Context, dstDir model.Obj, name string, size int64, hash utils.HashInfo) (model.Obj, error)
+}
+
 type MkdirResult interface {
 	MakeDir(ctx context.Context, parentDir model.Obj, dirName string) (model.Obj, error)
 }
@@ -0,0 +1,280 @@
+// Package ebook reads the metadata of the ebook files, the metadata of the
+// zip based formats is read with ranged reads by the zip archive tool
+package ebook
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/url"
+	stdpath "path"
+	"sort"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/archive/tool"
+	_ "github.com/OpenListTeam/OpenList/v4/internal/archive/zip"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var mimeTypes = map[string]string{
+	"epub": "application/epub+zip",
+	"pdf":  "application/pdf",
+	"cbz":  "application/vnd.comicbook+zip",
+	"cbr":  "application/vnd.comicbook-rar",
+	"mobi": "application/x-mobipocket-ebook",
+	"azw3": "application/vnd.amazon.ebook",
+	"fb2":  "application/x-fictionbook+xml",
+	"djvu": "image/vnd.djvu",
+}
+
+// Roots returns the cleaned catalog roots of the config
+func Roots() []string {
+	var roots []string
+	for _, root := range conf.Conf.OPDS.Roots {
+		if root = strings.TrimSpace(root); root == "" {
+			continue
+		}
+		root = utils.FixAndCleanPath(root)
+		if !utils.SliceContains(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+// MimeType returns the mime type of the ebook, empty if name is not an ebook
+func MimeType(name string) string {
+	return mimeTypes[utils.Ext(name)]
+}
+
+// maxDocSize limits the size of the documents read from the archives
+const maxDocSize = 4 * 1024 * 1024
+
+// GetMeta returns the metadata of the ebook at p, the metadata of EPUB and
+// CBZ files is read once and cached in the database
+func GetMeta(ctx context.Context, p string, obj model.Obj) *model.EbookMeta {
+	dir, name := stdpath.Dir(p), obj.GetName()
+	m := &model.EbookMeta{
+		Dir:      dir,
+		Name:     name,
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		Title:    strings.TrimSuffix(name, stdpath.Ext(name)),
+	}
+	ext := utils.Ext(name)
+	if ext != "epub" && ext != "cbz" {
+		return m
+	}
+	cached, err := db.GetEbookMeta(dir, name)
+	if err == nil {
+		if cached.Size == m.Size && cached.Modified.Unix() == m.Modified.Unix() {
+			return cached
+		}
+		m.ID = cached.ID
+	}
+	if ext == "epub" {
+		err = readEPUB(ctx, p, m)
+	} else {
+		err = readCBZ(ctx, p, m)
+	}
+	if err != nil {
+		// the fallback is cached too, the broken files are not read again until they change
+		log.Warnf("failed read ebook metadata of [%s]: %+v", p, err)
+	}
+	if err := db.SaveEbookMeta(m); err != nil {
+		log.Errorf("failed save ebook metadata of [%s]: %+v", p, err)
+	}
+	return m
+}
+
+func openStream(ctx context.Context, p string) (*stream.SeekableStream, error) {
+	link, obj, err := fs.Link(ctx, p, model.LinkArgs{})
+	if err != nil {
+		return nil, err
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{Obj: obj, Ctx: ctx}, link)
+	if err != nil {
+		_ = link.Close()
+		return nil, err
+	}
+	return ss, nil
+}
+
+func zipTool() (tool.Tool, error) {
+	_, t, err := tool.GetArchiveTool(".zip")
+	return t, err
+}
+
+// OpenInner opens the file at inner in the zip archive at p
+func OpenInner(ctx context.Context, p, inner string) (io.ReadCloser, int64, error) {
+	t, err := zipTool()
+	if err != nil {
+		return nil, 0, err
+	}
+	ss, err := openStream(ctx, p)
+	if err != nil {
+		return nil, 0, err
+	}
+	rc, size, err := t.Extract([]*stream.SeekableStream{ss}, model.ArchiveInnerArgs{InnerPath: inner})
+	if err != nil {
+		_ = ss.Close()
+		return nil, 0, err
+	}
+	return utils.NewReadCloser(rc, func() error {
+		_ = rc.Close()
+		return ss.Close()
+	}), size, nil
+}
+
+func readInner(ctx context.Context, p, inner string) ([]byte, error) {
+	rc, size, err := OpenInner(ctx, p, inner)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	if size > maxDocSize {
+		return nil, errors.Errorf("%s is too large", inner)
+	}
+	return io.ReadAll(io.LimitReader(rc, maxDocSize))
+}
+
+type container struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type opf struct {
+	Metadata struct {
+		Title       []string `xml:"title"`
+		Creator     []string `xml:"creator"`
+		Language    []string `xml:"language"`
+		Publisher   []string `xml:"publisher"`
+		Identifier  []string `xml:"identifier"`
+		Date        []string `xml:"date"`
+		Description []string `xml:"description"`
+		Meta        []struct {
+			Name    string `xml:"name,attr"`
+			Content string `xml:"content,attr"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+	Items []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+}
+
+func first(values []string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// readEPUB reads the package document found by META-INF/container.xml
+func readEPUB(ctx context.Context, p string, m *model.EbookMeta) error {
+	data, err := readInner(ctx, p, "META-INF/container.xml")
+	if err != nil {
+		return err
+	}
+	var c container
+	if err := xml.Unmarshal(data, &c); err != nil {
+		return errors.WithStack(err)
+	}
+	if len(c.Rootfiles) == 0 {
+		return errors.New("no rootfile in container.xml")
+	}
+	opfPath := c.Rootfiles[0].FullPath
+	if data, err = readInner(ctx, p, opfPath); err != nil {
+		return err
+	}
+	var pkg opf
+	if err := xml.Unmarshal(data, &pkg); err != nil {
+		return errors.WithStack(err)
+	}
+	md := pkg.Metadata
+	m.Title = utils.GetNoneEmpty(first(md.Title), m.Title)
+	var authors []string
+	for _, creator := range md.Creator {
+		if creator = strings.TrimSpace(creator); creator != "" {
+			authors = append(authors, creator)
+		}
+	}
+	m.Author = strings.Join(authors, ", ")
+	m.Language = first(md.Language)
+	m.Publisher = first(md.Publisher)
+	m.Identifier = first(md.Identifier)
+	m.Published = first(md.Date)
+	m.Description = first(md.Description)
+
+	// the cover is marked by the properties in EPUB 3 and by a meta in EPUB 2
+	var coverID string
+	for _, meta := range md.Meta {
+		if meta.Name == "cover" {
+			coverID = meta.Content
+		}
+	}
+	var href string
+	for _, item := range pkg.Items {
+		if utils.SliceContains(strings.Fields(item.Properties), "cover-image") {
+			href = item.Href
+			break
+		}
+		if href == "" && coverID != "" && item.ID == coverID && strings.HasPrefix(item.MediaType, "image/") {
+			href = item.Href
+		}
+	}
+	if href != "" {
+		if unescaped, err := url.PathUnescape(href); err == nil {
+			href = unescaped
+		}
+		m.Cover = stdpath.Join(stdpath.Dir(opfPath), href)
+	}
+	return nil
+}
+
+// readCBZ uses the first image of the archive as the cover
+func readCBZ(ctx context.Context, p string, m *model.EbookMeta) error {
+	t, err := zipTool()
+	if err != nil {
+		return err
+	}
+	ss, err := openStream(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer ss.Close()
+	meta, err := t.GetMeta([]*stream.SeekableStream{ss}, model.ArchiveArgs{})
+	if err != nil {
+		return err
+	}
+	var images []string
+	var walk func(dir string, tree []model.ObjTree)
+	walk = func(dir string, tree []model.ObjTree) {
+		for _, node := range tree {
+			p := stdpath.Join(dir, node.GetName())
+			if node.IsDir() {
+				walk(p, node.GetChildren())
+			} else if utils.GetFileType(node.GetName()) == conf.IMAGE {
+				images = append(images, p)
+			}
+		}
+	}
+	walk("", meta.GetTree())
+	if len(images) > 0 {
+		sort.Strings(images)
+		m.Cover = images[0]
+	}
+	return nil
+}
@@ -18,6 +18,10 @@ var (
 	StorageNotInit     = errors.New("storage not init")
 	StreamIncomplete   = errors.New("upload/download stream incomplete, possible network issue")
 	StreamPeekFail     = errors.New("StreamPeekFail")
+	RapidUploadMiss    = errors.New("rapid upload missed, content not found in storage")
+	ChecksumMismatch   = errors.New("checksum mismatch")
+	// ChecksumNotVerifiable means no hash of the expected types is available to compare
+	ChecksumNotVerifiable = errors.New("checksum not verifiable")
 
 	UnknownArchiveFormat      = errors.New("unknown archive format")
 	WrongArchivePassword      = errors.New("wrong archive password")
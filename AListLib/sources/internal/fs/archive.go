@@ -20,6 +20,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
 	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/OpenListTeam/tache"
@@ -115,6 +116,7 @@ func (t *ArchiveDownloadTask) RunWithoutPushUploadTask() (*ArchiveContentUploadT
 		TaskExtension: task.TaskExtension{
 			Creator: t.Creator,
 			ApiUrl:  t.ApiUrl,
+			Trace:   tracing.Carrier(t.Ctx()),
 		},
 		ObjName:       baseName,
 		InPlace:       !t.PutIntoNewDir,
@@ -218,6 +220,7 @@ func (t *ArchiveContentUploadTask) RunWithNextTaskCallback(f func(nextTask *Arch
 				TaskExtension: task.TaskExtension{
 					Creator: t.Creator,
 					ApiUrl:  t.ApiUrl,
+					Trace:   tracing.Carrier(t.Ctx()),
 				},
 				ObjName:       entry.Name(),
 				InPlace:       false,
@@ -380,6 +383,9 @@ func archiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args
 	}
 	tsk := &ArchiveDownloadTask{
 		TaskData: TaskData{
+			TaskExtension: task.TaskExtension{
+				Trace: tracing.Carrier(ctx),
+			},
 			SrcStorage:    srcStorage,
 			DstStorage:    dstStorage,
 			SrcActualPath: srcObjActualPath,
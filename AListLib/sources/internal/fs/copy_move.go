@@ -7,16 +7,19 @@ import (
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
 	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/OpenListTeam/tache"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 type taskType uint8
@@ -130,6 +133,9 @@ func transfer(ctx context.Context, taskType taskType, srcObjPath, dstDirPath str
 	// not in the same storage
 	t := &FileTransferTask{
 		TaskData: TaskData{
+			TaskExtension: task.TaskExtension{
+				Trace: tracing.Carrier(ctx),
+			},
 			SrcStorage:    srcStorage,
 			DstStorage:    dstStorage,
 			SrcActualPath: srcObjActualPath,
@@ -226,6 +232,7 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 					TaskExtension: task.TaskExtension{
 						Creator: t.Creator,
 						ApiUrl:  t.ApiUrl,
+						Trace:   tracing.Carrier(t.Ctx()),
 					},
 					SrcStorage:    t.SrcStorage,
 					DstStorage:    t.DstStorage,
@@ -244,8 +251,12 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 		return nil
 	}
 
+	if t.tryRapidPut(srcObj) {
+		return nil
+	}
+
 	t.Status = "getting src object link"
-	link, srcObj, err := op.Link(t.Ctx(), t.SrcStorage, t.SrcActualPath, model.LinkArgs{})
+	link, srcObj, err := op.Link(t.Ctx(), t.SrcStorage, t.SrcActualPath, model.LinkArgs{NoBlockCache: true})
 	if err != nil {
 		return errors.WithMessagef(err, "failed get [%s] link", t.SrcActualPath)
 	}
@@ -260,7 +271,34 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 	}
 	t.SetTotalBytes(ss.GetSize())
 	t.Status = "uploading"
-	return op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, ss, t.SetProgress)
+	err = op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, ss, t.SetProgress)
+	if err != nil || !NeedVerify() {
+		return err
+	}
+	t.Status = "verifying"
+	return VerifyUploaded(t.Ctx(), t.DstStorage, t.DstActualPath, srcObj.GetName(), srcObj.GetSize(), srcObj.GetHash())
+}
+
+// tryRapidPut try to create the dst file from the hash already exposed by srcObj,
+// so that the content does not need to be transferred.
+func (t *FileTransferTask) tryRapidPut(srcObj model.Obj) bool {
+	if _, ok := t.DstStorage.(driver.RapidPut); !ok {
+		return false
+	}
+	hash := srcObj.GetHash()
+	if len(hash.Export()) == 0 {
+		return false
+	}
+	t.Status = "trying rapid upload"
+	_, err := op.RapidPut(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, srcObj.GetName(), srcObj.GetSize(), hash)
+	if err != nil {
+		log.Debugf("rapid upload [%s] to [%s](%s) failed, fallback to normal upload: %v", srcObj.GetName(), t.DstStorageMp, t.DstActualPath, err)
+		return false
+	}
+	t.SetTotalBytes(srcObj.GetSize())
+	t.SetProgress(100)
+	t.Status = "rapid uploaded"
+	return true
 }
 
 var (
@@ -11,6 +11,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/pkg/errors"
 )
 
@@ -199,6 +200,21 @@ func PutURL(ctx context.Context, path, dstName, urlStr string) error {
 	return op.PutURL(ctx, storage, dstDirActualPath, dstName, urlStr)
 }
 
+func RapidPut(ctx context.Context, dstDirPath, dstName string, size int64, hash utils.HashInfo) (model.Obj, error) {
+	storage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDirPath)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get storage")
+	}
+	if storage.Config().NoUpload {
+		return nil, errors.WithStack(errs.UploadNotSupported)
+	}
+	obj, err := op.RapidPut(ctx, storage, dstDirActualPath, dstName, size, hash)
+	if err != nil && !errors.Is(err, errs.RapidUploadMiss) {
+		log.Errorf("failed rapid put %s to %s: %+v", dstName, dstDirPath, err)
+	}
+	return obj, err
+}
+
 func GetDirectUploadInfo(ctx context.Context, tool, path, dstName string, fileSize int64, overwrite bool) (any, error) {
 	info, err := getDirectUploadInfo(ctx, tool, path, dstName, fileSize, overwrite)
 	if err != nil {
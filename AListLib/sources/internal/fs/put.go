@@ -16,6 +16,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
 	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	"github.com/OpenListTeam/tache"
 	"github.com/pkg/errors"
 )
@@ -82,6 +83,7 @@ func putAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer)
 		TaskExtension: task.TaskExtension{
 			Creator: taskCreator,
 			ApiUrl:  common.GetApiUrl(ctx),
+			Trace:   tracing.Carrier(ctx),
 		},
 		storage:          storage,
 		dstDirActualPath: dstDirActualPath,
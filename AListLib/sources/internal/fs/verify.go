@@ -0,0 +1,63 @@
+package fs
+
+import (
+	"context"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	hash_extend "github.com/OpenListTeam/OpenList/v4/pkg/utils/hash"
+	"github.com/pkg/errors"
+)
+
+// NeedVerify reports whether transferred files should be verified after uploading
+func NeedVerify() bool {
+	return setting.GetBool(conf.TaskVerifyChecksum)
+}
+
+// VerifyUploaded checks the uploaded object against the expected size and hashes.
+// A negative size skips the size check, the content is hashed when the dst storage exposes none of the expected types.
+func VerifyUploaded(ctx context.Context, storage driver.Driver, dstDirPath, name string, size int64, hash utils.HashInfo) error {
+	// the temp object cached by the upload carries the size of the stream
+	obj, err := op.Get(ctx, storage, stdpath.Join(dstDirPath, name), true)
+	if err != nil {
+		return errors.WithMessagef(err, "failed get uploaded [%s]", name)
+	}
+	if size >= 0 && obj.GetSize() != size {
+		return errors.WithStack(errs.NewErr(errs.ChecksumMismatch, "[%s] size expected %d, got %d", name, size, obj.GetSize()))
+	}
+	err = hash_extend.Compare(hash, obj.GetHash())
+	if errors.Is(err, errs.ChecksumNotVerifiable) {
+		err = VerifyContent(ctx, storage, stdpath.Join(dstDirPath, name), hash)
+	}
+	if err != nil {
+		return errors.WithMessagef(err, "[%s]", name)
+	}
+	return nil
+}
+
+// VerifyContent downloads the object at path and checks its content against the expected hashes
+func VerifyContent(ctx context.Context, storage driver.Driver, path string, hash utils.HashInfo) error {
+	link, obj, err := op.Link(ctx, storage, path, model.LinkArgs{NoBlockCache: true})
+	if err != nil {
+		return errors.WithMessage(err, "failed get link")
+	}
+	defer link.Close()
+	rr, err := stream.GetRangeReaderFromLink(obj.GetSize(), link)
+	if err != nil {
+		return err
+	}
+	rc, err := rr.RangeRead(ctx, http_range.Range{Length: -1})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return hash_extend.VerifyReader(rc, obj.GetSize(), hash)
+}
@@ -0,0 +1,139 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// truncating stores one byte less than it is given
+type truncating struct {
+	model.Storage
+	driver.RootPath
+	files map[string]int64
+}
+
+func (d *truncating) Config() driver.Config {
+	return driver.Config{Name: "Truncating", LocalSort: true}
+}
+
+func (d *truncating) GetAddition() driver.Additional {
+	return &d.RootPath
+}
+
+func (d *truncating) Init(ctx context.Context) error {
+	d.files = make(map[string]int64)
+	return nil
+}
+
+func (d *truncating) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *truncating) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	var res []model.Obj
+	for name, size := range d.files {
+		res = append(res, &model.Object{Name: name, Size: size, Modified: time.Now()})
+	}
+	return res, nil
+}
+
+func (d *truncating) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	return nil, errs.NotImplement
+}
+
+func (d *truncating) Put(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up driver.UpdateProgress) error {
+	n, err := io.Copy(io.Discard, file)
+	if err != nil {
+		return err
+	}
+	d.files[file.GetName()] = n - 1
+	return nil
+}
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	op.RegisterDriver(func() driver.Driver {
+		return &truncating{}
+	})
+}
+
+func TestVerifyUploaded(t *testing.T) {
+	ctx := context.Background()
+	if _, err := op.CreateStorage(ctx, model.Storage{Driver: "Truncating", MountPath: "/verify", CacheExpiration: 30, Addition: "{}"}); err != nil {
+		t.Fatal(err)
+	}
+	storage, err := op.GetStorageByMountPath("/verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the listing is cached so the upload adds a temp object to it, an empty
+	// listing is not cached
+	storage.(*truncating).files["other.txt"] = 1
+	if _, err := op.List(ctx, storage, "/", model.ListArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	file := &stream.FileStream{
+		Obj:    &model.Object{Name: "a.txt", Size: 5, Modified: time.Now()},
+		Reader: strings.NewReader("hello"),
+	}
+	if err := op.Put(ctx, storage, "/", file, nil); err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyUploaded(ctx, storage, "/", "a.txt", 5, utils.HashInfo{})
+	if !errors.Is(err, errs.ChecksumMismatch) {
+		t.Errorf("expect the size mismatch, got %v", err)
+	}
+}
+
+func TestVerifyUploadedHashesContent(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/verify_local",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, dir),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	storage, err := op.GetStorageByMountPath("/verify_local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the local storage exposes no hash, the content is read instead
+	hello := utils.NewHashInfo(utils.SHA256, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	if err = VerifyUploaded(ctx, storage, "/", "b.txt", 5, hello); err != nil {
+		t.Errorf("expect the content to match, got %v", err)
+	}
+	other := utils.NewHashInfo(utils.SHA256, "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7")
+	err = VerifyUploaded(ctx, storage, "/", "b.txt", 5, other)
+	if !errors.Is(err, errs.ChecksumMismatch) {
+		t.Errorf("expect the checksum mismatch, got %v", err)
+	}
+}
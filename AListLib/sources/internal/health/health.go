@@ -0,0 +1,327 @@
+// Package health probes the storages periodically and initializes again the
+// ones failing several probes in a row, waiting longer after each failure.
+// The checks are kept for the admin api and the changes of the health are
+// sent as events.
+package health
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ActionProbe = "probe"
+	ActionInit  = "init"
+)
+
+const (
+	// EventDown is sent when a healthy storage fails
+	EventDown = "down"
+	// EventUp is sent when a failed storage works again
+	EventUp = "up"
+	// EventRetryFailed is sent when the initialization of a failed storage fails again
+	EventRetryFailed = "retry_failed"
+)
+
+type Check struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`
+	Healthy bool      `json:"healthy"`
+	// Latency is in milliseconds
+	Latency int64  `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+type StorageHealth struct {
+	MountPath string     `json:"mount_path"`
+	Driver    string     `json:"driver"`
+	Status    string     `json:"status"`
+	Healthy   bool       `json:"healthy"`
+	Failures  int        `json:"failures"`
+	LastCheck *time.Time `json:"last_check,omitempty"`
+	NextRetry *time.Time `json:"next_retry,omitempty"`
+	History   []Check    `json:"history"`
+}
+
+type Event struct {
+	MountPath string    `json:"mount_path"`
+	Type      string    `json:"type"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+type state struct {
+	healthy  bool
+	checking bool
+	// probeFailures counts the probes failed in a row of a working storage
+	probeFailures int
+	failures      int
+	nextRetry     time.Time
+	history       []Check
+}
+
+var (
+	mu       sync.Mutex
+	states   = map[string]*state{}
+	handlers []func(Event)
+	cancel   context.CancelFunc
+)
+
+// OnEvent registers a handler of the events, it is called out of the lock
+// and should return quickly
+func OnEvent(handler func(Event)) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers = append(handlers, handler)
+}
+
+// Start starts probing the storages if it is enabled
+func Start() {
+	cfg := conf.Conf.StorageHealth
+	if !cfg.Enable || cfg.Interval <= 0 {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	var ctx context.Context
+	ctx, cancel = context.WithCancel(context.Background())
+	go run(ctx, time.Duration(cfg.Interval)*time.Second)
+}
+
+// Stop stops probing the storages
+func Stop() {
+	mu.Lock()
+	defer mu.Unlock()
+	if cancel != nil {
+		cancel()
+		cancel = nil
+	}
+}
+
+func run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			CheckAll(ctx, now)
+		}
+	}
+}
+
+// CheckAll checks the enabled storages whose retry is due at now, each one
+// in its own goroutine. A storage still being checked is skipped.
+func CheckAll(ctx context.Context, now time.Time) {
+	storages := op.GetAllStorages()
+	mu.Lock()
+	defer mu.Unlock()
+	seen := make(map[string]struct{}, len(storages))
+	for _, s := range storages {
+		storage := s.GetStorage()
+		seen[storage.MountPath] = struct{}{}
+		if storage.Disabled {
+			continue
+		}
+		st := getState(storage.MountPath)
+		if st.checking || now.Before(st.nextRetry) {
+			continue
+		}
+		st.checking = true
+		go check(ctx, s, now)
+	}
+	for mountPath, st := range states {
+		if _, ok := seen[mountPath]; !ok && !st.checking {
+			delete(states, mountPath)
+		}
+	}
+}
+
+func getState(mountPath string) *state {
+	st, ok := states[mountPath]
+	if !ok {
+		st = &state{healthy: true}
+		states[mountPath] = st
+	}
+	return st
+}
+
+func check(ctx context.Context, storageDriver driver.Driver, now time.Time) {
+	mountPath := storageDriver.GetStorage().MountPath
+	defer func() {
+		mu.Lock()
+		getState(mountPath).checking = false
+		mu.Unlock()
+	}()
+	if storageDriver.GetStorage().Status == op.WORK {
+		err := timed(mountPath, ActionProbe, func() error {
+			probeCtx, cancel := context.WithTimeout(ctx, time.Duration(conf.Conf.StorageHealth.Timeout)*time.Second)
+			defer cancel()
+			return op.ProbeStorage(probeCtx, storageDriver)
+		})
+		if err == nil {
+			succeed(mountPath)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		// a storage may fail a probe now and then, it is only initialized
+		// again once it keeps failing
+		mu.Lock()
+		st := getState(mountPath)
+		st.probeFailures++
+		probeFailures := st.probeFailures
+		mu.Unlock()
+		if probeFailures < conf.Conf.StorageHealth.FailureThreshold {
+			log.Warnf("storage [%s] failed the probe %d times in a row: %+v", mountPath, probeFailures, err)
+			return
+		}
+		log.Warnf("storage [%s] failed the probe %d times in a row, initialize it again: %+v", mountPath, probeFailures, err)
+	}
+	// the drivers may keep the context of the initialization, so it is not
+	// canceled with the monitor
+	err := timed(mountPath, ActionInit, func() error {
+		return op.ReinitStorage(context.Background(), storageDriver)
+	})
+	mu.Lock()
+	getState(mountPath).probeFailures = 0
+	mu.Unlock()
+	if err == nil {
+		succeed(mountPath)
+		return
+	}
+	fail(mountPath, now, err)
+}
+
+// timed runs fn and records its result in the history of the storage
+func timed(mountPath, action string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	c := Check{
+		Time:    start,
+		Action:  action,
+		Healthy: err == nil,
+		Latency: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	st := getState(mountPath)
+	st.history = append(st.history, c)
+	if keep := conf.Conf.StorageHealth.History; keep > 0 && len(st.history) > keep {
+		st.history = append([]Check(nil), st.history[len(st.history)-keep:]...)
+	}
+	return err
+}
+
+func succeed(mountPath string) {
+	mu.Lock()
+	st := getState(mountPath)
+	recovered := !st.healthy
+	st.healthy = true
+	st.probeFailures = 0
+	st.failures = 0
+	st.nextRetry = time.Time{}
+	mu.Unlock()
+	if recovered {
+		emit(Event{MountPath: mountPath, Type: EventUp, Message: "storage works again"})
+	}
+}
+
+func fail(mountPath string, now time.Time, err error) {
+	mu.Lock()
+	st := getState(mountPath)
+	wasHealthy := st.healthy
+	st.healthy = false
+	st.failures++
+	st.nextRetry = now.Add(Backoff(st.failures))
+	mu.Unlock()
+	typ := EventRetryFailed
+	if wasHealthy {
+		typ = EventDown
+	}
+	emit(Event{MountPath: mountPath, Type: typ, Message: err.Error()})
+}
+
+// Backoff returns the time to wait before initializing again a storage which
+// failed the given number of times in a row, doubling from the interval up
+// to the max backoff
+func Backoff(failures int) time.Duration {
+	cfg := conf.Conf.StorageHealth
+	interval := time.Duration(cfg.Interval) * time.Second
+	maxBackoff := time.Duration(cfg.MaxBackoff) * time.Second
+	if maxBackoff < interval {
+		maxBackoff = interval
+	}
+	d := interval
+	for i := 1; i < failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+func emit(e Event) {
+	e.Time = time.Now()
+	if e.Type == EventUp {
+		log.Infof("storage [%s] health: %s", e.MountPath, e.Type)
+	} else {
+		log.Warnf("storage [%s] health: %s, %s", e.MountPath, e.Type, e.Message)
+	}
+	mu.Lock()
+	hs := slices.Clone(handlers)
+	mu.Unlock()
+	for _, h := range hs {
+		h(e)
+	}
+}
+
+// List returns the health of all the storages sorted by mount path
+func List() []StorageHealth {
+	storages := op.GetAllStorages()
+	mu.Lock()
+	defer mu.Unlock()
+	res := make([]StorageHealth, 0, len(storages))
+	for _, s := range storages {
+		storage := s.GetStorage()
+		h := StorageHealth{
+			MountPath: storage.MountPath,
+			Driver:    storage.Driver,
+			Status:    storage.Status,
+			Healthy:   !storage.Disabled && storage.Status == op.WORK,
+			History:   []Check{},
+		}
+		if st, ok := states[storage.MountPath]; ok {
+			h.Healthy = h.Healthy && st.healthy
+			h.Failures = st.failures
+			h.History = append(h.History, st.history...)
+			if len(st.history) > 0 {
+				last := st.history[len(st.history)-1].Time
+				h.LastCheck = &last
+			}
+			if !st.nextRetry.IsZero() {
+				next := st.nextRetry
+				h.NextRetry = &next
+			}
+		}
+		res = append(res, h)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].MountPath < res[j].MountPath
+	})
+	return res
+}
@@ -0,0 +1,163 @@
+package health
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestBackoff(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		1:  300 * time.Second,
+		2:  600 * time.Second,
+		4:  2400 * time.Second,
+		5:  3600 * time.Second,
+		30: 3600 * time.Second,
+	} {
+		if got := Backoff(failures); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "root")
+	_, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/health",
+		Addition:  `{"root_folder_path":"` + filepath.ToSlash(root) + `"}`,
+	})
+	if err == nil {
+		t.Fatal("expect the init to fail without the root folder")
+	}
+	storage, err := op.GetStorageByMountPath("/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	OnEvent(func(e Event) {
+		if e.MountPath == "/health" {
+			events = append(events, e.Type)
+		}
+	})
+	now := time.Now()
+	check(context.Background(), storage, now)
+	if err = os.Mkdir(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// the retry is not due yet
+	CheckAll(context.Background(), now)
+	if h := find(t); h.Failures != 1 || len(h.History) != 1 || h.NextRetry == nil {
+		t.Fatalf("unexpected health after the failure: %+v", h)
+	}
+	check(context.Background(), storage, now.Add(Backoff(1)))
+	// the storage is replaced by a new instance when it is initialized again
+	check(context.Background(), mounted(t, "/health"), now.Add(Backoff(1)))
+	h := find(t)
+	if !h.Healthy || h.Failures != 0 || h.NextRetry != nil {
+		t.Fatalf("unexpected health after the recovery: %+v", h)
+	}
+	var actions []string
+	for _, c := range h.History {
+		actions = append(actions, c.Action)
+	}
+	if want := []string{ActionInit, ActionInit, ActionProbe}; !slices.Equal(actions, want) {
+		t.Errorf("actions = %v, want %v", actions, want)
+	}
+	if want := []string{EventDown, EventUp}; !slices.Equal(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
+
+func TestFailureThreshold(t *testing.T) {
+	root := t.TempDir()
+	if _, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/health_threshold",
+		Addition:  `{"root_folder_path":"` + filepath.ToSlash(root) + `"}`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	storage, err := op.GetStorageByMountPath("/health_threshold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Remove(root); err != nil {
+		t.Fatal(err)
+	}
+	conf.Conf.StorageHealth.FailureThreshold = 2
+	defer func() { conf.Conf.StorageHealth.FailureThreshold = 3 }()
+	now := time.Now()
+	// the first failed probe is tolerated
+	check(context.Background(), storage, now)
+	if h := findPath(t, "/health_threshold"); !h.Healthy || len(h.History) != 1 {
+		t.Fatalf("unexpected health after a failed probe: %+v", h)
+	}
+	check(context.Background(), mounted(t, "/health_threshold"), now)
+	h := findPath(t, "/health_threshold")
+	var actions []string
+	for _, c := range h.History {
+		actions = append(actions, c.Action)
+	}
+	if want := []string{ActionProbe, ActionProbe, ActionInit}; !slices.Equal(actions, want) || h.Failures != 1 {
+		t.Fatalf("actions = %v, want %v: %+v", actions, want, h)
+	}
+	if err = os.Mkdir(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	check(context.Background(), mounted(t, "/health_threshold"), now.Add(Backoff(1)))
+	// the failed instance is replaced by a new one
+	reloaded := mounted(t, "/health_threshold")
+	if reloaded == storage || reloaded.GetStorage().Status != op.WORK {
+		t.Errorf("the storage is not replaced by a working instance: %s", reloaded.GetStorage().Status)
+	}
+	if h = findPath(t, "/health_threshold"); !h.Healthy || h.Failures != 0 {
+		t.Errorf("unexpected health after the recovery: %+v", h)
+	}
+}
+
+func mounted(t *testing.T, mountPath string) driver.Driver {
+	t.Helper()
+	storage, err := op.GetStorageByMountPath(mountPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage
+}
+
+func find(t *testing.T) StorageHealth {
+	t.Helper()
+	return findPath(t, "/health")
+}
+
+func findPath(t *testing.T, mountPath string) StorageHealth {
+	t.Helper()
+	for _, h := range List() {
+		if h.MountPath == mountPath {
+			return h
+		}
+	}
+	t.Fatalf("storage %s not listed", mountPath)
+	return StorageHealth{}
+}
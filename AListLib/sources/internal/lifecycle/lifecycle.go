@@ -0,0 +1,374 @@
+// Package lifecycle evaluates the lifecycle rules periodically, the files
+// matched by a rule are moved or copied to another mount through the task
+// managers, or deleted.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	stdpath "path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultInterval of evaluation in minutes
+const DefaultInterval = 24 * 60
+
+var (
+	mu      sync.Mutex
+	runners = make(map[uint]context.CancelFunc)
+	running = make(map[uint]bool)
+)
+
+// Report is the result of a run with the matched files
+type Report struct {
+	Run   model.LifecycleRun    `json:"run"`
+	Items []model.LifecycleItem `json:"items"`
+}
+
+// Start schedules the evaluation of all enabled rules
+func Start() {
+	rules, err := db.GetEnabledLifecycleRules()
+	if err != nil {
+		log.Errorf("failed get enabled lifecycle rules: %+v", err)
+		return
+	}
+	for i := range rules {
+		schedule(&rules[i])
+	}
+}
+
+// Stop stops the evaluation of all rules
+func Stop() {
+	mu.Lock()
+	defer mu.Unlock()
+	for id, cancel := range runners {
+		cancel()
+		delete(runners, id)
+	}
+}
+
+func schedule(r *model.LifecycleRule) {
+	mu.Lock()
+	defer mu.Unlock()
+	if cancel, ok := runners[r.ID]; ok {
+		cancel()
+		delete(runners, r.ID)
+	}
+	if r.Disabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	runners[r.ID] = cancel
+	go runPeriodically(ctx, r.ID, time.Duration(r.Interval)*time.Minute)
+}
+
+func unschedule(id uint) {
+	mu.Lock()
+	defer mu.Unlock()
+	if cancel, ok := runners[id]; ok {
+		cancel()
+		delete(runners, id)
+	}
+}
+
+func runPeriodically(ctx context.Context, id uint, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		r, err := db.GetLifecycleRuleById(id)
+		if err != nil {
+			log.Errorf("failed get lifecycle rule [%d]: %+v", id, err)
+			return
+		}
+		// only one of the instances sharing the database evaluates the rule
+		if cache.Own(fmt.Sprintf("lifecycle:%d", id), 2*interval) {
+			if _, err := Run(ctx, r, false); err != nil {
+				log.Warnf("failed run lifecycle rule [%s]: %+v", r.Name, err)
+			}
+		}
+	}
+}
+
+func validate(r *model.LifecycleRule) error {
+	if r.Interval <= 0 {
+		r.Interval = DefaultInterval
+	}
+	r.SrcPath = utils.FixAndCleanPath(r.SrcPath)
+	if r.Name == "" {
+		r.Name = r.SrcPath
+	}
+	if r.MinSize < 0 || r.MaxSize < 0 || r.MinAge < 0 {
+		return errors.New("the size and the age can't be negative")
+	}
+	if r.MaxSize > 0 && r.MaxSize < r.MinSize {
+		return errors.New("the max size is less than the min size")
+	}
+	switch r.Action {
+	case model.LifecycleDelete:
+		r.DstPath = ""
+	case model.LifecycleMove, model.LifecycleCopy:
+		if r.DstPath == "" {
+			return errors.Errorf("the destination is required to %s", r.Action)
+		}
+		r.DstPath = utils.FixAndCleanPath(r.DstPath)
+		if utils.IsSubPath(r.SrcPath, r.DstPath) || utils.IsSubPath(r.DstPath, r.SrcPath) {
+			return errors.New("the source and the destination can't contain each other")
+		}
+	default:
+		return errors.Errorf("invalid action: %s", r.Action)
+	}
+	return nil
+}
+
+func GetRules(pageIndex, pageSize int) ([]model.LifecycleRule, int64, error) {
+	return db.GetLifecycleRules(pageIndex, pageSize)
+}
+
+func GetRuleById(id uint) (*model.LifecycleRule, error) {
+	return db.GetLifecycleRuleById(id)
+}
+
+func CreateRule(r *model.LifecycleRule) error {
+	r.ID = 0
+	if err := validate(r); err != nil {
+		return err
+	}
+	if err := db.CreateLifecycleRule(r); err != nil {
+		return err
+	}
+	schedule(r)
+	return nil
+}
+
+func UpdateRule(r *model.LifecycleRule) error {
+	old, err := db.GetLifecycleRuleById(r.ID)
+	if err != nil {
+		return err
+	}
+	if err := validate(r); err != nil {
+		return err
+	}
+	r.CreatorId = old.CreatorId
+	r.LastRun = old.LastRun
+	r.LastError = old.LastError
+	if err := db.UpdateLifecycleRule(r); err != nil {
+		return err
+	}
+	schedule(r)
+	return nil
+}
+
+func DeleteRuleById(id uint) error {
+	unschedule(id)
+	return db.DeleteLifecycleRuleById(id)
+}
+
+func GetRuns(ruleId uint, pageIndex, pageSize int) ([]model.LifecycleRun, int64, error) {
+	return db.GetLifecycleRuns(ruleId, pageIndex, pageSize)
+}
+
+func GetItems(runId uint, pageIndex, pageSize int) ([]model.LifecycleItem, int64, error) {
+	return db.GetLifecycleItems(runId, pageIndex, pageSize)
+}
+
+// Run evaluates the rule and records the run, a dry run only reports the
+// files which would be moved, copied or deleted
+func Run(ctx context.Context, r *model.LifecycleRule, dryRun bool) (*Report, error) {
+	mu.Lock()
+	if running[r.ID] {
+		mu.Unlock()
+		return nil, errors.Errorf("lifecycle rule [%s] is running", r.Name)
+	}
+	running[r.ID] = true
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		delete(running, r.ID)
+		mu.Unlock()
+	}()
+
+	report := &Report{Run: model.LifecycleRun{
+		RuleId:  r.ID,
+		DryRun:  dryRun,
+		Started: time.Now(),
+	}}
+	err := evaluate(ctx, r, report)
+	report.Run.Finished = time.Now()
+	if err != nil {
+		report.Run.Error = err.Error()
+	}
+	if err := saveReport(report); err != nil {
+		log.Errorf("failed save run of lifecycle rule [%s]: %+v", r.Name, err)
+	}
+	if !dryRun {
+		r.LastRun = report.Run.Started
+		r.LastError = report.Run.Error
+		if err := db.UpdateLifecycleRuleRunResult(r); err != nil {
+			log.Errorf("failed update lifecycle rule [%s]: %+v", r.Name, err)
+		}
+	}
+	return report, err
+}
+
+func saveReport(report *Report) error {
+	if err := db.CreateLifecycleRun(&report.Run); err != nil {
+		return err
+	}
+	for i := range report.Items {
+		report.Items[i].RunId = report.Run.ID
+	}
+	return db.CreateLifecycleItems(report.Items)
+}
+
+func evaluate(ctx context.Context, r *model.LifecycleRule, report *Report) error {
+	creator, err := op.GetUserById(r.CreatorId)
+	if err != nil {
+		return errors.WithMessage(err, "failed get lifecycle rule creator")
+	}
+	srcPath, err := creator.JoinPath(r.SrcPath)
+	if err != nil {
+		return err
+	}
+	dstPath := ""
+	if r.Action != model.LifecycleDelete {
+		if dstPath, err = creator.JoinPath(r.DstPath); err != nil {
+			return err
+		}
+	}
+	ctx = context.WithValue(ctx, conf.UserKey, creator)
+	ctx = context.WithValue(ctx, conf.ApiUrlKey, common.GetApiUrlFromRequest(nil))
+	report.Items, err = match(ctx, r, srcPath, dstPath, report.Run.Started)
+	report.Run.Matched = len(report.Items)
+	if err != nil {
+		return err
+	}
+	for i := range report.Items {
+		item := &report.Items[i]
+		if report.Run.DryRun {
+			item.Status = model.LifecycleItemPlanned
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		apply(ctx, r.Action, item)
+		if item.Status == model.LifecycleItemFailed {
+			report.Run.Failed++
+		}
+	}
+	if report.Run.Failed > 0 {
+		return errors.Errorf("failed %s %d of %d files", r.Action, report.Run.Failed, report.Run.Matched)
+	}
+	return nil
+}
+
+// match walks srcPath and returns the files matching the rule, with the
+// directory they go to under dstPath
+func match(ctx context.Context, r *model.LifecycleRule, srcPath, dstPath string, now time.Time) ([]model.LifecycleItem, error) {
+	exts := make(map[string]struct{})
+	for _, ext := range strings.Split(r.Extensions, ",") {
+		if ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), ".")); ext != "" {
+			exts[ext] = struct{}{}
+		}
+	}
+	root, err := fs.Get(ctx, srcPath, &fs.GetArgs{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get source")
+	}
+	var items []model.LifecycleItem
+	err = fs.WalkFS(ctx, -1, srcPath, root, func(p string, obj model.Obj) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !matchObj(r, exts, obj, now) {
+			return nil
+		}
+		size := obj.GetSize()
+		item := model.LifecycleItem{
+			Path:     p,
+			Size:     size,
+			Modified: obj.ModTime(),
+		}
+		if dstPath != "" {
+			item.DstPath = dstPath
+			if p != srcPath {
+				item.DstPath = stdpath.Join(dstPath, strings.TrimPrefix(stdpath.Dir(p), srcPath))
+			}
+			// the file copied by a previous run is not copied again
+			if r.Action == model.LifecycleCopy {
+				if dst, err := fs.Get(ctx, stdpath.Join(item.DstPath, obj.GetName()), &fs.GetArgs{NoLog: true}); err == nil && dst.GetSize() == size {
+					return nil
+				}
+			}
+		}
+		items = append(items, item)
+		return nil
+	})
+	return items, err
+}
+
+// matchObj tells whether the file matches the extensions, the size and the
+// age of the rule. The age of a file without a modified time is unknown, so
+// it is never matched by a rule with a minimum age.
+func matchObj(r *model.LifecycleRule, exts map[string]struct{}, obj model.Obj, now time.Time) bool {
+	if obj.IsDir() {
+		return false
+	}
+	if _, ok := exts[utils.Ext(obj.GetName())]; len(exts) > 0 && !ok {
+		return false
+	}
+	size := obj.GetSize()
+	if size < r.MinSize || (r.MaxSize > 0 && size > r.MaxSize) {
+		return false
+	}
+	if r.MinAge > 0 && (obj.ModTime().IsZero() || now.Sub(obj.ModTime()) < time.Duration(r.MinAge)*24*time.Hour) {
+		return false
+	}
+	return true
+}
+
+func apply(ctx context.Context, action string, item *model.LifecycleItem) {
+	var err error
+	switch action {
+	case model.LifecycleDelete:
+		err = fs.Remove(ctx, item.Path)
+	case model.LifecycleMove, model.LifecycleCopy:
+		if err = fs.MakeDir(ctx, item.DstPath); err != nil {
+			break
+		}
+		transfer := fs.Copy
+		if action == model.LifecycleMove {
+			transfer = fs.Move
+		}
+		t, e := transfer(ctx, item.Path, item.DstPath)
+		if err = e; err == nil && t != nil {
+			item.Status = model.LifecycleItemSubmitted
+			item.TaskId = t.GetID()
+			return
+		}
+	}
+	if err != nil {
+		item.Status = model.LifecycleItemFailed
+		item.Error = err.Error()
+		return
+	}
+	item.Status = model.LifecycleItemDone
+}
@@ -0,0 +1,166 @@
+package lifecycle
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func setup(t *testing.T) (uint, string) {
+	root := t.TempDir()
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	for name, modified := range map[string]time.Time{
+		"a/old.log": old,
+		"new.log":   time.Now(),
+		"old.txt":   old,
+	} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("hello"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "cold"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	mountPath := "/" + t.Name()
+	_, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: mountPath,
+		Addition:  `{"root_folder_path":"` + filepath.ToSlash(root) + `"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{Username: t.Name(), BasePath: "/", Role: model.ADMIN}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	return user.ID, root
+}
+
+func TestDryRun(t *testing.T) {
+	creator, root := setup(t)
+	r := &model.LifecycleRule{
+		SrcPath:    "/TestDryRun",
+		Extensions: "LOG, .bak",
+		MinAge:     7,
+		Action:     model.LifecycleMove,
+		DstPath:    "/TestDryRun/cold",
+		CreatorId:  creator,
+	}
+	if err := CreateRule(r); err == nil {
+		t.Fatal("expect the destination in the source to be refused")
+	}
+	r.SrcPath, r.DstPath = "/TestDryRun/a", "/TestDryRun/cold"
+	if err := CreateRule(r); err != nil {
+		t.Fatal(err)
+	}
+	defer unschedule(r.ID)
+	report, err := Run(context.Background(), r, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Items) != 1 {
+		t.Fatalf("expect 1 matched file, got %+v", report.Items)
+	}
+	item := report.Items[0]
+	if item.Path != "/TestDryRun/a/old.log" || item.DstPath != "/TestDryRun/cold" || item.Status != model.LifecycleItemPlanned {
+		t.Errorf("unexpected item: %+v", item)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a", "old.log")); err != nil {
+		t.Errorf("the dry run changed the files: %v", err)
+	}
+	if r.LastRun != (time.Time{}) {
+		t.Errorf("the dry run is recorded as the last run")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	creator, root := setup(t)
+	r := &model.LifecycleRule{
+		SrcPath:   "/TestDelete",
+		MinAge:    7,
+		Action:    model.LifecycleDelete,
+		CreatorId: creator,
+	}
+	if err := CreateRule(r); err != nil {
+		t.Fatal(err)
+	}
+	defer unschedule(r.ID)
+	report, err := Run(context.Background(), r, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Run.Matched != 2 {
+		t.Fatalf("expect 2 matched files, got %+v", report.Items)
+	}
+	for _, name := range []string{"a/old.log", "old.txt"} {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Errorf("%s is not deleted", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "new.log")); err != nil {
+		t.Errorf("new.log is deleted")
+	}
+	runs, total, err := GetRuns(r.ID, 1, 10)
+	if err != nil || total != 1 {
+		t.Fatalf("expect 1 run, got %d: %v", total, err)
+	}
+	items, _, err := GetItems(runs[0].ID, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if item.Status != model.LifecycleItemDone {
+			t.Errorf("unexpected item: %+v", item)
+		}
+	}
+}
+
+func TestMatchObj(t *testing.T) {
+	now := time.Now()
+	r := &model.LifecycleRule{MinAge: 7}
+	for _, c := range []struct {
+		obj    model.Obj
+		expect bool
+	}{
+		{&model.Object{Name: "old.log", Size: 5, Modified: now.Add(-10 * 24 * time.Hour)}, true},
+		{&model.Object{Name: "new.log", Size: 5, Modified: now}, false},
+		// a driver not giving the modified time
+		{&model.Object{Name: "unknown.log", Size: 5}, false},
+		{&model.Object{Name: "dir", IsFolder: true, Modified: now.Add(-10 * 24 * time.Hour)}, false},
+	} {
+		if got := matchObj(r, nil, c.obj, now); got != c.expect {
+			t.Errorf("%s: expect %v, got %v", c.obj.GetName(), c.expect, got)
+		}
+	}
+	// without a minimum age the modified time is not needed
+	if !matchObj(&model.LifecycleRule{}, nil, &model.Object{Name: "unknown.log", Size: 5}, now) {
+		t.Error("expect the file without a modified time to match the rule without a minimum age")
+	}
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// GaugeFunc is a collector of the gauges computed on every scrape, for the
+// state kept by other packages such as the tasks and the storages.
+type GaugeFunc struct {
+	desc    *prometheus.Desc
+	collect func(emit func(value float64, labelValues ...string))
+}
+
+func NewGaugeFunc(name, help string, labels []string, collect func(emit func(value float64, labelValues ...string))) *GaugeFunc {
+	return &GaugeFunc{
+		desc:    prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, labels, nil),
+		collect: collect,
+	}
+}
+
+func (g *GaugeFunc) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+func (g *GaugeFunc) Collect(ch chan<- prometheus.Metric) {
+	g.collect(func(value float64, labelValues ...string) {
+		ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, value, labelValues...)
+	})
+}
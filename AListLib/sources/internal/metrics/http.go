@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeGroups maps the first segments of the request paths to the route
+// groups, the other requests are counted in "other" to keep the label set
+// bounded.
+var routeGroups = []struct {
+	prefix string
+	group  string
+}{
+	{"/api/fs", "api_fs"},
+	{"/api", "api"},
+	{"/d", "d"},
+	{"/p", "p"},
+	{"/dav", "dav"},
+	{"/s3", "s3"},
+}
+
+// RouteGroup returns the route group of the path relative to the base path
+func RouteGroup(path string) string {
+	for _, g := range routeGroups {
+		if path == g.prefix || strings.HasPrefix(path, g.prefix+"/") {
+			return g.group
+		}
+	}
+	return "other"
+}
+
+// HTTP records the requests served by the engine. The requests are counted in
+// group, or in the route group of their path under basePath when group is empty.
+func HTTP(basePath, group string) gin.HandlerFunc {
+	basePath = strings.TrimSuffix(basePath, "/")
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		g := group
+		if g == "" {
+			g = RouteGroup(strings.TrimPrefix(c.Request.URL.Path, basePath))
+		}
+		httpRequests.WithLabelValues(g, c.Request.Method, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(g).Observe(time.Since(start).Seconds())
+	}
+}
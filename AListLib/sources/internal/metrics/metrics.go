@@ -0,0 +1,128 @@
+// Package metrics collects the prometheus metrics of the server, the
+// requests served by the protocols, the calls made to the drivers, the
+// lookups of the caches and the bytes moved through the stream limiters.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "openlist"
+
+// Registry holds all the metrics of the server, the collectors computed from
+// the state of other packages are added by Register.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of the http requests served, by route group, method and status code.",
+	}, []string{"group", "method", "code"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the http requests served, by route group.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"group"})
+	sessions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sessions",
+		Help:      "Number of the open sessions, by protocol.",
+	}, []string{"protocol"})
+	sessionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sessions_total",
+		Help:      "Number of the sessions opened, by protocol.",
+	}, []string{"protocol"})
+	driverCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "driver",
+		Name:      "calls_total",
+		Help:      "Number of the calls made to the drivers, by driver, operation and result.",
+	}, []string{"driver", "op", "result"})
+	driverDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "driver",
+		Name:      "call_duration_seconds",
+		Help:      "Latency of the calls made to the drivers, by driver and operation.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"driver", "op"})
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Number of the cache lookups, by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+	streamBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "stream",
+		Name:      "bytes_total",
+		Help:      "Bytes transferred through the stream limiters, by direction.",
+	}, []string{"direction"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		sessions, sessionsTotal,
+		driverCalls, driverDuration,
+		cacheLookups,
+		streamBytes,
+	)
+}
+
+// Register adds a collector to the registry, it panics if the metrics of the
+// collector are registered already.
+func Register(c prometheus.Collector) {
+	Registry.MustRegister(c)
+}
+
+// Handler serves the metrics in the prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// SessionOpened counts a session of the protocol, the session is closed by
+// calling SessionClosed with the same protocol.
+func SessionOpened(protocol string) {
+	sessions.WithLabelValues(protocol).Inc()
+	sessionsTotal.WithLabelValues(protocol).Inc()
+}
+
+func SessionClosed(protocol string) {
+	sessions.WithLabelValues(protocol).Dec()
+}
+
+// ObserveDriverCall records a call to the driver started at start
+func ObserveDriverCall(driver, op string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	driverCalls.WithLabelValues(driver, op, result).Inc()
+	driverDuration.WithLabelValues(driver, op).Observe(time.Since(start).Seconds())
+}
+
+func ObserveCacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+func AddStreamBytes(direction string, n int) {
+	if n > 0 {
+		streamBytes.WithLabelValues(direction).Add(float64(n))
+	}
+}
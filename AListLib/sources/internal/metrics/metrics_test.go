@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRouteGroup(t *testing.T) {
+	for path, group := range map[string]string{
+		"/api/fs/list":   "api_fs",
+		"/api/me":        "api",
+		"/api":           "api",
+		"/d/local/a.txt": "d",
+		"/p/local/a.txt": "p",
+		"/dav":           "dav",
+		"/dav/local":     "dav",
+		"/s3/bucket":     "s3",
+		"/dl/a":          "other",
+		"/assets/x.js":   "other",
+	} {
+		if got := RouteGroup(path); got != group {
+			t.Errorf("expect %s in %s, got %s", path, group, got)
+		}
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(HTTP("/base/", ""))
+	r.GET("/base/api/fs/list", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("api_fs", http.MethodGet, "200"))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/base/api/fs/list", nil))
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("api_fs", http.MethodGet, "200")); got != before+1 {
+		t.Fatalf("expect the request counted, got %v", got-before)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ObserveDriverCall("Test", "list", time.Now(), errors.New("failed"))
+	ObserveCacheLookup("dir", true)
+	AddStreamBytes("client_download", 10)
+	Register(NewGaugeFunc("test_up", "Test gauge.", []string{"name"}, func(emit func(float64, ...string)) {
+		emit(1, "a")
+	}))
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`openlist_driver_calls_total{driver="Test",op="list",result="error"} 1`,
+		`openlist_cache_lookups_total{cache="dir",result="hit"} 1`,
+		`openlist_stream_bytes_total{direction="client_download"} 10`,
+		`openlist_test_up{name="a"} 1`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expect %s in the metrics", want)
+		}
+	}
+}
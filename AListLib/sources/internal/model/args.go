@@ -23,6 +23,8 @@ type LinkArgs struct {
 	Header   http.Header
 	Type     string
 	Redirect bool
+	// NoBlockCache reads the upstream directly, for the tasks reading the whole files
+	NoBlockCache bool
 }
 
 type Link struct {
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// DirCache is a persisted directory listing, it warms the cache of the
+// listings when the storage is loaded again
+type DirCache struct {
+	ID        uint `json:"id" gorm:"primaryKey"`
+	StorageID uint `json:"storage_id" gorm:"index"`
+	// Path is the full path of the directory including the mount path
+	Path string `json:"path" gorm:"index"`
+	// Objs is the listing encoded in json
+	Objs      string    `json:"objs" gorm:"type:text"`
+	ListedAt  time.Time `json:"listed_at"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+}
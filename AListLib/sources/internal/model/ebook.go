@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// EbookMeta caches the metadata read from an ebook file, it is refreshed
+// when the size or the modified time of the file changes
+type EbookMeta struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Dir         string    `json:"dir" gorm:"index"`
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	Modified    time.Time `json:"modified"`
+	Title       string    `json:"title"`
+	Author      string    `json:"author"`
+	Language    string    `json:"language"`
+	Publisher   string    `json:"publisher"`
+	Identifier  string    `json:"identifier"`
+	Published   string    `json:"published"`
+	Description string    `json:"description" gorm:"type:text"`
+	// Cover is the path of the cover image in the archive, empty if there is none
+	Cover string `json:"cover" gorm:"type:text"`
+}
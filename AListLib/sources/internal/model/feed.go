@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// Feed is a RSS / Atom subscription, matched items are added as offline download tasks
+type Feed struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+	Url  string `json:"url" binding:"required"`
+	// Interval of polling in minutes
+	Interval int `json:"interval"`
+	// Include and Exclude are regular expressions matched against item titles,
+	// an empty Include matches every item
+	Include      string    `json:"include"`
+	Exclude      string    `json:"exclude"`
+	DstPath      string    `json:"dst_path" binding:"required"`
+	Tool         string    `json:"tool" binding:"required"`
+	DeletePolicy string    `json:"delete_policy"`
+	Disabled     bool      `json:"disabled"`
+	CreatorId    uint      `json:"-"`
+	LastChecked  time.Time `json:"last_checked"`
+	LastError    string    `json:"last_error"`
+}
+
+const (
+	FeedItemAdded   = "added"
+	FeedItemSkipped = "skipped"
+	FeedItemFailed  = "failed"
+)
+
+// FeedItem records an item seen in a feed, used for dedupe and history
+type FeedItem struct {
+	ID      uint      `json:"id" gorm:"primaryKey"`
+	FeedId  uint      `json:"feed_id" gorm:"uniqueIndex:idx_feed_guid"`
+	Guid    string    `json:"guid" gorm:"type:varchar(512);uniqueIndex:idx_feed_guid"`
+	Title   string    `json:"title"`
+	Url     string    `json:"url" gorm:"type:text"`
+	Status  string    `json:"status"`
+	TaskId  string    `json:"task_id"`
+	Error   string    `json:"error" gorm:"type:text"`
+	Created time.Time `json:"created"`
+	// Attempts counts the failed attempts to add the task, RetryAt is the
+	// earliest time of the next attempt
+	Attempts int       `json:"attempts"`
+	RetryAt  time.Time `json:"retry_at"`
+}
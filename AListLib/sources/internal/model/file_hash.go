@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// FileHash caches a hash computed from the content of a file, it is valid
+// while the size and the modified time of the file are unchanged
+type FileHash struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+	// Key is the sha1 of the path, the paths may be too long for an index
+	Key      string    `json:"-" gorm:"type:varchar(40);uniqueIndex"`
+	Path     string    `json:"path" gorm:"type:text"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Hash     string    `json:"hash"`
+	Updated  time.Time `json:"updated"`
+}
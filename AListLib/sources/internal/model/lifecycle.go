@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+const (
+	LifecycleMove   = "move"
+	LifecycleCopy   = "copy"
+	LifecycleDelete = "delete"
+)
+
+// LifecycleRule moves, copies or deletes the files under SrcPath matching the
+// conditions periodically, the matched files keep their path relative to
+// SrcPath under DstPath
+type LifecycleRule struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	Name    string `json:"name"`
+	SrcPath string `json:"src_path" binding:"required"`
+	// Extensions is a comma separated list of extensions without the dot,
+	// an empty list matches every file
+	Extensions string `json:"extensions"`
+	// MinSize and MaxSize in bytes, 0 is no limit
+	MinSize int64 `json:"min_size"`
+	MaxSize int64 `json:"max_size"`
+	// MinAge in days since the file is modified
+	MinAge  int    `json:"min_age"`
+	Action  string `json:"action" binding:"required"`
+	DstPath string `json:"dst_path"`
+	// Interval of evaluation in minutes
+	Interval  int       `json:"interval"`
+	Disabled  bool      `json:"disabled"`
+	CreatorId uint      `json:"-"`
+	LastRun   time.Time `json:"last_run"`
+	LastError string    `json:"last_error"`
+}
+
+const (
+	LifecycleItemPlanned   = "planned"
+	LifecycleItemSubmitted = "submitted"
+	LifecycleItemDone      = "done"
+	LifecycleItemFailed    = "failed"
+)
+
+// LifecycleRun records an evaluation of a rule, a dry run only plans the items
+type LifecycleRun struct {
+	ID       uint      `json:"id" gorm:"primaryKey"`
+	RuleId   uint      `json:"rule_id" gorm:"index"`
+	DryRun   bool      `json:"dry_run"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Matched  int       `json:"matched"`
+	Failed   int       `json:"failed"`
+	Error    string    `json:"error" gorm:"type:text"`
+}
+
+// LifecycleItem is a file matched in a run and what was done with it
+type LifecycleItem struct {
+	ID       uint      `json:"id" gorm:"primaryKey"`
+	RunId    uint      `json:"run_id" gorm:"index"`
+	Path     string    `json:"path" gorm:"type:text"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	DstPath  string    `json:"dst_path" gorm:"type:text"`
+	Status   string    `json:"status"`
+	TaskId   string    `json:"task_id"`
+	Error    string    `json:"error" gorm:"type:text"`
+}
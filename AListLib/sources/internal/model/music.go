@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// MusicArtist, MusicAlbum and MusicTrack are the index of the music library,
+// they are built by scanning the music roots and served by the Subsonic API
+
+type MusicArtist struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	Name       string `json:"name" gorm:"index"`
+	AlbumCount int    `json:"album_count"`
+}
+
+type MusicAlbum struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name" gorm:"index"`
+	ArtistID uint   `json:"artist_id" gorm:"index"`
+	Artist   string `json:"artist"`
+	// Dir is the directory of the first track of the album
+	Dir       string `json:"dir" gorm:"index"`
+	Year      int    `json:"year"`
+	Genre     string `json:"genre"`
+	SongCount int    `json:"song_count"`
+	Duration  int    `json:"duration"`
+	// CoverPath is an image in the directory of the album, or a track with
+	// an embedded picture if CoverEmbedded is set
+	CoverPath     string    `json:"cover_path" gorm:"type:text"`
+	CoverEmbedded bool      `json:"cover_embedded"`
+	Created       time.Time `json:"created"`
+}
+
+type MusicTrack struct {
+	ID       uint      `json:"id" gorm:"primaryKey"`
+	Dir      string    `json:"dir" gorm:"index"`
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+
+	Title       string `json:"title" gorm:"index"`
+	Album       string `json:"album"`
+	Artist      string `json:"artist"`
+	AlbumArtist string `json:"album_artist"`
+	Genre       string `json:"genre"`
+	Year        int    `json:"year"`
+	Track       int    `json:"track"`
+	Disc        int    `json:"disc"`
+	// Duration in seconds, 0 if it is unknown
+	Duration int  `json:"duration"`
+	HasCover bool `json:"has_cover"`
+
+	AlbumID  uint      `json:"album_id" gorm:"index"`
+	ArtistID uint      `json:"artist_id" gorm:"index"`
+	Created  time.Time `json:"created"`
+}
+
+func (t *MusicTrack) GetPath() string {
+	if t.Dir == "/" {
+		return "/" + t.Name
+	}
+	return t.Dir + "/" + t.Name
+}
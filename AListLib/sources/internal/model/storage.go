@@ -36,6 +36,8 @@ type Proxy struct {
 	DownProxyURL string `json:"down_proxy_url"`
 	// Disable sign for DownProxyURL
 	DisableProxySign bool `json:"disable_proxy_sign"`
+	// Cache the proxied reads on the local disk
+	BlockCache bool `json:"block_cache"`
 }
 
 func (s *Storage) GetStorage() *Storage {
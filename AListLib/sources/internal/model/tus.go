@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// TusUpload is the state of a resumable upload of the tus protocol, the
+// received bytes are staged in a file named by the ID under the tus dir
+type TusUpload struct {
+	ID     string `json:"id" gorm:"primaryKey;type:varchar(64)"`
+	UserID uint   `json:"user_id" gorm:"index"`
+	// Path is the destination of the upload in the storages
+	Path      string    `json:"path" gorm:"type:text"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	Metadata  string    `json:"metadata" gorm:"type:text"`
+	Mimetype  string    `json:"mimetype"`
+	Modified  time.Time `json:"modified"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	// Put tells the staged file is handed to an upload task, which removes
+	// the upload when it is done
+	Put bool `json:"put"`
+}
@@ -6,10 +6,11 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
-	"github.com/OpenListTeam/go-cache"
+	gocache "github.com/OpenListTeam/go-cache"
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/pkg/errors"
 )
@@ -30,7 +31,9 @@ const (
 	GuestCannotGenerate2FA    = "Guest user can not generate 2FA code"
 )
 
-var LoginCache = cache.NewMemCache[int]()
+// LoginCache counts the failed sign-ins by ip, it is shared by the instances
+// so the lockout holds on all of them
+var LoginCache gocache.ICache[int] = cache.NewSharedCache[int]("login")
 
 var (
 	DefaultLockDuration   = time.Minute * 5
@@ -64,6 +67,7 @@ type User struct {
 	//   13: can decompress archives
 	//   14: can share
 	//   15: can customize share id
+	//   16: MCP is read-only
 	Permission int32  `json:"permission"`
 	OtpSecret  string `json:"-"`
 	SsoID      string `json:"sso_id"` // unique by sso platform
@@ -228,6 +232,14 @@ func (u *User) CanCustomizeShareID() bool {
 	return CanCustomizeShareID(u.Permission)
 }
 
+func IsMCPReadOnly(permission int32) bool {
+	return (permission>>16)&1 == 1
+}
+
+func (u *User) IsMCPReadOnly() bool {
+	return IsMCPReadOnly(u.Permission)
+}
+
 func (u *User) JoinPath(reqPath string) (string, error) {
 	return utils.JoinBasePath(u.BasePath, reqPath)
 }
@@ -0,0 +1,124 @@
+package music
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Status is the progress of the running scan or the result of the last one
+type Status struct {
+	Scanning bool `json:"scanning"`
+	// Count is the number of the tracks found by the scan
+	Count    int64     `json:"count"`
+	LastScan time.Time `json:"last_scan"`
+	Error    string    `json:"error"`
+}
+
+var (
+	mu     sync.Mutex
+	status Status
+	stop   context.CancelFunc
+)
+
+var ErrScanning = errors.New("the music library is being scanned")
+
+func GetStatus() Status {
+	mu.Lock()
+	defer mu.Unlock()
+	return status
+}
+
+func begin() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if status.Scanning {
+		return false
+	}
+	status.Scanning, status.Count = true, 0
+	return true
+}
+
+func finish(err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	status.Scanning, status.LastScan, status.Error = false, time.Now(), ""
+	if err != nil {
+		status.Error = err.Error()
+	}
+}
+
+// Scan scans the music roots and updates the library index
+func Scan(ctx context.Context) error {
+	if !begin() {
+		return ErrScanning
+	}
+	err := scan(ctx)
+	finish(err)
+	return err
+}
+
+// StartScan scans the music roots in background, false is returned if a scan is running
+func StartScan() bool {
+	if !begin() {
+		return false
+	}
+	go func() {
+		err := scan(context.Background())
+		finish(err)
+		if err != nil {
+			log.Errorf("failed scan music library: %+v", err)
+		}
+	}()
+	return true
+}
+
+// Start schedules the periodic scans of the music roots
+func Start() {
+	if !conf.Conf.Subsonic.Enable || conf.Conf.Subsonic.ScanInterval <= 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	mu.Lock()
+	stop = cancel
+	mu.Unlock()
+	go func() {
+		ticker := time.NewTicker(time.Duration(conf.Conf.Subsonic.ScanInterval) * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// only one of the instances sharing the database scans the library
+				if !cache.Own("music_scan", 2*time.Duration(conf.Conf.Subsonic.ScanInterval)*time.Minute) {
+					continue
+				}
+				if err := Scan(ctx); err != nil && !errors.Is(err, ErrScanning) {
+					log.Errorf("failed scan music library: %+v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic scans
+func Stop() {
+	mu.Lock()
+	defer mu.Unlock()
+	if stop != nil {
+		stop()
+		stop = nil
+	}
+}
+
+func addCount() {
+	mu.Lock()
+	defer mu.Unlock()
+	status.Count++
+}
@@ -0,0 +1,310 @@
+package music
+
+import (
+	"context"
+	stdpath "path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const UnknownArtist = "Unknown Artist"
+
+// coverNames are the names of the folder images, in the order of preference
+var coverNames = []string{"cover", "folder", "front", "album", "albumart"}
+
+func coverRank(name string) int {
+	base := strings.ToLower(strings.TrimSuffix(name, stdpath.Ext(name)))
+	for i, n := range coverNames {
+		if base == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// Roots returns the cleaned music roots of the config
+func Roots() []string {
+	var roots []string
+	for _, root := range conf.Conf.Subsonic.MusicRoots {
+		if root = strings.TrimSpace(root); root == "" {
+			continue
+		}
+		root = utils.FixAndCleanPath(root)
+		if !utils.SliceContains(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+func inAny(p string, dirs []string) bool {
+	for _, dir := range dirs {
+		if utils.IsSubPath(dir, p) {
+			return true
+		}
+	}
+	return false
+}
+
+type scanner struct {
+	ctx context.Context
+	// tracks are all the indexed tracks by path
+	tracks  map[string]*model.MusicTrack
+	seen    map[string]bool
+	changed map[string]bool
+	// covers are the folder images by directory
+	covers     map[string]string
+	coverRanks map[string]int
+	// failed are the roots that can not be walked, their tracks are kept
+	failed []string
+}
+
+func scan(ctx context.Context) error {
+	all, err := db.GetAllMusicTracks()
+	if err != nil {
+		return err
+	}
+	s := &scanner{
+		ctx:        ctx,
+		tracks:     make(map[string]*model.MusicTrack, len(all)),
+		seen:       make(map[string]bool),
+		changed:    make(map[string]bool),
+		covers:     make(map[string]string),
+		coverRanks: make(map[string]int),
+	}
+	for i := range all {
+		s.tracks[all[i].GetPath()] = &all[i]
+	}
+	for _, root := range Roots() {
+		if err := s.walk(root); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Warnf("failed scan music root [%s]: %+v", root, err)
+			s.failed = append(s.failed, root)
+		}
+	}
+	var removed []uint
+	for p, t := range s.tracks {
+		if !s.seen[p] && !inAny(p, s.failed) {
+			removed = append(removed, t.ID)
+			delete(s.tracks, p)
+		}
+	}
+	if err := db.DeleteMusicTracks(removed); err != nil {
+		return err
+	}
+	return s.rebuild()
+}
+
+func (s *scanner) walk(root string) error {
+	obj, err := fs.Get(s.ctx, root, &fs.GetArgs{})
+	if err != nil {
+		return err
+	}
+	return fs.WalkFS(s.ctx, -1, root, obj, func(p string, obj model.Obj) error {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+		if obj.IsDir() {
+			return nil
+		}
+		switch utils.GetFileType(obj.GetName()) {
+		case conf.IMAGE:
+			s.addCover(p)
+		case conf.AUDIO:
+			s.index(p, obj)
+		}
+		return nil
+	})
+}
+
+func (s *scanner) addCover(p string) {
+	rank := coverRank(stdpath.Base(p))
+	if rank < 0 {
+		return
+	}
+	dir := stdpath.Dir(p)
+	if old, ok := s.coverRanks[dir]; ok && old <= rank {
+		return
+	}
+	s.covers[dir], s.coverRanks[dir] = p, rank
+}
+
+// index reads the tags of the track at p if it is new or modified
+func (s *scanner) index(p string, obj model.Obj) {
+	s.seen[p] = true
+	addCount()
+	t := s.tracks[p]
+	if t != nil && t.Size == obj.GetSize() && t.Modified.Unix() == obj.ModTime().Unix() {
+		return
+	}
+	if t == nil {
+		t = &model.MusicTrack{Created: time.Now()}
+		s.tracks[p] = t
+	}
+	s.changed[p] = true
+	*t = model.MusicTrack{
+		ID:       t.ID,
+		Dir:      stdpath.Dir(p),
+		Name:     obj.GetName(),
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		Title:    strings.TrimSuffix(obj.GetName(), stdpath.Ext(obj.GetName())),
+		AlbumID:  t.AlbumID,
+		ArtistID: t.ArtistID,
+		Created:  t.Created,
+	}
+	m, duration, err := readTags(s.ctx, p, obj.GetSize())
+	if err != nil {
+		log.Debugf("failed read tags of [%s]: %+v", p, err)
+		return
+	}
+	if title := strings.TrimSpace(m.Title()); title != "" {
+		t.Title = title
+	}
+	t.Album = strings.TrimSpace(m.Album())
+	t.Artist = strings.TrimSpace(m.Artist())
+	t.AlbumArtist = strings.TrimSpace(m.AlbumArtist())
+	t.Genre = strings.TrimSpace(m.Genre())
+	t.Year = m.Year()
+	t.Track, _ = m.Track()
+	t.Disc, _ = m.Disc()
+	t.Duration = duration
+	t.HasCover = m.Picture() != nil
+}
+
+// rebuild groups the tracks into albums and artists
+func (s *scanner) rebuild() error {
+	artists, err := db.GetAllMusicArtists()
+	if err != nil {
+		return err
+	}
+	albums, err := db.GetAllMusicAlbums()
+	if err != nil {
+		return err
+	}
+	artistByName := make(map[string]*model.MusicArtist, len(artists))
+	for i := range artists {
+		artistByName[artists[i].Name] = &artists[i]
+	}
+	type albumKey struct {
+		artist uint
+		name   string
+	}
+	albumByKey := make(map[albumKey]*model.MusicAlbum, len(albums))
+	for i := range albums {
+		albumByKey[albumKey{albums[i].ArtistID, albums[i].Name}] = &albums[i]
+	}
+
+	paths := make([]string, 0, len(s.tracks))
+	for p := range s.tracks {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	albumTracks := make(map[uint][]*model.MusicTrack)
+	var saves []*model.MusicTrack
+	for _, p := range paths {
+		t := s.tracks[p]
+		artistName := utils.GetNoneEmpty(t.AlbumArtist, t.Artist, UnknownArtist)
+		artist := artistByName[artistName]
+		if artist == nil {
+			artist = &model.MusicArtist{Name: artistName}
+			if err := db.SaveMusicArtist(artist); err != nil {
+				return err
+			}
+			artistByName[artistName] = artist
+		}
+		albumName := utils.GetNoneEmpty(t.Album, stdpath.Base(t.Dir))
+		album := albumByKey[albumKey{artist.ID, albumName}]
+		if album == nil {
+			album = &model.MusicAlbum{Name: albumName, ArtistID: artist.ID, Artist: artist.Name, Dir: t.Dir, Created: time.Now()}
+			if err := db.SaveMusicAlbum(album); err != nil {
+				return err
+			}
+			albumByKey[albumKey{artist.ID, albumName}] = album
+		}
+		albumTracks[album.ID] = append(albumTracks[album.ID], t)
+		if s.changed[p] || t.AlbumID != album.ID || t.ArtistID != artist.ID {
+			t.AlbumID, t.ArtistID = album.ID, artist.ID
+			saves = append(saves, t)
+		}
+	}
+	if err := db.SaveMusicTracks(saves); err != nil {
+		return err
+	}
+
+	var removedAlbums []uint
+	albumCounts := make(map[uint]int)
+	for _, album := range albumByKey {
+		tracks := albumTracks[album.ID]
+		if len(tracks) == 0 {
+			removedAlbums = append(removedAlbums, album.ID)
+			continue
+		}
+		albumCounts[album.ArtistID]++
+		old := *album
+		s.summarize(album, tracks)
+		if *album != old {
+			if err := db.SaveMusicAlbum(album); err != nil {
+				return err
+			}
+		}
+	}
+	if err := db.DeleteMusicAlbums(removedAlbums); err != nil {
+		return err
+	}
+
+	var removedArtists []uint
+	for _, artist := range artistByName {
+		count := albumCounts[artist.ID]
+		if count == 0 {
+			removedArtists = append(removedArtists, artist.ID)
+			continue
+		}
+		if artist.AlbumCount != count {
+			artist.AlbumCount = count
+			if err := db.SaveMusicArtist(artist); err != nil {
+				return err
+			}
+		}
+	}
+	return db.DeleteMusicArtists(removedArtists)
+}
+
+// summarize fills the album with the details of its tracks, which are sorted by path
+func (s *scanner) summarize(album *model.MusicAlbum, tracks []*model.MusicTrack) {
+	album.Dir = tracks[0].Dir
+	album.SongCount, album.Duration, album.Year, album.Genre = len(tracks), 0, 0, ""
+	var embedded string
+	for _, t := range tracks {
+		album.Duration += t.Duration
+		if album.Year == 0 {
+			album.Year = t.Year
+		}
+		if album.Genre == "" {
+			album.Genre = t.Genre
+		}
+		if embedded == "" && t.HasCover {
+			embedded = t.GetPath()
+		}
+	}
+	switch {
+	case s.covers[album.Dir] != "":
+		album.CoverPath, album.CoverEmbedded = s.covers[album.Dir], false
+	case embedded != "":
+		album.CoverPath, album.CoverEmbedded = embedded, true
+	case inAny(album.Dir, s.failed):
+		// the folder images of the roots that can not be walked are unknown
+	default:
+		album.CoverPath, album.CoverEmbedded = "", false
+	}
+}
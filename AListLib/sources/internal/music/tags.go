@@ -0,0 +1,178 @@
+package music
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/dhowden/tag"
+	"github.com/pkg/errors"
+)
+
+// open returns a reader of the file at path, only the ranges that are read
+// are requested from the storage
+func open(ctx context.Context, path string) (model.File, *stream.SeekableStream, error) {
+	link, obj, err := fs.Link(ctx, path, model.LinkArgs{})
+	if err != nil {
+		return nil, nil, err
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{Obj: obj, Ctx: ctx}, link)
+	if err != nil {
+		_ = link.Close()
+		return nil, nil, err
+	}
+	r, err := stream.NewReadAtSeeker(ss, 0, true)
+	if err != nil {
+		_ = ss.Close()
+		return nil, nil, err
+	}
+	return r, ss, nil
+}
+
+// readTags reads the tags and the duration in seconds of the file at path
+func readTags(ctx context.Context, path string, size int64) (tag.Metadata, int, error) {
+	r, ss, err := open(ctx, path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer ss.Close()
+	m, err := tag.ReadFrom(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	var duration int
+	if _, err := r.Seek(0, io.SeekStart); err == nil {
+		switch m.FileType() {
+		case tag.FLAC:
+			duration = flacDuration(r)
+		case tag.MP3:
+			duration = mp3Duration(r, size)
+		}
+	}
+	return m, duration, nil
+}
+
+// maxCoverSize limits the size of the folder images that are read
+const maxCoverSize = 20 * 1024 * 1024
+
+// ReadCover reads the cover at path, which is the embedded picture of the
+// audio file if embedded is set, and returns it with its mime type
+func ReadCover(ctx context.Context, path string, embedded bool) ([]byte, string, error) {
+	r, ss, err := open(ctx, path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer ss.Close()
+	if !embedded {
+		if ss.GetSize() > maxCoverSize {
+			return nil, "", errors.New("the cover is too large")
+		}
+		data, err := io.ReadAll(r)
+		return data, utils.GetMimeType(path), err
+	}
+	m, err := tag.ReadFrom(r)
+	if err != nil {
+		return nil, "", err
+	}
+	pic := m.Picture()
+	if pic == nil {
+		return nil, "", errs.ObjectNotFound
+	}
+	mimeType := pic.MIMEType
+	if mimeType == "" {
+		mimeType = http.DetectContentType(pic.Data)
+	}
+	return pic.Data, mimeType, nil
+}
+
+// flacDuration reads the duration from the STREAMINFO block, which is
+// always the first metadata block
+func flacDuration(r io.Reader) int {
+	var b [4 + 4 + 34]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil || string(b[:4]) != "fLaC" || b[4]&0x7f != 0 {
+		return 0
+	}
+	info := b[8:]
+	sampleRate := uint64(info[10])<<12 | uint64(info[11])<<4 | uint64(info[12])>>4
+	samples := uint64(info[13]&0x0f)<<32 | uint64(binary.BigEndian.Uint32(info[14:18]))
+	if sampleRate == 0 {
+		return 0
+	}
+	return int(samples / sampleRate)
+}
+
+var (
+	mp3Bitrates = [2][16]int{
+		// MPEG 1 layer III
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+		// MPEG 2 and 2.5 layer III
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	}
+	mp3SampleRates = [3]int{44100, 48000, 32000}
+)
+
+// mp3Duration reads the frame count of the Xing / Info header of the first
+// frame, the bitrate of the first frame is used if there is no such header
+func mp3Duration(r io.ReadSeeker, size int64) int {
+	var head [10]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0
+	}
+	var start int64
+	if string(head[:3]) == "ID3" {
+		start = int64(head[6]&0x7f)<<21 | int64(head[7]&0x7f)<<14 | int64(head[8]&0x7f)<<7 | int64(head[9]&0x7f) + 10
+		if head[5]&0x10 != 0 {
+			start += 10
+		}
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return 0
+	}
+	buf := make([]byte, 8192)
+	n, _ := io.ReadFull(r, buf)
+	buf = buf[:n]
+	for i := 0; i+4 <= len(buf); i++ {
+		if buf[i] != 0xff || buf[i+1]&0xe0 != 0xe0 {
+			continue
+		}
+		version, layer := buf[i+1]>>3&3, buf[i+1]>>1&3
+		bitrateIdx, rateIdx := buf[i+2]>>4, buf[i+2]>>2&3
+		// only layer III is used by mp3 files
+		if version == 1 || layer != 1 || rateIdx == 3 {
+			continue
+		}
+		mpeg1, mono := version == 3, buf[i+3]>>6 == 3
+		sampleRate, samplesPerFrame, table := mp3SampleRates[rateIdx], 1152, 0
+		if !mpeg1 {
+			sampleRate, samplesPerFrame, table = sampleRate/2, 576, 1
+			if version == 0 {
+				sampleRate /= 2
+			}
+		}
+		bitrate := mp3Bitrates[table][bitrateIdx]
+		if bitrate == 0 {
+			continue
+		}
+		sideInfo := 32
+		switch {
+		case mpeg1 && mono, !mpeg1 && !mono:
+			sideInfo = 17
+		case !mpeg1 && mono:
+			sideInfo = 9
+		}
+		x := i + 4 + sideInfo
+		if x+12 <= len(buf) && (string(buf[x:x+4]) == "Xing" || string(buf[x:x+4]) == "Info") &&
+			binary.BigEndian.Uint32(buf[x+4:x+8])&1 != 0 {
+			frames := binary.BigEndian.Uint32(buf[x+8 : x+12])
+			return int(int64(frames) * int64(samplesPerFrame) / int64(sampleRate))
+		}
+		return int((size - start - int64(i)) * 8 / int64(bitrate*1000))
+	}
+	return 0
+}
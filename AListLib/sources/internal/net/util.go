@@ -74,6 +74,12 @@ func checkIfMatch(w http.ResponseWriter, r *http.Request) condResult {
 		return condNone
 	}
 	r.Header.Del("If-Match")
+	return ifMatch(im, w.Header().Get("Etag"), true)
+}
+
+// ifMatch evaluates the If-Match value im against the current etag, exists
+// reports whether the resource exists, "*" only matches an existing one
+func ifMatch(im, etag string, exists bool) condResult {
 	for {
 		im = textproto.TrimString(im)
 		if len(im) == 0 {
@@ -84,13 +90,16 @@ func checkIfMatch(w http.ResponseWriter, r *http.Request) condResult {
 			continue
 		}
 		if im[0] == '*' {
-			return condTrue
+			if exists {
+				return condTrue
+			}
+			return condFalse
 		}
-		etag, remain := scanETag(im)
-		if etag == "" {
+		etag2, remain := scanETag(im)
+		if etag2 == "" {
 			break
 		}
-		if etagStrongMatch(etag, w.Header().Get("Etag")) {
+		if etagStrongMatch(etag2, etag) {
 			return condTrue
 		}
 		im = remain
@@ -105,6 +114,10 @@ func checkIfUnmodifiedSince(r *http.Request, modtime time.Time) condResult {
 		return condNone
 	}
 	r.Header.Del("If-Unmodified-Since")
+	return ifUnmodifiedSince(ius, modtime)
+}
+
+func ifUnmodifiedSince(ius string, modtime time.Time) condResult {
 	if isZeroTime(modtime) {
 		return condNone
 	}
@@ -122,6 +135,20 @@ func checkIfUnmodifiedSince(r *http.Request, modtime time.Time) condResult {
 	return condFalse
 }
 
+// CheckWritePreconditions evaluates the If-Match and If-Unmodified-Since
+// headers of a request changing a resource, etag is empty and modtime is zero
+// if the resource does not exist. It returns false if the request should fail
+// with 412 Precondition Failed. The headers are left untouched.
+func CheckWritePreconditions(h http.Header, etag string, modtime time.Time) bool {
+	if im := h.Get("If-Match"); im != "" {
+		return ifMatch(im, etag, etag != "") != condFalse
+	}
+	if ius := h.Get("If-Unmodified-Since"); ius != "" {
+		return ifUnmodifiedSince(ius, modtime) != condFalse
+	}
+	return true
+}
+
 func checkIfNoneMatch(w http.ResponseWriter, r *http.Request) condResult {
 	inm := r.Header.Get("If-None-Match")
 	if inm == "" {
@@ -6,6 +6,7 @@ import (
 	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/123"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/123_open"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/aria2"
+	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/bittorrent"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/http"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/pikpak"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/qbit"
@@ -0,0 +1,395 @@
+package bittorrent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/drivers/base"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	pkgtorrent "github.com/OpenListTeam/OpenList/v4/pkg/torrent"
+	"github.com/anacrolix/generics"
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxTorrentFileSize is the max size of a .torrent file fetched by url
+const maxTorrentFileSize = 10 * 1024 * 1024
+
+type BitTorrent struct {
+	client *torrent.Client
+	mu     sync.Mutex
+	// tasks maps the gid of a download task to its torrent
+	tasks map[string]*btTask
+}
+
+type btTask struct {
+	t       *torrent.Torrent
+	storage storage.ClientImplCloser
+	tempDir string
+	// selected is the indexes of the files to download, nil means all
+	selected []int
+	// cleaned is true once the unselected files have been removed from tempDir
+	cleaned bool
+}
+
+func (b *BitTorrent) Run(task *tool.DownloadTask) error {
+	return errs.NotSupport
+}
+
+func (b *BitTorrent) Name() string {
+	return "BitTorrent"
+}
+
+func (b *BitTorrent) Items() []model.SettingItem {
+	// built-in bittorrent settings
+	return []model.SettingItem{
+		{Key: conf.BitTorrentEnabled, Value: "false", Type: conf.TypeBool, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.BitTorrentListenPort, Value: "0", Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.BitTorrentEnableDHT, Value: "true", Type: conf.TypeBool, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.BitTorrentSeedtime, Value: "0", Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.BitTorrentSeedRatio, Value: "0", Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+	}
+}
+
+func (b *BitTorrent) Init() (string, error) {
+	b.close()
+	if !setting.GetBool(conf.BitTorrentEnabled) {
+		return "", errors.New("built-in bittorrent is disabled")
+	}
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = filepath.Join(conf.Conf.TempDir, b.Name())
+	cfg.ListenPort = setting.GetInt(conf.BitTorrentListenPort, 0)
+	cfg.NoDHT = !setting.GetBool(conf.BitTorrentEnableDHT)
+	cfg.Seed = true
+	if err := b.initClient(cfg); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("listening on port %d", b.client.LocalPort()), nil
+}
+
+func (b *BitTorrent) initClient(cfg *torrent.ClientConfig) error {
+	cfg.DefaultStorage = newFileStorage(cfg.DataDir)
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to init bittorrent client")
+	}
+	b.mu.Lock()
+	b.client = client
+	b.tasks = make(map[string]*btTask)
+	b.mu.Unlock()
+	return nil
+}
+
+// newFileStorage store the torrent data in dir as plain files,
+// the piece completion is kept in memory so that nothing but the data is written into dir
+func newFileStorage(dir string) storage.ClientImplCloser {
+	return storage.NewFileOpts(storage.NewFileClientOpts{
+		ClientBaseDir:   dir,
+		PieceCompletion: storage.NewMapPieceCompletion(),
+		UsePartFiles:    generics.Some(false),
+	})
+}
+
+func (b *BitTorrent) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.client == nil {
+		return
+	}
+	for _, bt := range b.tasks {
+		bt.t.Drop()
+		_ = bt.storage.Close()
+	}
+	b.client.Close()
+	b.client = nil
+	b.tasks = nil
+}
+
+func (b *BitTorrent) IsReady() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.client != nil
+}
+
+func (b *BitTorrent) AddURL(args *tool.AddUrlArgs) (string, error) {
+	spec, err := b.torrentSpec(args.Ctx, args.Url)
+	if err != nil {
+		return "", err
+	}
+	st := newFileStorage(args.TempDir)
+	spec.Storage = st
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.client == nil {
+		_ = st.Close()
+		return "", errors.New("bittorrent client is not ready")
+	}
+	t, isNew, err := b.client.AddTorrentSpec(spec)
+	if err != nil {
+		_ = st.Close()
+		return "", errors.Wrap(err, "failed to add torrent")
+	}
+	if !isNew {
+		_ = st.Close()
+		return "", fmt.Errorf("torrent %s is already downloading", t.InfoHash().HexString())
+	}
+	bt := &btTask{
+		t:        t,
+		storage:  st,
+		tempDir:  args.TempDir,
+		selected: args.SelectedFiles,
+	}
+	b.tasks[args.UID] = bt
+	go bt.start()
+	return args.UID, nil
+}
+
+// torrentSpec build the spec from a magnet uri or a http url of .torrent file
+func (b *BitTorrent) torrentSpec(ctx context.Context, uri string) (*torrent.TorrentSpec, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse url")
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "magnet":
+		spec, err := torrent.TorrentSpecFromMagnetUri(uri)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse magnet uri")
+		}
+		return spec, nil
+	case "http", "https":
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		resp, err := net.RequestHttp(ctx, http.MethodGet, http.Header{"User-Agent": []string{base.UserAgent}}, uri)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get .torrent file")
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxTorrentFileSize+1))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read .torrent file")
+		}
+		if len(data) > maxTorrentFileSize {
+			return nil, errors.New(".torrent file is too large (max 10MB)")
+		}
+		return specFromTorrentFile(data)
+	default:
+		return nil, fmt.Errorf("unsupported url scheme: %s", u.Scheme)
+	}
+}
+
+func specFromTorrentFile(data []byte) (*torrent.TorrentSpec, error) {
+	t, err := pkgtorrent.Decode(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse .torrent file")
+	}
+	if len(t.Info.Pieces) == 0 || len(t.Info.Pieces)%20 != 0 {
+		return nil, errors.New("invalid .torrent file: bad pieces length")
+	}
+	mi, err := metainfo.Load(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load .torrent file")
+	}
+	return torrent.TorrentSpecFromMetaInfoErr(mi)
+}
+
+// start wait for the metadata then mark the selected files to download
+func (bt *btTask) start() {
+	select {
+	case <-bt.t.GotInfo():
+	case <-bt.t.Closed():
+		return
+	}
+	if len(bt.selected) == 0 {
+		bt.t.DownloadAll()
+		return
+	}
+	files := bt.t.Files()
+	for i, f := range files {
+		if bt.isSelected(i) {
+			f.Download()
+		} else {
+			f.SetPriority(torrent.PiecePriorityNone)
+		}
+	}
+}
+
+func (bt *btTask) isSelected(i int) bool {
+	if len(bt.selected) == 0 {
+		return true
+	}
+	for _, s := range bt.selected {
+		if s == i {
+			return true
+		}
+	}
+	return false
+}
+
+func (bt *btTask) selectedFiles() []*torrent.File {
+	files := bt.t.Files()
+	res := make([]*torrent.File, 0, len(files))
+	for i, f := range files {
+		if bt.isSelected(i) {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// removeUnselected remove the unselected files which may be partially written
+// because their pieces overlap with selected files, so they will not be transferred
+func (bt *btTask) removeUnselected() {
+	if bt.cleaned {
+		return
+	}
+	bt.cleaned = true
+	if len(bt.selected) == 0 {
+		return
+	}
+	for i, f := range bt.t.Files() {
+		if bt.isSelected(i) {
+			continue
+		}
+		p := filepath.Join(bt.tempDir, filepath.FromSlash(f.Path()))
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			log.Warnf("[bittorrent] failed to remove unselected file %s: %v", p, err)
+		}
+	}
+}
+
+func (b *BitTorrent) getTask(gid string) (*btTask, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bt, ok := b.tasks[gid]
+	if !ok {
+		return nil, fmt.Errorf("failed get status, wrong gid: %s", gid)
+	}
+	return bt, nil
+}
+
+func (b *BitTorrent) Remove(task *tool.DownloadTask) error {
+	b.mu.Lock()
+	bt, ok := b.tasks[task.GID]
+	delete(b.tasks, task.GID)
+	b.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	bt.t.Drop()
+	return bt.storage.Close()
+}
+
+func (b *BitTorrent) Status(task *tool.DownloadTask) (*tool.Status, error) {
+	bt, err := b.getTask(task.GID)
+	if err != nil {
+		return nil, err
+	}
+	s := &tool.Status{}
+	select {
+	case <-bt.t.GotInfo():
+	default:
+		s.Status = fmt.Sprintf("fetching metadata, %d peers", bt.t.Stats().ActivePeers)
+		return s, nil
+	}
+	var total, completed int64
+	for _, f := range bt.selectedFiles() {
+		total += f.Length()
+		completed += f.BytesCompleted()
+	}
+	if total == 0 && len(bt.selected) > 0 {
+		s.Err = errors.New("none of the selected files exists in the torrent")
+		return s, nil
+	}
+	s.TotalBytes = total
+	if total > 0 {
+		s.Progress = float64(completed) / float64(total) * 100
+	}
+	if completed >= total {
+		bt.removeUnselected()
+		s.Completed = true
+		s.Progress = 100
+		return s, nil
+	}
+	stats := bt.t.Stats()
+	s.Status = fmt.Sprintf("downloading, %d/%d peers", stats.ActivePeers, stats.TotalPeers)
+	return s, nil
+}
+
+// Seed keep seeding the completed torrent until the seed ratio or the seed time is reached
+func (b *BitTorrent) Seed(task *tool.DownloadTask) (bool, error) {
+	seedTime := setting.GetInt(conf.BitTorrentSeedtime, 0)
+	seedRatio := setting.GetFloat(conf.BitTorrentSeedRatio, 0)
+	if seedTime < 0 {
+		// seed until the tool is reinitialized
+		return false, nil
+	}
+	if seedTime == 0 && seedRatio <= 0 {
+		return true, nil
+	}
+	bt, err := b.getTask(task.GID)
+	if err != nil {
+		return true, err
+	}
+	var deadline <-chan time.Time
+	if seedTime > 0 {
+		timer := time.NewTimer(time.Minute * time.Duration(seedTime))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	ticker := time.NewTicker(time.Second * 10)
+	defer ticker.Stop()
+	for {
+		if seedRatio > 0 && bt.ratio() >= seedRatio {
+			return true, nil
+		}
+		select {
+		case <-task.CtxDone():
+			return true, nil
+		case <-bt.t.Closed():
+			return false, nil
+		case <-deadline:
+			return true, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (bt *btTask) ratio() float64 {
+	var total int64
+	for _, f := range bt.selectedFiles() {
+		total += f.Length()
+	}
+	if total == 0 {
+		return 0
+	}
+	stats := bt.t.Stats()
+	return float64(stats.BytesWrittenData.Int64()) / float64(total)
+}
+
+var (
+	_ tool.Tool   = (*BitTorrent)(nil)
+	_ tool.Seeder = (*BitTorrent)(nil)
+)
+
+func init() {
+	tool.Tools.Add(&BitTorrent{})
+}
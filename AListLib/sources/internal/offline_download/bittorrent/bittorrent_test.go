@@ -0,0 +1,163 @@
+package bittorrent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// testingConfig returns a loopback only client config for tests
+func testingConfig(t *testing.T) *torrent.ClientConfig {
+	cfg := torrent.TestingConfig(t)
+	// the default testing limit is too small to serve a whole chunk
+	cfg.MaxAllocPeerRequestDataPerConn = 1 << 20
+	return cfg
+}
+
+// newSeeder start a local seeder sharing a two-file torrent named "pack"
+func newSeeder(t *testing.T) (*torrent.Client, *metainfo.MetaInfo, map[string][]byte) {
+	srcDir := t.TempDir()
+	files := map[string][]byte{
+		"a.bin": make([]byte, 40*1024+123),
+		"b.bin": make([]byte, 70*1024+45),
+	}
+	if err := os.MkdirAll(filepath.Join(srcDir, "pack"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		_, _ = rand.Read(data)
+		if err := os.WriteFile(filepath.Join(srcDir, "pack", name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info := metainfo.Info{PieceLength: 16 * 1024}
+	if err := info.BuildFromFilePath(filepath.Join(srcDir, "pack")); err != nil {
+		t.Fatal(err)
+	}
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mi := &metainfo.MetaInfo{InfoBytes: infoBytes}
+
+	cfg := testingConfig(t)
+	cfg.Seed = true
+	cfg.DefaultStorage = newFileStorage(srcDir)
+	seeder, err := torrent.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { seeder.Close() })
+	st, err := seeder.AddTorrent(mi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.VerifyData(); err != nil {
+		t.Fatal(err)
+	}
+	return seeder, mi, files
+}
+
+func newLeecher(t *testing.T) *BitTorrent {
+	b := &BitTorrent{}
+	if err := b.initClient(testingConfig(t)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(b.close)
+	return b
+}
+
+func waitCompleted(t *testing.T, b *BitTorrent, task *tool.DownloadTask) *tool.Status {
+	deadline := time.Now().Add(time.Second * 30)
+	for time.Now().Before(deadline) {
+		s, err := b.Status(task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Err != nil {
+			t.Fatal(s.Err)
+		}
+		if s.Completed {
+			return s
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+	t.Fatal("download timeout")
+	return nil
+}
+
+func TestDownloadMagnetWithSelectedFiles(t *testing.T) {
+	seeder, mi, files := newSeeder(t)
+	b := newLeecher(t)
+
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	magnet := mi.Magnet(nil, &info).String() + fmt.Sprintf("&x.pe=127.0.0.1:%d", seeder.LocalPort())
+	selected := -1
+	for i, f := range info.UpvertedFiles() {
+		if f.BestPath()[0] == "b.bin" {
+			selected = i
+		}
+	}
+
+	tempDir := t.TempDir()
+	gid, err := b.AddURL(&tool.AddUrlArgs{
+		Url:           magnet,
+		UID:           "test",
+		TempDir:       tempDir,
+		SelectedFiles: []int{selected},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := &tool.DownloadTask{GID: gid}
+	s := waitCompleted(t, b, task)
+	if s.TotalBytes != int64(len(files["b.bin"])) {
+		t.Errorf("expect total bytes %d, got %d", len(files["b.bin"]), s.TotalBytes)
+	}
+
+	got, err := os.ReadFile(filepath.Join(tempDir, "pack", "b.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, files["b.bin"]) {
+		t.Error("downloaded content mismatch")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "pack", "a.bin")); !os.IsNotExist(err) {
+		t.Errorf("unselected file should be removed, stat err: %v", err)
+	}
+
+	if err := b.Remove(task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Status(task); err == nil {
+		t.Error("expect error after removing the task")
+	}
+}
+
+func TestAddDuplicatedTorrent(t *testing.T) {
+	_, mi, _ := newSeeder(t)
+	b := newLeecher(t)
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	magnet := mi.Magnet(nil, &info).String()
+	if _, err := b.AddURL(&tool.AddUrlArgs{Url: magnet, UID: "1", TempDir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.AddURL(&tool.AddUrlArgs{Url: magnet, UID: "2", TempDir: t.TempDir()}); err == nil {
+		t.Error("expect error when adding the same torrent twice")
+	}
+}
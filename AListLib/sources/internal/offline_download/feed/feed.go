@@ -0,0 +1,287 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultInterval of polling in minutes
+	DefaultInterval = 30
+	// maxFeedSize limits the size of a feed document
+	maxFeedSize = 10 * 1024 * 1024
+	// maxGuidLength is the size of FeedItem.Guid column
+	maxGuidLength = 512
+	// maxAttempts of adding the task of an item, the failed item is not retried after that
+	maxAttempts = 5
+)
+
+// addURL and retryBackoff are replaced in tests
+var (
+	addURL = tool.AddURL
+	// retryBackoff is doubled after each failed attempt
+	retryBackoff = 10 * time.Minute
+)
+
+var (
+	mu       sync.Mutex
+	runners  = make(map[uint]context.CancelFunc)
+	checking = make(map[uint]bool)
+)
+
+// Start schedules polling of all enabled feeds
+func Start() {
+	feeds, err := db.GetEnabledFeeds()
+	if err != nil {
+		log.Errorf("failed get enabled feeds: %+v", err)
+		return
+	}
+	for i := range feeds {
+		schedule(&feeds[i])
+	}
+}
+
+// Stop stops polling of all feeds
+func Stop() {
+	mu.Lock()
+	defer mu.Unlock()
+	for id, cancel := range runners {
+		cancel()
+		delete(runners, id)
+	}
+}
+
+func schedule(f *model.Feed) {
+	mu.Lock()
+	defer mu.Unlock()
+	if cancel, ok := runners[f.ID]; ok {
+		cancel()
+		delete(runners, f.ID)
+	}
+	if f.Disabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	runners[f.ID] = cancel
+	go run(ctx, f.ID, time.Duration(f.Interval)*time.Minute)
+}
+
+func unschedule(id uint) {
+	mu.Lock()
+	defer mu.Unlock()
+	if cancel, ok := runners[id]; ok {
+		cancel()
+		delete(runners, id)
+	}
+}
+
+func run(ctx context.Context, id uint, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		f, err := db.GetFeedById(id)
+		if err != nil {
+			log.Errorf("failed get feed [%d]: %+v", id, err)
+			return
+		}
+		// only one of the instances sharing the database checks the feed
+		if cache.Own(fmt.Sprintf("feed:%d", id), 2*interval) {
+			if _, err := Check(ctx, f); err != nil {
+				log.Warnf("failed check feed [%s]: %+v", f.Name, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func validate(f *model.Feed) error {
+	if f.Interval <= 0 {
+		f.Interval = DefaultInterval
+	}
+	if f.Name == "" {
+		f.Name = f.Url
+	}
+	if _, err := regexp.Compile(f.Include); err != nil {
+		return errors.WithMessage(err, "invalid include rule")
+	}
+	if _, err := regexp.Compile(f.Exclude); err != nil {
+		return errors.WithMessage(err, "invalid exclude rule")
+	}
+	if _, err := tool.Tools.Get(f.Tool); err != nil && f.Tool != "SimpleHttp" {
+		return err
+	}
+	f.DstPath = utils.FixAndCleanPath(f.DstPath)
+	return nil
+}
+
+func GetFeeds(pageIndex, pageSize int) ([]model.Feed, int64, error) {
+	return db.GetFeeds(pageIndex, pageSize)
+}
+
+func GetFeedById(id uint) (*model.Feed, error) {
+	return db.GetFeedById(id)
+}
+
+func CreateFeed(f *model.Feed) error {
+	f.ID = 0
+	if err := validate(f); err != nil {
+		return err
+	}
+	if err := db.CreateFeed(f); err != nil {
+		return err
+	}
+	schedule(f)
+	return nil
+}
+
+func UpdateFeed(f *model.Feed) error {
+	old, err := db.GetFeedById(f.ID)
+	if err != nil {
+		return err
+	}
+	if err := validate(f); err != nil {
+		return err
+	}
+	f.CreatorId = old.CreatorId
+	f.LastChecked = old.LastChecked
+	f.LastError = old.LastError
+	if err := db.UpdateFeed(f); err != nil {
+		return err
+	}
+	schedule(f)
+	return nil
+}
+
+func DeleteFeedById(id uint) error {
+	unschedule(id)
+	return db.DeleteFeedById(id)
+}
+
+func GetFeedItems(feedId uint, pageIndex, pageSize int) ([]model.FeedItem, int64, error) {
+	return db.GetFeedItems(feedId, pageIndex, pageSize)
+}
+
+// Check fetches the feed and adds offline download tasks for new matched items,
+// it returns the number of added tasks
+func Check(ctx context.Context, f *model.Feed) (int, error) {
+	mu.Lock()
+	if checking[f.ID] {
+		mu.Unlock()
+		return 0, errors.Errorf("feed [%s] is being checked", f.Name)
+	}
+	checking[f.ID] = true
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		delete(checking, f.ID)
+		mu.Unlock()
+	}()
+
+	added, err := check(ctx, f)
+	f.LastChecked = time.Now()
+	f.LastError = ""
+	if err != nil {
+		f.LastError = err.Error()
+	}
+	if err := db.UpdateFeedCheckResult(f); err != nil {
+		log.Errorf("failed update feed [%s]: %+v", f.Name, err)
+	}
+	return added, err
+}
+
+func check(ctx context.Context, f *model.Feed) (int, error) {
+	include, err := regexp.Compile(f.Include)
+	if err != nil {
+		return 0, errors.WithMessage(err, "invalid include rule")
+	}
+	exclude, err := regexp.Compile(f.Exclude)
+	if err != nil {
+		return 0, errors.WithMessage(err, "invalid exclude rule")
+	}
+	creator, err := op.GetUserById(f.CreatorId)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed get feed creator")
+	}
+	dstPath, err := creator.JoinPath(f.DstPath)
+	if err != nil {
+		return 0, err
+	}
+	items, err := fetch(ctx, f.Url)
+	if err != nil {
+		return 0, err
+	}
+	ctx = context.WithValue(ctx, conf.UserKey, creator)
+	ctx = context.WithValue(ctx, conf.ApiUrlKey, common.GetApiUrlFromRequest(nil))
+	added := 0
+	for _, i := range items {
+		guid := i.Guid
+		if len(guid) > maxGuidLength {
+			guid = utils.HashData(utils.SHA1, []byte(guid))
+		}
+		record, err := db.GetFeedItem(f.ID, guid)
+		if err == nil && (record.Status != model.FeedItemFailed ||
+			record.Attempts >= maxAttempts || time.Now().Before(record.RetryAt)) {
+			continue
+		}
+		if err != nil {
+			record = &model.FeedItem{FeedId: f.ID, Guid: guid}
+		}
+		record.Title = i.Title
+		record.Url = i.Url
+		record.Created = time.Now()
+		record.Error = ""
+		if !include.MatchString(i.Title) || (f.Exclude != "" && exclude.MatchString(i.Title)) {
+			record.Status = model.FeedItemSkipped
+		} else if t, err := addURL(ctx, &tool.AddURLArgs{
+			URL:          i.Url,
+			DstDirPath:   dstPath,
+			Tool:         f.Tool,
+			DeletePolicy: tool.DeletePolicy(f.DeletePolicy),
+		}); err != nil {
+			record.Status = model.FeedItemFailed
+			record.Error = err.Error()
+			record.Attempts++
+			record.RetryAt = record.Created.Add(retryBackoff << (record.Attempts - 1))
+		} else {
+			record.Status = model.FeedItemAdded
+			if t != nil {
+				record.TaskId = t.GetID()
+			}
+			added++
+		}
+		if err := db.SaveFeedItem(record); err != nil {
+			return added, err
+		}
+	}
+	return added, nil
+}
+
+func fetch(ctx context.Context, url string) ([]Item, error) {
+	res, err := net.RequestHttp(ctx, http.MethodGet, http.Header{}, url)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed fetch feed")
+	}
+	defer res.Body.Close()
+	return Parse(io.LimitReader(res.Body, maxFeedSize))
+}
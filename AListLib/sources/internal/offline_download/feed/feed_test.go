@@ -0,0 +1,281 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/glebarez/sqlite"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+const rssFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+  <title>Podcast</title>
+  <item>
+    <title>Episode 1 [1080p]</title>
+    <link>https://example.com/ep1</link>
+    <guid>ep-1</guid>
+    <enclosure url="https://example.com/ep1.mp3" length="1024" type="audio/mpeg"/>
+  </item>
+  <item>
+    <title>Episode 2 [720p]</title>
+    <link>https://example.com/ep2.mp3</link>
+  </item>
+  <item>
+    <title>Episode 3 [1080p] trailer</title>
+    <guid>ep-3</guid>
+    <enclosure url="https://example.com/ep3.mp3"/>
+  </item>
+  <item>
+    <title>No link</title>
+  </item>
+</channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Releases</title>
+  <entry>
+    <title>v1.0.0</title>
+    <id>tag:example.com,2024:v1.0.0</id>
+    <link href="https://example.com/releases/v1.0.0"/>
+    <link rel="enclosure" href="https://example.com/v1.0.0.tar.gz"/>
+  </entry>
+  <entry>
+    <title>v1.1.0</title>
+    <id>tag:example.com,2024:v1.1.0</id>
+    <link rel="alternate" href="https://example.com/v1.1.0.tar.gz"/>
+  </entry>
+</feed>`
+
+func TestParse(t *testing.T) {
+	items, err := Parse(strings.NewReader(rssFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Item{
+		{Guid: "ep-1", Title: "Episode 1 [1080p]", Url: "https://example.com/ep1.mp3"},
+		{Guid: "https://example.com/ep2.mp3", Title: "Episode 2 [720p]", Url: "https://example.com/ep2.mp3"},
+		{Guid: "ep-3", Title: "Episode 3 [1080p] trailer", Url: "https://example.com/ep3.mp3"},
+	}
+	if len(items) != len(expect) {
+		t.Fatalf("expect %d items, got %+v", len(expect), items)
+	}
+	for i := range expect {
+		if items[i] != expect[i] {
+			t.Errorf("expect %+v, got %+v", expect[i], items[i])
+		}
+	}
+
+	items, err = Parse(strings.NewReader(atomFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Url != "https://example.com/v1.0.0.tar.gz" || items[1].Url != "https://example.com/v1.1.0.tar.gz" {
+		t.Errorf("unexpected atom items: %+v", items)
+	}
+
+	if _, err := Parse(strings.NewReader("<html></html>")); err == nil {
+		t.Error("expect error for non feed document")
+	}
+}
+
+type fakeTask struct {
+	task.TaskExtension
+}
+
+func (f *fakeTask) GetName() string   { return "fake" }
+func (f *fakeTask) GetStatus() string { return "" }
+func (f *fakeTask) Run() error        { return nil }
+
+func TestCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(rssFeed))
+	}))
+	defer srv.Close()
+
+	var (
+		lock  sync.Mutex
+		added []*tool.AddURLArgs
+		fail  = true
+	)
+	addURL = func(ctx context.Context, args *tool.AddURLArgs) (task.TaskExtensionInfo, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if _, ok := ctx.Value(conf.UserKey).(*model.User); !ok {
+			t.Error("expect task creator in context")
+		}
+		// the first attempt of episode 1 fails, it should be retried in the next check
+		if fail && strings.HasSuffix(args.URL, "ep1.mp3") {
+			fail = false
+			return nil, errors.New("tool not ready")
+		}
+		added = append(added, args)
+		return &fakeTask{}, nil
+	}
+	retryBackoff = 0
+	defer func() { addURL, retryBackoff = tool.AddURL, 10*time.Minute }()
+
+	user := &model.User{Username: "feed", BasePath: "/base", Role: model.ADMIN}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	f := &model.Feed{
+		Url:       srv.URL,
+		Include:   `\[1080p\]`,
+		Exclude:   `(?i)trailer`,
+		DstPath:   "/podcast",
+		Tool:      "SimpleHttp",
+		Disabled:  true,
+		CreatorId: user.ID,
+	}
+	if err := CreateFeed(f); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := Check(context.Background(), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 || len(added) != 0 {
+		t.Fatalf("expect no task added in the first check, got %d", n)
+	}
+	n, err = Check(context.Background(), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || len(added) != 1 {
+		t.Fatalf("expect 1 task added in the second check, got %d", n)
+	}
+	if added[0].URL != "https://example.com/ep1.mp3" || added[0].DstDirPath != "/base/podcast" || added[0].Tool != "SimpleHttp" {
+		t.Errorf("unexpected add url args: %+v", added[0])
+	}
+	// items are deduped by guid
+	if n, err = Check(context.Background(), f); err != nil || n != 0 {
+		t.Fatalf("expect no task added in the third check, got %d, %v", n, err)
+	}
+
+	items, total, err := GetFeedItems(f.ID, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 {
+		t.Fatalf("expect 3 history items, got %d", total)
+	}
+	status := make(map[string]string)
+	for _, i := range items {
+		status[i.Guid] = i.Status
+	}
+	if status["ep-1"] != model.FeedItemAdded || status["https://example.com/ep2.mp3"] != model.FeedItemSkipped || status["ep-3"] != model.FeedItemSkipped {
+		t.Errorf("unexpected history: %+v", status)
+	}
+
+	saved, err := GetFeedById(f.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.LastChecked.IsZero() || saved.LastError != "" {
+		t.Errorf("unexpected check result: %+v", saved)
+	}
+
+	if err := DeleteFeedById(f.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ := GetFeedItems(f.ID, 1, 10); total != 0 {
+		t.Errorf("expect history removed with the feed, got %d", total)
+	}
+}
+
+func TestCheckRetryLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(rssFeed))
+	}))
+	defer srv.Close()
+
+	attempts := 0
+	addURL = func(ctx context.Context, args *tool.AddURLArgs) (task.TaskExtensionInfo, error) {
+		attempts++
+		return nil, errors.New("tool not ready")
+	}
+	defer func() { addURL, retryBackoff = tool.AddURL, 10*time.Minute }()
+
+	user := &model.User{Username: "feed_retry", BasePath: "/", Role: model.ADMIN}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	f := &model.Feed{
+		Url:       srv.URL,
+		Include:   `Episode 1`,
+		DstPath:   "/podcast",
+		Tool:      "SimpleHttp",
+		Disabled:  true,
+		CreatorId: user.ID,
+	}
+	if err := CreateFeed(f); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = DeleteFeedById(f.ID) }()
+
+	// the failed item waits for the backoff
+	for i := 0; i < 2; i++ {
+		if _, err := Check(context.Background(), f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if attempts != 1 {
+		t.Fatalf("expect the retry to wait for the backoff, got %d attempts", attempts)
+	}
+	record, err := db.GetFeedItem(f.ID, "ep-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record.RetryAt = time.Now()
+	if err = db.SaveFeedItem(record); err != nil {
+		t.Fatal(err)
+	}
+	retryBackoff = 0
+	for i := 0; i < maxAttempts+2; i++ {
+		if _, err := Check(context.Background(), f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if attempts != maxAttempts {
+		t.Errorf("expect %d attempts, got %d", maxAttempts, attempts)
+	}
+	if record, err = db.GetFeedItem(f.ID, "ep-1"); err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != model.FeedItemFailed || record.Attempts != maxAttempts {
+		t.Errorf("unexpected item: %+v", record)
+	}
+}
+
+func TestCreateFeedInvalidRule(t *testing.T) {
+	f := &model.Feed{Url: "http://127.0.0.1/feed", Include: "(", DstPath: "/", Tool: "SimpleHttp", Disabled: true}
+	if err := CreateFeed(f); err == nil {
+		t.Error("expect error for invalid include rule")
+	}
+}
@@ -0,0 +1,107 @@
+package feed
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html/charset"
+)
+
+// Item is a downloadable entry of a RSS / Atom feed
+type Item struct {
+	Guid  string
+	Title string
+	Url   string
+}
+
+type rssItem struct {
+	Title     string `xml:"title"`
+	Link      string `xml:"link"`
+	Guid      string `xml:"guid"`
+	Enclosure struct {
+		Url string `xml:"url,attr"`
+	} `xml:"enclosure"`
+}
+
+type atomEntry struct {
+	Title string `xml:"title"`
+	Id    string `xml:"id"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+}
+
+type document struct {
+	XMLName xml.Name
+	// RSS 2.0
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	// RSS 1.0 (RDF) puts items at the root
+	Items []rssItem `xml:"item"`
+	// Atom
+	Entries []atomEntry `xml:"entry"`
+}
+
+// Parse parses a RSS 0.9x/1.0/2.0 or Atom document,
+// items without any downloadable url are dropped
+func Parse(r io.Reader) ([]Item, error) {
+	var doc document
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.CharsetReader = charset.NewReaderLabel
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, errors.Wrap(err, "failed to parse feed")
+	}
+	var items []Item
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss", "rdf":
+		for _, i := range append(doc.Channel.Items, doc.Items...) {
+			items = appendItem(items, i.Guid, i.Title, i.Enclosure.Url, i.Link)
+		}
+	case "feed":
+		for _, e := range doc.Entries {
+			var enclosure, link string
+			for _, l := range e.Links {
+				switch l.Rel {
+				case "enclosure":
+					if enclosure == "" {
+						enclosure = l.Href
+					}
+				case "", "alternate":
+					if link == "" {
+						link = l.Href
+					}
+				}
+			}
+			items = appendItem(items, e.Id, e.Title, enclosure, link)
+		}
+	default:
+		return nil, errors.Errorf("unknown feed format: %s", doc.XMLName.Local)
+	}
+	return items, nil
+}
+
+// appendItem prefers the enclosure over the link as the download url,
+// and the url is used as guid if the feed does not provide one
+func appendItem(items []Item, guid, title, enclosure, link string) []Item {
+	u := strings.TrimSpace(enclosure)
+	if u == "" {
+		u = strings.TrimSpace(link)
+	}
+	if u == "" {
+		return items
+	}
+	guid = strings.TrimSpace(guid)
+	if guid == "" {
+		guid = u
+	}
+	return append(items, Item{
+		Guid:  guid,
+		Title: strings.TrimSpace(title),
+		Url:   u,
+	})
+}
@@ -23,6 +23,8 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
+	hash_extend "github.com/OpenListTeam/OpenList/v4/pkg/utils/hash"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -39,13 +41,21 @@ const (
 )
 
 type AddURLArgs struct {
-	URL          string
-	DstDirPath   string
-	Tool         string
-	DeletePolicy DeletePolicy
+	URL           string
+	DstDirPath    string
+	Tool          string
+	DeletePolicy  DeletePolicy
+	SelectedFiles []int
+	// Checksum is the expected checksum of the downloaded file, e.g. "sha256:<hex>"
+	Checksum string
 }
 
 func AddURL(ctx context.Context, args *AddURLArgs) (task.TaskExtensionInfo, error) {
+	if args.Checksum != "" {
+		if _, err := hash_extend.ParseChecksum(args.Checksum); err != nil {
+			return nil, err
+		}
+	}
 	// check storage
 	storage, dstDirActualPath, err := op.GetStorageAndActualPath(args.DstDirPath)
 	if err != nil {
@@ -72,9 +82,12 @@ func AddURL(ctx context.Context, args *AddURLArgs) (task.TaskExtensionInfo, erro
 		if isSimpleHttpSchemeUnsupported(args.URL) {
 			return nil, fmt.Errorf("SimpleHttp tool does not support this URL scheme, please use aria2 or other tools for magnet/ed2k links")
 		}
-		err = tryPutUrl(ctx, args.DstDirPath, args.URL)
-		if err == nil || !errors.Is(err, errs.NotImplement) {
-			return nil, err
+		// the storage fetches the url by itself, the content can not be verified
+		if args.Checksum == "" {
+			err = tryPutUrl(ctx, args.DstDirPath, args.URL)
+			if err == nil || !errors.Is(err, errs.NotImplement) {
+				return nil, err
+			}
 		}
 		// Fallback to creating a download task when storage lacks native PutURL support.
 	}
@@ -169,13 +182,16 @@ func AddURL(ctx context.Context, args *AddURLArgs) (task.TaskExtensionInfo, erro
 		TaskExtension: task.TaskExtension{
 			Creator: taskCreator,
 			ApiUrl:  common.GetApiUrl(ctx),
+			Trace:   tracing.Carrier(ctx),
 		},
-		Url:          args.URL,
-		DstDirPath:   args.DstDirPath,
-		TempDir:      tempDir,
-		DeletePolicy: deletePolicy,
-		Toolname:     args.Tool,
-		tool:         tool,
+		Url:           args.URL,
+		DstDirPath:    args.DstDirPath,
+		TempDir:       tempDir,
+		DeletePolicy:  deletePolicy,
+		Toolname:      args.Tool,
+		SelectedFiles: args.SelectedFiles,
+		Checksum:      args.Checksum,
+		tool:          tool,
 	}
 	DownloadTaskManager.Add(t)
 	return t, nil
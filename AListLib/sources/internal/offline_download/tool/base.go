@@ -12,6 +12,8 @@ type AddUrlArgs struct {
 	TempDir string
 	Signal  chan int
 	Ctx     context.Context
+	// SelectedFiles is the indexes of the files to download in a multi-file torrent, empty means all
+	SelectedFiles []int
 }
 
 type Status struct {
@@ -39,3 +41,10 @@ type Tool interface {
 	// Run for simple http download
 	Run(task *DownloadTask) error
 }
+
+// Seeder is implemented by tools which keep seeding after the download completed.
+// Seed blocks until the seeding limits are reached or the task is canceled,
+// return false if the download should be kept in the tool after seeding.
+type Seeder interface {
+	Seed(task *DownloadTask) (remove bool, err error)
+}
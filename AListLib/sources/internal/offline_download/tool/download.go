@@ -2,7 +2,9 @@ package tool
 
 import (
 	"fmt"
+	"os"
 	"path"
+	"path/filepath"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
@@ -13,6 +15,8 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
 	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
+	hash_extend "github.com/OpenListTeam/OpenList/v4/pkg/utils/hash"
 	"github.com/OpenListTeam/tache"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -25,6 +29,8 @@ type DownloadTask struct {
 	TempDir           string       `json:"temp_dir"`
 	DeletePolicy      DeletePolicy `json:"delete_policy"`
 	Toolname          string       `json:"toolname"`
+	SelectedFiles     []int        `json:"selected_files,omitempty"`
+	Checksum          string       `json:"checksum,omitempty"`
 	Status            string       `json:"-"`
 	Signal            chan int     `json:"-"`
 	GID               string       `json:"-"`
@@ -45,6 +51,9 @@ func (t *DownloadTask) Run() error {
 	}
 	if err := t.tool.Run(t); !errs.IsNotSupportError(err) {
 		if err == nil {
+			if err = t.verify(); err != nil {
+				return err
+			}
 			return t.Transfer()
 		}
 		return err
@@ -54,11 +63,12 @@ func (t *DownloadTask) Run() error {
 		t.Signal = nil
 	}()
 	gid, err := t.tool.AddURL(&AddUrlArgs{
-		Ctx:     t.Ctx(),
-		Url:     t.Url,
-		UID:     t.ID,
-		TempDir: t.TempDir,
-		Signal:  t.Signal,
+		Ctx:           t.Ctx(),
+		Url:           t.Url,
+		UID:           t.ID,
+		TempDir:       t.TempDir,
+		Signal:        t.Signal,
+		SelectedFiles: t.SelectedFiles,
 	})
 	if err != nil {
 		return err
@@ -127,6 +137,30 @@ outer:
 		}
 	}
 
+	if seeder, ok := t.tool.(Seeder); ok {
+		t.Status = "offline download completed, seeding"
+		remove, seedErr := seeder.Seed(t)
+		if remove {
+			if err := t.tool.Remove(t); err != nil {
+				log.Errorln(err.Error())
+			}
+		} else if t.DeletePolicy != UploadDownloadStream {
+			// the tool keeps seeding the downloaded files
+			t.DeletePolicy = DeleteNever
+		}
+		// a canceled or failed seeding does not transfer the files
+		if err := t.Ctx().Err(); err != nil {
+			return err
+		}
+		if seedErr != nil {
+			return errors.WithMessage(seedErr, "failed to seed")
+		}
+		// the seeded files are transferred once the seeding ends, or they
+		// may be deleted while they are seeded
+		t.Status = "offline download completed, maybe transferring"
+		return errors.WithMessage(t.Transfer(), "failed to transfer file")
+	}
+
 	if t.tool.Name() == "Transmission" {
 		// hack for transmission
 		seedTime := setting.GetInt(conf.TransmissionSeedtime, 0)
@@ -164,6 +198,13 @@ func (t *DownloadTask) Update() (bool, error) {
 	}
 	// if download completed
 	if info.Completed {
+		if err := t.verify(); err != nil {
+			return true, err
+		}
+		if _, ok := t.tool.(Seeder); ok {
+			// transferred after the seeding, see Run
+			return true, nil
+		}
 		err := t.Transfer()
 		return true, errors.WithMessage(err, "failed to transfer file")
 	}
@@ -174,9 +215,86 @@ func (t *DownloadTask) Update() (bool, error) {
 	return false, nil
 }
 
+// downloadToStorage reports whether the tool downloads into a storage instead of the local temp dir
+func downloadToStorage(toolName string) bool {
+	return toolName == "115 Cloud" || toolName == "115 Open" || toolName == "123 Open" || toolName == "123Pan" || toolName == "PikPak" || toolName == "Thunder" || toolName == "ThunderX" || toolName == "ThunderBrowser"
+}
+
+// verify checks the downloaded file against the expected checksum.
+// The local temp dir is cleaned on mismatch, so that the retry downloads the file again.
+func (t *DownloadTask) verify() error {
+	// the streamed file is verified by the transfer task after uploading
+	if t.Checksum == "" || t.DeletePolicy == UploadDownloadStream {
+		return nil
+	}
+	expected, err := hash_extend.ParseChecksum(t.Checksum)
+	if err != nil {
+		return err
+	}
+	t.Status = "verifying checksum"
+	if downloadToStorage(t.tool.Name()) {
+		if t.TempDir == t.DstDirPath {
+			// the file is mixed with others in the dst dir and can not be located
+			log.Warnf("skip checksum verification of %s, downloaded to the dst dir directly", t.Url)
+			return nil
+		}
+		storage, dirActualPath, err := op.GetStorageAndActualPath(t.TempDir)
+		if err != nil {
+			return errors.WithMessage(err, "failed get temp storage")
+		}
+		objs, err := op.List(t.Ctx(), storage, dirActualPath, model.ListArgs{Refresh: true})
+		if err != nil {
+			return errors.WithMessagef(err, "failed list [%s]", t.TempDir)
+		}
+		if len(objs) != 1 || objs[0].IsDir() {
+			return errors.Errorf("checksum verification requires exactly one downloaded file in [%s]", t.TempDir)
+		}
+		err = hash_extend.Compare(expected, objs[0].GetHash())
+		if errors.Is(err, errs.ChecksumNotVerifiable) {
+			// the storage does not expose the expected hash types, the content is hashed instead
+			return fs.VerifyContent(t.Ctx(), storage, path.Join(dirActualPath, objs[0].GetName()), expected)
+		}
+		return err
+	}
+	var files []string
+	err = filepath.WalkDir(t.TempDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.Errorf("checksum verification requires exactly one downloaded file, got %d", len(files))
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	// the size is needed by GCID
+	err = hash_extend.VerifyReader(f, info.Size(), expected)
+	_ = f.Close()
+	if errors.Is(err, errs.ChecksumMismatch) {
+		if rmErr := os.RemoveAll(t.TempDir); rmErr != nil {
+			log.Errorf("failed to remove temp dir %s: %v", t.TempDir, rmErr)
+		}
+	}
+	return errors.WithMessagef(err, "failed verify [%s]", filepath.Base(files[0]))
+}
+
 func (t *DownloadTask) Transfer() error {
 	toolName := t.tool.Name()
-	if toolName == "115 Cloud" || toolName == "115 Open" || toolName == "123 Open" || toolName == "123Pan" || toolName == "PikPak" || toolName == "Thunder" || toolName == "ThunderX" || toolName == "ThunderBrowser" {
+	if downloadToStorage(toolName) {
 		// 如果不是直接下载到目标路径，则进行转存
 		if t.TempDir != t.DstDirPath {
 			return transferObj(t.Ctx(), t.TempDir, t.DstDirPath, t.DeletePolicy)
@@ -194,6 +312,7 @@ func (t *DownloadTask) Transfer() error {
 				TaskExtension: task.TaskExtension{
 					Creator: taskCreator,
 					ApiUrl:  t.ApiUrl,
+					Trace:   tracing.Carrier(t.Ctx()),
 				},
 				SrcActualPath: t.TempDir,
 				DstActualPath: dstDirActualPath,
@@ -202,6 +321,7 @@ func (t *DownloadTask) Transfer() error {
 			},
 			DeletePolicy: t.DeletePolicy,
 			Url:          t.Url,
+			Checksum:     t.Checksum,
 		}
 		tsk.SetTotalBytes(t.GetTotalBytes())
 		tsk.groupID = path.Join(tsk.DstStorageMp, tsk.DstActualPath)
@@ -209,7 +329,7 @@ func (t *DownloadTask) Transfer() error {
 		TransferTaskManager.Add(tsk)
 		return nil
 	}
-	return transferStd(t.Ctx(), t.TempDir, t.DstDirPath, t.DeletePolicy)
+	return transferStd(t.Ctx(), t.TempDir, t.DstDirPath, t.DeletePolicy, t.Checksum)
 }
 
 func (t *DownloadTask) GetName() string {
@@ -3,6 +3,7 @@ package tool
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"path"
 	stdpath "path"
@@ -17,9 +18,11 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
 	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
 	"github.com/OpenListTeam/OpenList/v4/pkg/torrent"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	hash_extend "github.com/OpenListTeam/OpenList/v4/pkg/utils/hash"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/OpenListTeam/tache"
 	"github.com/pkg/errors"
@@ -30,6 +33,7 @@ type TransferTask struct {
 	fs.TaskData
 	DeletePolicy DeletePolicy `json:"delete_policy"`
 	Url          string       `json:"url"`
+	Checksum     string       `json:"checksum,omitempty"`
 	groupID      string       `json:"-"`
 }
 
@@ -75,7 +79,11 @@ func (t *TransferTask) Run() error {
 				Mimetype: mimetype,
 				Closers:  utils.NewClosers(r),
 			}
-			return op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, s, t.SetProgress)
+			err = op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, s, t.SetProgress)
+			if err != nil {
+				return err
+			}
+			return t.verify(name, t.GetTotalBytes(), utils.HashInfo{})
 		}
 		return transferStdPath(t)
 	}
@@ -125,7 +133,31 @@ var (
 	TransferTaskManager *tache.Manager[*TransferTask]
 )
 
-func transferStd(ctx context.Context, tempDir, dstDirPath string, deletePolicy DeletePolicy) error {
+// verify checks the uploaded file. The size and the hashes of src are checked when verification is enabled,
+// while the checksum given by the user is always checked.
+func (t *TransferTask) verify(name string, size int64, hash utils.HashInfo) error {
+	verify := fs.NeedVerify()
+	if t.Checksum == "" && !verify {
+		return nil
+	}
+	m := make(map[*utils.HashType]string)
+	if verify {
+		maps.Copy(m, hash.Export())
+	} else {
+		size = -1
+	}
+	if t.Checksum != "" {
+		expected, err := hash_extend.ParseChecksum(t.Checksum)
+		if err != nil {
+			return err
+		}
+		maps.Copy(m, expected.Export())
+	}
+	t.Status = "verifying"
+	return fs.VerifyUploaded(t.Ctx(), t.DstStorage, t.DstActualPath, name, size, utils.NewHashInfoByMap(m))
+}
+
+func transferStd(ctx context.Context, tempDir, dstDirPath string, deletePolicy DeletePolicy, checksum string) error {
 	dstStorage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDirPath)
 	if err != nil {
 		return errors.WithMessage(err, "failed get dst storage")
@@ -141,6 +173,7 @@ func transferStd(ctx context.Context, tempDir, dstDirPath string, deletePolicy D
 				TaskExtension: task.TaskExtension{
 					Creator: taskCreator,
 					ApiUrl:  common.GetApiUrl(ctx),
+					Trace:   tracing.Carrier(ctx),
 				},
 				SrcActualPath: stdpath.Join(tempDir, entry.Name()),
 				DstActualPath: dstDirActualPath,
@@ -148,6 +181,7 @@ func transferStd(ctx context.Context, tempDir, dstDirPath string, deletePolicy D
 				DstStorageMp:  dstStorage.GetStorage().MountPath,
 			},
 			DeletePolicy: deletePolicy,
+			Checksum:     checksum,
 		}
 		t.groupID = path.Join(t.DstStorageMp, t.DstActualPath)
 		task_group.TransferCoordinator.AddTask(t.groupID, nil)
@@ -177,6 +211,7 @@ func transferStdPath(t *TransferTask) error {
 					TaskExtension: task.TaskExtension{
 						Creator: t.Creator,
 						ApiUrl:  t.ApiUrl,
+						Trace:   tracing.Carrier(t.Ctx()),
 					},
 					SrcActualPath: srcRawPath,
 					DstActualPath: dstDirActualPath,
@@ -243,8 +278,7 @@ func transferStdFile(t *TransferTask) error {
 	if err != nil {
 		return err
 	}
-
-	return nil
+	return t.verify(s.GetName(), info.Size(), utils.HashInfo{})
 }
 
 func removeStdTemp(t *TransferTask) {
@@ -277,6 +311,7 @@ func transferObj(ctx context.Context, tempDir, dstDirPath string, deletePolicy D
 				TaskExtension: task.TaskExtension{
 					Creator: taskCreator,
 					ApiUrl:  common.GetApiUrl(ctx),
+					Trace:   tracing.Carrier(ctx),
 				},
 				SrcActualPath: stdpath.Join(srcObjActualPath, obj.GetName()),
 				DstActualPath: dstDirActualPath,
@@ -319,6 +354,7 @@ func transferObjPath(t *TransferTask) error {
 					TaskExtension: task.TaskExtension{
 						Creator: t.Creator,
 						ApiUrl:  t.ApiUrl,
+						Trace:   tracing.Carrier(t.Ctx()),
 					},
 					SrcActualPath: srcObjPath,
 					DstActualPath: dstDirActualPath,
@@ -342,7 +378,7 @@ func transferObjFile(t *TransferTask) error {
 	if err != nil {
 		return errors.WithMessagef(err, "failed get src [%s] file", t.SrcActualPath)
 	}
-	link, srcFile, err := op.Link(t.Ctx(), t.SrcStorage, t.SrcActualPath, model.LinkArgs{})
+	link, srcFile, err := op.Link(t.Ctx(), t.SrcStorage, t.SrcActualPath, model.LinkArgs{NoBlockCache: true})
 	if err != nil {
 		return errors.WithMessagef(err, "failed get [%s] link", t.SrcActualPath)
 	}
@@ -356,7 +392,11 @@ func transferObjFile(t *TransferTask) error {
 		return errors.WithMessagef(err, "failed get [%s] stream", t.SrcActualPath)
 	}
 	t.SetTotalBytes(ss.GetSize())
-	return op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, ss, t.SetProgress)
+	err = op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, ss, t.SetProgress)
+	if err != nil {
+		return err
+	}
+	return t.verify(srcFile.GetName(), srcFile.GetSize(), srcFile.GetHash())
 }
 
 func removeObjTemp(t *TransferTask) {
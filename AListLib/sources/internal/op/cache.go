@@ -2,13 +2,16 @@ package op
 
 import (
 	stdpath "path"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/cache"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
 )
 
 type CacheManager struct {
@@ -32,6 +35,40 @@ func NewCacheManager() *CacheManager {
 // global instance
 var Cache = NewCacheManager()
 
+// cacheTopic broadcasts the invalidations to the other instances sharing the
+// cache backend, they drop the same entries from their own caches
+const cacheTopic = "op_cache"
+
+func init() {
+	cache.OnBroadcast(cacheTopic, Cache.applyInvalidation)
+}
+
+func broadcastInvalidation(kind, key string) {
+	cache.Broadcast(cacheTopic, kind+":"+key)
+}
+
+// applyInvalidation drops the entries invalidated by another instance
+func (cm *CacheManager) applyInvalidation(message string) {
+	kind, key, _ := strings.Cut(message, ":")
+	switch kind {
+	case "dir":
+		cm.popDirectory(key)
+	case "tree":
+		cm.popDirectoryTree(key)
+	case "link":
+		cm.linkCache.DeleteKey(key)
+	case "links":
+		cm.popLinks(key)
+	case "user":
+		cm.userCache.Delete(key)
+	case "detail":
+		cm.detailCache.Delete(key)
+	default:
+		// everything, or the invalidations may be lost
+		cm.clearAll()
+	}
+}
+
 func Key(storage driver.Driver, path string) string {
 	return utils.GetFullPath(storage.GetStorage().MountPath, path)
 }
@@ -44,10 +81,15 @@ func (cm *CacheManager) DeleteDirectoryTree(storage driver.Driver, dirPath strin
 	cm.deleteDirectoryTree(Key(storage, dirPath))
 }
 func (cm *CacheManager) deleteDirectoryTree(key string) {
+	dropPersistedDirTree(key)
+	cm.popDirectoryTree(key)
+	broadcastInvalidation("tree", key)
+}
+func (cm *CacheManager) popDirectoryTree(key string) {
 	if dirCache, exists := cm.dirCache.Pop(key); exists {
 		for _, obj := range dirCache.objs {
 			if obj.IsDir() {
-				cm.deleteDirectoryTree(stdpath.Join(key, obj.GetName()))
+				cm.popDirectoryTree(stdpath.Join(key, obj.GetName()))
 			} else {
 				cm.linkCache.DeleteKey(stdpath.Join(key, obj.GetName()))
 			}
@@ -55,12 +97,41 @@ func (cm *CacheManager) deleteDirectoryTree(key string) {
 	}
 }
 
+// DeleteLinks drops the cached links of all the files in the storage, the
+// files may be linked without their directories listed
+func (cm *CacheManager) DeleteLinks(storage driver.Driver) {
+	key := Key(storage, "/")
+	cm.popLinks(key)
+	broadcastInvalidation("links", key)
+}
+func (cm *CacheManager) popLinks(key string) {
+	if key != "/" {
+		cm.linkCache.DeleteKey(key)
+		key += "/"
+	}
+	cm.linkCache.DeletePrefix(key)
+}
+
 // remove directory from dirCache
 func (cm *CacheManager) DeleteDirectory(storage driver.Driver, dirPath string) {
 	if storage.Config().NoCache {
 		return
 	}
-	cm.dirCache.Delete(Key(storage, dirPath))
+	key := Key(storage, dirPath)
+	dropPersistedDir(key)
+	cm.dirCache.Delete(key)
+	broadcastInvalidation("dir", key)
+}
+
+// popDirectory drops the listing and the links of the files in it
+func (cm *CacheManager) popDirectory(key string) {
+	if dirCache, exists := cm.dirCache.Pop(key); exists {
+		for _, obj := range dirCache.objs {
+			if !obj.IsDir() {
+				cm.linkCache.DeleteKey(stdpath.Join(key, obj.GetName()))
+			}
+		}
+	}
 }
 
 // remove object from dirCache.
@@ -70,6 +141,7 @@ func (cm *CacheManager) removeDirectoryObject(storage driver.Driver, dirPath str
 	key := Key(storage, dirPath)
 	if !obj.IsDir() {
 		cm.linkCache.DeleteKey(stdpath.Join(key, obj.GetName()))
+		broadcastInvalidation("link", stdpath.Join(key, obj.GetName()))
 	}
 
 	if storage.Config().NoCache {
@@ -96,6 +168,7 @@ func (cm *CacheManager) GetUser(username string) (*model.User, bool) {
 // remove user data from cache
 func (cm *CacheManager) DeleteUser(username string) {
 	cm.userCache.Delete(username)
+	broadcastInvalidation("user", username)
 }
 
 // caches setting
@@ -141,11 +214,23 @@ func (cm *CacheManager) GetStorageDetails(storage driver.Driver) (*model.Storage
 }
 
 func (cm *CacheManager) InvalidateStorageDetails(storage driver.Driver) {
-	cm.detailCache.Delete(utils.GetActualMountPath(storage.GetStorage().MountPath))
+	key := utils.GetActualMountPath(storage.GetStorage().MountPath)
+	cm.detailCache.Delete(key)
+	broadcastInvalidation("detail", key)
 }
 
 // clears all caches
 func (cm *CacheManager) ClearAll() {
+	if persistDirCacheEnabled() {
+		if err := db.ClearDirCaches(); err != nil {
+			log.Warnf("failed clear dir caches: %+v", err)
+		}
+	}
+	cm.clearAll()
+	broadcastInvalidation("all", "")
+}
+
+func (cm *CacheManager) clearAll() {
 	cm.dirCache.Clear()
 	cm.linkCache.Clear()
 	cm.userCache.Clear()
@@ -154,6 +239,9 @@ func (cm *CacheManager) ClearAll() {
 }
 
 type directoryCache struct {
+	// key is the full path of the directory, its persisted listing and the
+	// listings of the other instances are dropped once it is changed
+	key    string
 	objs   []model.Obj
 	sorted []model.Obj
 	mu     sync.RWMutex
@@ -166,16 +254,24 @@ const (
 	dirtyUpdate                   // 对象更新：需要执行 full sort + extract
 )
 
-func newDirectoryCache(objs []model.Obj) *directoryCache {
+func newDirectoryCache(key string, objs []model.Obj) *directoryCache {
 	sorted := make([]model.Obj, len(objs))
 	copy(sorted, objs)
 	return &directoryCache{
+		key:    key,
 		objs:   objs,
 		sorted: sorted,
 	}
 }
 
+// dirChanged drops the copies of the listing outside the memory
+func dirChanged(key string) {
+	dropPersistedDir(key)
+	broadcastInvalidation("dir", key)
+}
+
 func (dc *directoryCache) RemoveObject(name string) {
+	defer dirChanged(dc.key)
 	dc.mu.Lock()
 	defer dc.mu.Unlock()
 	for i, obj := range dc.objs {
@@ -188,6 +284,7 @@ func (dc *directoryCache) RemoveObject(name string) {
 }
 
 func (dc *directoryCache) UpdateObject(oldName string, newObj model.Obj) {
+	defer dirChanged(dc.key)
 	dc.mu.Lock()
 	defer dc.mu.Unlock()
 	if oldName != "" {
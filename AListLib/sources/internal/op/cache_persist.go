@@ -0,0 +1,160 @@
+package op
+
+import (
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// persistedObj is the json form of an obj in a persisted directory listing
+type persistedObj struct {
+	ID       string        `json:"id,omitempty"`
+	Path     string        `json:"path,omitempty"`
+	Name     string        `json:"name"`
+	Size     int64         `json:"size"`
+	Modified time.Time     `json:"modified"`
+	Ctime    time.Time     `json:"ctime"`
+	IsFolder bool          `json:"is_folder"`
+	Hash     string        `json:"hash,omitempty"`
+	Thumb    string        `json:"thumb,omitempty"`
+	Mask     model.ObjMask `json:"mask,omitempty"`
+}
+
+func persistDirCacheEnabled() bool {
+	return conf.Conf != nil && conf.Conf.PersistDirCache
+}
+
+// persistDirCache saves the listing so that it survives a restart
+func persistDirCache(storage driver.Driver, key string, objs []model.Obj, ttl time.Duration) {
+	if !persistDirCacheEnabled() {
+		return
+	}
+	items := make([]persistedObj, 0, len(objs))
+	for _, obj := range objs {
+		item := persistedObj{
+			ID:       obj.GetID(),
+			Path:     obj.GetPath(),
+			Name:     obj.GetName(),
+			Size:     obj.GetSize(),
+			Modified: obj.ModTime(),
+			Ctime:    obj.CreateTime(),
+			IsFolder: obj.IsDir(),
+			Mask:     model.GetObjMask(obj),
+		}
+		if hash := obj.GetHash(); len(hash.Export()) > 0 {
+			item.Hash = hash.String()
+		}
+		item.Thumb, _ = model.GetThumb(obj)
+		items = append(items, item)
+	}
+	data, err := utils.Json.MarshalToString(items)
+	if err != nil {
+		log.Warnf("failed encode dir cache of %s: %+v", key, err)
+		return
+	}
+	now := time.Now()
+	if err = db.SaveDirCache(&model.DirCache{
+		StorageID: storage.GetStorage().ID,
+		Path:      key,
+		Objs:      data,
+		ListedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}); err != nil {
+		log.Warnf("failed save dir cache of %s: %+v", key, err)
+	}
+}
+
+func dropPersistedDir(key string) {
+	if !persistDirCacheEnabled() {
+		return
+	}
+	if err := db.DeleteDirCache(key); err != nil {
+		log.Warnf("failed delete dir cache of %s: %+v", key, err)
+	}
+}
+
+func dropPersistedDirTree(key string) {
+	if !persistDirCacheEnabled() {
+		return
+	}
+	if err := db.DeleteDirCacheTree(key); err != nil {
+		log.Warnf("failed delete dir caches under %s: %+v", key, err)
+	}
+}
+
+func dropPersistedStorage(storageID uint) {
+	if !persistDirCacheEnabled() {
+		return
+	}
+	if err := db.DeleteDirCachesByStorage(storageID); err != nil {
+		log.Warnf("failed delete dir caches of storage %d: %+v", storageID, err)
+	}
+}
+
+// warmDirCache loads the persisted listings of the storage into the cache.
+// The ttl is computed again from the current cache policies of the storage.
+// The restored objs are marked as temp, so the operations of the driver
+// always get the objs from a fresh listing, and only browsing is served
+// by the restored listings.
+func warmDirCache(storage driver.Driver) {
+	if !persistDirCacheEnabled() || storage.Config().NoCache {
+		return
+	}
+	now := time.Now()
+	if err := db.DeleteExpiredDirCaches(now); err != nil {
+		log.Warnf("failed delete expired dir caches: %+v", err)
+	}
+	caches, err := db.GetDirCachesByStorage(storage.GetStorage().ID, now)
+	if err != nil {
+		log.Warnf("failed load dir caches: %+v", err)
+		return
+	}
+	mountPath := utils.GetActualMountPath(storage.GetStorage().MountPath)
+	for _, c := range caches {
+		path, ok := strings.CutPrefix(c.Path, strings.TrimSuffix(mountPath, "/"))
+		if !ok || (path != "" && !strings.HasPrefix(path, "/")) {
+			// the mount path is changed
+			dropPersistedDir(c.Path)
+			continue
+		}
+		expiresAt := c.ListedAt.Add(dirCacheTTL(storage, utils.FixAndCleanPath(path)))
+		if !expiresAt.After(now) {
+			dropPersistedDir(c.Path)
+			continue
+		}
+		var items []persistedObj
+		if err = utils.Json.UnmarshalFromString(c.Objs, &items); err != nil || len(items) == 0 {
+			dropPersistedDir(c.Path)
+			continue
+		}
+		objs := make([]model.Obj, 0, len(items))
+		for _, item := range items {
+			obj := model.Object{
+				ID:       item.ID,
+				Path:     item.Path,
+				Name:     item.Name,
+				Size:     item.Size,
+				Modified: item.Modified,
+				Ctime:    item.Ctime,
+				IsFolder: item.IsFolder,
+				Mask:     item.Mask | model.Temp,
+			}
+			if item.Hash != "" {
+				obj.HashInfo = utils.FromString(item.Hash)
+			}
+			if item.Thumb != "" {
+				objs = append(objs, &model.ObjThumb{Object: obj, Thumbnail: model.Thumbnail{Thumbnail: item.Thumb}})
+			} else {
+				objs = append(objs, &obj)
+			}
+		}
+		Cache.dirCache.SetWithTTL(c.Path, newDirectoryCache(c.Path, objs), expiresAt.Sub(now))
+	}
+	log.Debugf("warm %d dir caches of %s", len(caches), mountPath)
+}
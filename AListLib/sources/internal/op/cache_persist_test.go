@@ -0,0 +1,77 @@
+package op_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestPersistDirCache(t *testing.T) {
+	conf.Conf.PersistDirCache = true
+	defer func() { conf.Conf.PersistDirCache = false }()
+	ctx := context.Background()
+	// the virtual storage lists random objs every time
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:          "Virtual",
+		MountPath:       "/persist",
+		CacheExpiration: 30,
+		Addition:        `{"num_file":3,"num_folder":0,"max_file_size":10,"min_file_size":1}`,
+	})
+	if err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/persist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed, err := op.List(ctx, storage, "/", model.ListArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caches, _ := db.GetDirCachesByStorage(id, time.Now()); len(caches) != 1 || caches[0].Path != "/persist" {
+		t.Fatalf("the listing is not persisted: %+v", caches)
+	}
+
+	// the storage is loaded again as after a restart, the listing in the
+	// memory is replaced by the persisted one
+	s, err := db.GetStorageById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = op.LoadStorage(ctx, *s); err != nil {
+		t.Fatal(err)
+	}
+	storage, _ = op.GetStorageByMountPath("/persist")
+	objs, err := op.List(ctx, storage, "/", model.ListArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != len(listed) {
+		t.Fatalf("expect the restored listing, got %+v", objs)
+	}
+	for i, obj := range objs {
+		if obj.GetName() != listed[i].GetName() || obj.GetSize() != listed[i].GetSize() || !model.ObjHasMask(obj, model.Temp) {
+			t.Fatalf("expect the restored obj of %+v, got %+v", listed[i], obj)
+		}
+	}
+	// the driver never gets the restored objs
+	if _, err = op.GetUnwrap(ctx, storage, "/"+listed[0].GetName()); err == nil {
+		t.Fatal("expect the obj to be got from a fresh listing")
+	}
+	if objs, err = op.List(ctx, storage, "/", model.ListArgs{}); err != nil || len(objs) == 0 || model.ObjHasMask(objs[0], model.Temp) {
+		t.Fatalf("expect the fresh listing, got %+v %v", objs, err)
+	}
+
+	// a change of the directory drops its persisted listing
+	if err = op.Remove(ctx, storage, "/"+objs[0].GetName()); err != nil {
+		t.Fatal(err)
+	}
+	if caches, _ := db.GetDirCachesByStorage(id, time.Now()); len(caches) != 0 {
+		t.Fatalf("the stale listing is kept: %+v", caches)
+	}
+}
@@ -0,0 +1,68 @@
+package op_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
+	"github.com/OpenListTeam/OpenList/v4/internal/cache/redistest"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestCacheInvalidationBroadcast(t *testing.T) {
+	srv, err := redistest.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	b, err := cache.NewRedisBackend(cache.RedisOptions{Address: srv.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cache.SetBackend(b); err != nil {
+		t.Fatal(err)
+	}
+	defer cache.SetBackend(cache.NewMemoryBackend())
+	peer, err := cache.NewRedisBackend(cache.RedisOptions{Address: srv.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+
+	ctx := context.Background()
+	// the virtual storage lists random objs every time
+	if _, err = op.CreateStorage(ctx, model.Storage{
+		Driver:          "Virtual",
+		MountPath:       "/shared",
+		CacheExpiration: 30,
+		Addition:        `{"num_file":3,"num_folder":0,"max_file_size":10,"min_file_size":1}`,
+	}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed, err := op.List(ctx, storage, "/", model.ListArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// another instance changed the directory
+	if err = peer.Publish("broadcast", "peer\top_cache\tdir:/shared"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		objs, err := op.List(ctx, storage, "/", model.ListArgs{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if objs[0].GetName() != listed[0].GetName() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("the listing invalidated by another instance is kept")
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/secret"
 	"github.com/pkg/errors"
 )
 
@@ -51,6 +52,7 @@ func registerDriverItems(config driver.Config, addition driver.Additional) {
 	}
 	mainItems := getMainItems(config)
 	additionalItems := getAdditionalItems(tAddition, config.DefaultRoot)
+	secret.RegisterConfidential(config.Name, getConfidentialFields(tAddition))
 	driverInfoMap[config.Name] = driver.Info{
 		Common:     mainItems,
 		Additional: additionalItems,
@@ -214,3 +216,22 @@ func getAdditionalItems(t reflect.Type, defaultRoot string) []driver.Item {
 	}
 	return items
 }
+
+// getConfidentialFields returns the json names of the fields tagged with `confidential:"true"`,
+// they are encrypted in the database
+func getConfidentialFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			fields = append(fields, getConfidentialFields(field.Type)...)
+			continue
+		}
+		name, ok := field.Tag.Lookup("json")
+		if !ok || field.Tag.Get("confidential") != "true" || field.Type.Kind() != reflect.String {
+			continue
+		}
+		fields = append(fields, strings.Split(name, ",")[0])
+	}
+	return fields
+}
@@ -0,0 +1,31 @@
+package op
+
+import (
+	"context"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// callDriver runs the call to the driver in a span and records it in the metrics
+func callDriver[T any](ctx context.Context, storage driver.Driver, op string, fn func(ctx context.Context) (T, error)) (T, error) {
+	ctx, span := tracing.Start(ctx, "driver."+op,
+		attribute.String("driver", storage.Config().Name),
+		attribute.String("storage", storage.GetStorage().MountPath))
+	start := time.Now()
+	v, err := fn(ctx)
+	metrics.ObserveDriverCall(storage.Config().Name, op, start, err)
+	tracing.End(span, err)
+	return v, err
+}
+
+// startSpan starts the span of an operation on the path of the storage
+func startSpan(ctx context.Context, name string, storage driver.Driver, path string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name,
+		attribute.String("storage", storage.GetStorage().MountPath),
+		attribute.String("path", path))
+}
@@ -7,11 +7,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/block_cache"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/bmatcuk/doublestar/v4"
@@ -23,7 +26,9 @@ import (
 var listG singleflight.Group[[]model.Obj]
 
 // List files in storage, not contains virtual file
-func List(ctx context.Context, storage driver.Driver, path string, args model.ListArgs) ([]model.Obj, error) {
+func List(ctx context.Context, storage driver.Driver, path string, args model.ListArgs) (objs []model.Obj, err error) {
+	ctx, span := startSpan(ctx, "op.List", storage, path)
+	defer func() { tracing.End(span, err) }()
 	return list(ctx, storage, path, args, nil)
 }
 
@@ -35,7 +40,9 @@ func list(ctx context.Context, storage driver.Driver, path string, args model.Li
 	log.Debugf("op.List %s", path)
 	key := Key(storage, path)
 	if !args.Refresh {
-		if dirCache, exists := Cache.dirCache.Get(key); exists {
+		dirCache, exists := Cache.dirCache.Get(key)
+		metrics.ObserveCacheLookup("dir", exists)
+		if exists {
 			log.Debugf("use cache when list %s", path)
 			objs := dirCache.GetSortedObjects(storage)
 			if resultValidator != nil {
@@ -57,7 +64,9 @@ func list(ctx context.Context, storage driver.Driver, path string, args model.Li
 		if !dir.IsDir() {
 			return nil, errors.WithStack(errs.NotFolder)
 		}
-		files, err := storage.List(ctx, dir, args)
+		files, err := callDriver(ctx, storage, "list", func(ctx context.Context) ([]model.Obj, error) {
+			return storage.List(ctx, dir, args)
+		})
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to list objs")
 		}
@@ -80,32 +89,9 @@ func list(ctx context.Context, storage driver.Driver, path string, args model.Li
 			if len(files) > 0 {
 				log.Debugf("set cache: %s => %+v", key, files)
 
-				ttl := storage.GetStorage().CacheExpiration
-
-				customCachePolicies := storage.GetStorage().CustomCachePolicies
-				if len(customCachePolicies) > 0 {
-					for configPolicy := range strings.SplitSeq(customCachePolicies, "\n") {
-						pattern, ttlstr, ok := strings.Cut(strings.TrimSpace(configPolicy), ":")
-						if !ok {
-							log.Warnf("Malformed custom cache policy entry: %s in storage %s for path %s. Expected format: pattern:ttl", configPolicy, storage.GetStorage().MountPath, path)
-							continue
-						}
-						if match, err1 := doublestar.Match(pattern, path); err1 != nil {
-							log.Warnf("Invalid glob pattern in custom cache policy: %s, error: %v", pattern, err1)
-							continue
-						} else if !match {
-							continue
-						}
-
-						if configTtl, err1 := strconv.ParseInt(ttlstr, 10, 64); err1 == nil {
-							ttl = int(configTtl)
-							break
-						}
-					}
-				}
-
-				duration := time.Minute * time.Duration(ttl)
-				Cache.dirCache.SetWithTTL(key, newDirectoryCache(files), duration)
+				duration := dirCacheTTL(storage, path)
+				Cache.dirCache.SetWithTTL(key, newDirectoryCache(key, files), duration)
+				persistDirCache(storage, key, files, duration)
 			} else {
 				log.Debugf("del cache: %s", key)
 				Cache.deleteDirectoryTree(key)
@@ -124,8 +110,39 @@ func list(ctx context.Context, storage driver.Driver, path string, args model.Li
 	return objs, nil
 }
 
+// dirCacheTTL returns the expiration of the cached listing of the path, the
+// first matched custom cache policy overrides the one of the storage
+func dirCacheTTL(storage driver.Driver, path string) time.Duration {
+	ttl := storage.GetStorage().CacheExpiration
+
+	customCachePolicies := storage.GetStorage().CustomCachePolicies
+	if len(customCachePolicies) > 0 {
+		for configPolicy := range strings.SplitSeq(customCachePolicies, "\n") {
+			pattern, ttlstr, ok := strings.Cut(strings.TrimSpace(configPolicy), ":")
+			if !ok {
+				log.Warnf("Malformed custom cache policy entry: %s in storage %s for path %s. Expected format: pattern:ttl", configPolicy, storage.GetStorage().MountPath, path)
+				continue
+			}
+			if match, err1 := doublestar.Match(pattern, path); err1 != nil {
+				log.Warnf("Invalid glob pattern in custom cache policy: %s, error: %v", pattern, err1)
+				continue
+			} else if !match {
+				continue
+			}
+
+			if configTtl, err1 := strconv.ParseInt(ttlstr, 10, 64); err1 == nil {
+				ttl = int(configTtl)
+				break
+			}
+		}
+	}
+	return time.Minute * time.Duration(ttl)
+}
+
 // Get object from list of files
-func Get(ctx context.Context, storage driver.Driver, path string, excludeTempObj ...bool) (model.Obj, error) {
+func Get(ctx context.Context, storage driver.Driver, path string, excludeTempObj ...bool) (obj model.Obj, err error) {
+	ctx, span := startSpan(ctx, "op.Get", storage, path)
+	defer func() { tracing.End(span, err) }()
 	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
 		return nil, errors.WithMessagef(errs.StorageNotInit, "storage status: %s", storage.GetStorage().Status)
 	}
@@ -182,7 +199,9 @@ func Get(ctx context.Context, storage driver.Driver, path string, excludeTempObj
 
 	// get the obj directly without list so that we can reduce the io
 	if g, ok := storage.(driver.Getter); ok {
-		obj, err := g.Get(ctx, path)
+		obj, err := callDriver(ctx, storage, "get", func(ctx context.Context) (model.Obj, error) {
+			return g.Get(ctx, path)
+		})
 		if err == nil {
 			return obj, nil
 		}
@@ -224,7 +243,9 @@ func GetUnwrap(ctx context.Context, storage driver.Driver, path string) (model.O
 var linkG = singleflight.Group[*objWithLink]{}
 
 // Link get link, if is an url. should have an expiry time
-func Link(ctx context.Context, storage driver.Driver, path string, args model.LinkArgs) (*model.Link, model.Obj, error) {
+func Link(ctx context.Context, storage driver.Driver, path string, args model.LinkArgs) (_ *model.Link, _ model.Obj, err error) {
+	ctx, span := startSpan(ctx, "op.Link", storage, path)
+	defer func() { tracing.End(span, err) }()
 	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
 		return nil, nil, errors.WithMessagef(errs.StorageNotInit, "storage status: %s", storage.GetStorage().Status)
 	}
@@ -241,10 +262,12 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 		typeKey += "/" + args.Header.Get("User-Agent")
 	}
 	key := Key(storage, path)
-	if ol, exists := Cache.linkCache.GetType(key, typeKey); exists {
+	ol, exists := Cache.linkCache.GetType(key, typeKey)
+	metrics.ObserveCacheLookup("link", exists)
+	if exists {
 		if ol.link.Expiration != nil ||
 			ol.link.SyncClosers.AcquireReference() || !ol.link.RequireReference {
-			return ol.link, ol.obj, nil
+			return wrapBlockCache(storage, path, ol, args), ol.obj, nil
 		}
 	}
 
@@ -257,7 +280,9 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 			return nil, errors.WithStack(errs.NotFile)
 		}
 
-		link, err := storage.Link(ctx, file, args)
+		link, err := callDriver(ctx, storage, "link", func(ctx context.Context) (*model.Link, error) {
+			return storage.Link(ctx, file, args)
+		})
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed get link")
 		}
@@ -275,11 +300,20 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 			return nil, nil, err
 		}
 		if ol.link.SyncClosers.AcquireReference() || !ol.link.RequireReference {
-			return ol.link, ol.obj, nil
+			return wrapBlockCache(storage, path, ol, args), ol.obj, nil
 		}
 	}
 }
 
+// wrapBlockCache makes the cached link read through the block cache unless
+// the caller reads the whole file
+func wrapBlockCache(storage driver.Driver, path string, ol *objWithLink, args model.LinkArgs) *model.Link {
+	if args.NoBlockCache {
+		return ol.link
+	}
+	return block_cache.Wrap(storage.GetStorage(), path, ol.obj, ol.link)
+}
+
 // Other api
 func Other(ctx context.Context, storage driver.Driver, args model.FsOtherArgs) (any, error) {
 	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
@@ -348,6 +382,7 @@ func MakeDir(ctx context.Context, storage driver.Driver, path string) error {
 		if err != nil && !errs.IsObjectAlreadyExists(err) {
 			return nil, errors.WithStack(err)
 		}
+		callObjWriteHooks(storage, path)
 		if storage.Config().NoCache {
 			return nil, nil
 		}
@@ -413,6 +448,7 @@ func Move(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 
 	srcKey := Key(storage, srcDirPath)
 	dstKey := Key(storage, dstDirPath)
+	callObjWriteHooks(storage, stdpath.Join(srcDirPath, srcRawObj.GetName()), stdpath.Join(dstDirPath, srcRawObj.GetName()))
 	if !srcRawObj.IsDir() {
 		Cache.linkCache.DeleteKey(stdpath.Join(srcKey, srcRawObj.GetName()))
 		Cache.linkCache.DeleteKey(stdpath.Join(dstKey, srcRawObj.GetName()))
@@ -477,6 +513,7 @@ func Rename(ctx context.Context, storage driver.Driver, srcPath, dstName string)
 	}
 
 	dirKey := Key(storage, stdpath.Dir(srcPath))
+	callObjWriteHooks(storage, srcPath, stdpath.Join(stdpath.Dir(srcPath), dstName))
 	if !srcRawObj.IsDir() {
 		Cache.linkCache.DeleteKey(stdpath.Join(dirKey, oldName))
 		Cache.linkCache.DeleteKey(stdpath.Join(dirKey, dstName))
@@ -546,6 +583,7 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	}
 
 	dstKey := Key(storage, dstDirPath)
+	callObjWriteHooks(storage, stdpath.Join(dstDirPath, srcRawObj.GetName()))
 	if !srcRawObj.IsDir() {
 		Cache.linkCache.DeleteKey(stdpath.Join(dstKey, srcRawObj.GetName()))
 	}
@@ -598,6 +636,7 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 		err = s.Remove(ctx, model.UnwrapObjName(rawObj))
 		if err == nil {
 			Cache.removeDirectoryObject(storage, dirPath, rawObj)
+			callObjWriteHooks(storage, path)
 		}
 	default:
 		return errs.NotImplement
@@ -605,7 +644,9 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 	return errors.WithStack(err)
 }
 
-func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file model.FileStreamer, up driver.UpdateProgress) error {
+func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file model.FileStreamer, up driver.UpdateProgress) (err error) {
+	ctx, span := startSpan(ctx, "op.Put", storage, stdpath.Join(dstDirPath, file.GetName()))
+	defer func() { tracing.End(span, err) }()
 	defer func() {
 		if err := file.Close(); err != nil {
 			log.Errorf("failed to close file streamer, %v", err)
@@ -668,14 +709,19 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	var newObj model.Obj
 	switch s := storage.(type) {
 	case driver.PutResult:
-		newObj, err = s.Put(ctx, parentDir, file, up)
+		newObj, err = callDriver(ctx, storage, "put", func(ctx context.Context) (model.Obj, error) {
+			return s.Put(ctx, parentDir, file, up)
+		})
 	case driver.Put:
-		err = s.Put(ctx, parentDir, file, up)
+		_, err = callDriver(ctx, storage, "put", func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, s.Put(ctx, parentDir, file, up)
+		})
 	default:
 		return errs.NotImplement
 	}
 	if err == nil {
 		Cache.linkCache.DeleteKey(Key(storage, dstPath))
+		callObjWriteHooks(storage, dstPath)
 		if !storage.Config().NoCache {
 			if cache, exist := Cache.dirCache.Get(Key(storage, dstDirPath)); exist {
 				if newObj == nil {
@@ -712,7 +758,9 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	return errors.WithStack(err)
 }
 
-func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url string) error {
+func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url string) (err error) {
+	ctx, span := startSpan(ctx, "op.PutURL", storage, stdpath.Join(dstDirPath, dstName))
+	defer func() { tracing.End(span, err) }()
 	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
 		return errors.WithMessagef(errs.StorageNotInit, "storage status: %s", storage.GetStorage().Status)
 	}
@@ -722,7 +770,7 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 	if _, err := Get(ctx, storage, dstPath); err == nil {
 		return errors.WithStack(errs.ObjectAlreadyExists)
 	}
-	err := MakeDir(ctx, storage, dstDirPath)
+	err = MakeDir(ctx, storage, dstDirPath)
 	if err != nil {
 		return errors.WithMessagef(err, "failed to make dir [%s]", dstDirPath)
 	}
@@ -736,14 +784,19 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 	var newObj model.Obj
 	switch s := storage.(type) {
 	case driver.PutURLResult:
-		newObj, err = s.PutURL(ctx, dstDir, dstName, url)
+		newObj, err = callDriver(ctx, storage, "put_url", func(ctx context.Context) (model.Obj, error) {
+			return s.PutURL(ctx, dstDir, dstName, url)
+		})
 	case driver.PutURL:
-		err = s.PutURL(ctx, dstDir, dstName, url)
+		_, err = callDriver(ctx, storage, "put_url", func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, s.PutURL(ctx, dstDir, dstName, url)
+		})
 	default:
 		return errors.WithStack(errs.NotImplement)
 	}
 	if err == nil {
 		Cache.linkCache.DeleteKey(Key(storage, dstPath))
+		callObjWriteHooks(storage, dstPath)
 		if !storage.Config().NoCache {
 			if cache, exist := Cache.dirCache.Get(Key(storage, dstDirPath)); exist {
 				if newObj == nil {
@@ -768,6 +821,59 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 	return errors.WithStack(err)
 }
 
+// RapidPut try to create dstDirPath/dstName from its hash only, without transferring the content.
+// return errs.NotImplement if the driver does not implement driver.RapidPut
+func RapidPut(ctx context.Context, storage driver.Driver, dstDirPath, dstName string, size int64, hash utils.HashInfo) (model.Obj, error) {
+	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
+		return nil, errors.WithMessagef(errs.StorageNotInit, "storage status: %s", storage.GetStorage().Status)
+	}
+	rp, ok := storage.(driver.RapidPut)
+	if !ok {
+		return nil, errors.WithStack(errs.NotImplement)
+	}
+	dstDirPath = utils.FixAndCleanPath(dstDirPath)
+	dstPath := stdpath.Join(dstDirPath, dstName)
+	err := MakeDir(ctx, storage, dstDirPath)
+	if err != nil && !errs.IsObjectAlreadyExists(err) {
+		return nil, errors.WithMessagef(err, "failed to make dir [%s]", dstDirPath)
+	}
+	dstDir, err := GetUnwrap(ctx, storage, dstDirPath)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get dir [%s]", dstDirPath)
+	}
+	if model.ObjHasMask(dstDir, model.NoWrite) {
+		return nil, errors.WithStack(errs.PermissionDenied)
+	}
+	newObj, err := rp.RapidPut(ctx, dstDir, dstName, size, hash)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	Cache.linkCache.DeleteKey(Key(storage, dstPath))
+	callObjWriteHooks(storage, dstPath)
+	if newObj == nil {
+		t := time.Now()
+		newObj = &model.Object{
+			Name:     dstName,
+			Size:     size,
+			Modified: t,
+			Ctime:    t,
+			HashInfo: hash,
+			Mask:     model.Temp,
+		}
+	}
+	newObj = wrapObjName(storage, newObj)
+	if !storage.Config().NoCache {
+		if cache, exist := Cache.dirCache.Get(Key(storage, dstDirPath)); exist {
+			cache.UpdateObject(newObj.GetName(), newObj)
+		}
+	}
+	if ctx.Value(conf.SkipHookKey) == nil && needHandleObjsUpdateHook() {
+		go objsUpdateHook(context.WithoutCancel(ctx), storage, dstDirPath, false)
+	}
+	log.Debugf("rapid put [%s](%d bytes) done", dstName, size)
+	return newObj, nil
+}
+
 func GetDirectUploadTools(storage driver.Driver) []string {
 	du, ok := storage.(driver.DirectUploader)
 	if !ok {
@@ -30,6 +30,27 @@ func HandleObjsUpdateHook(ctx context.Context, parent string, objs []model.Obj)
 	}
 }
 
+// Write
+// ObjWriteHook is called after the object at path of the storage is written,
+// moved or removed, the path may be a folder
+type ObjWriteHook = func(storage driver.Driver, path string)
+
+var (
+	objWriteHooks = make([]ObjWriteHook, 0)
+)
+
+func RegisterObjWriteHook(hook ObjWriteHook) {
+	objWriteHooks = append(objWriteHooks, hook)
+}
+
+func callObjWriteHooks(storage driver.Driver, paths ...string) {
+	for _, hook := range objWriteHooks {
+		for _, p := range paths {
+			hook(storage, p)
+		}
+	}
+}
+
 // Setting
 type SettingItemHook func(item *model.SettingItem) error
 
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
@@ -28,8 +29,22 @@ func RegisterSettingChangingCallback(f func()) {
 	settingChangingCallbacks = append(settingChangingCallbacks, f)
 }
 
+// settingTopic broadcasts the changes of the settings to the other instances
+const settingTopic = "setting"
+
+func init() {
+	cache.OnBroadcast(settingTopic, func(string) {
+		runSettingChangingCallbacks()
+	})
+}
+
 func SettingCacheUpdate() {
 	Cache.ClearAll()
+	runSettingChangingCallbacks()
+	cache.Broadcast(settingTopic, "changed")
+}
+
+func runSettingChangingCallbacks() {
 	for _, cb := range settingChangingCallbacks {
 		cb()
 	}
@@ -14,6 +14,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/secret"
 	"github.com/OpenListTeam/OpenList/v4/pkg/generic_sync"
 	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
@@ -107,8 +108,17 @@ func initStorage(ctx context.Context, storage model.Storage, storageDriver drive
 			storagesMap.Store(driverStorage.MountPath, storageDriver)
 		}
 	}()
+	// the values which could not be decrypted when the storage was read are still encrypted,
+	// they are unmarshalled as is so that they are written back untouched
+	addition, decryptErr := secret.DecryptAddition(driverStorage.Driver, driverStorage.Addition)
+	if decryptErr != nil {
+		addition = driverStorage.Addition
+	}
 	// Unmarshal Addition
-	err = utils.Json.UnmarshalFromString(driverStorage.Addition, storageDriver.GetAddition())
+	err = utils.Json.UnmarshalFromString(addition, storageDriver.GetAddition())
+	if err == nil && decryptErr != nil {
+		err = errors.WithMessage(decryptErr, "failed decrypt addition")
+	}
 	if err == nil {
 		if ref, ok := storageDriver.(driver.Reference); ok {
 			if strings.HasPrefix(driverStorage.Remark, "ref:/") {
@@ -145,6 +155,7 @@ func initStorage(ctx context.Context, storage model.Storage, storageDriver drive
 		err = errors.Wrap(err, "failed init storage")
 	} else {
 		driverStorage.SetStatus(WORK)
+		warmDirCache(storageDriver)
 	}
 	MustSaveDriverStorage(storageDriver)
 	return err
@@ -250,6 +261,8 @@ func UpdateStorage(ctx context.Context, storage model.Storage) error {
 	if err != nil {
 		return errors.Wrapf(err, "failed drop storage")
 	}
+	// the persisted listings may be from the old options
+	dropPersistedStorage(storage.ID)
 
 	err = initStorage(ctx, storage, storageDriver)
 	go callStorageHooks("update", storageDriver)
@@ -257,6 +270,59 @@ func UpdateStorage(ctx context.Context, storage model.Storage) error {
 	return err
 }
 
+// ReinitStorage replaces the storage by a new instance initialized with its
+// saved options, as the reload of all the storages does for one storage. The
+// old instance is dropped and the cached listings and links are cleared, so
+// nothing of the failed instance is used again.
+func ReinitStorage(ctx context.Context, storageDriver driver.Driver) error {
+	storage, err := db.GetStorageById(storageDriver.GetStorage().ID)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	if storage.Disabled {
+		return errors.Errorf("storage [%s] is disabled", storage.MountPath)
+	}
+	driverNew, err := GetDriver(storage.Driver)
+	if err != nil {
+		return errors.WithMessage(err, "failed get driver new")
+	}
+	if err := storageDriver.Drop(ctx); err != nil {
+		log.Warnf("failed drop storage [%s]: %+v", storage.MountPath, err)
+	}
+	Cache.DeleteDirectoryTree(storageDriver, "/")
+	Cache.DeleteLinks(storageDriver)
+	Cache.InvalidateStorageDetails(storageDriver)
+	storage.MountPath = utils.FixAndCleanPath(storage.MountPath)
+	newDriver := driverNew()
+	err = initStorage(ctx, *storage, newDriver)
+	go callStorageHooks("update", newDriver)
+	return err
+}
+
+// ProbeStorage checks the storage answers, by its details if the driver
+// provides them or else by listing its root without the cache
+func ProbeStorage(ctx context.Context, storageDriver driver.Driver) error {
+	if storageDriver.GetStorage().Status != WORK {
+		return errors.WithMessagef(errs.StorageNotInit, "storage status: %s", storageDriver.GetStorage().Status)
+	}
+	if wd, ok := storageDriver.(driver.WithDetails); ok {
+		_, err := callDriver(ctx, storageDriver, "details", func(ctx context.Context) (*model.StorageDetails, error) {
+			return wd.GetDetails(ctx)
+		})
+		if !errs.IsNotImplementError(err) && !errs.IsNotSupportError(err) {
+			return err
+		}
+	}
+	root, err := GetUnwrap(ctx, storageDriver, "/")
+	if err != nil {
+		return errors.WithMessage(err, "failed get root")
+	}
+	_, err = callDriver(ctx, storageDriver, "list", func(ctx context.Context) ([]model.Obj, error) {
+		return storageDriver.List(ctx, root, model.ListArgs{Refresh: true, SkipHook: true})
+	})
+	return err
+}
+
 func DeleteStorageById(ctx context.Context, id uint) error {
 	storage, err := db.GetStorageById(id)
 	if err != nil {
@@ -278,6 +344,7 @@ func DeleteStorageById(ctx context.Context, id uint) error {
 		Cache.InvalidateStorageDetails(storageDriver)
 		go callStorageHooks("del", storageDriver)
 	}
+	dropPersistedStorage(id)
 	// delete the storage in the database
 	if err := db.DeleteStorageById(id); err != nil {
 		return errors.WithMessage(err, "failed delete storage in database")
@@ -2,6 +2,7 @@ package op_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
@@ -88,3 +89,25 @@ func setupStorages(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadStorageUndecryptable(t *testing.T) {
+	// the password was encrypted with a master key that is not loaded
+	addition := `{"address":"127.0.0.1:21","username":"u","password":"enc:v1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}`
+	id, err := op.CreateStorage(context.Background(), model.Storage{Driver: "FTP", MountPath: "/undecryptable", Addition: addition})
+	if err == nil {
+		t.Fatal("expect the storage with undecryptable addition to fail")
+	}
+	storage, err := db.GetStorageById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = op.LoadStorage(context.Background(), *storage); err == nil || !strings.Contains(err.Error(), "failed decrypt addition") {
+		t.Errorf("expect the decryption error, got %v", err)
+	}
+	if storage, err = db.GetStorageById(id); err != nil || !strings.Contains(storage.Status, "failed decrypt addition") {
+		t.Errorf("expect the decryption error in the status, got %q, %v", storage.Status, err)
+	}
+	if !strings.Contains(storage.Addition, "enc:v1:AAAA") {
+		t.Errorf("expect the encrypted password to be kept, got %s", storage.Addition)
+	}
+}
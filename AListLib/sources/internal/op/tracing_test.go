@@ -0,0 +1,50 @@
+package op_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing/tracingtest"
+)
+
+func TestListSpans(t *testing.T) {
+	exp := tracingtest.Record()
+	ctx, parent := tracing.Start(context.Background(), "request")
+	if _, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:          "Virtual",
+		MountPath:       "/traced",
+		CacheExpiration: 30,
+		Addition:        `{"num_file":3,"num_folder":0,"max_file_size":10,"min_file_size":1}`,
+	}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/traced")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = op.List(ctx, storage, "/", model.ListArgs{Refresh: true}); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	ids := map[string]string{}
+	parents := map[string]string{}
+	for _, span := range exp.GetSpans() {
+		ids[span.Name] = span.SpanContext.SpanID().String()
+		parents[span.Name] = span.Parent.SpanID().String()
+	}
+	for name, parent := range map[string]string{
+		"op.List":     "request",
+		"driver.list": "op.List",
+	} {
+		if _, ok := ids[name]; !ok {
+			t.Fatalf("expect the span %s, got %v", name, ids)
+		}
+		if parents[name] != ids[parent] {
+			t.Errorf("expect %s to be the parent of %s", parent, name)
+		}
+	}
+}
@@ -9,7 +9,10 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/search/searcher"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var instance searcher.Searcher = nil
@@ -47,13 +50,30 @@ func Init(mode string) error {
 	return err
 }
 
-func Search(ctx context.Context, req model.SearchReq) ([]model.SearchNode, int64, error) {
+func Search(ctx context.Context, req model.SearchReq) (_ []model.SearchNode, _ int64, err error) {
+	if instance == nil {
+		return nil, 0, errs.SearchNotAvailable
+	}
+	ctx, span := startSpan(ctx, req)
+	defer func() { tracing.End(span, err) }()
 	return instance.Search(ctx, req)
 }
 
 const searchBatchSize = 1000
 
-func SearchFiltered(ctx context.Context, req model.SearchReq, filter searcher.Filter) ([]model.SearchNode, int64, error) {
+func startSpan(ctx context.Context, req model.SearchReq) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "search.Search",
+		attribute.String("search.mode", instance.Config().Name),
+		attribute.String("search.parent", req.Parent),
+		attribute.String("search.keywords", req.Keywords))
+}
+
+func SearchFiltered(ctx context.Context, req model.SearchReq, filter searcher.Filter) (_ []model.SearchNode, _ int64, err error) {
+	if instance == nil {
+		return nil, 0, errs.SearchNotAvailable
+	}
+	ctx, span := startSpan(ctx, req)
+	defer func() { tracing.End(span, err) }()
 	if filteredSearcher, ok := instance.(searcher.FilteredSearcher); ok {
 		return filteredSearcher.SearchFiltered(ctx, req, filter)
 	}
@@ -0,0 +1,93 @@
+package secret
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// confidentialFields holds the json names of the addition fields tagged
+// with `confidential:"true"` of every driver
+var confidentialFields sync.Map
+
+// RegisterConfidential is called when a driver is registered
+func RegisterConfidential(driver string, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	confidentialFields.Store(driver, fields)
+}
+
+func ConfidentialFields(driver string) []string {
+	v, ok := confidentialFields.Load(driver)
+	if !ok {
+		return nil
+	}
+	return v.([]string)
+}
+
+// EncryptAddition encrypts the confidential fields of the addition of a storage
+func EncryptAddition(driver, addition string) (string, error) {
+	return transformAddition(driver, addition, Encrypt)
+}
+
+// DecryptAddition decrypts the confidential fields of the addition of a storage
+func DecryptAddition(driver, addition string) (string, error) {
+	return transformAddition(driver, addition, Decrypt)
+}
+
+// HasEncrypted reports whether any confidential field of the addition is still encrypted,
+// which means it could not be decrypted with the current master key
+func HasEncrypted(driver, addition string) bool {
+	_, err := transformAddition(driver, addition, func(s string) (string, error) {
+		if IsEncrypted(s) {
+			return "", ErrNoKey
+		}
+		return s, nil
+	})
+	return err != nil
+}
+
+// transformAddition applies f to the string values of the confidential fields,
+// the addition is returned unchanged if it is not a json object or nothing changes
+func transformAddition(driver, addition string, f func(string) (string, error)) (string, error) {
+	fields := ConfidentialFields(driver)
+	if len(fields) == 0 || addition == "" {
+		return addition, nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(addition), &m); err != nil {
+		return addition, nil
+	}
+	changed := false
+	for _, field := range fields {
+		raw, ok := m[field]
+		if !ok {
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil || value == "" {
+			continue
+		}
+		newValue, err := f(value)
+		if err != nil {
+			return "", errors.WithMessagef(err, "field %s", field)
+		}
+		if newValue == value {
+			continue
+		}
+		if m[field], err = json.Marshal(newValue); err != nil {
+			return "", errors.WithStack(err)
+		}
+		changed = true
+	}
+	if !changed {
+		return addition, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(b), nil
+}
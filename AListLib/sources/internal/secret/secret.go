@@ -0,0 +1,188 @@
+// Package secret encrypts the confidential fields of storage additions at rest.
+//
+// The master secret is looked up in this order: the platform keystore (see
+// SetKeystoreKey), the MASTER_KEY env and the master key file, which is
+// generated on the first launch if none of them exists.
+// Encrypted values look like "enc:v1:<base64 of nonce and ciphertext>".
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/pkg/errors"
+)
+
+type Source string
+
+const (
+	SourceNone     Source = ""
+	SourceKeystore Source = "keystore"
+	SourceEnv      Source = "env"
+	SourceFile     Source = "file"
+
+	prefix = "enc:v1:"
+)
+
+var ErrNoKey = errors.New("master key is not loaded, encrypted values can not be decrypted")
+
+var (
+	mu          sync.RWMutex
+	aead        cipher.AEAD
+	keySource   Source
+	keystoreKey string
+)
+
+// SetKeystoreKey is called by the platform (e.g. the Android keystore) before
+// the bootstrap, the key takes precedence over the env and the key file
+func SetKeystoreKey(key string) {
+	mu.Lock()
+	defer mu.Unlock()
+	keystoreKey = key
+}
+
+// KeyFile returns the path of the master key file
+func KeyFile() string {
+	if conf.Conf != nil && conf.Conf.MasterKeyFile != "" {
+		return conf.Conf.MasterKeyFile
+	}
+	return filepath.Join(flags.DataDir, "master.key")
+}
+
+// Init loads the master secret, a new one is written to the key file if there is none
+func Init() error {
+	mu.RLock()
+	key := keystoreKey
+	mu.RUnlock()
+	if key != "" {
+		return SetMasterKey(key, SourceKeystore)
+	}
+	if conf.Conf != nil && conf.Conf.MasterKey != "" {
+		return SetMasterKey(conf.Conf.MasterKey, SourceEnv)
+	}
+	keyFile := KeyFile()
+	data, err := os.ReadFile(keyFile)
+	if err == nil {
+		key = strings.TrimSpace(string(data))
+		if key == "" {
+			return errors.Errorf("master key file %s is empty", keyFile)
+		}
+		return SetMasterKey(key, SourceFile)
+	}
+	if !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed read master key file %s", keyFile)
+	}
+	key, err = GenerateKey()
+	if err != nil {
+		return err
+	}
+	if err := WriteKeyFile(keyFile, key); err != nil {
+		return err
+	}
+	return SetMasterKey(key, SourceFile)
+}
+
+// GenerateKey returns a random master secret
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// WriteKeyFile writes the master secret to path, readable by the owner only
+func WriteKeyFile(path, key string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.WriteFile(path, []byte(key+"\n"), 0o600))
+}
+
+// SetMasterKey replaces the key used by Encrypt and Decrypt, any non-empty
+// string is accepted and stretched into an AES-256 key
+func SetMasterKey(key string, source Source) error {
+	if key == "" {
+		return errors.New("master key is empty")
+	}
+	a, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	aead = a
+	keySource = source
+	return nil
+}
+
+// KeySource reports where the current master key comes from
+func KeySource() Source {
+	mu.RLock()
+	defer mu.RUnlock()
+	return keySource
+}
+
+func newAEAD(key string) (cipher.AEAD, error) {
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	a, err := cipher.NewGCM(block)
+	return a, errors.WithStack(err)
+}
+
+func current() cipher.AEAD {
+	mu.RLock()
+	defer mu.RUnlock()
+	return aead
+}
+
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, prefix)
+}
+
+// Encrypt seals s with the master key, it is a no-op for empty or already
+// encrypted values and when no master key is loaded
+func Encrypt(s string) (string, error) {
+	a := current()
+	if a == nil || s == "" || IsEncrypted(s) {
+		return s, nil
+	}
+	nonce := make([]byte, a.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.WithStack(err)
+	}
+	sealed := a.Seal(nonce, nonce, []byte(s), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value returned by Encrypt, plain values are returned as is
+func Decrypt(s string) (string, error) {
+	if !IsEncrypted(s) {
+		return s, nil
+	}
+	a := current()
+	if a == nil {
+		return "", ErrNoKey
+	}
+	sealed, err := base64.StdEncoding.DecodeString(s[len(prefix):])
+	if err != nil || len(sealed) < a.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	plain, err := a.Open(nil, sealed[:a.NonceSize()], sealed[a.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("failed decrypt value, the master key may be wrong")
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,53 @@
+package secret
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEncryptAddition(t *testing.T) {
+	if err := SetMasterKey("old", SourceEnv); err != nil {
+		t.Fatal(err)
+	}
+	RegisterConfidential("Test", []string{"password", "token"})
+	addition := `{"password":"p@ss","token":"","root":"/"}`
+	encrypted, err := EncryptAddition("Test", addition)
+	if err != nil {
+		t.Fatalf("failed encrypt: %+v", err)
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(encrypted), &m); err != nil {
+		t.Fatalf("encrypted addition is not json: %+v", err)
+	}
+	if !IsEncrypted(m["password"]) || m["token"] != "" || m["root"] != "/" {
+		t.Fatalf("unexpected encrypted addition: %s", encrypted)
+	}
+	again, _ := EncryptAddition("Test", encrypted)
+	if again != encrypted {
+		t.Fatalf("encrypted values should not be encrypted twice")
+	}
+	if plain, _ := EncryptAddition("Other", addition); plain != addition {
+		t.Fatalf("drivers without confidential fields should be untouched")
+	}
+
+	decrypted, err := DecryptAddition("Test", encrypted)
+	if err != nil {
+		t.Fatalf("failed decrypt: %+v", err)
+	}
+	if err := json.Unmarshal([]byte(decrypted), &m); err != nil || m["password"] != "p@ss" {
+		t.Fatalf("unexpected decrypted addition: %s", decrypted)
+	}
+	if HasEncrypted("Test", decrypted) {
+		t.Fatalf("decrypted addition should not have encrypted values")
+	}
+
+	if err := SetMasterKey("new", SourceEnv); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptAddition("Test", encrypted); err == nil {
+		t.Fatalf("decrypt with a wrong key should fail")
+	}
+	if !HasEncrypted("Test", encrypted) {
+		t.Fatalf("addition encrypted with the old key should be reported")
+	}
+}
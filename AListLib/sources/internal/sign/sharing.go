@@ -0,0 +1,41 @@
+package sign
+
+import (
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/sign"
+)
+
+var onceSharing sync.Once
+var instanceSharing sign.Sign
+
+func SignSharing(data string) string {
+	expire := setting.GetInt(conf.LinkExpiration, 0)
+	if expire == 0 {
+		return NotExpiredSharing(data)
+	} else {
+		return WithDurationSharing(data, time.Duration(expire)*time.Hour)
+	}
+}
+
+func WithDurationSharing(data string, d time.Duration) string {
+	onceSharing.Do(InstanceSharing)
+	return instanceSharing.Sign(data, time.Now().Add(d).Unix())
+}
+
+func NotExpiredSharing(data string) string {
+	onceSharing.Do(InstanceSharing)
+	return instanceSharing.Sign(data, 0)
+}
+
+func VerifySharing(data string, sign string) error {
+	onceSharing.Do(InstanceSharing)
+	return instanceSharing.Verify(data, sign)
+}
+
+func InstanceSharing() {
+	instanceSharing = sign.NewHMACSign([]byte(setting.GetStr(conf.Token) + "-sharing"))
+}
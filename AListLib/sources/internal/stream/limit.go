@@ -5,6 +5,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
 	"golang.org/x/time/rate"
@@ -34,6 +35,24 @@ var (
 	ServerUploadLimit   Limiter
 )
 
+// countBytes records the bytes passed through the limiter in the metrics
+func countBytes(l Limiter, n int) {
+	direction := "other"
+	if l != nil {
+		switch l {
+		case ClientDownloadLimit:
+			direction = "client_download"
+		case ClientUploadLimit:
+			direction = "client_upload"
+		case ServerDownloadLimit:
+			direction = "server_download"
+		case ServerUploadLimit:
+			direction = "server_upload"
+		}
+	}
+	metrics.AddStreamBytes(direction, n)
+}
+
 type RateLimitReader struct {
 	io.Reader
 	Limiter Limiter
@@ -45,6 +64,7 @@ func (r *RateLimitReader) Read(p []byte) (n int, err error) {
 		return 0, err
 	}
 	n, err = r.Reader.Read(p)
+	countBytes(r.Limiter, n)
 	if err != nil {
 		return
 	}
@@ -72,6 +92,7 @@ func (w *RateLimitWriter) Write(p []byte) (n int, err error) {
 		return 0, err
 	}
 	n, err = w.Writer.Write(p)
+	countBytes(w.Limiter, n)
 	if err != nil {
 		return
 	}
@@ -99,6 +120,7 @@ func (r *RateLimitFile) Read(p []byte) (n int, err error) {
 		return 0, err
 	}
 	n, err = r.File.Read(p)
+	countBytes(r.Limiter, n)
 	if err != nil {
 		return
 	}
@@ -113,6 +135,7 @@ func (r *RateLimitFile) ReadAt(p []byte, off int64) (n int, err error) {
 		return 0, err
 	}
 	n, err = r.File.ReadAt(p, off)
+	countBytes(r.Limiter, n)
 	if err != nil {
 		return
 	}
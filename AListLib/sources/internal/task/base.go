@@ -2,11 +2,15 @@ package task
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	"github.com/OpenListTeam/tache"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type TaskExtension struct {
@@ -16,6 +20,11 @@ type TaskExtension struct {
 	endTime    *time.Time
 	TotalBytes int64
 	ApiUrl     string
+	// Trace is the trace context of the request creating the task, the runs
+	// of the task continue the trace
+	Trace   map[string]string `json:"trace,omitempty"`
+	spanMu  sync.Mutex
+	runSpan trace.Span
 }
 
 func (t *TaskExtension) SetCtx(ctx context.Context) {
@@ -28,6 +37,28 @@ func (t *TaskExtension) SetCtx(ctx context.Context) {
 	t.Base.SetCtx(ctx)
 }
 
+// SetState records the runs of the task in spans, a span ends when the task
+// leaves the running state.
+func (t *TaskExtension) SetState(state tache.State) {
+	t.Base.SetState(state)
+	t.spanMu.Lock()
+	defer t.spanMu.Unlock()
+	if t.runSpan != nil {
+		var err error
+		if state != tache.StateSucceeded {
+			// the error of the last failed run is kept until the task succeeds
+			err = t.GetErr()
+		}
+		tracing.End(t.runSpan, err)
+		t.runSpan = nil
+	}
+	if state == tache.StateRunning && t.Ctx() != nil {
+		ctx, span := tracing.StartFromCarrier(t.Ctx(), t.Trace, "task.Run", attribute.String("task.id", t.GetID()))
+		t.Base.SetCtx(ctx)
+		t.runSpan = span
+	}
+}
+
 func (t *TaskExtension) SetCreator(creator *model.User) {
 	t.Creator = creator
 	t.Persist()
@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing/tracingtest"
+)
+
+func TestStartFromCarrier(t *testing.T) {
+	exp := tracingtest.Record()
+	ctx, request := Start(context.Background(), "request")
+	carrier := Carrier(ctx)
+	request.End()
+	if len(carrier) == 0 {
+		t.Fatal("expect the trace context in the carrier")
+	}
+
+	// the run of a task continues the trace of its request, not the span in its context
+	taskCtx, other := Start(context.Background(), "other")
+	_, run := StartFromCarrier(taskCtx, carrier, "task.Run")
+	run.End()
+	other.End()
+	if run.SpanContext().TraceID() != request.SpanContext().TraceID() {
+		t.Fatal("the run is not in the trace of the request")
+	}
+	for _, span := range exp.GetSpans() {
+		if span.Name == "task.Run" && span.Parent.SpanID() != request.SpanContext().SpanID() {
+			t.Fatal("expect the request span to be the parent of the run")
+		}
+	}
+
+	_, root := StartFromCarrier(taskCtx, nil, "task.Run")
+	defer root.End()
+	if root.SpanContext().TraceID() == other.SpanContext().TraceID() {
+		t.Fatal("expect a new trace without the carrier")
+	}
+}
@@ -0,0 +1,19 @@
+// Package tracingtest records the spans of the tracing package in the tests.
+package tracingtest
+
+import (
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Record installs a provider exporting all the spans to the returned
+// in-process exporter synchronously.
+func Record() *tracetest.InMemoryExporter {
+	exp := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exp),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	))
+	return exp
+}
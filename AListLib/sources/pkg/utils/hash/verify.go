@@ -0,0 +1,103 @@
+package hash_extend
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// ParseChecksum parses a checksum like "sha256:<hex>" or "SHA-256=<hex>",
+// several checksums can be joined with ","
+func ParseChecksum(str string) (utils.HashInfo, error) {
+	m := make(map[*utils.HashType]string)
+	for _, item := range strings.Split(str, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, ":")
+		if !ok {
+			name, value, ok = strings.Cut(item, "=")
+		}
+		if !ok {
+			return utils.HashInfo{}, errors.Errorf("invalid checksum: %s", item)
+		}
+		ht, ok := getHashType(name)
+		if !ok {
+			return utils.HashInfo{}, errors.Errorf("unsupported checksum type: %s", name)
+		}
+		value = strings.ToLower(strings.TrimSpace(value))
+		if _, err := hex.DecodeString(value); err != nil || len(value) != ht.Width {
+			return utils.HashInfo{}, errors.Errorf("invalid %s checksum: %s", ht.Name, value)
+		}
+		m[ht] = value
+	}
+	return utils.NewHashInfoByMap(m), nil
+}
+
+func getHashType(name string) (*utils.HashType, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if ht, ok := utils.GetHashByName(name); ok {
+		return ht, true
+	}
+	return utils.GetHashByName(strings.ReplaceAll(name, "-", ""))
+}
+
+// Compare compares the hashes of the same types, types missing on either side are ignored.
+// errs.ChecksumNotVerifiable is returned when expected has hashes but none of them is in actual.
+func Compare(expected, actual utils.HashInfo) error {
+	expectedAny, compared := false, false
+	for ht, e := range expected.All() {
+		if e == "" {
+			continue
+		}
+		expectedAny = true
+		a := actual.GetHash(ht)
+		if a == "" {
+			continue
+		}
+		if !strings.EqualFold(e, a) {
+			return errors.WithStack(newMismatch(ht.Name, e, a))
+		}
+		compared = true
+	}
+	if expectedAny && !compared {
+		return errors.WithStack(errs.ChecksumNotVerifiable)
+	}
+	return nil
+}
+
+// VerifyReader reads all of r and checks the size and the expected hashes,
+// a negative size skips the size check
+func VerifyReader(r io.Reader, size int64, expected utils.HashInfo) error {
+	hashers := make(map[*utils.HashType]hash.Hash)
+	writers := make([]io.Writer, 0, len(expected.Export()))
+	for ht := range expected.All() {
+		// GCID depends on the file size
+		h := ht.NewFunc(size)
+		hashers[ht] = h
+		writers = append(writers, h)
+	}
+	n, err := utils.CopyWithBuffer(io.MultiWriter(writers...), r)
+	if err != nil {
+		return err
+	}
+	if size >= 0 && n != size {
+		return errors.WithStack(newMismatch("size", fmt.Sprint(size), fmt.Sprint(n)))
+	}
+	actual := make(map[*utils.HashType]string, len(hashers))
+	for ht, h := range hashers {
+		actual[ht] = hex.EncodeToString(h.Sum(nil))
+	}
+	return Compare(expected, utils.NewHashInfoByMap(actual))
+}
+
+func newMismatch(name, expected, actual string) error {
+	return errs.NewErr(errs.ChecksumMismatch, "%s expected %s, got %s", name, expected, actual)
+}
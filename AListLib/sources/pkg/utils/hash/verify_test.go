@@ -0,0 +1,60 @@
+package hash_extend
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var verifyData = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}
+
+const (
+	verifyMD5    = "bf13fc19e5151ac57d4252e0e0f87abe"
+	verifySHA256 = "c839e57675862af5c21bd0a15413c3ec579e0d5522dab600bc6c3489b05b8f54"
+)
+
+func TestParseChecksum(t *testing.T) {
+	hi, err := ParseChecksum("md5:" + verifyMD5 + ", SHA-256=" + verifySHA256)
+	require.NoError(t, err)
+	assert.Equal(t, verifyMD5, hi.GetHash(utils.MD5))
+	assert.Equal(t, verifySHA256, hi.GetHash(utils.SHA256))
+
+	_, err = ParseChecksum("crc32:12345678")
+	assert.Error(t, err)
+	_, err = ParseChecksum("md5:1234")
+	assert.Error(t, err)
+	_, err = ParseChecksum(verifyMD5)
+	assert.Error(t, err)
+}
+
+func TestVerifyReader(t *testing.T) {
+	expected, err := ParseChecksum("md5:" + verifyMD5 + ",sha256:" + verifySHA256)
+	require.NoError(t, err)
+	assert.NoError(t, VerifyReader(bytes.NewReader(verifyData), int64(len(verifyData)), expected))
+	assert.NoError(t, VerifyReader(bytes.NewReader(verifyData), -1, expected))
+
+	err = VerifyReader(bytes.NewReader(verifyData[1:]), -1, expected)
+	assert.True(t, errors.Is(err, errs.ChecksumMismatch))
+	err = VerifyReader(bytes.NewReader(verifyData), int64(len(verifyData)+1), utils.HashInfo{})
+	assert.True(t, errors.Is(err, errs.ChecksumMismatch))
+
+	gcid := utils.HashData(GCID, verifyData, len(verifyData))
+	assert.NoError(t, VerifyReader(bytes.NewReader(verifyData), int64(len(verifyData)), utils.NewHashInfo(GCID, gcid)))
+}
+
+func TestCompare(t *testing.T) {
+	expected := utils.NewHashInfo(utils.MD5, verifyMD5)
+	assert.NoError(t, Compare(expected, utils.NewHashInfo(utils.MD5, "BF13FC19E5151AC57D4252E0E0F87ABE")))
+	// no common hash type to compare
+	err := Compare(expected, utils.NewHashInfo(utils.SHA256, verifySHA256))
+	assert.True(t, errors.Is(err, errs.ChecksumNotVerifiable))
+	// nothing expected
+	assert.NoError(t, Compare(utils.HashInfo{}, utils.NewHashInfo(utils.SHA256, verifySHA256)))
+	err = Compare(expected, utils.NewHashInfo(utils.MD5, "d41d8cd98f00b204e9800998ecf8427e"))
+	assert.True(t, errors.Is(err, errs.ChecksumMismatch))
+}
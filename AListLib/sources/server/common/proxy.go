@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"maps"
 
@@ -87,6 +88,7 @@ func attachHeader(w http.ResponseWriter, file model.Obj, link *model.Link) {
 		w.Header().Set("Content-Type", utils.GetMimeType(fileName))
 	}
 }
+
 func GetEtag(file model.Obj, size int64) string {
 	hash := ""
 	for _, v := range file.GetHash().Export() {
@@ -101,6 +103,34 @@ func GetEtag(file model.Obj, size int64) string {
 	return fmt.Sprintf(`"%x-%x"`, file.ModTime().Unix(), size)
 }
 
+// etagHashTypes are the hashes used for the write ETag by preference, so the
+// ETag of a file does not depend on which of its hashes the driver returns
+var etagHashTypes = []*utils.HashType{utils.SHA256, utils.SHA1, utils.MD5}
+
+// GetWriteEtag returns the ETag checked by the write preconditions, it is
+// built from the size, the modified time in nanoseconds and the hash, so a
+// save within the same second still changes it
+func GetWriteEtag(file model.Obj) string {
+	etag := fmt.Sprintf("%x-%x", file.ModTime().UnixNano(), file.GetSize())
+	hashInfo := file.GetHash()
+	for _, ht := range etagHashTypes {
+		if v := hashInfo.GetHash(ht); v != "" {
+			return fmt.Sprintf(`"%s-%s"`, etag, strings.ToLower(v))
+		}
+	}
+	return fmt.Sprintf(`"%s"`, etag)
+}
+
+// CheckWritePreconditions checks the If-Match and If-Unmodified-Since headers
+// in h against the write ETag of obj before it is changed, obj is nil if the
+// target does not exist
+func CheckWritePreconditions(h http.Header, obj model.Obj) bool {
+	if obj == nil {
+		return net.CheckWritePreconditions(h, "", time.Time{})
+	}
+	return net.CheckWritePreconditions(h, GetWriteEtag(obj), obj.ModTime())
+}
+
 func ProxyRange(ctx context.Context, link *model.Link, size int64) *model.Link {
 	if link.RangeReader == nil && !strings.HasPrefix(link.URL, GetApiUrl(ctx)+"/") {
 		if link.ContentLength > 0 {
@@ -4,7 +4,9 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
@@ -52,3 +54,25 @@ func TestProxyOverridesUpstreamContentDisposition(t *testing.T) {
 		t.Errorf("body = %q, want %q", got, want)
 	}
 }
+
+func TestGetWriteEtag(t *testing.T) {
+	md5 := "f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0"
+	sha1 := "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"
+	modified := time.Unix(1, 0)
+	// the etag is kept when another hash of the file is known
+	a := &model.Object{Name: "a", Size: 5, Modified: modified, HashInfo: utils.NewHashInfo(utils.SHA1, sha1)}
+	b := &model.Object{Name: "a", Size: 5, Modified: modified, HashInfo: utils.NewHashInfoByMap(map[*utils.HashType]string{utils.MD5: md5, utils.SHA1: sha1})}
+	if GetWriteEtag(a) != GetWriteEtag(b) || !strings.Contains(GetWriteEtag(a), sha1) {
+		t.Errorf("unexpected etags: %s %s", GetWriteEtag(a), GetWriteEtag(b))
+	}
+	// a save within the same second changes the etag
+	c := &model.Object{Name: "a", Size: 5, Modified: modified.Add(time.Millisecond)}
+	d := &model.Object{Name: "a", Size: 5, Modified: modified}
+	if GetWriteEtag(c) == GetWriteEtag(d) {
+		t.Errorf("expect the etags to differ: %s", GetWriteEtag(c))
+	}
+	// the download etag is kept as is
+	if GetEtag(a, 5) != `"`+sha1+`"` {
+		t.Errorf("unexpected etag: %s", GetEtag(a, 5))
+	}
+}
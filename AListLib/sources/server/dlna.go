@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/dlna"
+	"github.com/OpenListTeam/OpenList/v4/server/handles"
+	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
+	"github.com/gin-gonic/gin"
+)
+
+// InitDLNA registers the media server and the download routes used by the renderers
+func InitDLNA(e *gin.Engine, s *dlna.Server) {
+	e.ContextWithFallback = true
+	s.Register(e)
+	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
+	signCheck := middlewares.Down(sign.Verify)
+	e.GET("/d/*path", middlewares.PathParse, signCheck, downloadLimiter, handles.Down)
+	e.HEAD("/d/*path", middlewares.PathParse, signCheck, handles.Down)
+}
+
+type DLNAServer struct {
+	srv  *http.Server
+	ssdp *dlna.SSDP
+}
+
+func NewDLNAServer(handler http.Handler, uuid string) *DLNAServer {
+	return &DLNAServer{
+		srv: &http.Server{Addr: conf.Conf.DLNA.Listen, Handler: handler},
+		ssdp: &dlna.SSDP{
+			UUID:     uuid,
+			Interval: time.Duration(conf.Conf.DLNA.NotifyInterval) * time.Second,
+		},
+	}
+}
+
+// ListenAndServe serves the http server and announces it by SSDP
+func (d *DLNAServer) ListenAndServe() error {
+	ln, err := net.Listen("tcp4", d.srv.Addr)
+	if err != nil {
+		return err
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	d.ssdp.Location = func(ip net.IP) string {
+		return fmt.Sprintf("http://%s/rootDesc.xml", net.JoinHostPort(ip.String(), fmt.Sprint(port)))
+	}
+	go func() {
+		if err := d.ssdp.ListenAndServe(); err != nil {
+			utils.Log.Errorf("failed to start dlna ssdp: %s", err.Error())
+		}
+	}()
+	return d.srv.Serve(ln)
+}
+
+func (d *DLNAServer) Shutdown(ctx context.Context) error {
+	_ = d.ssdp.Close()
+	return d.srv.Shutdown(ctx)
+}
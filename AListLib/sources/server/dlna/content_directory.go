@@ -0,0 +1,323 @@
+package dlna
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	stdpath "path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+const (
+	rootID = "0"
+
+	classFolder = "object.container.storageFolder"
+	classVideo  = "object.item.videoItem"
+	classAudio  = "object.item.audioItem.musicTrack"
+	classImage  = "object.item.imageItem.photo"
+)
+
+// mimeTypes covers the media types that are unknown to the mime package on some platforms
+var mimeTypes = map[string]string{
+	".mkv":  "video/x-matroska",
+	".ts":   "video/mp2t",
+	".m2ts": "video/mp2t",
+	".flv":  "video/x-flv",
+	".wmv":  "video/x-ms-wmv",
+	".avi":  "video/x-msvideo",
+	".rmvb": "application/vnd.rn-realmedia-vbr",
+	".webm": "video/webm",
+	".mov":  "video/quicktime",
+	".flac": "audio/flac",
+	".ape":  "audio/x-ape",
+	".m4a":  "audio/mp4",
+	".wma":  "audio/x-ms-wma",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".webp": "image/webp",
+	".heic": "image/heic",
+}
+
+type didlRes struct {
+	ProtocolInfo string `xml:"protocolInfo,attr"`
+	Size         int64  `xml:"size,attr,omitempty"`
+	URL          string `xml:",chardata"`
+}
+
+type didlObject struct {
+	ID          string    `xml:"id,attr"`
+	ParentID    string    `xml:"parentID,attr"`
+	Restricted  int       `xml:"restricted,attr"`
+	Title       string    `xml:"dc:title"`
+	Class       string    `xml:"upnp:class"`
+	Date        string    `xml:"dc:date,omitempty"`
+	AlbumArtURI string    `xml:"upnp:albumArtURI,omitempty"`
+	Res         []didlRes `xml:"res"`
+}
+
+type didlLite struct {
+	XMLName    xml.Name     `xml:"DIDL-Lite"`
+	XMLNS      string       `xml:"xmlns,attr"`
+	DC         string       `xml:"xmlns:dc,attr"`
+	UPnP       string       `xml:"xmlns:upnp,attr"`
+	Containers []didlObject `xml:"container"`
+	Items      []didlObject `xml:"item"`
+}
+
+func (d *didlLite) add(o didlObject) {
+	// nothing can be modified by the control points
+	o.Restricted = 1
+	if o.Class == classFolder {
+		d.Containers = append(d.Containers, o)
+	} else {
+		d.Items = append(d.Items, o)
+	}
+}
+
+func (s *Server) contentDirectory(c *gin.Context) {
+	handleSOAP(c, cdServiceType, func(action string, args map[string]string) ([]soapArg, *upnpError) {
+		switch action {
+		case "Browse":
+			return s.browse(c, args)
+		case "GetSearchCapabilities":
+			return []soapArg{{"SearchCaps", ""}}, nil
+		case "GetSortCapabilities":
+			return []soapArg{{"SortCaps", ""}}, nil
+		case "GetSystemUpdateID":
+			return []soapArg{{"Id", s.updateID()}}, nil
+		default:
+			return nil, &upnpError{Code: errInvalidAction, Description: "invalid action"}
+		}
+	})
+}
+
+func (s *Server) connectionManager(c *gin.Context) {
+	handleSOAP(c, cmServiceType, func(action string, args map[string]string) ([]soapArg, *upnpError) {
+		switch action {
+		case "GetProtocolInfo":
+			return []soapArg{{"Source", sourceProtocolInfo()}, {"Sink", ""}}, nil
+		case "GetCurrentConnectionIDs":
+			return []soapArg{{"ConnectionIDs", "0"}}, nil
+		case "GetCurrentConnectionInfo":
+			if args["ConnectionID"] != "0" {
+				return nil, &upnpError{Code: 706, Description: "invalid connection reference"}
+			}
+			return []soapArg{
+				{"RcsID", "-1"},
+				{"AVTransportID", "-1"},
+				{"ProtocolInfo", ""},
+				{"PeerConnectionManager", ""},
+				{"PeerConnectionID", "-1"},
+				{"Direction", "Output"},
+				{"Status", "OK"},
+			}, nil
+		default:
+			return nil, &upnpError{Code: errInvalidAction, Description: "invalid action"}
+		}
+	})
+}
+
+func (s *Server) updateID() string {
+	return strconv.FormatUint(uint64(atomic.LoadUint32(&s.systemUpdateID)), 10)
+}
+
+func (s *Server) browse(c *gin.Context, args map[string]string) ([]soapArg, *upnpError) {
+	id := args["ObjectID"]
+	flag := args["BrowseFlag"]
+	if id == "" || (flag != "BrowseMetadata" && flag != "BrowseDirectChildren") {
+		return nil, &upnpError{Code: errInvalidArgs, Description: "invalid args"}
+	}
+	start, _ := strconv.Atoi(args["StartingIndex"])
+	count, _ := strconv.Atoi(args["RequestedCount"])
+	if start < 0 || count < 0 {
+		return nil, &upnpError{Code: errInvalidArgs, Description: "invalid args"}
+	}
+	user, err := s.user()
+	if err != nil || user.Disabled {
+		return nil, &upnpError{Code: errCannotProcess, Description: "the browsing user is not available"}
+	}
+	p, ok := objectPath(id)
+	if !ok {
+		return nil, &upnpError{Code: errNoSuchObject, Description: "no such object"}
+	}
+	reqPath, err := user.JoinPath(p)
+	if err != nil {
+		return nil, &upnpError{Code: errNoSuchObject, Description: "no such object"}
+	}
+	meta, err := op.GetNearestMeta(reqPath)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return nil, &upnpError{Code: errCannotProcess, Description: err.Error()}
+	}
+	if !common.CanAccess(user, meta, reqPath, "") {
+		return nil, &upnpError{Code: errNoSuchObject, Description: "no such object"}
+	}
+	ctx := context.WithValue(c.Request.Context(), conf.UserKey, user)
+	ctx = context.WithValue(ctx, conf.MetaKey, meta)
+	base := "http://" + c.Request.Host
+
+	var didl didlLite
+	var total int
+	if flag == "BrowseMetadata" {
+		if id == rootID {
+			didl.add(didlObject{ID: rootID, ParentID: "-1", Title: s.FriendlyName, Class: classFolder})
+		} else {
+			obj, err := fs.Get(ctx, reqPath, &fs.GetArgs{NoLog: true})
+			if err != nil {
+				return nil, &upnpError{Code: errNoSuchObject, Description: err.Error()}
+			}
+			o, ok := s.toObject(obj, p, reqPath, base)
+			if !ok {
+				return nil, &upnpError{Code: errNoSuchObject, Description: "no such object"}
+			}
+			didl.add(o)
+		}
+		total = 1
+	} else {
+		objs, err := fs.List(ctx, reqPath, &fs.ListArgs{NoLog: true})
+		if err != nil {
+			return nil, &upnpError{Code: errNoSuchObject, Description: err.Error()}
+		}
+		children := make([]didlObject, 0, len(objs))
+		for _, obj := range objs {
+			childPath := stdpath.Join(reqPath, obj.GetName())
+			if obj.IsDir() && !canAccessDir(user, childPath) {
+				continue
+			}
+			if o, ok := s.toObject(obj, stdpath.Join(p, obj.GetName()), childPath, base); ok {
+				children = append(children, o)
+			}
+		}
+		// containers first, the order of the storage is kept otherwise
+		sort.SliceStable(children, func(i, j int) bool {
+			return children[i].Class == classFolder && children[j].Class != classFolder
+		})
+		total = len(children)
+		if start > total {
+			start = total
+		}
+		end := total
+		if count > 0 && start+count < end {
+			end = start + count
+		}
+		for _, o := range children[start:end] {
+			didl.add(o)
+		}
+	}
+	didl.XMLNS = "urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/"
+	didl.DC = "http://purl.org/dc/elements/1.1/"
+	didl.UPnP = "urn:schemas-upnp-org:metadata-1-0/upnp/"
+	result, err := xml.Marshal(didl)
+	if err != nil {
+		return nil, &upnpError{Code: errActionFailed, Description: err.Error()}
+	}
+	return []soapArg{
+		{"Result", string(result)},
+		{"NumberReturned", strconv.Itoa(len(didl.Containers) + len(didl.Items))},
+		{"TotalMatches", strconv.Itoa(total)},
+		{"UpdateID", s.updateID()},
+	}, nil
+}
+
+func canAccessDir(user *model.User, reqPath string) bool {
+	meta, err := op.GetNearestMeta(reqPath)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return false
+	}
+	return common.CanAccess(user, meta, reqPath, "")
+}
+
+// toObject converts obj to a DIDL-Lite object, p is the path relative to the base path of the user,
+// ok is false if obj is not a folder or a media file
+func (s *Server) toObject(obj model.Obj, p, reqPath, base string) (didlObject, bool) {
+	o := didlObject{
+		ID:       p,
+		ParentID: parentID(p),
+		Title:    obj.GetName(),
+	}
+	if !obj.ModTime().IsZero() {
+		o.Date = obj.ModTime().Format("2006-01-02T15:04:05")
+	}
+	if obj.IsDir() {
+		o.Class = classFolder
+		return o, true
+	}
+	switch utils.GetFileType(obj.GetName()) {
+	case conf.VIDEO:
+		o.Class = classVideo
+	case conf.AUDIO:
+		o.Class = classAudio
+	case conf.IMAGE:
+		o.Class = classImage
+	default:
+		return o, false
+	}
+	if thumb, ok := model.GetThumb(obj); ok {
+		o.AlbumArtURI = thumb
+	}
+	o.Res = []didlRes{{
+		ProtocolInfo: fmt.Sprintf("http-get:*:%s:*", mimeType(obj.GetName())),
+		Size:         obj.GetSize(),
+		URL:          fmt.Sprintf("%s/d%s?sign=%s", base, utils.EncodePath(reqPath, true), sign.Sign(reqPath)),
+	}}
+	return o, true
+}
+
+// objectPath returns the path of the object relative to the base path of the user
+func objectPath(id string) (string, bool) {
+	if id == rootID {
+		return "/", true
+	}
+	if !strings.HasPrefix(id, "/") {
+		return "", false
+	}
+	return utils.FixAndCleanPath(id), true
+}
+
+func parentID(p string) string {
+	if p == "/" {
+		return "-1"
+	}
+	dir := stdpath.Dir(p)
+	if dir == "/" {
+		return rootID
+	}
+	return dir
+}
+
+func mimeType(name string) string {
+	if m, ok := mimeTypes[strings.ToLower(stdpath.Ext(name))]; ok {
+		return m
+	}
+	return utils.GetMimeType(name)
+}
+
+func sourceProtocolInfo() string {
+	seen := map[string]bool{}
+	var infos []string
+	for _, types := range []string{conf.VideoTypes, conf.AudioTypes, conf.ImageTypes} {
+		for _, ext := range conf.SlicesMap[types] {
+			m := mimeType("." + ext)
+			if m == "application/octet-stream" || seen[m] {
+				continue
+			}
+			seen[m] = true
+			infos = append(infos, fmt.Sprintf("http-get:*:%s:*", m))
+		}
+	}
+	sort.Strings(infos)
+	return strings.Join(infos, ",")
+}
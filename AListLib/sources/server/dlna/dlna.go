@@ -0,0 +1,156 @@
+// Package dlna implements a UPnP MediaServer with a ContentDirectory service
+// that browses the virtual file system, media is served by the /d routes.
+package dlna
+
+import (
+	"encoding/xml"
+	"net/http"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	descriptionPath = "/rootDesc.xml"
+	cdSCPDPath      = "/ContentDirectory.xml"
+	cmSCPDPath      = "/ConnectionManager.xml"
+	cdControlPath   = "/ctl/ContentDirectory"
+	cmControlPath   = "/ctl/ConnectionManager"
+	cdEventPath     = "/evt/ContentDirectory"
+	cmEventPath     = "/evt/ConnectionManager"
+)
+
+type Server struct {
+	UUID         string
+	FriendlyName string
+	// Username is the user who browses the file system, the guest is used if it is empty
+	Username string
+
+	systemUpdateID uint32
+}
+
+func NewServer(friendlyName, username string) *Server {
+	return &Server{
+		UUID:           DeviceUUID(),
+		FriendlyName:   friendlyName,
+		Username:       username,
+		systemUpdateID: uint32(time.Now().Unix()),
+	}
+}
+
+// DeviceUUID is stable for an installation so that clients recognize the server after restarts
+func DeviceUUID() string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte("openlist-dlna:"+conf.Conf.JwtSecret)).String()
+}
+
+// Register adds the description, control and event routes
+func (s *Server) Register(g gin.IRoutes) {
+	g.GET(descriptionPath, s.description)
+	g.GET(cdSCPDPath, func(c *gin.Context) {
+		c.Data(http.StatusOK, `text/xml; charset="utf-8"`, []byte(contentDirectorySCPD))
+	})
+	g.GET(cmSCPDPath, func(c *gin.Context) {
+		c.Data(http.StatusOK, `text/xml; charset="utf-8"`, []byte(connectionManagerSCPD))
+	})
+	g.POST(cdControlPath, s.contentDirectory)
+	g.POST(cmControlPath, s.connectionManager)
+	for _, p := range []string{cdEventPath, cmEventPath} {
+		g.Handle("SUBSCRIBE", p, subscribe)
+		g.Handle("UNSUBSCRIBE", p, func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+	}
+}
+
+func (s *Server) user() (*model.User, error) {
+	if s.Username == "" {
+		return op.GetGuest()
+	}
+	return op.GetUserByName(s.Username)
+}
+
+type specVersion struct {
+	Major int `xml:"major"`
+	Minor int `xml:"minor"`
+}
+
+type service struct {
+	ServiceType string `xml:"serviceType"`
+	ServiceID   string `xml:"serviceId"`
+	SCPDURL     string `xml:"SCPDURL"`
+	ControlURL  string `xml:"controlURL"`
+	EventSubURL string `xml:"eventSubURL"`
+}
+
+type device struct {
+	DeviceType       string    `xml:"deviceType"`
+	FriendlyName     string    `xml:"friendlyName"`
+	Manufacturer     string    `xml:"manufacturer"`
+	ManufacturerURL  string    `xml:"manufacturerURL"`
+	ModelDescription string    `xml:"modelDescription"`
+	ModelName        string    `xml:"modelName"`
+	ModelNumber      string    `xml:"modelNumber"`
+	UDN              string    `xml:"UDN"`
+	DLNADoc          string    `xml:"dlna:X_DLNADOC"`
+	ServiceList      []service `xml:"serviceList>service"`
+}
+
+type deviceDescription struct {
+	XMLName     xml.Name    `xml:"urn:schemas-upnp-org:device-1-0 root"`
+	DLNA        string      `xml:"xmlns:dlna,attr"`
+	SpecVersion specVersion `xml:"specVersion"`
+	Device      device      `xml:"device"`
+}
+
+func (s *Server) description(c *gin.Context) {
+	desc := deviceDescription{
+		DLNA:        "urn:schemas-dlna-org:device-1-0",
+		SpecVersion: specVersion{Major: 1, Minor: 0},
+		Device: device{
+			DeviceType:       deviceType,
+			FriendlyName:     s.FriendlyName,
+			Manufacturer:     "OpenList",
+			ManufacturerURL:  "https://github.com/OpenListTeam/OpenList",
+			ModelDescription: "OpenList DLNA media server",
+			ModelName:        "OpenList",
+			ModelNumber:      conf.Version,
+			UDN:              "uuid:" + s.UUID,
+			DLNADoc:          "DMS-1.50",
+			ServiceList: []service{{
+				ServiceType: cdServiceType,
+				ServiceID:   "urn:upnp-org:serviceId:ContentDirectory",
+				SCPDURL:     cdSCPDPath,
+				ControlURL:  cdControlPath,
+				EventSubURL: cdEventPath,
+			}, {
+				ServiceType: cmServiceType,
+				ServiceID:   "urn:upnp-org:serviceId:ConnectionManager",
+				SCPDURL:     cmSCPDPath,
+				ControlURL:  cmControlPath,
+				EventSubURL: cmEventPath,
+			}},
+		},
+	}
+	data, err := xml.Marshal(desc)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(http.StatusOK, `text/xml; charset="utf-8"`, append([]byte(xml.Header), data...))
+}
+
+// subscribe accepts event subscriptions, no event is sent since the
+// content is not watched, clients fall back to browsing again
+func subscribe(c *gin.Context) {
+	sid := c.GetHeader("SID")
+	if sid == "" {
+		sid = "uuid:" + uuid.NewString()
+	}
+	c.Header("SID", sid)
+	c.Header("TIMEOUT", "Second-1800")
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,231 @@
+package dlna
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestSSDPSearch(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &SSDP{
+		UUID: "test-uuid",
+		Location: func(ip net.IP) string {
+			return "http://" + ip.String() + ":5223/rootDesc.xml"
+		},
+	}
+	go func() { _ = s.Serve(conn) }()
+	defer s.Close()
+
+	client, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	req := "M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 1\r\nST: " + deviceType + "\r\n\r\n"
+	if _, err := client.WriteTo([]byte(req), conn.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	_ = client.SetReadDeadline(time.Now().Add(3 * time.Second))
+	buf := make([]byte, 2048)
+	n, _, err := client.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no answer to M-SEARCH: %v", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(string(buf[:n]))), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("ST") != deviceType || resp.Header.Get("USN") != "uuid:test-uuid::"+deviceType ||
+		resp.Header.Get("LOCATION") != "http://127.0.0.1:5223/rootDesc.xml" {
+		t.Fatalf("unexpected answer: %v", resp.Header)
+	}
+}
+
+// upnpClient is a minimal control point
+type upnpClient struct {
+	t          *testing.T
+	base       string
+	controlURL string
+}
+
+func (c *upnpClient) discover() {
+	resp, err := http.Get(c.base + descriptionPath)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var desc deviceDescription
+	if err := xml.NewDecoder(resp.Body).Decode(&desc); err != nil {
+		c.t.Fatalf("invalid device description: %v", err)
+	}
+	if desc.Device.DeviceType != deviceType {
+		c.t.Fatalf("unexpected device type: %s", desc.Device.DeviceType)
+	}
+	for _, s := range desc.Device.ServiceList {
+		if s.ServiceType == cdServiceType {
+			c.controlURL = s.ControlURL
+		}
+	}
+	if c.controlURL == "" {
+		c.t.Fatal("ContentDirectory service is missing")
+	}
+}
+
+// didlObject and didlLite are written with prefixed names, the decoder matches local names only
+type parsedObject struct {
+	ID       string    `xml:"id,attr"`
+	ParentID string    `xml:"parentID,attr"`
+	Title    string    `xml:"title"`
+	Class    string    `xml:"class"`
+	Res      []didlRes `xml:"res"`
+}
+
+type parsedDIDL struct {
+	Containers []parsedObject `xml:"container"`
+	Items      []parsedObject `xml:"item"`
+}
+
+type browseResult struct {
+	Result         string `xml:"Body>BrowseResponse>Result"`
+	NumberReturned int    `xml:"Body>BrowseResponse>NumberReturned"`
+	TotalMatches   int    `xml:"Body>BrowseResponse>TotalMatches"`
+	ErrorCode      int    `xml:"Body>Fault>detail>UPnPError>errorCode"`
+}
+
+func (c *upnpClient) browse(id, flag string, start, count int) (browseResult, parsedDIDL) {
+	body := fmt.Sprintf(`<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>`+
+		`<u:Browse xmlns:u="%s"><ObjectID>%s</ObjectID><BrowseFlag>%s</BrowseFlag><Filter>*</Filter>`+
+		`<StartingIndex>%d</StartingIndex><RequestedCount>%d</RequestedCount><SortCriteria></SortCriteria></u:Browse>`+
+		`</s:Body></s:Envelope>`, cdServiceType, id, flag, start, count)
+	req, _ := http.NewRequest(http.MethodPost, c.base+c.controlURL, strings.NewReader(body))
+	req.Header.Set("SOAPACTION", `"`+cdServiceType+`#Browse"`)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var res browseResult
+	if err := xml.NewDecoder(resp.Body).Decode(&res); err != nil {
+		c.t.Fatalf("invalid browse response: %v", err)
+	}
+	var didl parsedDIDL
+	if res.Result != "" {
+		if err := xml.Unmarshal([]byte(res.Result), &didl); err != nil {
+			c.t.Fatalf("invalid DIDL-Lite: %v", err)
+		}
+	}
+	return res, didl
+}
+
+func TestBrowse(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"movie.mp4", "song.mp3", "notes.txt", "sub/clip.mp4", "secret/a.mp4"} {
+		p := filepath.Join(dir, name)
+		_ = os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conf.SlicesMap[conf.VideoTypes] = []string{"mp4"}
+	conf.SlicesMap[conf.AudioTypes] = []string{"mp3"}
+	if err := db.CreateUser(&model.User{Username: "guest", Role: model.GUEST, BasePath: "/"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/media",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, dir),
+	}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	if err := op.CreateMeta(&model.Meta{Path: "/media/secret", Password: "pass"}); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.ContextWithFallback = true
+	NewServer("Test", "").Register(e)
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+	client := &upnpClient{t: t, base: ts.URL}
+	client.discover()
+
+	res, didl := client.browse(rootID, "BrowseDirectChildren", 0, 0)
+	if res.TotalMatches != 1 || len(didl.Containers) != 1 || didl.Containers[0].ID != "/media" || didl.Containers[0].ParentID != rootID {
+		t.Fatalf("unexpected root: %+v %+v", res, didl)
+	}
+
+	res, didl = client.browse("/media", "BrowseDirectChildren", 0, 0)
+	if res.TotalMatches != 3 || len(didl.Containers) != 1 || len(didl.Items) != 2 {
+		t.Fatalf("password protected folders and non media files should be skipped: %+v", didl)
+	}
+	if didl.Containers[0].Title != "sub" {
+		t.Fatalf("unexpected container: %+v", didl.Containers[0])
+	}
+	for _, item := range didl.Items {
+		switch item.Title {
+		case "movie.mp4":
+			if item.Class != classVideo || len(item.Res) != 1 || !strings.HasPrefix(item.Res[0].ProtocolInfo, "http-get:*:video/mp4") {
+				t.Fatalf("unexpected video item: %+v", item)
+			}
+			if !strings.HasPrefix(item.Res[0].URL, ts.URL+"/d/media/movie.mp4?sign=") || item.Res[0].Size != 4 {
+				t.Fatalf("unexpected resource: %+v", item.Res[0])
+			}
+		case "song.mp3":
+			if item.Class != classAudio {
+				t.Fatalf("unexpected audio item: %+v", item)
+			}
+		default:
+			t.Fatalf("unexpected item: %+v", item)
+		}
+	}
+
+	res, didl = client.browse("/media", "BrowseDirectChildren", 1, 1)
+	if res.TotalMatches != 3 || res.NumberReturned != 1 || len(didl.Items) != 1 {
+		t.Fatalf("unexpected page: %+v", res)
+	}
+
+	res, didl = client.browse("/media/sub/clip.mp4", "BrowseMetadata", 0, 0)
+	if len(didl.Items) != 1 || didl.Items[0].ParentID != "/media/sub" {
+		t.Fatalf("unexpected metadata: %+v", didl)
+	}
+
+	if res, _ = client.browse("/media/secret", "BrowseDirectChildren", 0, 0); res.ErrorCode != errNoSuchObject {
+		t.Fatalf("password protected folder should not be browsed: %+v", res)
+	}
+	if res, _ = client.browse("/nothing", "BrowseMetadata", 0, 0); res.ErrorCode != errNoSuchObject {
+		t.Fatalf("expected no such object: %+v", res)
+	}
+}
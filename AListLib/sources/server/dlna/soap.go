@@ -0,0 +1,129 @@
+package dlna
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UPnP error codes
+const (
+	errInvalidAction    = 401
+	errInvalidArgs      = 402
+	errActionFailed     = 501
+	errNoSuchObject     = 701
+	errCannotProcess    = 720
+	maxSOAPRequestBytes = 64 * 1024
+)
+
+type upnpError struct {
+	Code        int
+	Description string
+}
+
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("UPnP error %d: %s", e.Code, e.Description)
+}
+
+// soapArg is an ordered output argument of an action
+type soapArg struct {
+	Name  string
+	Value string
+}
+
+// parseSOAPAction splits the SOAPACTION header into the service type and the action name
+func parseSOAPAction(header string) (string, string) {
+	header = strings.Trim(header, `"`)
+	i := strings.LastIndex(header, "#")
+	if i < 0 {
+		return "", ""
+	}
+	return header[:i], header[i+1:]
+}
+
+// readSOAPArgs returns the arguments of the action in the body of the envelope
+func readSOAPArgs(r io.Reader) (map[string]string, error) {
+	d := xml.NewDecoder(io.LimitReader(r, maxSOAPRequestBytes))
+	args := map[string]string{}
+	// depth 0: Envelope, 1: Body, 2: action, 3: arguments
+	depth := 0
+	var name string
+	var value strings.Builder
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return args, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 4 {
+				name = t.Name.Local
+				value.Reset()
+			}
+		case xml.CharData:
+			if depth == 4 {
+				value.Write(t)
+			}
+		case xml.EndElement:
+			if depth == 4 {
+				args[name] = value.String()
+			}
+			depth--
+		}
+	}
+}
+
+func writeSOAP(c *gin.Context, serviceType, action string, args []soapArg) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&buf, `<u:%sResponse xmlns:u="%s">`, action, serviceType)
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "<%s>", arg.Name)
+		_ = xml.EscapeText(&buf, []byte(arg.Value))
+		fmt.Fprintf(&buf, "</%s>", arg.Name)
+	}
+	fmt.Fprintf(&buf, `</u:%sResponse></s:Body></s:Envelope>`, action)
+	c.Header("EXT", "")
+	c.Data(http.StatusOK, `text/xml; charset="utf-8"`, buf.Bytes())
+}
+
+func writeSOAPError(c *gin.Context, e *upnpError) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body><s:Fault>`)
+	buf.WriteString(`<faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail>`)
+	fmt.Fprintf(&buf, `<UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode><errorDescription>`, e.Code)
+	_ = xml.EscapeText(&buf, []byte(e.Description))
+	buf.WriteString(`</errorDescription></UPnPError></detail></s:Fault></s:Body></s:Envelope>`)
+	c.Data(http.StatusInternalServerError, `text/xml; charset="utf-8"`, buf.Bytes())
+}
+
+// handleSOAP reads the action of the request and writes the result of f
+func handleSOAP(c *gin.Context, serviceType string, f func(action string, args map[string]string) ([]soapArg, *upnpError)) {
+	st, action := parseSOAPAction(c.GetHeader("SOAPACTION"))
+	if st != serviceType {
+		writeSOAPError(c, &upnpError{Code: errInvalidAction, Description: "invalid action"})
+		return
+	}
+	args, err := readSOAPArgs(c.Request.Body)
+	if err != nil {
+		writeSOAPError(c, &upnpError{Code: errInvalidArgs, Description: err.Error()})
+		return
+	}
+	res, e := f(action, args)
+	if e != nil {
+		writeSOAPError(c, e)
+		return
+	}
+	writeSOAP(c, serviceType, action, res)
+}
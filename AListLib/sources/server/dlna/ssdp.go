@@ -0,0 +1,236 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	ssdpAddr   = "239.255.255.250:1900"
+	ssdpMaxAge = 1800
+
+	rootDeviceType = "upnp:rootdevice"
+	deviceType     = "urn:schemas-upnp-org:device:MediaServer:1"
+	cdServiceType  = "urn:schemas-upnp-org:service:ContentDirectory:1"
+	cmServiceType  = "urn:schemas-upnp-org:service:ConnectionManager:1"
+)
+
+var ssdpGroup = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+
+// SSDP answers M-SEARCH requests and announces the media server
+type SSDP struct {
+	UUID string
+	// Location returns the url of the device description reachable from ip
+	Location func(ip net.IP) string
+	// Interval of the alive announcements, no announcement is sent if it is 0
+	Interval time.Duration
+
+	mu     sync.Mutex
+	conn   net.PacketConn
+	done   chan struct{}
+	closed bool
+}
+
+func (s *SSDP) server() string {
+	return fmt.Sprintf("%s/1.0 UPnP/1.0 OpenList/%s", runtime.GOOS, conf.Version)
+}
+
+// targets returns the notification types and unique service names of the device
+func (s *SSDP) targets() [][2]string {
+	udn := "uuid:" + s.UUID
+	return [][2]string{
+		{rootDeviceType, udn + "::" + rootDeviceType},
+		{udn, udn},
+		{deviceType, udn + "::" + deviceType},
+		{cdServiceType, udn + "::" + cdServiceType},
+		{cmServiceType, udn + "::" + cmServiceType},
+	}
+}
+
+// ListenAndServe joins the SSDP multicast group on all interfaces and serves until Close
+func (s *SSDP) ListenAndServe() error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, ssdpGroup)
+	if err != nil {
+		return err
+	}
+	p := ipv4.NewPacketConn(conn)
+	for _, iface := range multicastInterfaces() {
+		_ = p.JoinGroup(&iface, ssdpGroup)
+	}
+	_ = p.SetMulticastTTL(2)
+	return s.Serve(conn)
+}
+
+// Serve answers the M-SEARCH requests received by conn and sends the announcements
+func (s *SSDP) Serve(conn net.PacketConn) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = conn.Close()
+		return net.ErrClosed
+	}
+	s.conn = conn
+	s.done = make(chan struct{})
+	s.mu.Unlock()
+	if s.Interval > 0 {
+		go s.advertise(conn)
+	}
+	buf := make([]byte, 2048)
+	for {
+		n, remote, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		s.handle(conn, buf[:n], remote)
+	}
+}
+
+func (s *SSDP) handle(conn net.PacketConn, data []byte, remote net.Addr) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil || req.Method != "M-SEARCH" || req.Header.Get("MAN") != `"ssdp:discover"` {
+		return
+	}
+	udpAddr, ok := remote.(*net.UDPAddr)
+	if !ok {
+		return
+	}
+	st := req.Header.Get("ST")
+	location := s.Location(localIPFor(udpAddr))
+	for _, t := range s.targets() {
+		if st != "ssdp:all" && st != t[0] {
+			continue
+		}
+		msg := fmt.Sprintf("HTTP/1.1 200 OK\r\n"+
+			"CACHE-CONTROL: max-age=%d\r\n"+
+			"DATE: %s\r\n"+
+			"EXT:\r\n"+
+			"LOCATION: %s\r\n"+
+			"SERVER: %s\r\n"+
+			"ST: %s\r\n"+
+			"USN: %s\r\n\r\n",
+			ssdpMaxAge, time.Now().UTC().Format(http.TimeFormat), location, s.server(), t[0], t[1])
+		if _, err := conn.WriteTo([]byte(msg), remote); err != nil {
+			utils.Log.Debugf("[dlna] failed to answer M-SEARCH of %s: %v", remote, err)
+			return
+		}
+	}
+}
+
+func (s *SSDP) notify(conn net.PacketConn, nts string) {
+	p := ipv4.NewPacketConn(conn)
+	for _, iface := range multicastInterfaces() {
+		ip := interfaceIPv4(iface)
+		if ip == nil {
+			continue
+		}
+		if err := p.SetMulticastInterface(&iface); err != nil {
+			continue
+		}
+		for _, t := range s.targets() {
+			msg := "NOTIFY * HTTP/1.1\r\n" +
+				"HOST: " + ssdpAddr + "\r\n" +
+				"NT: " + t[0] + "\r\n" +
+				"NTS: " + nts + "\r\n" +
+				"USN: " + t[1] + "\r\n"
+			if nts == "ssdp:alive" {
+				msg += fmt.Sprintf("CACHE-CONTROL: max-age=%d\r\n", ssdpMaxAge) +
+					"LOCATION: " + s.Location(ip) + "\r\n" +
+					"SERVER: " + s.server() + "\r\n"
+			}
+			msg += "\r\n"
+			if _, err := p.WriteTo([]byte(msg), nil, ssdpGroup); err != nil {
+				utils.Log.Debugf("[dlna] failed to send %s on %s: %v", nts, iface.Name, err)
+				break
+			}
+		}
+	}
+}
+
+func (s *SSDP) advertise(conn net.PacketConn) {
+	s.notify(conn, "ssdp:alive")
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.notify(conn, "ssdp:alive")
+		}
+	}
+}
+
+// Close says goodbye and stops serving
+func (s *SSDP) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+	if s.Interval > 0 {
+		s.notify(s.conn, "ssdp:byebye")
+	}
+	close(s.done)
+	return s.conn.Close()
+}
+
+func multicastInterfaces() []net.Interface {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var res []net.Interface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		if interfaceIPv4(iface) != nil {
+			res = append(res, iface)
+		}
+	}
+	return res
+}
+
+func interfaceIPv4(iface net.Interface) net.IP {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			if ip := ipNet.IP.To4(); ip != nil {
+				return ip
+			}
+		}
+	}
+	return nil
+}
+
+// localIPFor returns the local address that routes to remote
+func localIPFor(remote *net.UDPAddr) net.IP {
+	conn, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: remote.IP, Port: remote.Port})
+	if err != nil {
+		return net.IPv4(127, 0, 0, 1)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP
+}
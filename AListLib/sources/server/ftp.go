@@ -15,6 +15,7 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/drivers/base"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
@@ -101,6 +102,7 @@ func (d *FtpMainDriver) ClientConnected(cc ftpserver.ClientContext) (string, err
 	}
 	defer d.shutdownLock.RUnlock()
 	d.clients[cc.ID()] = cc
+	metrics.SessionOpened("ftp")
 	return "OpenList FTP Endpoint", nil
 }
 
@@ -110,6 +112,7 @@ func (d *FtpMainDriver) ClientDisconnected(cc ftpserver.ClientContext) {
 		utils.Log.Errorf("failed to close client: %v", err)
 	}
 	delete(d.clients, cc.ID())
+	metrics.SessionClosed("ftp")
 }
 
 func (d *FtpMainDriver) AuthUser(cc ftpserver.ClientContext, user, pass string) (ftpserver.ClientDriver, error) {
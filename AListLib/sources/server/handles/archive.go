@@ -299,7 +299,7 @@ func FsArchiveDecompress(c *gin.Context) {
 		}
 	}
 	common.SuccessResp(c, gin.H{
-		"task": getTaskInfos(tasks),
+		"task": GetTaskInfos(tasks),
 	})
 }
 
@@ -0,0 +1,78 @@
+package handles
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/backup"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type ExportBackupReq struct {
+	Password string `json:"password"`
+}
+
+func ExportBackup(c *gin.Context) {
+	var req ExportBackupReq
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBind(&req); err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+	}
+	data, err := backup.Export()
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	var buf bytes.Buffer
+	if err := backup.Encode(&buf, data, req.Password); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	fileName := fmt.Sprintf("openlist-backup-%s.olbk", data.Created.Format("20060102-150405"))
+	c.DataFromReader(200, int64(buf.Len()), "application/octet-stream", &buf, map[string]string{
+		"Content-Disposition": utils.GenerateContentDisposition(fileName),
+		"Cache-Control":       "no-store",
+	})
+}
+
+// ImportBackup accepts the archive as the multipart field "file",
+// the form fields "mode", "dry_run" and "password" are optional
+func ImportBackup(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		common.ErrorResp(c, errors.WithMessage(err, "failed get backup file"), 400)
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	defer f.Close()
+	data, err := backup.Decode(f, c.PostForm("password"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.PostForm("dry_run"))
+	start := time.Now()
+	report, err := backup.Import(c.Request.Context(), data, backup.ImportOptions{
+		Mode:         backup.Mode(c.PostForm("mode")),
+		DryRun:       dryRun,
+		LoadStorages: true,
+	})
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	utils.Log.Infof("backup created at %s imported in %s, mode: %s, dry run: %t",
+		data.Created.Format(time.RFC3339), time.Since(start), report.Mode, report.DryRun)
+	common.SuccessResp(c, report)
+}
@@ -0,0 +1,58 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/block_cache"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type BlockCacheStorageResp struct {
+	block_cache.StorageStats
+	MountPath string `json:"mount_path"`
+}
+
+type BlockCacheStatsResp struct {
+	block_cache.Stats
+	Storages []BlockCacheStorageResp `json:"storages"`
+}
+
+func GetBlockCacheStats(c *gin.Context) {
+	if block_cache.Instance == nil {
+		common.ErrorStrResp(c, "block cache is disabled", 400)
+		return
+	}
+	stats := block_cache.Instance.Stats()
+	mountPaths := make(map[uint]string)
+	for _, storage := range op.GetAllStorages() {
+		mountPaths[storage.GetStorage().ID] = storage.GetStorage().MountPath
+	}
+	resp := BlockCacheStatsResp{Stats: stats, Storages: make([]BlockCacheStorageResp, 0, len(stats.Storages))}
+	for _, s := range stats.Storages {
+		resp.Storages = append(resp.Storages, BlockCacheStorageResp{StorageStats: s, MountPath: mountPaths[s.StorageID]})
+	}
+	common.SuccessResp(c, resp)
+}
+
+// ClearBlockCache drops the cached blocks of the storage of the id, or all if the id is absent
+func ClearBlockCache(c *gin.Context) {
+	if block_cache.Instance == nil {
+		common.ErrorStrResp(c, "block cache is disabled", 400)
+		return
+	}
+	idStr := c.Query("id")
+	if idStr == "" {
+		block_cache.Instance.Clear()
+		common.SuccessResp(c)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	block_cache.Instance.DropStorage(uint(id))
+	common.SuccessResp(c)
+}
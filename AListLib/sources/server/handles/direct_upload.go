@@ -40,7 +40,7 @@ func FsGetDirectUploadInfo(c *gin.Context) {
 		common.ErrorResp(c, err, 403)
 		return
 	}
-	if err := checkRelativePath(req.FileName); err != nil {
+	if err := common.CheckRelativePath(req.FileName); err != nil {
 		common.ErrorResp(c, err, 403)
 		return
 	}
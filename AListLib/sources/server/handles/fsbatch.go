@@ -188,12 +188,12 @@ func FsBatchRename(c *gin.Context) {
 		if renameObject.SrcName == "" || renameObject.NewName == "" {
 			continue
 		}
-		err = checkRelativePath(renameObject.SrcName)
+		err = common.CheckRelativePath(renameObject.SrcName)
 		if err != nil {
 			common.ErrorResp(c, err, 403)
 			return
 		}
-		err = checkRelativePath(renameObject.NewName)
+		err = common.CheckRelativePath(renameObject.NewName)
 		if err != nil {
 			common.ErrorResp(c, err, 403)
 			return
@@ -258,7 +258,7 @@ func FsRegexRename(c *gin.Context) {
 	for _, file := range files {
 		if srcRegexp.MatchString(file.GetName()) {
 			newFileName := srcRegexp.ReplaceAllString(file.GetName(), req.NewNameRegex)
-			err := checkRelativePath(newFileName)
+			err := common.CheckRelativePath(newFileName)
 			if err != nil {
 				common.ErrorResp(c, err, 403)
 				return
@@ -0,0 +1,103 @@
+package handles
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/dedupe"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type FsDuplicatesScanReq struct {
+	Paths   []string `json:"paths" binding:"required"`
+	MinSize int64    `json:"min_size"`
+}
+
+// FsDuplicatesScan adds a task finding the duplicated files in the paths
+func FsDuplicatesScan(c *gin.Context) {
+	var req FsDuplicatesScanReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	paths := make([]string, 0, len(req.Paths))
+	for _, p := range req.Paths {
+		reqPath, err := user.JoinPath(p)
+		if err != nil {
+			common.ErrorResp(c, err, 403)
+			return
+		}
+		paths = append(paths, reqPath)
+	}
+	t, err := dedupe.Add(c.Request.Context(), paths, req.MinSize)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"task": GetTaskInfo(t),
+	})
+}
+
+func getDedupeTask(c *gin.Context, tid string) (*dedupe.ScanTask, bool) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	t, ok := dedupe.TaskManager.GetByID(tid)
+	if !ok || (!user.IsAdmin() && t.GetCreator().ID != user.ID) {
+		common.ErrorStrResp(c, "task not found", 404)
+		return nil, false
+	}
+	return t, true
+}
+
+// FsDuplicates returns the report of a finished scan
+func FsDuplicates(c *gin.Context) {
+	t, ok := getDedupeTask(c, c.Query("tid"))
+	if !ok {
+		return
+	}
+	report, ok := t.Report()
+	if !ok {
+		common.ErrorStrResp(c, "the scan is not finished", 400)
+		return
+	}
+	common.SuccessResp(c, report)
+}
+
+type FsDuplicatesResolveReq struct {
+	Tid string `json:"tid" binding:"required"`
+	// Keep is the file kept in its group
+	Keep string `json:"keep" binding:"required"`
+	// Others are the duplicates of Keep removed or moved to DstDir
+	Others []string `json:"others" binding:"required"`
+	Action string   `json:"action" binding:"required"`
+	DstDir string   `json:"dst_dir"`
+}
+
+// FsDuplicatesResolve keeps one file of a group and removes or moves the others
+func FsDuplicatesResolve(c *gin.Context) {
+	var req FsDuplicatesResolveReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	t, ok := getDedupeTask(c, req.Tid)
+	if !ok {
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	dstDir := ""
+	if req.Action == dedupe.ActionMove {
+		var err error
+		if dstDir, err = user.JoinPath(req.DstDir); err != nil {
+			common.ErrorResp(c, err, 403)
+			return
+		}
+	}
+	results, err := t.Resolve(c.Request.Context(), req.Keep, req.Others, req.Action, dstDir)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, results)
+}
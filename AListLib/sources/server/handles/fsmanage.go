@@ -2,6 +2,7 @@ package handles
 
 import (
 	"fmt"
+	"net/http"
 	stdpath "path"
 	"strings"
 
@@ -136,6 +137,12 @@ func FsMove(c *gin.Context) {
 			}
 		}
 	}
+	if !checkWritePreconditions(c, req.Names...) {
+		return
+	}
+	if req.Overwrite && !checkOverwritePreconditions(c, dstDir, req.Names...) {
+		return
+	}
 
 	// Create all tasks immediately without any synchronous validation
 	// All validation will be done asynchronously in the background
@@ -158,7 +165,7 @@ func FsMove(c *gin.Context) {
 	if len(addedTasks) > 0 {
 		common.SuccessResp(c, gin.H{
 			"message": fmt.Sprintf("Successfully created %d move task(s)", len(addedTasks)),
-			"tasks":   getTaskInfos(addedTasks),
+			"tasks":   GetTaskInfos(addedTasks),
 		})
 	} else {
 		common.SuccessResp(c, gin.H{
@@ -240,6 +247,12 @@ func FsCopy(c *gin.Context) {
 			}
 		}
 	}
+	if !checkWritePreconditions(c, req.Names...) {
+		return
+	}
+	if req.Overwrite && !checkOverwritePreconditions(c, dstDir, req.Names...) {
+		return
+	}
 
 	// Create all tasks immediately without any synchronous validation
 	// All validation will be done asynchronously in the background
@@ -267,7 +280,7 @@ func FsCopy(c *gin.Context) {
 	if len(addedTasks) > 0 {
 		common.SuccessResp(c, gin.H{
 			"message": fmt.Sprintf("Successfully created %d copy task(s)", len(addedTasks)),
-			"tasks":   getTaskInfos(addedTasks),
+			"tasks":   GetTaskInfos(addedTasks),
 		})
 	} else {
 		common.SuccessResp(c, gin.H{
@@ -295,7 +308,7 @@ func FsRename(c *gin.Context) {
 	}
 	reqPath, err := user.JoinPath(req.Path)
 	if err == nil {
-		err = checkRelativePath(req.Name)
+		err = common.CheckRelativePath(req.Name)
 	}
 	if err != nil {
 		common.ErrorResp(c, err, 403)
@@ -320,6 +333,9 @@ func FsRename(c *gin.Context) {
 			}
 		}
 	}
+	if !checkWritePreconditions(c, reqPath) {
+		return
+	}
 	if err := fs.Rename(c.Request.Context(), reqPath, req.Name); err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -327,11 +343,61 @@ func FsRename(c *gin.Context) {
 	common.SuccessResp(c)
 }
 
-func checkRelativePath(path string) error {
-	if strings.ContainsAny(path, "/\\") || path == "" || path == "." || path == ".." {
-		return errs.RelativePath
+// checkWritePreconditions responds 412 if the path does not satisfy the
+// If-Match or If-Unmodified-Since headers, so a stale client can not clobber
+// the changes of others. The headers hold the ETag of a single file, so they
+// are refused for the requests of several paths
+func checkWritePreconditions(c *gin.Context, paths ...string) bool {
+	return checkPreconditions(c, c.Request.Header, paths...)
+}
+
+// checkOverwritePreconditions checks the files in dstDir overwritten by the
+// moved or copied srcPaths against the Dst-If-Match and Dst-If-Unmodified-Since
+// headers, the If-Match header is checked against the source
+func checkOverwritePreconditions(c *gin.Context, dstDir string, srcPaths ...string) bool {
+	h := http.Header{}
+	if v := c.GetHeader("Dst-If-Match"); v != "" {
+		h.Set("If-Match", v)
+	}
+	if v := c.GetHeader("Dst-If-Unmodified-Since"); v != "" {
+		h.Set("If-Unmodified-Since", v)
+	}
+	dstPaths := make([]string, len(srcPaths))
+	for i, p := range srcPaths {
+		if p != "" {
+			dstPaths[i] = stdpath.Join(dstDir, stdpath.Base(p))
+		}
+	}
+	return checkPreconditions(c, h, dstPaths...)
+}
+
+func checkPreconditions(c *gin.Context, h http.Header, paths ...string) bool {
+	if h.Get("If-Match") == "" && h.Get("If-Unmodified-Since") == "" {
+		return true
 	}
-	return nil
+	var path string
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if path != "" {
+			common.ErrorStrResp(c, "the preconditions are only supported for a single file", 400)
+			return false
+		}
+		path = p
+	}
+	if path == "" {
+		return true
+	}
+	var obj model.Obj
+	if res, err := fs.Get(c.Request.Context(), path, &fs.GetArgs{NoLog: true}); err == nil {
+		obj = res
+	}
+	if !common.CheckWritePreconditions(h, obj) {
+		common.ErrorStrResp(c, fmt.Sprintf("precondition failed: [%s] has been changed", stdpath.Base(path)), 412)
+		return false
+	}
+	return true
 }
 
 type RemoveReq struct {
@@ -380,6 +446,9 @@ func FsRemove(c *gin.Context) {
 		}
 		req.Names[i] = fullPath
 	}
+	if !checkWritePreconditions(c, req.Names...) {
+		return
+	}
 	for _, path := range req.Names {
 		if path == "" {
 			continue
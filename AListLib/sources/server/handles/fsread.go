@@ -43,6 +43,7 @@ type ObjResp struct {
 	Type         int                        `json:"type"`
 	HashInfoStr  string                     `json:"hashinfo"`
 	HashInfo     map[*utils.HashType]string `json:"hash_info"`
+	Etag         string                     `json:"etag"`
 	MountDetails *model.StorageDetails      `json:"mount_details,omitempty"`
 }
 
@@ -239,6 +240,7 @@ func toObjsResp(objs []model.Obj, parent string, encrypt bool) []ObjResp {
 			HashInfoStr:  obj.GetHash().String(),
 			HashInfo:     obj.GetHash().Export(),
 			Sign:         common.Sign(obj, parent, encrypt),
+			Etag:         common.GetWriteEtag(obj),
 			Thumb:        thumb,
 			Type:         utils.GetObjType(obj.GetName(), obj.IsDir()),
 			MountDetails: mountDetails,
@@ -366,6 +368,7 @@ func FsGet(c *gin.Context, req *FsGetReq, user *model.User) {
 			HashInfoStr:  obj.GetHash().String(),
 			HashInfo:     obj.GetHash().Export(),
 			Sign:         common.Sign(obj, parentPath, isEncrypt(meta, reqPath)),
+			Etag:         common.GetWriteEtag(obj),
 			Type:         utils.GetFileType(obj.GetName()),
 			Thumb:        thumb,
 			MountDetails: mountDetails,
@@ -65,6 +65,9 @@ func FsStream(c *gin.Context) {
 			return
 		}
 	}
+	if !checkWritePreconditions(c, path) {
+		return
+	}
 	dir, name := stdpath.Split(path)
 	// Check if system file should be ignored
 	if shouldIgnoreSystemFile(name) {
@@ -123,7 +126,7 @@ func FsStream(c *gin.Context) {
 		return
 	}
 	common.SuccessResp(c, gin.H{
-		"task": getTaskInfo(t),
+		"task": GetTaskInfo(t),
 	})
 }
 
@@ -154,6 +157,9 @@ func FsForm(c *gin.Context) {
 			return
 		}
 	}
+	if !checkWritePreconditions(c, path) {
+		return
+	}
 	storage, err := fs.GetStorage(path, &fs.GetStoragesArgs{})
 	if err != nil {
 		common.ErrorResp(c, err, 400)
@@ -223,6 +229,6 @@ func FsForm(c *gin.Context) {
 		return
 	}
 	common.SuccessResp(c, gin.H{
-		"task": getTaskInfo(t),
+		"task": GetTaskInfo(t),
 	})
 }
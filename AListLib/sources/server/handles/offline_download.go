@@ -116,6 +116,43 @@ func SetTransmission(c *gin.Context) {
 	common.SuccessResp(c, "ok")
 }
 
+type SetBitTorrentReq struct {
+	Enabled    string `json:"enabled" form:"enabled"`
+	ListenPort string `json:"listen_port" form:"listen_port"`
+	EnableDHT  string `json:"enable_dht" form:"enable_dht"`
+	Seedtime   string `json:"seedtime" form:"seedtime"`
+	SeedRatio  string `json:"seed_ratio" form:"seed_ratio"`
+}
+
+func SetBitTorrent(c *gin.Context) {
+	var req SetBitTorrentReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	items := []model.SettingItem{
+		{Key: conf.BitTorrentEnabled, Value: req.Enabled, Type: conf.TypeBool, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.BitTorrentListenPort, Value: req.ListenPort, Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.BitTorrentEnableDHT, Value: req.EnableDHT, Type: conf.TypeBool, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.BitTorrentSeedtime, Value: req.Seedtime, Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.BitTorrentSeedRatio, Value: req.SeedRatio, Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+	}
+	if err := op.SaveSettingItems(items); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	_tool, err := tool.Tools.Get("BitTorrent")
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	if _, err := _tool.Init(); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, "ok")
+}
+
 type Set115Req struct {
 	TempDir string `json:"temp_dir" form:"temp_dir"`
 }
@@ -482,6 +519,10 @@ type AddOfflineDownloadReq struct {
 	Path         string   `json:"path"`
 	Tool         string   `json:"tool"`
 	DeletePolicy string   `json:"delete_policy"`
+	// SelectedFiles are indexes of files in the torrent to download, empty means all
+	SelectedFiles []int `json:"selected_files"`
+	// Checksum is the expected checksum of the downloaded file, only allowed with a single url
+	Checksum string `json:"checksum"`
 }
 
 func AddOfflineDownload(c *gin.Context) {
@@ -510,6 +551,10 @@ func AddOfflineDownload(c *gin.Context) {
 		common.ErrorResp(c, errs.PermissionDenied, 403)
 		return
 	}
+	if req.Checksum != "" && len(req.Urls) > 1 {
+		common.ErrorStrResp(c, "checksum is only allowed with a single url", 400)
+		return
+	}
 	var tasks []task.TaskExtensionInfo
 	for _, url := range req.Urls {
 		// Filter out empty lines and whitespace-only strings
@@ -519,10 +564,12 @@ func AddOfflineDownload(c *gin.Context) {
 		}
 
 		t, err := tool.AddURL(c, &tool.AddURLArgs{
-			URL:          trimmedUrl,
-			DstDirPath:   reqPath,
-			Tool:         req.Tool,
-			DeletePolicy: tool.DeletePolicy(req.DeletePolicy),
+			URL:           trimmedUrl,
+			DstDirPath:    reqPath,
+			Tool:          req.Tool,
+			DeletePolicy:  tool.DeletePolicy(req.DeletePolicy),
+			SelectedFiles: req.SelectedFiles,
+			Checksum:      req.Checksum,
 		})
 		if err != nil {
 			common.ErrorResp(c, err, 500)
@@ -533,6 +580,6 @@ func AddOfflineDownload(c *gin.Context) {
 		}
 	}
 	common.SuccessResp(c, gin.H{
-		"tasks": getTaskInfos(tasks),
+		"tasks": GetTaskInfos(tasks),
 	})
 }
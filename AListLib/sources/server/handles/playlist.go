@@ -0,0 +1,370 @@
+package handles
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	stdpath "path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sharing"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type PlaylistReq struct {
+	Path     string `json:"path" form:"path"`
+	Password string `json:"password" form:"password"`
+	// Format is one of m3u8, xspf and podcast-rss
+	Format    string `json:"format" form:"format"`
+	Recursive bool   `json:"recursive" form:"recursive"`
+}
+
+type playlistEntry struct {
+	// Path is the path of the file in the storages, which is used to find the
+	// duration in the music library
+	Path string
+	// ID is the path seen by the client, which is the guid of the podcast item
+	ID  string
+	Obj model.Obj
+	URL string
+	// Duration is in seconds, 0 if it is unknown
+	Duration int
+	Title    string
+	Artist   string
+	Album    string
+}
+
+// FsPlaylist lists the audio and video files of a folder as a playlist or a
+// podcast feed, the links are signed so the players need no credentials
+func FsPlaylist(c *gin.Context) {
+	var req PlaylistReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.Format == "" {
+		req.Format = "m3u8"
+	}
+	if !utils.SliceContains([]string{"m3u8", "xspf", "podcast-rss"}, req.Format) {
+		common.ErrorStrResp(c, "unsupported playlist format: "+req.Format, 400)
+		return
+	}
+	var (
+		title   string
+		entries []*playlistEntry
+	)
+	if strings.HasPrefix(req.Path, "/@s") {
+		var ok bool
+		if title, entries, ok = sharingPlaylist(c, &req); !ok {
+			return
+		}
+	} else {
+		user := c.Request.Context().Value(conf.UserKey).(*model.User)
+		if user.IsGuest() && user.Disabled {
+			common.ErrorStrResp(c, "Guest user is disabled, login please", 401)
+			return
+		}
+		var ok bool
+		if title, entries, ok = fsPlaylist(c, &req, user); !ok {
+			return
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+	fillPlaylistDurations(entries)
+	switch req.Format {
+	case "m3u8":
+		writeM3U8(c, entries)
+	case "xspf":
+		writeXSPF(c, title, entries)
+	default:
+		writePodcast(c, title, req.Path, entries)
+	}
+}
+
+func isMediaFile(name string) bool {
+	t := utils.GetFileType(name)
+	return t == conf.AUDIO || t == conf.VIDEO
+}
+
+func fsPlaylist(c *gin.Context, req *PlaylistReq, user *model.User) (string, []*playlistEntry, bool) {
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return "", nil, false
+	}
+	meta, err := op.GetNearestMeta(reqPath)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		common.ErrorResp(c, err, 500, true)
+		return "", nil, false
+	}
+	common.GinAppendValues(c, conf.MetaKey, meta)
+	if !common.CanAccess(user, meta, reqPath, req.Password) {
+		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
+		return "", nil, false
+	}
+	obj, err := fs.Get(c.Request.Context(), reqPath, &fs.GetArgs{})
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return "", nil, false
+	}
+	if !obj.IsDir() {
+		common.ErrorStrResp(c, "the path is not a folder", 400)
+		return "", nil, false
+	}
+	depth := 1
+	if req.Recursive {
+		depth = -1
+	}
+	api := common.GetApiUrl(c)
+	var entries []*playlistEntry
+	err = fs.WalkFS(c.Request.Context(), depth, reqPath, obj, func(p string, info model.Obj) error {
+		if info.IsDir() {
+			if p == reqPath {
+				return nil
+			}
+			m, err := op.GetNearestMeta(p)
+			if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+				return err
+			}
+			if !common.CanAccess(user, m, p, req.Password) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isMediaFile(info.GetName()) {
+			entries = append(entries, &playlistEntry{
+				Path: p,
+				ID:   p,
+				Obj:  info,
+				URL:  fmt.Sprintf("%s/d%s?sign=%s", api, utils.EncodePath(p, true), sign.Sign(p)),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return "", nil, false
+	}
+	return utils.GetNoneEmpty(stdpath.Base(reqPath), "/"), entries, true
+}
+
+// sharingPlaylist lists the files of a share, the links are signed with the
+// share so they stop working when the share is invalid or its password changes
+func sharingPlaylist(c *gin.Context, req *PlaylistReq) (string, []*playlistEntry, bool) {
+	sid, path, _ := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(req.Path, "/@s"), "/"), "/")
+	if sid == "" {
+		common.ErrorStrResp(c, "invalid share id", 400)
+		return "", nil, false
+	}
+	path = utils.FixAndCleanPath(path)
+	args := model.SharingListArgs{Pwd: req.Password}
+	s, obj, err := sharing.Get(c.Request.Context(), sid, path, args)
+	if dealError(c, err) {
+		return "", nil, false
+	}
+	if !obj.IsDir() {
+		common.ErrorStrResp(c, "the path is not a folder", 400)
+		return "", nil, false
+	}
+	_ = countAccess(c.ClientIP(), s)
+	api := common.GetApiUrl(c)
+	var entries []*playlistEntry
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		_, objs, err := sharing.List(c.Request.Context(), sid, dir, args)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objs {
+			p := stdpath.Join(dir, obj.GetName())
+			if obj.IsDir() {
+				if req.Recursive {
+					if err = walk(p); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if !isMediaFile(obj.GetName()) {
+				continue
+			}
+			unwrapPath, err := op.GetSharingUnwrapPath(s, p)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, &playlistEntry{
+				Path: unwrapPath,
+				ID:   stdpath.Join("/@s", sid, p),
+				Obj:  obj,
+				URL: fmt.Sprintf("%s/sd/%s%s?sign=%s", api, sid, utils.EncodePath(p, true),
+					sign.SignSharing(sharingSignData(s, p))),
+			})
+		}
+		return nil
+	}
+	if dealError(c, walk(path)) {
+		return "", nil, false
+	}
+	title := stdpath.Base(path)
+	if path == "/" {
+		title = utils.GetNoneEmpty(s.Remark, sid)
+	}
+	return title, entries, true
+}
+
+// fillPlaylistDurations fills the durations and the tags found by the scans
+// of the music library, the title defaults to the file name
+func fillPlaylistDurations(entries []*playlistEntry) {
+	tracks := make(map[string]map[string]model.MusicTrack)
+	for _, e := range entries {
+		name := e.Obj.GetName()
+		e.Title = strings.TrimSuffix(name, stdpath.Ext(name))
+		dir := stdpath.Dir(e.Path)
+		if _, ok := tracks[dir]; !ok {
+			tracks[dir] = make(map[string]model.MusicTrack)
+			if list, err := db.GetMusicTracksByDir(dir); err == nil {
+				for _, t := range list {
+					tracks[dir][t.Name] = t
+				}
+			}
+		}
+		if t, ok := tracks[dir][name]; ok {
+			e.Duration = t.Duration
+			e.Title = utils.GetNoneEmpty(t.Title, e.Title)
+			e.Artist, e.Album = t.Artist, t.Album
+		}
+	}
+}
+
+func writeM3U8(c *gin.Context, entries []*playlistEntry) {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	for _, e := range entries {
+		duration := -1
+		if e.Duration > 0 {
+			duration = e.Duration
+		}
+		title := e.Title
+		if e.Artist != "" {
+			title = e.Artist + " - " + title
+		}
+		// the line breaks would end the directive
+		title = strings.NewReplacer("\r", " ", "\n", " ").Replace(title)
+		fmt.Fprintf(&b, "#EXTINF:%d,%s\n%s\n", duration, title, e.URL)
+	}
+	c.Header("Content-Disposition", `inline; filename="playlist.m3u8"`)
+	c.Data(http.StatusOK, "audio/x-mpegurl; charset=utf-8", []byte(b.String()))
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Version string      `xml:"version,attr"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title"`
+	Creator  string `xml:"creator,omitempty"`
+	Album    string `xml:"album,omitempty"`
+	// Duration is in milliseconds
+	Duration int `xml:"duration,omitempty"`
+}
+
+func writeXSPF(c *gin.Context, title string, entries []*playlistEntry) {
+	playlist := xspfPlaylist{Version: "1", Xmlns: "http://xspf.org/ns/0/", Title: title}
+	for _, e := range entries {
+		playlist.Tracks = append(playlist.Tracks, xspfTrack{
+			Location: e.URL,
+			Title:    e.Title,
+			Creator:  e.Artist,
+			Album:    e.Album,
+			Duration: e.Duration * 1000,
+		})
+	}
+	writePlaylistXML(c, "application/xspf+xml", playlist)
+}
+
+type podcastRSS struct {
+	XMLName xml.Name       `xml:"rss"`
+	Version string         `xml:"version,attr"`
+	Itunes  string         `xml:"xmlns:itunes,attr"`
+	Channel podcastChannel `xml:"channel"`
+}
+
+type podcastChannel struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Description string        `xml:"description"`
+	Items       []podcastItem `xml:"item"`
+}
+
+type podcastItem struct {
+	Title     string `xml:"title"`
+	Enclosure struct {
+		URL    string `xml:"url,attr"`
+		Length int64  `xml:"length,attr"`
+		Type   string `xml:"type,attr"`
+	} `xml:"enclosure"`
+	GUID struct {
+		IsPermaLink bool   `xml:"isPermaLink,attr"`
+		Value       string `xml:",chardata"`
+	} `xml:"guid"`
+	PubDate  string `xml:"pubDate,omitempty"`
+	Author   string `xml:"itunes:author,omitempty"`
+	Duration string `xml:"itunes:duration,omitempty"`
+}
+
+func writePodcast(c *gin.Context, title, path string, entries []*playlistEntry) {
+	rss := podcastRSS{
+		Version: "2.0",
+		Itunes:  "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		Channel: podcastChannel{
+			Title:       title,
+			Link:        common.GetApiUrl(c) + utils.EncodePath(path, true),
+			Description: title,
+		},
+	}
+	for _, e := range entries {
+		item := podcastItem{Title: e.Title, Author: e.Artist}
+		item.Enclosure.URL = e.URL
+		item.Enclosure.Length = e.Obj.GetSize()
+		item.Enclosure.Type = utils.GetMimeType(e.Obj.GetName())
+		item.GUID.Value = e.ID
+		if !e.Obj.ModTime().IsZero() {
+			item.PubDate = e.Obj.ModTime().UTC().Format(time.RFC1123Z)
+		}
+		if e.Duration > 0 {
+			item.Duration = strconv.Itoa(e.Duration)
+		}
+		rss.Channel.Items = append(rss.Channel.Items, item)
+	}
+	writePlaylistXML(c, "application/rss+xml", rss)
+}
+
+func writePlaylistXML(c *gin.Context, contentType string, v any) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	c.Data(http.StatusOK, contentType+"; charset=utf-8", append([]byte(xml.Header), data...))
+}
@@ -0,0 +1,161 @@
+package handles
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func initPlaylistTest(t *testing.T) (*gin.Engine, *model.User) {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	conf.SlicesMap[conf.AudioTypes] = []string{"mp3"}
+	conf.SlicesMap[conf.VideoTypes] = []string{"mp4"}
+
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"a.mp3":        "audio a",
+		"b.mp4":        "video b",
+		"notes.txt":    "not media",
+		"sub/c.mp3":    "audio c",
+		"sub/d.mp3":    "audio d",
+		"sub/cover.md": "not media",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/media",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, dir),
+	}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	user := &model.User{Username: "listener", Role: model.GENERAL, BasePath: "/", Permission: 1 << 14}
+	if err := db.CreateUser(user.SetPassword("secret")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveMusicTracks([]*model.MusicTrack{
+		{Dir: "/media", Name: "a.mp3", Title: "Song A", Artist: "Artist", Duration: 125},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/fs/playlist", func(c *gin.Context) {
+		common.GinAppendValues(c, conf.UserKey, user)
+		c.Next()
+	}, FsPlaylist)
+	r.GET("/sd/:sid/*path", middlewares.PathParse, middlewares.SharingIdParse, SharingDown)
+	return r, user
+}
+
+func getPlaylist(t *testing.T, r http.Handler, query string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/fs/playlist?"+query, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: status %d", query, w.Code)
+	}
+	return w.Body.String()
+}
+
+func TestFsPlaylist(t *testing.T) {
+	r, user := initPlaylistTest(t)
+
+	m3u8 := getPlaylist(t, r, "path=/media")
+	want := "#EXTM3U\n" +
+		"#EXTINF:125,Artist - Song A\n/d/media/a.mp3?sign=" + sign.Sign("/media/a.mp3") + "\n" +
+		"#EXTINF:-1,b\n/d/media/b.mp4?sign=" + sign.Sign("/media/b.mp4") + "\n"
+	if m3u8 != want {
+		t.Fatalf("unexpected m3u8:\n%s", m3u8)
+	}
+	if m3u8 = getPlaylist(t, r, "path=/media&recursive=true"); strings.Count(m3u8, "#EXTINF") != 4 ||
+		!strings.Contains(m3u8, "/d/media/sub/d.mp3?sign=") {
+		t.Fatalf("unexpected recursive m3u8:\n%s", m3u8)
+	}
+
+	var xspf xspfPlaylist
+	if err := xml.Unmarshal([]byte(getPlaylist(t, r, "path=/media&format=xspf")), &xspf); err != nil {
+		t.Fatal(err)
+	}
+	if xspf.Title != "media" || len(xspf.Tracks) != 2 || xspf.Tracks[0].Duration != 125000 || xspf.Tracks[1].Title != "b" {
+		t.Fatalf("unexpected xspf: %+v", xspf)
+	}
+
+	var rss struct {
+		Items []struct {
+			Title     string `xml:"title"`
+			Enclosure struct {
+				URL    string `xml:"url,attr"`
+				Length int64  `xml:"length,attr"`
+			} `xml:"enclosure"`
+			Duration string `xml:"duration"`
+		} `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal([]byte(getPlaylist(t, r, "path=/media&format=podcast-rss")), &rss); err != nil {
+		t.Fatal(err)
+	}
+	if len(rss.Items) != 2 || rss.Items[0].Enclosure.Length != int64(len("audio a")) || rss.Items[0].Duration != "125" {
+		t.Fatalf("unexpected podcast: %+v", rss)
+	}
+
+	// the links of a share are signed with the share
+	s := &model.Sharing{
+		SharingDB: &model.SharingDB{Pwd: "code"},
+		Files:     []string{"/media/sub"},
+		Creator:   user,
+	}
+	sid, err := op.CreateSharing(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/fs/playlist?path=/@s/"+sid, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"code":403`) {
+		t.Fatalf("expect the share code to be required, got %s", w.Body.String())
+	}
+	m3u8 = getPlaylist(t, r, "path=/@s/"+sid+"&password=code")
+	lines := strings.Split(strings.TrimSpace(m3u8), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[2], "/sd/"+sid+"/c.mp3?sign=") {
+		t.Fatalf("unexpected share m3u8:\n%s", m3u8)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, lines[2], nil))
+	if w.Code != http.StatusOK || w.Body.String() != "audio c" {
+		t.Fatalf("failed download with the share signature: %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, strings.Replace(lines[2], "c.mp3", "d.mp3", 1), nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expect the signature of another file to be rejected, got %d", w.Code)
+	}
+}
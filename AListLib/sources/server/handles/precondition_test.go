@@ -0,0 +1,144 @@
+package handles
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func apiRequest(t *testing.T, r http.Handler, method, target, body string, header ...string) common.Resp[json.RawMessage] {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp common.Resp[json.RawMessage]
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s: %v %s", method, target, err, w.Body.String())
+	}
+	return resp
+}
+
+func TestWritePreconditions(t *testing.T) {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	dir := t.TempDir()
+	if err = os.WriteFile(filepath.Join(dir, "note.txt"), []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/edit",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, dir),
+	}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	user := &model.User{Username: "editor", Role: model.GENERAL, BasePath: "/", Permission: 0xffff}
+	if err = db.CreateUser(user.SetPassword("secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	g := r.Group("/api/fs", func(c *gin.Context) {
+		common.GinAppendValues(c, conf.UserKey, user)
+		c.Next()
+	})
+	g.POST("/get", FsGetSplit)
+	g.PUT("/put", middlewares.FsUp, FsStream)
+	g.POST("/remove", FsRemove)
+	g.POST("/move", FsMove)
+
+	resp := apiRequest(t, r, http.MethodPost, "/api/fs/get", `{"path":"/edit/note.txt"}`, "Content-Type", "application/json")
+	var obj FsGetResp
+	if err = json.Unmarshal(resp.Data, &obj); err != nil || obj.Etag == "" {
+		t.Fatalf("expect the etag in the response: %s %v", resp.Data, err)
+	}
+
+	// the first save wins, the second one is based on the stale etag
+	put := func(data string) common.Resp[json.RawMessage] {
+		return apiRequest(t, r, http.MethodPut, "/api/fs/put", data, "File-Path", "/edit/note.txt", "If-Match", obj.Etag)
+	}
+	if resp = put("v2 by alice"); resp.Code != 200 {
+		t.Fatalf("failed put: %+v", resp)
+	}
+	if resp = put("v2 by bob"); resp.Code != 412 {
+		t.Fatalf("expect the stale put to be rejected, got %+v", resp)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "note.txt")); string(data) != "v2 by alice" {
+		t.Fatalf("the file is clobbered: %q", data)
+	}
+	if resp = apiRequest(t, r, http.MethodPost, "/api/fs/remove", `{"dir":"/edit","names":["note.txt"]}`,
+		"Content-Type", "application/json", "If-Match", obj.Etag); resp.Code != 412 {
+		t.Fatalf("expect the stale remove to be rejected, got %+v", resp)
+	}
+	if resp = apiRequest(t, r, http.MethodPost, "/api/fs/remove", `{"dir":"/edit","names":["note.txt"]}`,
+		"Content-Type", "application/json", "If-Unmodified-Since", "Mon, 01 Jan 2024 00:00:00 GMT"); resp.Code != 412 {
+		t.Fatalf("expect the modified file to be kept, got %+v", resp)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "note.txt")); err != nil {
+		t.Fatalf("the file is removed: %v", err)
+	}
+
+	// the overwritten destination is checked as well as the moved file
+	if err = os.MkdirAll(filepath.Join(dir, "draft"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "draft", "note.txt"), []byte("draft"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resp = apiRequest(t, r, http.MethodPost, "/api/fs/get", `{"path":"/edit/draft/note.txt"}`, "Content-Type", "application/json")
+	var draft FsGetResp
+	if err = json.Unmarshal(resp.Data, &draft); err != nil {
+		t.Fatal(err)
+	}
+	move := func(header ...string) common.Resp[json.RawMessage] {
+		return apiRequest(t, r, http.MethodPost, "/api/fs/move", `{"src_dir":"/edit/draft","dst_dir":"/edit","names":["note.txt"],"overwrite":true}`,
+			append([]string{"Content-Type", "application/json", "If-Match", draft.Etag}, header...)...)
+	}
+	if resp = move("Dst-If-Match", obj.Etag); resp.Code != 412 {
+		t.Fatalf("expect the move over the changed file to be rejected, got %+v", resp)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "note.txt")); string(data) != "v2 by alice" {
+		t.Fatalf("the file is clobbered: %q", data)
+	}
+	// the etag of a single file is refused for several files
+	if resp = apiRequest(t, r, http.MethodPost, "/api/fs/remove", `{"dir":"/edit","names":["note.txt","draft"]}`,
+		"Content-Type", "application/json", "If-Match", obj.Etag); resp.Code != 400 {
+		t.Fatalf("expect the preconditions of several files to be refused, got %+v", resp)
+	}
+
+	resp = apiRequest(t, r, http.MethodPost, "/api/fs/get", `{"path":"/edit/note.txt"}`, "Content-Type", "application/json")
+	if err = json.Unmarshal(resp.Data, &obj); err != nil {
+		t.Fatal(err)
+	}
+	if resp = move("Dst-If-Match", obj.Etag); resp.Code != 200 {
+		t.Fatalf("failed move with the current etags: %+v", resp)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "note.txt")); string(data) != "draft" {
+		t.Fatalf("the file is not overwritten: %q", data)
+	}
+}
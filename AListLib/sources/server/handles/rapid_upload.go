@@ -0,0 +1,134 @@
+package handles
+
+import (
+	"fmt"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// maxRapidUploadFiles is the max number of files in one hash manifest
+const maxRapidUploadFiles = 1000
+
+type RapidUploadFile struct {
+	Name string `json:"name" binding:"required"`
+	Size int64  `json:"size"`
+	// Hash maps a hash name (md5, sha1, sha256, gcid...) to its hex value
+	Hash map[string]string `json:"hash" binding:"required"`
+}
+
+type RapidUploadReq struct {
+	Path  string            `json:"path" binding:"required"`
+	Files []RapidUploadFile `json:"files" binding:"required"`
+}
+
+type RapidUploadResult struct {
+	Name    string `json:"name"`
+	Success bool   `json:"success"`
+	// Miss is true when the storage does not hold the content, the file needs a normal upload
+	Miss  bool   `json:"miss"`
+	Error string `json:"error,omitempty"`
+}
+
+func (f *RapidUploadFile) hashInfo() (utils.HashInfo, error) {
+	m := make(map[*utils.HashType]string, len(f.Hash))
+	for name, value := range f.Hash {
+		ht, ok := utils.GetHashByName(name)
+		if !ok {
+			return utils.HashInfo{}, fmt.Errorf("unsupported hash type: %s", name)
+		}
+		if len(value) != ht.Width {
+			return utils.HashInfo{}, fmt.Errorf("invalid %s hash length: %d", name, len(value))
+		}
+		m[ht] = value
+	}
+	if len(m) == 0 {
+		return utils.HashInfo{}, fmt.Errorf("no hash provided")
+	}
+	return utils.NewHashInfoByMap(m), nil
+}
+
+// FsRapidUpload creates files from a hash manifest on storages which support
+// instant upload, the existing files are kept with the Overwrite header false
+// as the other uploads do
+func FsRapidUpload(c *gin.Context) {
+	var req RapidUploadReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if len(req.Files) == 0 {
+		common.ErrorStrResp(c, "Empty files", 400)
+		return
+	}
+	if len(req.Files) > maxRapidUploadFiles {
+		common.ErrorStrResp(c, fmt.Sprintf("Too many files (max %d)", maxRapidUploadFiles), 400)
+		return
+	}
+	overwrite := c.GetHeader("Overwrite") != "false"
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	meta, err := op.GetNearestMeta(reqPath)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	if !user.CanWriteContent() && !common.CanWriteContentBypassUserPerms(meta, reqPath) {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	if !common.CanWrite(user, meta, reqPath) {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	results := make([]RapidUploadResult, 0, len(req.Files))
+	for i := range req.Files {
+		f := &req.Files[i]
+		res := RapidUploadResult{Name: f.Name}
+		if err := common.CheckRelativePath(f.Name); err != nil {
+			res.Error = err.Error()
+			results = append(results, res)
+			continue
+		}
+		hash, err := f.hashInfo()
+		if err != nil {
+			res.Error = err.Error()
+			results = append(results, res)
+			continue
+		}
+		if !overwrite {
+			if obj, _ := fs.Get(c.Request.Context(), stdpath.Join(reqPath, f.Name), &fs.GetArgs{NoLog: true}); obj != nil {
+				res.Error = "file exists"
+				results = append(results, res)
+				continue
+			}
+		}
+		_, err = fs.RapidPut(c.Request.Context(), reqPath, f.Name, f.Size, hash)
+		switch {
+		case err == nil:
+			res.Success = true
+		case errors.Is(err, errs.RapidUploadMiss):
+			res.Miss = true
+		case errors.Is(err, errs.NotImplement):
+			// the storage can not rapid upload at all, no need to try the rest
+			common.ErrorStrResp(c, "storage does not support rapid upload", 400)
+			return
+		default:
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	common.SuccessResp(c, results)
+}
@@ -0,0 +1,149 @@
+package handles
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// knownMD5 is the md5 of the content held by the backend of the rapid driver
+const knownMD5 = "5d41402abc4b2a76b9719d911017c592"
+
+// rapid creates the files whose content is known by its backend
+type rapid struct {
+	model.Storage
+	driver.RootPath
+	files map[string]int64
+}
+
+func (d *rapid) Config() driver.Config {
+	return driver.Config{Name: "Rapid", LocalSort: true}
+}
+
+func (d *rapid) GetAddition() driver.Additional {
+	return &d.RootPath
+}
+
+func (d *rapid) Init(ctx context.Context) error {
+	d.files = make(map[string]int64)
+	return nil
+}
+
+func (d *rapid) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *rapid) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	var res []model.Obj
+	for name, size := range d.files {
+		res = append(res, &model.Object{Name: name, Size: size, Modified: time.Now()})
+	}
+	return res, nil
+}
+
+func (d *rapid) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	return nil, errs.NotImplement
+}
+
+func (d *rapid) RapidPut(ctx context.Context, dstDir model.Obj, name string, size int64, hash utils.HashInfo) (model.Obj, error) {
+	if hash.GetHash(utils.MD5) != knownMD5 {
+		return nil, errs.RapidUploadMiss
+	}
+	d.files[name] = size
+	return &model.Object{Name: name, Size: size, Modified: time.Now(), HashInfo: hash}, nil
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &rapid{}
+	})
+}
+
+func TestRapidUpload(t *testing.T) {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	if _, err = op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Rapid",
+		MountPath: "/rapid",
+		Addition:  `{}`,
+	}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	if _, err = op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/local",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, t.TempDir()),
+	}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	user := &model.User{Username: "rapid", Role: model.GENERAL, BasePath: "/", Permission: 0xffff}
+	if err = db.CreateUser(user.SetPassword("secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/fs/rapid_upload", func(c *gin.Context) {
+		common.GinAppendValues(c, conf.UserKey, user)
+		c.Next()
+	}, FsRapidUpload)
+	upload := func(path, files string, header ...string) ([]RapidUploadResult, common.Resp[json.RawMessage]) {
+		t.Helper()
+		resp := apiRequest(t, r, http.MethodPost, "/api/fs/rapid_upload", fmt.Sprintf(`{"path":%q,"files":%s}`, path, files),
+			append([]string{"Content-Type", "application/json"}, header...)...)
+		var results []RapidUploadResult
+		if resp.Code == 200 {
+			if err := json.Unmarshal(resp.Data, &results); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return results, resp
+	}
+
+	results, resp := upload("/rapid", `[
+		{"name":"a.txt","size":5,"hash":{"md5":"`+knownMD5+`"}},
+		{"name":"b.txt","size":5,"hash":{"md5":"00000000000000000000000000000000"}},
+		{"name":"../c.txt","size":5,"hash":{"md5":"`+knownMD5+`"}},
+		{"name":"d.txt","size":5,"hash":{"md5":"short"}}
+	]`)
+	if resp.Code != 200 || len(results) != 4 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if !results[0].Success || !results[1].Miss || results[2].Error == "" || results[3].Error == "" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	// the existing file is kept without overwrite
+	results, _ = upload("/rapid", `[{"name":"a.txt","size":5,"hash":{"md5":"`+knownMD5+`"}}]`, "Overwrite", "false")
+	if len(results) != 1 || results[0].Success || results[0].Error != "file exists" {
+		t.Errorf("expect the existing file to be kept: %+v", results)
+	}
+	results, _ = upload("/rapid", `[{"name":"a.txt","size":5,"hash":{"md5":"`+knownMD5+`"}}]`)
+	if len(results) != 1 || !results[0].Success {
+		t.Errorf("expect the existing file to be overwritten: %+v", results)
+	}
+
+	if _, resp = upload("/local", `[{"name":"a.txt","size":5,"hash":{"md5":"`+knownMD5+`"}}]`); resp.Code != 400 {
+		t.Errorf("expect the storage without rapid upload to be refused: %+v", resp)
+	}
+}
@@ -24,6 +24,7 @@ func ResetToken(c *gin.Context) {
 		return
 	}
 	sign.Instance()
+	sign.InstanceSharing()
 	common.SuccessResp(c, token)
 }
 
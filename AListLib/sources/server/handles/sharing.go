@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
@@ -14,9 +15,10 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/sharing"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
-	"github.com/OpenListTeam/go-cache"
+	gocache "github.com/OpenListTeam/go-cache"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 )
@@ -197,7 +199,7 @@ func SharingDown(c *gin.Context) {
 	if err == nil {
 		if !s.Valid() {
 			err = errs.InvalidSharing
-		} else if !s.Verify(pwd) {
+		} else if !s.Verify(pwd) && sign.VerifySharing(sharingSignData(s, path), c.Query("sign")) != nil {
 			err = errs.WrongShareCode
 		} else if len(s.Files) != 1 && path == "/" {
 			err = errors.New("cannot get sharing root link")
@@ -317,6 +319,12 @@ func SharingArchiveExtract(c *gin.Context) {
 	}
 }
 
+// sharingSignData is the data signed for the links of a file in a share, the
+// signature is bound to the share and is invalidated by changing the password
+func sharingSignData(s *model.Sharing, path string) string {
+	return fmt.Sprintf("/%s%s:%s", s.ID, path, s.Pwd)
+}
+
 func dealError(c *gin.Context, err error) bool {
 	if err == nil {
 		return false
@@ -613,15 +621,15 @@ func SetEnableSharing(disable bool) func(ctx *gin.Context) {
 }
 
 var (
-	AccessCache      = cache.NewMemCache[interface{}]()
-	AccessCountDelay = 30 * time.Minute
+	AccessCache      gocache.ICache[interface{}] = cache.NewSharedCache[interface{}]("sharing_access")
+	AccessCountDelay                             = 30 * time.Minute
 )
 
 func countAccess(ip string, s *model.Sharing) error {
 	key := fmt.Sprintf("%s:%s", s.ID, ip)
 	_, ok := AccessCache.Get(key)
 	if !ok {
-		AccessCache.Set(key, struct{}{}, cache.WithEx[interface{}](AccessCountDelay))
+		AccessCache.Set(key, struct{}{}, gocache.WithEx[interface{}](AccessCountDelay))
 		s.Accessed += 1
 		return op.UpdateSharing(s, true)
 	}
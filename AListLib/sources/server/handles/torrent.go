@@ -8,11 +8,13 @@ import (
 
 	_189pc "github.com/OpenListTeam/OpenList/v4/drivers/189pc"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
 	"github.com/OpenListTeam/OpenList/v4/pkg/torrent"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
@@ -150,7 +152,7 @@ type TorrentRapidUploadReq struct {
 	Path string `json:"path" binding:"required"`
 }
 
-// TorrentRapidUpload 从 torrent 文件中提取 CAS 信息尝试秒传到天翼云
+// TorrentRapidUpload 从 torrent 文件中提取 CAS 信息尝试秒传到支持秒传的存储
 func TorrentRapidUpload(c *gin.Context) {
 	user := c.Request.Context().Value(conf.UserKey).(*model.User)
 
@@ -214,15 +216,18 @@ func TorrentRapidUpload(c *gin.Context) {
 		return
 	}
 
-	// 检查是否是天翼云 PC 驱动
-	cloud189PC, ok := storage.(*_189pc.Cloud189PC)
-	if !ok {
-		common.ErrorResp(c, fmt.Errorf("目标存储不是天翼云PC驱动，不支持 CAS 秒传"), 400)
+	var obj model.Obj
+	if cloud189PC, ok := storage.(*_189pc.Cloud189PC); ok {
+		// 天翼云PC驱动可以使用 torrent 中完整的 CAS 分片信息秒传
+		obj, err = cloud189PC.RapidUploadFromTorrent(c.Request.Context(), dstDir, torrentData, true)
+	} else if _, ok := storage.(driver.RapidPut); ok {
+		// 其他支持秒传的驱动使用整文件 MD5 秒传
+		obj, err = op.RapidPut(c.Request.Context(), storage, dstDirActualPath, t.Info.Name, t.GetTotalSize(),
+			utils.NewHashInfo(utils.MD5, strings.ToLower(t.CAS.FileMD5)))
+	} else {
+		common.ErrorResp(c, fmt.Errorf("目标存储不支持秒传"), 400)
 		return
 	}
-
-	// 尝试秒传
-	obj, err := cloud189PC.RapidUploadFromTorrent(c.Request.Context(), dstDir, torrentData, true)
 	if err != nil {
 		common.ErrorResp(c, fmt.Errorf("秒传失败: %w", err), 400)
 		return
@@ -0,0 +1,403 @@
+package handles
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	stdpath "path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// the tus 1.0 resumable upload protocol, see https://tus.io/protocols/resumable-upload
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,creation-with-upload,termination,expiration"
+	tusChunkType  = "application/offset+octet-stream"
+)
+
+// tusLocks prevents the concurrent PATCH requests of an upload
+var tusLocks sync.Map
+
+func tusStagePath(id string) string {
+	return filepath.Join(conf.Conf.Tus.Dir, id)
+}
+
+func tusExpires() time.Time {
+	return time.Now().Add(time.Duration(conf.Conf.Tus.Expire) * time.Hour)
+}
+
+// tusMaxSize is the largest Upload-Length accepted, 0 is unlimited
+func tusMaxSize() int64 {
+	return conf.Conf.Tus.MaxSize * 1024 * 1024
+}
+
+func tusLock(id string) *sync.Mutex {
+	mu, _ := tusLocks.LoadOrStore(id, &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
+
+// TusResumable sets the protocol headers and rejects the unsupported versions
+func TusResumable(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	if c.Request.Method == http.MethodOptions {
+		c.Next()
+		return
+	}
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.AbortWithStatus(http.StatusPreconditionFailed)
+		return
+	}
+	c.Next()
+}
+
+func TusOptions(c *gin.Context) {
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	if maxSize := tusMaxSize(); maxSize > 0 {
+		c.Header("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// parseTusMetadata decodes the Upload-Metadata header, the values are base64 encoded
+func parseTusMetadata(s string) map[string]string {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.Fields(pair)
+		if len(kv) == 0 {
+			continue
+		}
+		var v []byte
+		if len(kv) > 1 {
+			v, _ = base64.StdEncoding.DecodeString(kv[1])
+		}
+		m[kv[0]] = string(v)
+	}
+	return m
+}
+
+// purgeTusUploads removes the expired uploads with their staged files
+func purgeTusUploads() {
+	uploads, err := db.GetExpiredTusUploads(time.Now())
+	if err != nil {
+		log.Errorf("%+v", err)
+		return
+	}
+	for _, u := range uploads {
+		removeTusUpload(u.ID)
+	}
+}
+
+func removeTusUpload(id string) {
+	if err := os.Remove(tusStagePath(id)); err != nil && !os.IsNotExist(err) {
+		log.Errorf("failed remove tus stage file: %+v", err)
+	}
+	if err := db.DeleteTusUpload(id); err != nil {
+		log.Errorf("%+v", err)
+	}
+}
+
+// TusCreate creates an upload for the File-Path, it is checked by the FsUp
+// middleware like the other uploads
+func TusCreate(c *gin.Context) {
+	purgeTusUploads()
+	path, err := url.PathUnescape(c.GetHeader("File-Path"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	path, err = user.JoinPath(path)
+	if err != nil {
+		c.String(http.StatusForbidden, err.Error())
+		return
+	}
+	name := stdpath.Base(path)
+	if shouldIgnoreSystemFile(name) {
+		c.String(http.StatusForbidden, errs.IgnoredSystemFile.Error())
+		return
+	}
+	if c.GetHeader("Overwrite") == "false" {
+		if res, _ := fs.Get(c.Request.Context(), path, &fs.GetArgs{NoLog: true}); res != nil {
+			c.String(http.StatusForbidden, "file exists")
+			return
+		}
+	}
+	size, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		c.String(http.StatusBadRequest, "invalid Upload-Length")
+		return
+	}
+	if maxSize := tusMaxSize(); maxSize > 0 && size > maxSize {
+		c.String(http.StatusRequestEntityTooLarge, "the upload is larger than %d bytes", maxSize)
+		return
+	}
+	metadata := c.GetHeader("Upload-Metadata")
+	mimetype := parseTusMetadata(metadata)["filetype"]
+	if mimetype == "" {
+		mimetype = utils.GetMimeType(name)
+	}
+	u := &model.TusUpload{
+		ID:        random.String(32),
+		UserID:    user.ID,
+		Path:      path,
+		Size:      size,
+		Metadata:  metadata,
+		Mimetype:  mimetype,
+		Modified:  getLastModified(c),
+		ExpiresAt: tusExpires(),
+	}
+	f, err := os.Create(tusStagePath(u.ID))
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	_ = f.Close()
+	if err = db.CreateTusUpload(u); err != nil {
+		_ = os.Remove(tusStagePath(u.ID))
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Header("Location", common.GetApiUrl(c)+"/api/fs/tus/"+u.ID)
+	c.Header("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	switch {
+	case size == 0:
+		if err = finishTusUpload(c, u); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+	case c.GetHeader("Content-Type") == tusChunkType:
+		writeTusChunk(c, u.ID, 0, http.StatusCreated)
+		return
+	}
+	c.Status(http.StatusCreated)
+}
+
+// getTusUpload returns the upload of the id owned by the current user
+func getTusUpload(c *gin.Context) (*model.TusUpload, bool) {
+	u, err := db.GetTusUpload(c.Param("id"))
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err != nil || u.UserID != user.ID {
+		c.Status(http.StatusNotFound)
+		return nil, false
+	}
+	if u.ExpiresAt.Before(time.Now()) {
+		removeTusUpload(u.ID)
+		c.Status(http.StatusGone)
+		return nil, false
+	}
+	return u, true
+}
+
+func TusHead(c *gin.Context) {
+	u, ok := getTusUpload(c)
+	if !ok {
+		return
+	}
+	// the put failed after the last chunk is retried, the client sees the
+	// upload finished only when it is handed to a task
+	if u.Offset == u.Size && !u.Put {
+		if lock := tusLock(u.ID); lock.TryLock() {
+			err := retryTusPut(c, u.ID)
+			lock.Unlock()
+			if err != nil {
+				c.String(http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(u.Size, 10))
+	if u.Metadata != "" {
+		c.Header("Upload-Metadata", u.Metadata)
+	}
+	c.Header("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusOK)
+}
+
+func TusPatch(c *gin.Context) {
+	u, ok := getTusUpload(c)
+	if !ok {
+		return
+	}
+	if c.GetHeader("Content-Type") != tusChunkType {
+		c.Status(http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid Upload-Offset")
+		return
+	}
+	writeTusChunk(c, u.ID, offset, http.StatusNoContent)
+}
+
+// writeTusChunk appends the body at the offset of the staged file, the upload
+// is put as a task when all the bytes are received
+func writeTusChunk(c *gin.Context, id string, offset int64, code int) {
+	lock := tusLock(id)
+	if !lock.TryLock() {
+		c.Status(http.StatusLocked)
+		return
+	}
+	defer lock.Unlock()
+	// reload as the offset may be changed by the request holding the lock
+	u, err := db.GetTusUpload(id)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	if offset != u.Offset {
+		c.Status(http.StatusConflict)
+		return
+	}
+	if u.Offset == u.Size {
+		// all the bytes are received, the put is retried if it failed
+		if err = finishTusUpload(c, u); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.Status(code)
+		return
+	}
+	f, err := os.OpenFile(tusStagePath(u.ID), os.O_WRONLY, 0o644)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	_, err = f.Seek(u.Offset, io.SeekStart)
+	var n int64
+	if err == nil {
+		// the received bytes are kept even if the connection is broken
+		n, err = utils.CopyWithBuffer(f, io.LimitReader(c.Request.Body, u.Size-u.Offset))
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	u.Offset += n
+	u.ExpiresAt = tusExpires()
+	if uerr := db.UpdateTusUpload(u); uerr != nil {
+		c.String(http.StatusInternalServerError, uerr.Error())
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	c.Header("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if u.Offset == u.Size {
+		if err = finishTusUpload(c, u); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	c.Status(code)
+}
+
+// retryTusPut reloads the upload and finishes it, the lock of the upload is held
+func retryTusPut(c *gin.Context, id string) error {
+	u, err := db.GetTusUpload(id)
+	if err != nil {
+		// the upload is removed by its task
+		return nil
+	}
+	return finishTusUpload(c, u)
+}
+
+// finishTusUpload puts the received upload once, the lock of the upload is
+// held. It is marked before the put as the task may remove it at once, and
+// unmarked if the put fails so that the next request retries it.
+func finishTusUpload(c *gin.Context, u *model.TusUpload) error {
+	if u.Put {
+		return nil
+	}
+	u.Put = true
+	if err := db.UpdateTusUpload(u); err != nil {
+		return err
+	}
+	if err := putTusUpload(c, u); err != nil {
+		u.Put = false
+		if uerr := db.UpdateTusUpload(u); uerr != nil {
+			log.Errorf("%+v", uerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// putTusUpload hands the staged file to an upload task, the file and the
+// state are removed when the task closes the stream
+func putTusUpload(c *gin.Context, u *model.TusUpload) error {
+	f, err := os.Open(tusStagePath(u.ID))
+	if err != nil {
+		return err
+	}
+	dir, name := stdpath.Split(u.Path)
+	s := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     name,
+			Size:     u.Size,
+			Modified: u.Modified,
+		},
+		Reader:       f,
+		Mimetype:     u.Mimetype,
+		WebPutAsTask: true,
+	}
+	id := u.ID
+	s.Add(utils.CloseFunc(func() error {
+		_ = f.Close()
+		removeTusUpload(id)
+		tusLocks.Delete(id)
+		return nil
+	}))
+	if _, err = fs.PutAsTask(c.Request.Context(), dir, s); err != nil {
+		// the staged file is kept for the retry
+		_ = f.Close()
+		return err
+	}
+	return nil
+}
+
+func TusDelete(c *gin.Context) {
+	u, ok := getTusUpload(c)
+	if !ok {
+		return
+	}
+	lock := tusLock(u.ID)
+	if !lock.TryLock() {
+		c.Status(http.StatusLocked)
+		return
+	}
+	if u.Put {
+		// the upload is owned by its task
+		lock.Unlock()
+		c.Status(http.StatusConflict)
+		return
+	}
+	removeTusUpload(u.ID)
+	tusLocks.Delete(u.ID)
+	lock.Unlock()
+	c.Status(http.StatusNoContent)
+}
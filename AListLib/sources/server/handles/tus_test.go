@@ -0,0 +1,168 @@
+package handles
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
+	"github.com/OpenListTeam/tache"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func tusRequest(r http.Handler, method, target, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Tus-Resumable", tusVersion)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestTus(t *testing.T) {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	conf.Conf.Tus.Dir = t.TempDir()
+	db.Init(dB)
+	fs.UploadTaskManager = tache.NewManager[*fs.UploadTask](tache.WithWorks(1))
+
+	dir := t.TempDir()
+	if _, err = op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/inbox",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, dir),
+	}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	user := &model.User{Username: "uploader", Role: model.GENERAL, BasePath: "/", Permission: 0xffff}
+	if err = db.CreateUser(user.SetPassword("secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	tus := r.Group("/api/fs/tus", func(c *gin.Context) {
+		common.GinAppendValues(c, conf.UserKey, user)
+		c.Next()
+	}, TusResumable)
+	tus.POST("", middlewares.FsUp, TusCreate)
+	tus.HEAD("/:id", TusHead)
+	tus.PATCH("/:id", TusPatch)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fs/tus", nil))
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expect the protocol version to be required, got %d", w.Code)
+	}
+
+	w = tusRequest(r, http.MethodPost, "/api/fs/tus", "", "File-Path", "/inbox/sub/a%20b.txt", "Upload-Length", "10")
+	location := w.Header().Get("Location")
+	if w.Code != http.StatusCreated || !strings.HasPrefix(location, "/api/fs/tus/") {
+		t.Fatalf("failed create upload: %d %s", w.Code, w.Body.String())
+	}
+	id := strings.TrimPrefix(location, "/api/fs/tus/")
+	if w = tusRequest(r, http.MethodPatch, location, "hello", "Content-Type", tusChunkType, "Upload-Offset", "0"); w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "5" {
+		t.Fatalf("failed patch: %d %s", w.Code, w.Body.String())
+	}
+	// the chunk is resumed from the offset of the server
+	if w = tusRequest(r, http.MethodPatch, location, "hello", "Content-Type", tusChunkType, "Upload-Offset", "0"); w.Code != http.StatusConflict {
+		t.Fatalf("expect the mismatched offset to conflict, got %d", w.Code)
+	}
+	if w = tusRequest(r, http.MethodHead, location, ""); w.Code != http.StatusOK ||
+		w.Header().Get("Upload-Offset") != "5" || w.Header().Get("Upload-Length") != "10" {
+		t.Fatalf("unexpected head: %d %v", w.Code, w.Header())
+	}
+	if u, err := db.GetTusUpload(id); err != nil || u.Offset != 5 {
+		t.Fatalf("the offset is not persisted: %+v %v", u, err)
+	}
+	if w = tusRequest(r, http.MethodPatch, location, "world!", "Content-Type", tusChunkType, "Upload-Offset", "5"); w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "10" {
+		t.Fatalf("failed patch: %d %s", w.Code, w.Body.String())
+	}
+
+	// the assembled file is put as a task
+	var data []byte
+	for i := 0; i < 50; i++ {
+		if data, err = os.ReadFile(filepath.Join(dir, "sub", "a b.txt")); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if string(data) != "helloworld" {
+		t.Fatalf("unexpected uploaded file: %q %v", data, err)
+	}
+	for i := 0; i < 50; i++ {
+		if _, err = db.GetTusUpload(id); err != nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err == nil {
+		t.Fatal("the finished upload is kept")
+	}
+	if _, err = os.Stat(tusStagePath(id)); !os.IsNotExist(err) {
+		t.Fatalf("the staged file is kept: %v", err)
+	}
+
+	conf.Conf.Tus.MaxSize = 1
+	if w = tusRequest(r, http.MethodPost, "/api/fs/tus", "", "File-Path", "/inbox/big.bin", "Upload-Length", "1048577"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expect the upload over the max size to be refused, got %d", w.Code)
+	}
+	tus.OPTIONS("", TusOptions)
+	if w = tusRequest(r, http.MethodOptions, "/api/fs/tus", ""); w.Header().Get("Tus-Max-Size") != "1048576" {
+		t.Fatalf("the max size is not advertised: %v", w.Header())
+	}
+
+	// the put failed as the storage is not mounted yet is retried
+	w = tusRequest(r, http.MethodPost, "/api/fs/tus", "", "File-Path", "/later/c.txt", "Upload-Length", "5")
+	location = w.Header().Get("Location")
+	id = strings.TrimPrefix(location, "/api/fs/tus/")
+	if w = tusRequest(r, http.MethodPatch, location, "hello", "Content-Type", tusChunkType, "Upload-Offset", "0"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expect the failed put to be reported, got %d", w.Code)
+	}
+	if u, err := db.GetTusUpload(id); err != nil || u.Put {
+		t.Fatalf("the failed upload is not kept for the retry: %+v %v", u, err)
+	}
+	if w = tusRequest(r, http.MethodHead, location, ""); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expect the retried put to fail, got %d", w.Code)
+	}
+	later := t.TempDir()
+	if _, err = op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/later",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, later),
+	}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	if w = tusRequest(r, http.MethodPatch, location, "", "Content-Type", tusChunkType, "Upload-Offset", "5"); w.Code != http.StatusNoContent {
+		t.Fatalf("failed retry the put: %d %s", w.Code, w.Body.String())
+	}
+	for i := 0; i < 50; i++ {
+		if data, err = os.ReadFile(filepath.Join(later, "c.txt")); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("unexpected uploaded file: %q %v", data, err)
+	}
+}
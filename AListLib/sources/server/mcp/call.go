@@ -4,12 +4,32 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/gin-gonic/gin"
 )
 
 type toolCallParams struct {
 	Name      string          `json:"name"`
 	Arguments json.RawMessage `json:"arguments"`
+	Meta      struct {
+		ProgressToken any `json:"progressToken"`
+	} `json:"_meta"`
+}
+
+// newProgressReporter returns nil if the client did not ask for progress
+func (s *Server) newProgressReporter(c *gin.Context, req request, sessionID string) *progressReporter {
+	var params toolCallParams
+	if len(req.Params) == 0 || json.Unmarshal(req.Params, &params) != nil || params.Meta.ProgressToken == nil {
+		return nil
+	}
+	return &progressReporter{
+		token:     params.Meta.ProgressToken,
+		c:         c,
+		server:    s,
+		sessionID: sessionID,
+		acceptSSE: acceptsEventStream(c.GetHeader("Accept")),
+	}
 }
 
 type toolResultContent struct {
@@ -34,6 +54,21 @@ func (s *Server) handleToolsCall(c *gin.Context, req request) (int, response) {
 		}
 	}
 
+	if t, ok := findTool(params.Name); ok && t.write {
+		if user, _ := c.Request.Context().Value(conf.UserKey).(*model.User); user != nil && user.IsMCPReadOnly() {
+			return http.StatusOK, response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Result: map[string]any{
+					"content": []toolResultContent{
+						{Type: "text", Text: "MCP is read-only for the current user"},
+					},
+					"isError": true,
+				},
+			}
+		}
+	}
+
 	var (
 		result any
 		err    *rpcError
@@ -45,6 +80,26 @@ func (s *Server) handleToolsCall(c *gin.Context, req request) (int, response) {
 		result, err = s.callFSGet(c, params.Arguments)
 	case "openlist.fs.link":
 		result, err = s.callFSLink(c, params.Arguments)
+	case "openlist.fs.search":
+		result, err = s.callFSSearch(c, params.Arguments)
+	case "openlist.fs.mkdir":
+		result, err = s.callFSMkdir(c, params.Arguments)
+	case "openlist.fs.rename":
+		result, err = s.callFSRename(c, params.Arguments)
+	case "openlist.fs.move":
+		result, err = s.callFSMove(c, params.Arguments)
+	case "openlist.fs.copy":
+		result, err = s.callFSCopy(c, params.Arguments)
+	case "openlist.fs.remove":
+		result, err = s.callFSRemove(c, params.Arguments)
+	case "openlist.fs.put_text":
+		result, err = s.callFSPutText(c, params.Arguments)
+	case "openlist.offline_download.add":
+		result, err = s.callOfflineDownloadAdd(c, params.Arguments)
+	case "openlist.task.status":
+		result, err = s.callTaskStatus(c, params.Arguments)
+	case "openlist.task.cancel":
+		result, err = s.callTaskCancel(c, params.Arguments)
 	default:
 		return http.StatusOK, response{
 			JSONRPC: "2.0",
@@ -85,7 +85,7 @@ func TestToolsListSuccess(t *testing.T) {
 		t.Fatalf("unexpected result type: %T", resp.Result)
 	}
 	tools, ok := result["tools"].([]any)
-	if !ok || len(tools) != 3 {
+	if !ok || len(tools) != len(openListTools) {
 		t.Fatalf("unexpected tools payload: %#v", result["tools"])
 	}
 	names := map[string]bool{}
@@ -97,11 +97,99 @@ func TestToolsListSuccess(t *testing.T) {
 		name, _ := currentTool["name"].(string)
 		names[name] = true
 	}
-	if !names["openlist.fs.list"] || !names["openlist.fs.get"] || !names["openlist.fs.link"] {
+	for _, name := range []string{
+		"openlist.fs.list", "openlist.fs.get", "openlist.fs.link", "openlist.fs.search",
+		"openlist.fs.mkdir", "openlist.fs.rename", "openlist.fs.move", "openlist.fs.copy",
+		"openlist.fs.remove", "openlist.fs.put_text", "openlist.offline_download.add",
+		"openlist.task.status", "openlist.task.cancel",
+	} {
+		if !names[name] {
+			t.Fatalf("missing tool %q: %#v", name, names)
+		}
+	}
+}
+
+func TestToolsListHidesWriteToolsForReadOnlyUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := newTestServer(map[string]*session{
+		"s5": {id: "s5", userID: 1, initialized: true},
+	})
+
+	r := gin.New()
+	r.POST("/mcp", func(c *gin.Context) {
+		common.GinAppendValues(c, conf.UserKey, &model.User{ID: 1, Role: model.ADMIN, Permission: 1 << 16})
+		srv.handlePost(c)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/mcp", strings.NewReader(`{
+		"jsonrpc":"2.0",
+		"id":5,
+		"method":"tools/list"
+	}`))
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set(ProtocolVersionHeader, ProtocolVersion)
+	req.Header.Set(SessionHeader, "s5")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	resp := decodeResponse(t, w)
+	if resp.Error != nil {
+		t.Fatalf("unexpected error response: %+v", resp.Error)
+	}
+	result, _ := resp.Result.(map[string]any)
+	tools, _ := result["tools"].([]any)
+	names := map[string]bool{}
+	for _, rawTool := range tools {
+		currentTool, _ := rawTool.(map[string]any)
+		name, _ := currentTool["name"].(string)
+		names[name] = true
+	}
+	if len(names) != 5 || !names["openlist.fs.list"] || !names["openlist.fs.search"] || !names["openlist.task.status"] {
 		t.Fatalf("unexpected tool names: %#v", names)
 	}
 }
 
+func TestToolsCallRejectsWriteToolForReadOnlyUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := newTestServer(map[string]*session{
+		"s6": {id: "s6", userID: 1, initialized: true},
+	})
+
+	r := gin.New()
+	r.POST("/mcp", func(c *gin.Context) {
+		common.GinAppendValues(c, conf.UserKey, &model.User{ID: 1, Role: model.ADMIN, Permission: 1 << 16})
+		srv.handlePost(c)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/mcp", strings.NewReader(`{
+		"jsonrpc":"2.0",
+		"id":6,
+		"method":"tools/call",
+		"params":{"name":"openlist.fs.mkdir","arguments":{"path":"/new"}}
+	}`))
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set(ProtocolVersionHeader, ProtocolVersion)
+	req.Header.Set(SessionHeader, "s6")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d", w.Code, http.StatusOK)
+	}
+	resp := decodeResponse(t, w)
+	if resp.Error != nil {
+		t.Fatalf("expected tool error result, got protocol error: %+v", resp.Error)
+	}
+	result, _ := resp.Result.(map[string]any)
+	if isError, _ := result["isError"].(bool); !isError {
+		t.Fatalf("expected write tool to be rejected: %#v", result)
+	}
+}
+
 func TestToolsCallUnknownTool(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	srv := newTestServer(map[string]*session{
@@ -0,0 +1,332 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	stdpath "path"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/handles"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type fsMkdirArgs struct {
+	Path string `json:"path"`
+}
+
+type fsRenameArgs struct {
+	Path      string `json:"path"`
+	Name      string `json:"name"`
+	Overwrite bool   `json:"overwrite"`
+}
+
+type fsMoveCopyArgs struct {
+	SrcDir       string   `json:"src_dir"`
+	DstDir       string   `json:"dst_dir"`
+	Names        []string `json:"names"`
+	Overwrite    bool     `json:"overwrite"`
+	SkipExisting bool     `json:"skip_existing"`
+	Merge        bool     `json:"merge"`
+	Wait         bool     `json:"wait"`
+}
+
+type fsRemoveArgs struct {
+	Dir   string   `json:"dir"`
+	Names []string `json:"names"`
+}
+
+type fsTasksResult struct {
+	Message string             `json:"message"`
+	Tasks   []handles.TaskInfo `json:"tasks"`
+}
+
+type fsDoneResult struct {
+	Message string `json:"message"`
+}
+
+func currentUser(c *gin.Context) (*model.User, *rpcError) {
+	user, ok := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !ok || user == nil {
+		return nil, &rpcError{Code: -32603, Message: "missing user context"}
+	}
+	if user.IsGuest() && user.Disabled {
+		return nil, &rpcError{Code: -32001, Message: "guest user is disabled"}
+	}
+	return user, nil
+}
+
+func nearestMeta(path string) (*model.Meta, *rpcError) {
+	meta, err := op.GetNearestMeta(path)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return nil, &rpcError{Code: -32603, Message: err.Error()}
+	}
+	return meta, nil
+}
+
+func permissionDenied() *rpcError {
+	return &rpcError{Code: -32003, Message: errs.PermissionDenied.Error()}
+}
+
+func parseArgs(raw json.RawMessage, name string, args any) *rpcError {
+	if len(raw) == 0 || string(raw) == "null" {
+		return &rpcError{Code: -32602, Message: fmt.Sprintf("invalid %s arguments", name)}
+	}
+	if err := json.Unmarshal(raw, args); err != nil {
+		return &rpcError{Code: -32602, Message: fmt.Sprintf("invalid %s arguments", name)}
+	}
+	return nil
+}
+
+func (s *Server) callFSMkdir(c *gin.Context, raw json.RawMessage) (any, *rpcError) {
+	var args fsMkdirArgs
+	if mcpErr := parseArgs(raw, "openlist.fs.mkdir", &args); mcpErr != nil {
+		return nil, mcpErr
+	}
+	if args.Path == "" {
+		return nil, &rpcError{Code: -32602, Message: "path is required"}
+	}
+	user, mcpErr := currentUser(c)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	reqPath, err := user.JoinPath(args.Path)
+	if err != nil {
+		return nil, &rpcError{Code: -32003, Message: err.Error()}
+	}
+	parentPath := stdpath.Dir(reqPath)
+	parentMeta, mcpErr := nearestMeta(parentPath)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if !user.CanWriteContent() && !common.CanWriteContentBypassUserPerms(parentMeta, parentPath) {
+		return nil, permissionDenied()
+	}
+	if !common.CanWrite(user, parentMeta, parentPath) {
+		return nil, permissionDenied()
+	}
+	if err := fs.MakeDir(c.Request.Context(), reqPath); err != nil {
+		return nil, &rpcError{Code: -32603, Message: err.Error()}
+	}
+	return fsDoneResult{Message: fmt.Sprintf("directory [%s] created", args.Path)}, nil
+}
+
+func (s *Server) callFSRename(c *gin.Context, raw json.RawMessage) (any, *rpcError) {
+	var args fsRenameArgs
+	if mcpErr := parseArgs(raw, "openlist.fs.rename", &args); mcpErr != nil {
+		return nil, mcpErr
+	}
+	if args.Path == "" || args.Name == "" {
+		return nil, &rpcError{Code: -32602, Message: "path and name are required"}
+	}
+	user, mcpErr := currentUser(c)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if !user.CanRename() {
+		return nil, permissionDenied()
+	}
+	reqPath, err := user.JoinPath(args.Path)
+	if err == nil {
+		err = common.CheckRelativePath(args.Name)
+	}
+	if err != nil {
+		return nil, &rpcError{Code: -32003, Message: err.Error()}
+	}
+	parentPath := stdpath.Dir(reqPath)
+	parentMeta, mcpErr := nearestMeta(parentPath)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if !common.CanWrite(user, parentMeta, parentPath) {
+		return nil, permissionDenied()
+	}
+	if !args.Overwrite {
+		dstPath := stdpath.Join(parentPath, args.Name)
+		if dstPath != reqPath {
+			if res, _ := fs.Get(c.Request.Context(), dstPath, &fs.GetArgs{NoLog: true}); res != nil {
+				return nil, &rpcError{Code: -32003, Message: fmt.Sprintf("file [%s] exists", args.Name)}
+			}
+		}
+	}
+	if err := fs.Rename(c.Request.Context(), reqPath, args.Name); err != nil {
+		return nil, &rpcError{Code: -32603, Message: err.Error()}
+	}
+	return fsDoneResult{Message: fmt.Sprintf("[%s] renamed to [%s]", args.Path, args.Name)}, nil
+}
+
+func (s *Server) callFSMove(c *gin.Context, raw json.RawMessage) (any, *rpcError) {
+	return s.moveOrCopy(c, raw, false)
+}
+
+func (s *Server) callFSCopy(c *gin.Context, raw json.RawMessage) (any, *rpcError) {
+	return s.moveOrCopy(c, raw, true)
+}
+
+// moveOrCopy follows the checks of handles.FsMove and handles.FsCopy
+func (s *Server) moveOrCopy(c *gin.Context, raw json.RawMessage, isCopy bool) (any, *rpcError) {
+	name, action := "openlist.fs.move", "move"
+	if isCopy {
+		name, action = "openlist.fs.copy", "copy"
+	}
+	var args fsMoveCopyArgs
+	if mcpErr := parseArgs(raw, name, &args); mcpErr != nil {
+		return nil, mcpErr
+	}
+	if len(args.Names) == 0 {
+		return nil, &rpcError{Code: -32602, Message: "names is required"}
+	}
+	if !isCopy && args.Merge {
+		return nil, &rpcError{Code: -32602, Message: "merge is only supported by copy"}
+	}
+	user, mcpErr := currentUser(c)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if (isCopy && !user.CanCopy()) || (!isCopy && !user.CanMove()) {
+		return nil, permissionDenied()
+	}
+	srcDir, err := user.JoinPath(args.SrcDir)
+	if err != nil {
+		return nil, &rpcError{Code: -32003, Message: err.Error()}
+	}
+	srcMeta, mcpErr := nearestMeta(srcDir)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if isCopy && !common.CanRead(user, srcMeta, srcDir) {
+		return nil, permissionDenied()
+	}
+	if !isCopy && !common.CanWrite(user, srcMeta, srcDir) {
+		return nil, permissionDenied()
+	}
+	dstDir, err := user.JoinPath(args.DstDir)
+	if err != nil {
+		return nil, &rpcError{Code: -32003, Message: err.Error()}
+	}
+	dstMeta, mcpErr := nearestMeta(dstDir)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if !common.CanWrite(user, dstMeta, dstDir) {
+		return nil, permissionDenied()
+	}
+
+	if !strings.HasSuffix(srcDir, "/") {
+		srcDir += "/"
+	}
+	paths := make([]string, 0, len(args.Names))
+	for _, n := range args.Names {
+		// ensure names are not relative paths
+		srcPath := stdpath.Join(srcDir, n)
+		if !strings.HasPrefix(srcPath+"/", srcDir) {
+			continue
+		}
+		if !args.Overwrite {
+			base := stdpath.Base(srcPath)
+			if base == "." || base == "/" {
+				return nil, &rpcError{Code: -32602, Message: fmt.Sprintf("invalid file name [%s]", n)}
+			}
+			if res, _ := fs.Get(c.Request.Context(), stdpath.Join(dstDir, base), &fs.GetArgs{NoLog: true}); res != nil {
+				if !args.SkipExisting && !args.Merge {
+					return nil, &rpcError{Code: -32003, Message: fmt.Sprintf("file [%s] exists", n)}
+				}
+				if !args.Merge || !res.IsDir() {
+					continue
+				}
+			}
+		}
+		paths = append(paths, srcPath)
+	}
+
+	var addedTasks []task.TaskExtensionInfo
+	for i, p := range paths {
+		var (
+			t   task.TaskExtensionInfo
+			err error
+		)
+		lazyCache := len(paths) > i+1
+		switch {
+		case !isCopy:
+			t, err = fs.Move(c.Request.Context(), p, dstDir, lazyCache)
+		case args.Merge:
+			t, err = fs.Merge(c.Request.Context(), p, dstDir, lazyCache)
+		default:
+			t, err = fs.Copy(c.Request.Context(), p, dstDir, lazyCache)
+		}
+		if t != nil {
+			addedTasks = append(addedTasks, t)
+		}
+		if err != nil {
+			return nil, &rpcError{Code: -32603, Message: err.Error()}
+		}
+	}
+	if len(addedTasks) == 0 {
+		return fsTasksResult{
+			Message: fmt.Sprintf("%s operations completed immediately", action),
+			Tasks:   []handles.TaskInfo{},
+		}, nil
+	}
+	infos := handles.GetTaskInfos(addedTasks)
+	if args.Wait {
+		if isCopy {
+			infos = waitTasks(c, fs.CopyTaskManager, infos)
+		} else {
+			infos = waitTasks(c, fs.MoveTaskManager, infos)
+		}
+	}
+	return fsTasksResult{
+		Message: fmt.Sprintf("successfully created %d %s task(s)", len(addedTasks), action),
+		Tasks:   infos,
+	}, nil
+}
+
+func (s *Server) callFSRemove(c *gin.Context, raw json.RawMessage) (any, *rpcError) {
+	var args fsRemoveArgs
+	if mcpErr := parseArgs(raw, "openlist.fs.remove", &args); mcpErr != nil {
+		return nil, mcpErr
+	}
+	if len(args.Names) == 0 {
+		return nil, &rpcError{Code: -32602, Message: "names is required"}
+	}
+	user, mcpErr := currentUser(c)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if !user.CanRemove() {
+		return nil, permissionDenied()
+	}
+	reqPath, err := user.JoinPath(args.Dir)
+	if err != nil {
+		return nil, &rpcError{Code: -32003, Message: err.Error()}
+	}
+	meta, mcpErr := nearestMeta(reqPath)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if !common.CanWrite(user, meta, reqPath) {
+		return nil, permissionDenied()
+	}
+	if !strings.HasSuffix(reqPath, "/") {
+		reqPath += "/"
+	}
+	removed := 0
+	for _, n := range args.Names {
+		fullPath := stdpath.Join(reqPath, n)
+		if !strings.HasPrefix(fullPath+"/", reqPath) {
+			continue
+		}
+		if err := fs.Remove(c.Request.Context(), fullPath); err != nil {
+			return nil, &rpcError{Code: -32603, Message: err.Error()}
+		}
+		removed++
+	}
+	return fsDoneResult{Message: fmt.Sprintf("%d item(s) removed", removed)}, nil
+}
@@ -0,0 +1,81 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// maxPutTextSize limits the content of openlist.fs.put_text,
+// larger files should be uploaded through /api/fs/put
+const maxPutTextSize = 512 * 1024
+
+type fsPutTextArgs struct {
+	Path      string `json:"path"`
+	Content   string `json:"content"`
+	Overwrite bool   `json:"overwrite"`
+}
+
+// callFSPutText follows the checks of middlewares.FsUp and handles.FsStream
+func (s *Server) callFSPutText(c *gin.Context, raw json.RawMessage) (any, *rpcError) {
+	var args fsPutTextArgs
+	if mcpErr := parseArgs(raw, "openlist.fs.put_text", &args); mcpErr != nil {
+		return nil, mcpErr
+	}
+	if args.Path == "" {
+		return nil, &rpcError{Code: -32602, Message: "path is required"}
+	}
+	if len(args.Content) > maxPutTextSize {
+		return nil, &rpcError{Code: -32602, Message: fmt.Sprintf("content exceeds %d bytes", maxPutTextSize)}
+	}
+	user, mcpErr := currentUser(c)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	reqPath, err := user.JoinPath(args.Path)
+	if err != nil {
+		return nil, &rpcError{Code: -32003, Message: err.Error()}
+	}
+	parentPath, name := stdpath.Split(reqPath)
+	if name == "" {
+		return nil, &rpcError{Code: -32602, Message: "path must point to a file"}
+	}
+	parentPath = stdpath.Clean(parentPath)
+	parentMeta, mcpErr := nearestMeta(parentPath)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if !user.CanWriteContent() && !common.CanWriteContentBypassUserPerms(parentMeta, parentPath) {
+		return nil, permissionDenied()
+	}
+	if !common.CanWrite(user, parentMeta, parentPath) {
+		return nil, permissionDenied()
+	}
+	if !args.Overwrite {
+		if res, _ := fs.Get(c.Request.Context(), reqPath, &fs.GetArgs{NoLog: true}); res != nil {
+			return nil, &rpcError{Code: -32003, Message: "file exists"}
+		}
+	}
+	file := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     name,
+			Size:     int64(len(args.Content)),
+			Modified: time.Now(),
+		},
+		Reader:   strings.NewReader(args.Content),
+		Mimetype: utils.GetMimeType(name),
+	}
+	if err := fs.PutDirectly(c.Request.Context(), parentPath, file); err != nil {
+		return nil, &rpcError{Code: -32603, Message: err.Error()}
+	}
+	return fsDoneResult{Message: fmt.Sprintf("%d bytes written to [%s]", len(args.Content), args.Path)}, nil
+}
@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/search"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/handles"
+	"github.com/gin-gonic/gin"
+)
+
+type fsSearchArgs struct {
+	Keywords string `json:"keywords"`
+	Parent   string `json:"parent"`
+	Scope    int    `json:"scope"`
+	Page     int    `json:"page"`
+	PerPage  int    `json:"per_page"`
+	Password string `json:"password"`
+}
+
+type fsSearchResult struct {
+	Content []handles.SearchResp `json:"content"`
+	Total   int64                `json:"total"`
+}
+
+// callFSSearch follows the filter of handles.Search
+func (s *Server) callFSSearch(c *gin.Context, raw json.RawMessage) (any, *rpcError) {
+	// the same as middlewares.SearchIndex
+	if setting.GetStr(conf.SearchIndex) == "none" {
+		return nil, &rpcError{Code: -32603, Message: errs.SearchNotAvailable.Error()}
+	}
+	args := fsSearchArgs{Parent: "/", Page: 1, PerPage: 100}
+	if mcpErr := parseArgs(raw, "openlist.fs.search", &args); mcpErr != nil {
+		return nil, mcpErr
+	}
+	if args.Keywords == "" {
+		return nil, &rpcError{Code: -32602, Message: "keywords is required"}
+	}
+	user, mcpErr := currentUser(c)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	parent, err := user.JoinPath(args.Parent)
+	if err != nil {
+		return nil, &rpcError{Code: -32003, Message: err.Error()}
+	}
+	req := model.SearchReq{
+		Parent:   parent,
+		Keywords: args.Keywords,
+		Scope:    args.Scope,
+		PageReq: model.PageReq{
+			Page:    args.Page,
+			PerPage: args.PerPage,
+		},
+	}
+	if err := req.Validate(); err != nil {
+		return nil, &rpcError{Code: -32602, Message: err.Error()}
+	}
+	reportProgress(c, 0, 1, "searching")
+	nodes, total, err := search.SearchFiltered(c.Request.Context(), req, func(node model.SearchNode) bool {
+		if !utils.IsSubPath(user.BasePath, node.Parent) {
+			return false
+		}
+		meta, mcpErr := nearestMeta(node.Parent)
+		if mcpErr != nil {
+			return false
+		}
+		return common.CanAccess(user, meta, stdpath.Join(node.Parent, node.Name), args.Password)
+	})
+	if err != nil {
+		return nil, &rpcError{Code: -32603, Message: err.Error()}
+	}
+	reportProgress(c, 1, 1, fmt.Sprintf("%d results found", total))
+	return fsSearchResult{
+		Content: utils.MustSliceConvert(nodes, func(node model.SearchNode) handles.SearchResp {
+			return handles.SearchResp{
+				SearchNode: node,
+				Type:       utils.GetObjType(node.Name, node.IsDir),
+			}
+		}),
+		Total: total,
+	}, nil
+}
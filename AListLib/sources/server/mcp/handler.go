@@ -14,6 +14,7 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
@@ -41,6 +42,10 @@ type session struct {
 type Server struct {
 	mu       sync.Mutex
 	sessions map[string]*session
+	// streams are the GET event streams by session id
+	streams map[string]*sseStream
+	// subscriptions are the subscribed resource uris and their full paths by session id
+	subscriptions map[string]map[string]string
 }
 
 type request struct {
@@ -69,30 +74,29 @@ type initializeParams struct {
 }
 
 var defaultServer = &Server{
-	sessions: map[string]*session{},
+	sessions:      map[string]*session{},
+	streams:       map[string]*sseStream{},
+	subscriptions: map[string]map[string]string{},
 }
 
+var registerHookOnce sync.Once
+
 var supportedProtocolVersions = map[string]struct{}{
 	"2025-11-25": {},
 	"2025-06-18": {},
 }
 
 func Register(g *gin.RouterGroup) {
+	registerHookOnce.Do(func() {
+		op.RegisterObjsUpdateHook(defaultServer.handleObjsUpdate)
+		op.RegisterObjWriteHook(defaultServer.handleObjWrite)
+	})
 	mcpGroup := g.Group("/mcp", middlewares.Auth(false), middlewares.AuthAdmin)
 	mcpGroup.GET("", defaultServer.handleGet)
 	mcpGroup.POST("", defaultServer.handlePost)
 	mcpGroup.DELETE("", defaultServer.handleDelete)
 }
 
-func (s *Server) handleGet(c *gin.Context) {
-	if !validateOrigin(c.Request) {
-		c.Status(http.StatusForbidden)
-		return
-	}
-	c.Header("Allow", "POST, DELETE")
-	c.Status(http.StatusMethodNotAllowed)
-}
-
 func (s *Server) handlePost(c *gin.Context) {
 	if !validateOrigin(c.Request) {
 		c.Status(http.StatusForbidden)
@@ -193,7 +197,28 @@ func (s *Server) handlePost(c *gin.Context) {
 			})
 			return
 		}
-		c.JSON(http.StatusOK, s.handleToolsList(req))
+		c.JSON(http.StatusOK, s.handleToolsList(c, req))
+	case "resources/list", "resources/read", "resources/templates/list", "resources/subscribe", "resources/unsubscribe":
+		if !s.sessionInitialized(sessionID) {
+			c.JSON(http.StatusBadRequest, response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   &rpcError{Code: -32002, Message: "MCP session not initialized"},
+			})
+			return
+		}
+		switch req.Method {
+		case "resources/list":
+			c.JSON(http.StatusOK, s.handleResourcesList(c, req))
+		case "resources/read":
+			c.JSON(http.StatusOK, s.handleResourcesRead(c, req))
+		case "resources/subscribe":
+			c.JSON(http.StatusOK, s.handleResourcesSubscribe(c, req, sessionID, true))
+		case "resources/unsubscribe":
+			c.JSON(http.StatusOK, s.handleResourcesSubscribe(c, req, sessionID, false))
+		default:
+			c.JSON(http.StatusOK, s.handleResourcesTemplatesList(req))
+		}
 	case "tools/call":
 		if !s.sessionInitialized(sessionID) {
 			c.JSON(http.StatusBadRequest, response{
@@ -203,8 +228,13 @@ func (s *Server) handlePost(c *gin.Context) {
 			})
 			return
 		}
-		status, resp := s.handleToolsCall(c, req)
-		c.JSON(status, resp)
+		reporter := s.newProgressReporter(c, req, sessionID)
+		if reporter == nil {
+			c.JSON(s.handleToolsCall(c, req))
+			return
+		}
+		c.Set(progressReporterKey, reporter)
+		reporter.finish(s.handleToolsCall(c, req))
 	default:
 		c.JSON(http.StatusOK, response{
 			JSONRPC: "2.0",
@@ -243,12 +273,16 @@ func (s *Server) handleInitialize(c *gin.Context, req request) {
 				"tools": map[string]any{
 					"listChanged": false,
 				},
+				"resources": map[string]any{
+					"subscribe":   true,
+					"listChanged": false,
+				},
 			},
 			"serverInfo": map[string]any{
 				"name":    "OpenList MCP",
 				"version": conf.Version,
 			},
-			"instructions": "Complete initialization with notifications/initialized, then use tools/list and tools/call. Available tools include openlist.fs.list, openlist.fs.get, and openlist.fs.link.",
+			"instructions": "Complete initialization with notifications/initialized, then use tools/list and tools/call. Available tools include openlist.fs.list, openlist.fs.get, openlist.fs.link and openlist.fs.search; unless MCP is read-only for the user, openlist.fs.mkdir, openlist.fs.rename, openlist.fs.move, openlist.fs.copy, openlist.fs.remove, openlist.fs.put_text, openlist.offline_download.add and openlist.task.cancel are available as well. Use openlist.task.status to follow the tasks returned by move, copy and offline download. Files are also exposed as openlist:// resources: use resources/read to read text, small binaries, directory listings and archive entries, and resources/subscribe to be notified of changes on the GET event stream. Pass _meta.progressToken with tools/call and wait=true to receive notifications/progress of long-running tasks.",
 		},
 	})
 }
@@ -260,11 +294,12 @@ func (s *Server) initializeSession(userID uint, requestedID string, protocolVers
 	now := time.Now()
 	s.pruneExpiredSessionsLocked(now)
 	if requestedID != "" {
-		currentSession, ok := s.sessions[requestedID]
-		if ok && currentSession != nil && currentSession.userID == userID {
+		currentSession, ok := s.lookupSessionLocked(requestedID, now)
+		if ok && currentSession.userID == userID {
 			currentSession.initialized = false
 			currentSession.protocolVersion = protocolVersion
 			currentSession.lastUsedAt = now
+			shareSession(currentSession)
 			return currentSession
 		}
 	}
@@ -314,6 +349,7 @@ func (s *Server) createSessionLocked(userID uint, protocolVersion string, now ti
 		lastUsedAt:      now,
 	}
 	s.sessions[currentSession.id] = currentSession
+	shareSession(currentSession)
 	return currentSession
 }
 
@@ -341,46 +377,36 @@ func (s *Server) getSession(id string) (session, bool) {
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	currentSession, ok := s.sessions[id]
-	if !ok || currentSession == nil {
-		return session{}, false
-	}
 	now := time.Now()
-	if sessionExpired(currentSession, now) {
-		delete(s.sessions, id)
+	currentSession, ok := s.lookupSessionLocked(id, now)
+	if !ok {
 		return session{}, false
 	}
 	currentSession.lastUsedAt = now
+	touchSharedSession(id)
 	return *currentSession, true
 }
 
 func (s *Server) markSessionInitialized(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	currentSession, ok := s.sessions[id]
-	if !ok || currentSession == nil {
-		return false
-	}
 	now := time.Now()
-	if sessionExpired(currentSession, now) {
-		delete(s.sessions, id)
+	currentSession, ok := s.lookupSessionLocked(id, now)
+	if !ok {
 		return false
 	}
 	currentSession.initialized = true
 	currentSession.lastUsedAt = now
+	shareSession(currentSession)
 	return true
 }
 
 func (s *Server) sessionInitialized(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	currentSession, ok := s.sessions[id]
-	if !ok || currentSession == nil {
-		return false
-	}
 	now := time.Now()
-	if sessionExpired(currentSession, now) {
-		delete(s.sessions, id)
+	currentSession, ok := s.lookupSessionLocked(id, now)
+	if !ok {
 		return false
 	}
 	currentSession.lastUsedAt = now
@@ -391,6 +417,12 @@ func (s *Server) deleteSession(id string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.sessions, id)
+	unshareSession(id)
+	delete(s.subscriptions, id)
+	if stream, ok := s.streams[id]; ok {
+		stream.close()
+		delete(s.streams, id)
+	}
 }
 
 func (s *Server) pruneExpiredSessionsLocked(now time.Time) {
@@ -422,6 +454,7 @@ func (s *Server) pruneLeastRecentlyUsedSessionsLocked(count int) {
 			return
 		}
 		delete(s.sessions, oldestID)
+		unshareSession(oldestID)
 	}
 }
 
@@ -458,6 +491,7 @@ func (s *Server) pruneLeastRecentlyUsedUserSessionsLocked(userID uint, count int
 			return
 		}
 		delete(s.sessions, oldestID)
+		unshareSession(oldestID)
 	}
 }
 
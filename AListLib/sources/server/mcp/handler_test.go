@@ -519,7 +519,7 @@ func TestDeleteRemovesSession(t *testing.T) {
 	}
 }
 
-func TestGetReturnsMethodNotAllowed(t *testing.T) {
+func TestGetRequiresEventStreamAccept(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	r := gin.New()
@@ -534,11 +534,20 @@ func TestGetReturnsMethodNotAllowed(t *testing.T) {
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
-	if w.Code != http.StatusMethodNotAllowed {
-		t.Fatalf("unexpected status: got %d want %d", w.Code, http.StatusMethodNotAllowed)
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatalf("unexpected status: got %d want %d", w.Code, http.StatusNotAcceptable)
 	}
-	if allow := w.Header().Get("Allow"); allow != "POST, DELETE" {
-		t.Fatalf("unexpected Allow header: got %q want %q", allow, "POST, DELETE")
+
+	req = httptest.NewRequest(http.MethodGet, "http://example.com/mcp", nil)
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(SessionHeader, "missing")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: got %d want %d", w.Code, http.StatusNotFound)
 	}
 }
 
@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func newToolContext(user *model.User) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "http://example.com/mcp", nil)
+	common.GinAppendValues(c, conf.UserKey, user)
+	return c
+}
+
+func TestFSRenameRejectsRelativeName(t *testing.T) {
+	c := newToolContext(&model.User{ID: 2, BasePath: "/", Permission: 1 << 4})
+	_, err := defaultServer.callFSRename(c, json.RawMessage(`{"path":"/a.txt","name":"../b.txt"}`))
+	if err == nil || err.Code != -32003 {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
+
+func TestFSManageRequiresUserPermission(t *testing.T) {
+	c := newToolContext(&model.User{ID: 2, BasePath: "/"})
+	for name, call := range map[string]func(*gin.Context, json.RawMessage) (any, *rpcError){
+		"rename": defaultServer.callFSRename,
+		"move":   defaultServer.callFSMove,
+		"copy":   defaultServer.callFSCopy,
+		"remove": defaultServer.callFSRemove,
+	} {
+		_, err := call(c, json.RawMessage(`{"path":"/a","name":"b","src_dir":"/","dst_dir":"/b","dir":"/","names":["a"]}`))
+		if err == nil || err.Code != -32003 {
+			t.Errorf("%s: unexpected error: %+v", name, err)
+		}
+	}
+}
+
+func TestFSPutTextRejectsLargeContent(t *testing.T) {
+	c := newToolContext(&model.User{ID: 1, Role: model.ADMIN, BasePath: "/"})
+	raw, _ := json.Marshal(fsPutTextArgs{Path: "/a.txt", Content: string(make([]byte, maxPutTextSize+1))})
+	_, err := defaultServer.callFSPutText(c, raw)
+	if err == nil || err.Code != -32602 {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
+
+func TestTaskStatusUnknownType(t *testing.T) {
+	c := newToolContext(&model.User{ID: 1, Role: model.ADMIN})
+	_, err := defaultServer.callTaskStatus(c, json.RawMessage(`{"type":"unknown","tid":"1"}`))
+	if err == nil || err.Code != -32602 {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
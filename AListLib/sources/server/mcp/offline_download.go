@@ -0,0 +1,81 @@
+package mcp
+
+import (
+	"encoding/json"
+	"strings"
+
+	offlineTool "github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/handles"
+	"github.com/gin-gonic/gin"
+)
+
+type offlineDownloadAddArgs struct {
+	Urls         []string `json:"urls"`
+	Path         string   `json:"path"`
+	Tool         string   `json:"tool"`
+	DeletePolicy string   `json:"delete_policy"`
+	Checksum     string   `json:"checksum"`
+	Wait         bool     `json:"wait"`
+}
+
+// callOfflineDownloadAdd follows the checks of handles.AddOfflineDownload
+func (s *Server) callOfflineDownloadAdd(c *gin.Context, raw json.RawMessage) (any, *rpcError) {
+	var args offlineDownloadAddArgs
+	if mcpErr := parseArgs(raw, "openlist.offline_download.add", &args); mcpErr != nil {
+		return nil, mcpErr
+	}
+	if len(args.Urls) == 0 || args.Path == "" || args.Tool == "" {
+		return nil, &rpcError{Code: -32602, Message: "urls, path and tool are required"}
+	}
+	if args.Checksum != "" && len(args.Urls) > 1 {
+		return nil, &rpcError{Code: -32602, Message: "checksum is only allowed with a single url"}
+	}
+	user, mcpErr := currentUser(c)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if !user.CanAddOfflineDownloadTasks() {
+		return nil, permissionDenied()
+	}
+	reqPath, err := user.JoinPath(args.Path)
+	if err != nil {
+		return nil, &rpcError{Code: -32003, Message: err.Error()}
+	}
+	meta, mcpErr := nearestMeta(reqPath)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if !common.CanWrite(user, meta, reqPath) {
+		return nil, permissionDenied()
+	}
+	var tasks []task.TaskExtensionInfo
+	for _, url := range args.Urls {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		t, err := offlineTool.AddURL(c.Request.Context(), &offlineTool.AddURLArgs{
+			URL:          url,
+			DstDirPath:   reqPath,
+			Tool:         args.Tool,
+			DeletePolicy: offlineTool.DeletePolicy(args.DeletePolicy),
+			Checksum:     args.Checksum,
+		})
+		if err != nil {
+			return nil, &rpcError{Code: -32603, Message: err.Error()}
+		}
+		if t != nil {
+			tasks = append(tasks, t)
+		}
+	}
+	infos := handles.GetTaskInfos(tasks)
+	if args.Wait {
+		infos = waitTasks(c, offlineTool.DownloadTaskManager, infos)
+	}
+	return fsTasksResult{
+		Message: "offline download tasks added",
+		Tasks:   infos,
+	}, nil
+}
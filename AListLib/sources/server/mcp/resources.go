@@ -0,0 +1,507 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+const (
+	resourceScheme = "openlist"
+	// maxResourceTextSize caps the bytes returned by a single resources/read
+	maxResourceTextSize = 1 << 20
+	// maxResourceBlobSize caps binary contents, larger files should be read by range or via openlist.fs.link
+	maxResourceBlobSize = 256 * 1024
+	resourcesPageSize   = 100
+	directoryMimeType   = "inode/directory"
+)
+
+type resource struct {
+	URI      string `json:"uri"`
+	Name     string `json:"name"`
+	Title    string `json:"title,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+}
+
+type resourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type resourceContents struct {
+	URI      string         `json:"uri"`
+	MimeType string         `json:"mimeType,omitempty"`
+	Text     *string        `json:"text,omitempty"`
+	Blob     string         `json:"blob,omitempty"`
+	Meta     map[string]any `json:"_meta,omitempty"`
+}
+
+type resourcesListParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type resourcesReadParams struct {
+	URI string `json:"uri"`
+}
+
+// resourceRef is a parsed openlist:// URI, e.g.
+// openlist:///docs/a.txt?offset=0&length=4096 or openlist:///docs/a.zip?inner=/readme.md
+type resourceRef struct {
+	Path        string
+	Password    string
+	Inner       string
+	ArchivePass string
+	Offset      int64
+	Length      int64
+}
+
+var openListResourceTemplates = []resourceTemplate{
+	{
+		URITemplate: "openlist://{+path}{?offset,length,password}",
+		Name:        "openlist-fs",
+		Title:       "OpenList File or Directory",
+		Description: "A file or directory by mount path. Text files are returned as text, small binaries as base64 blobs and directories as a JSON listing. offset and length select a byte range.",
+	},
+	{
+		URITemplate: "openlist://{+path}{?inner,archive_pass,offset,length,password}",
+		Name:        "openlist-archive",
+		Title:       "OpenList Archive Entry",
+		Description: "A file inside an archive. An inner path ending with \"/\" returns a JSON listing of the archive directory.",
+	},
+}
+
+func resourceURI(path string) string {
+	return (&url.URL{Scheme: resourceScheme, Path: path}).String()
+}
+
+func parseResourceURI(raw string) (*resourceRef, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != resourceScheme {
+		return nil, errors.Errorf("unsupported resource uri scheme: %s", u.Scheme)
+	}
+	// both openlist:///a/b and openlist://a/b are accepted
+	p := u.Path
+	if u.Host != "" {
+		p = "/" + u.Host + p
+	}
+	q := u.Query()
+	ref := &resourceRef{
+		Path:        utils.FixAndCleanPath(p),
+		Password:    q.Get("password"),
+		Inner:       q.Get("inner"),
+		ArchivePass: q.Get("archive_pass"),
+	}
+	if v := q.Get("offset"); v != "" {
+		if ref.Offset, err = strconv.ParseInt(v, 10, 64); err != nil || ref.Offset < 0 {
+			return nil, errors.Errorf("invalid offset: %s", v)
+		}
+	}
+	if v := q.Get("length"); v != "" {
+		if ref.Length, err = strconv.ParseInt(v, 10, 64); err != nil || ref.Length <= 0 {
+			return nil, errors.Errorf("invalid length: %s", v)
+		}
+	}
+	return ref, nil
+}
+
+func (s *Server) handleResourcesTemplatesList(req request) response {
+	return response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]any{
+			"resourceTemplates": openListResourceTemplates,
+		},
+	}
+}
+
+// handleResourcesList lists the root directory of the current user,
+// deeper objects are reached by reading directories or through the templates
+func (s *Server) handleResourcesList(c *gin.Context, req request) response {
+	var params resourcesListParams
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   &rpcError{Code: -32602, Message: "invalid resources/list params"},
+			}
+		}
+	}
+	offset := 0
+	if params.Cursor != "" {
+		n, err := strconv.Atoi(params.Cursor)
+		if err != nil || n < 0 {
+			return response{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error:   &rpcError{Code: -32602, Message: "invalid cursor"},
+			}
+		}
+		offset = n
+	}
+	user, mcpErr := currentUser(c)
+	if mcpErr != nil {
+		return response{JSONRPC: "2.0", ID: req.ID, Error: mcpErr}
+	}
+	meta, mcpErr := nearestMeta(user.BasePath)
+	if mcpErr != nil {
+		return response{JSONRPC: "2.0", ID: req.ID, Error: mcpErr}
+	}
+	// the listing takes no password, a protected root is read by the uri
+	if !common.CanAccess(user, meta, user.BasePath, "") {
+		return response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &rpcError{Code: -32003, Message: "password is incorrect or you have no permission"},
+		}
+	}
+	ctx := context.WithValue(c.Request.Context(), conf.MetaKey, meta)
+	objs, err := fs.List(ctx, user.BasePath, &fs.ListArgs{})
+	if err != nil {
+		return response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &rpcError{Code: -32603, Message: err.Error()},
+		}
+	}
+	resources := make([]resource, 0, resourcesPageSize)
+	for i := offset; i < len(objs) && len(resources) < resourcesPageSize; i++ {
+		resources = append(resources, toResource(objs[i], "/"))
+	}
+	result := map[string]any{
+		"resources": resources,
+	}
+	if next := offset + len(resources); next < len(objs) {
+		result["nextCursor"] = strconv.Itoa(next)
+	}
+	return response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
+func toResource(obj model.Obj, parent string) resource {
+	r := resource{
+		URI:  resourceURI(stdpath.Join(parent, obj.GetName())),
+		Name: obj.GetName(),
+		Size: obj.GetSize(),
+	}
+	if obj.IsDir() {
+		r.MimeType = directoryMimeType
+		r.Size = 0
+	} else {
+		r.MimeType = utils.GetMimeType(obj.GetName())
+	}
+	return r
+}
+
+func (s *Server) handleResourcesRead(c *gin.Context, req request) response {
+	var params resourcesReadParams
+	if len(req.Params) == 0 || json.Unmarshal(req.Params, &params) != nil || params.URI == "" {
+		return response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &rpcError{Code: -32602, Message: "invalid resources/read params"},
+		}
+	}
+	contents, mcpErr := s.readResource(c, params.URI)
+	if mcpErr != nil {
+		return response{JSONRPC: "2.0", ID: req.ID, Error: mcpErr}
+	}
+	return response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]any{
+			"contents": []resourceContents{*contents},
+		},
+	}
+}
+
+func (s *Server) readResource(c *gin.Context, uri string) (*resourceContents, *rpcError) {
+	ref, err := parseResourceURI(uri)
+	if err != nil {
+		return nil, &rpcError{Code: -32602, Message: err.Error()}
+	}
+	user, mcpErr := currentUser(c)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	reqPath, err := user.JoinPath(ref.Path)
+	if err != nil {
+		return nil, &rpcError{Code: -32003, Message: err.Error()}
+	}
+	meta, mcpErr := nearestMeta(reqPath)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if !common.CanAccess(user, meta, reqPath, ref.Password) {
+		return nil, &rpcError{Code: -32003, Message: "password is incorrect or you have no permission"}
+	}
+	ctx := context.WithValue(c.Request.Context(), conf.MetaKey, meta)
+	if ref.Inner != "" {
+		return readArchiveResource(ctx, user, uri, reqPath, ref)
+	}
+
+	obj, err := fs.Get(ctx, reqPath, &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return nil, resourceError(err)
+	}
+	if obj.IsDir() {
+		objs, err := fs.List(ctx, reqPath, &fs.ListArgs{})
+		if err != nil {
+			return nil, resourceError(err)
+		}
+		return directoryContents(uri, ref.Path, objs)
+	}
+
+	offset, length := resourceRange(ref, obj.GetSize())
+	if offset >= obj.GetSize() && obj.GetSize() > 0 {
+		return nil, &rpcError{Code: -32602, Message: "offset is beyond the end of file"}
+	}
+	data, err := readRange(ctx, reqPath, offset, length)
+	if err != nil {
+		return nil, &rpcError{Code: -32603, Message: err.Error()}
+	}
+	return fileContents(uri, obj.GetName(), data, offset, obj.GetSize(), ref.Length > 0)
+}
+
+func readArchiveResource(ctx context.Context, user *model.User, uri, reqPath string, ref *resourceRef) (*resourceContents, *rpcError) {
+	if !user.CanReadArchives() {
+		return nil, &rpcError{Code: -32003, Message: errs.PermissionDenied.Error()}
+	}
+	innerPath := utils.FixAndCleanPath(ref.Inner)
+	args := model.ArchiveInnerArgs{
+		ArchiveArgs: model.ArchiveArgs{Password: ref.ArchivePass},
+		InnerPath:   innerPath,
+	}
+	if strings.HasSuffix(ref.Inner, "/") {
+		objs, err := fs.ArchiveList(ctx, reqPath, model.ArchiveListArgs{ArchiveInnerArgs: args})
+		if err != nil {
+			return nil, resourceError(err)
+		}
+		return archiveDirectoryContents(uri, ref, innerPath, objs)
+	}
+	rc, size, err := fs.ArchiveInternalExtract(ctx, reqPath, args)
+	if err != nil {
+		return nil, resourceError(err)
+	}
+	defer rc.Close()
+	offset, length := resourceRange(ref, size)
+	if offset > 0 {
+		if _, err := utils.CopyWithBuffer(io.Discard, io.LimitReader(rc, offset)); err != nil {
+			return nil, &rpcError{Code: -32603, Message: err.Error()}
+		}
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, length))
+	if err != nil {
+		return nil, &rpcError{Code: -32603, Message: err.Error()}
+	}
+	return fileContents(uri, stdpath.Base(innerPath), data, offset, size, ref.Length > 0)
+}
+
+// resourceRange returns the range to read, an unknown size is treated as unbounded
+func resourceRange(ref *resourceRef, size int64) (int64, int64) {
+	length := int64(maxResourceTextSize)
+	if ref.Length > 0 && ref.Length < length {
+		length = ref.Length
+	}
+	if size >= 0 && ref.Offset+length > size {
+		length = max(0, size-ref.Offset)
+	}
+	return ref.Offset, length
+}
+
+func readRange(ctx context.Context, path string, offset, length int64) ([]byte, error) {
+	if length == 0 {
+		return []byte{}, nil
+	}
+	link, obj, err := fs.Link(ctx, path, model.LinkArgs{})
+	if err != nil {
+		return nil, err
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{
+		Obj: obj,
+		Ctx: ctx,
+	}, link)
+	if err != nil {
+		_ = link.Close()
+		return nil, err
+	}
+	defer ss.Close()
+	r, err := ss.RangeRead(http_range.Range{Start: offset, Length: length})
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.LimitReader(r, length))
+}
+
+func fileContents(uri, name string, data []byte, offset, size int64, ranged bool) (*resourceContents, *rpcError) {
+	mimeType := utils.GetMimeType(name)
+	end := offset + int64(len(data))
+	resourceMeta := map[string]any{
+		"size":      size,
+		"offset":    offset,
+		"length":    len(data),
+		"truncated": size < 0 || end < size,
+	}
+	if isTextContent(name, mimeType, data) {
+		text := string(trimPartialRune(data))
+		if mimeType == "application/octet-stream" {
+			mimeType = "text/plain"
+		}
+		return &resourceContents{URI: uri, MimeType: mimeType, Text: &text, Meta: resourceMeta}, nil
+	}
+	if len(data) > maxResourceBlobSize || (!ranged && (size < 0 || end < size)) {
+		return nil, &rpcError{
+			Code:    -32602,
+			Message: fmt.Sprintf("binary resource is larger than %d bytes, read it by range with offset and length or use openlist.fs.link", maxResourceBlobSize),
+		}
+	}
+	return &resourceContents{
+		URI:      uri,
+		MimeType: mimeType,
+		Blob:     base64.StdEncoding.EncodeToString(data),
+		Meta:     resourceMeta,
+	}, nil
+}
+
+type resourceEntry struct {
+	URI      string    `json:"uri"`
+	Name     string    `json:"name"`
+	IsDir    bool      `json:"is_dir"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+func directoryContents(uri, dir string, objs []model.Obj) (*resourceContents, *rpcError) {
+	entries := make([]resourceEntry, 0, len(objs))
+	for _, obj := range objs {
+		entries = append(entries, resourceEntry{
+			URI:      resourceURI(stdpath.Join(dir, obj.GetName())),
+			Name:     obj.GetName(),
+			IsDir:    obj.IsDir(),
+			Size:     obj.GetSize(),
+			Modified: obj.ModTime(),
+		})
+	}
+	return jsonContents(uri, entries)
+}
+
+func archiveDirectoryContents(uri string, ref *resourceRef, innerDir string, objs []model.Obj) (*resourceContents, *rpcError) {
+	entries := make([]resourceEntry, 0, len(objs))
+	for _, obj := range objs {
+		inner := stdpath.Join(innerDir, obj.GetName())
+		if obj.IsDir() {
+			inner += "/"
+		}
+		q := url.Values{"inner": {inner}}
+		if ref.ArchivePass != "" {
+			q.Set("archive_pass", ref.ArchivePass)
+		}
+		u := url.URL{Scheme: resourceScheme, Path: ref.Path, RawQuery: q.Encode()}
+		entries = append(entries, resourceEntry{
+			URI:      u.String(),
+			Name:     obj.GetName(),
+			IsDir:    obj.IsDir(),
+			Size:     obj.GetSize(),
+			Modified: obj.ModTime(),
+		})
+	}
+	return jsonContents(uri, entries)
+}
+
+func jsonContents(uri string, v any) (*resourceContents, *rpcError) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, &rpcError{Code: -32603, Message: err.Error()}
+	}
+	text := string(data)
+	return &resourceContents{URI: uri, MimeType: "application/json", Text: &text}, nil
+}
+
+var textMimeTypes = map[string]struct{}{
+	"application/json":       {},
+	"application/xml":        {},
+	"application/javascript": {},
+	"application/x-yaml":     {},
+	"application/yaml":       {},
+	"application/toml":       {},
+	"application/x-sh":       {},
+}
+
+// isTextContent trusts the configured text types and the mime type first,
+// files of unknown types are treated as text if they are valid UTF-8 without NUL
+func isTextContent(name, mimeType string, data []byte) bool {
+	switch utils.GetFileType(name) {
+	case conf.TEXT:
+		return true
+	case conf.AUDIO, conf.VIDEO, conf.IMAGE:
+		return false
+	}
+	base, _, _ := strings.Cut(mimeType, ";")
+	if strings.HasPrefix(base, "text/") {
+		return true
+	}
+	if _, ok := textMimeTypes[base]; ok {
+		return true
+	}
+	if base != "application/octet-stream" {
+		return false
+	}
+	data = trimPartialRune(data)
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence cut at the end of a range
+func trimPartialRune(data []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		b := data[len(data)-i]
+		if b < utf8.RuneSelf {
+			return data
+		}
+		if utf8.RuneStart(b) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				return data[:len(data)-i]
+			}
+			return data
+		}
+	}
+	return data
+}
+
+func resourceError(err error) *rpcError {
+	if errs.IsNotFoundError(err) {
+		return &rpcError{Code: -32002, Message: "resource not found"}
+	}
+	if errors.Is(err, errs.WrongArchivePassword) {
+		return &rpcError{Code: -32003, Message: err.Error()}
+	}
+	return &rpcError{Code: -32603, Message: err.Error()}
+}
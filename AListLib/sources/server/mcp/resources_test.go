@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestParseResourceURI(t *testing.T) {
+	ref, err := parseResourceURI("openlist:///docs/a%20b.zip?inner=/readme.md&offset=10&length=20&archive_pass=p")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ref.Path != "/docs/a b.zip" || ref.Inner != "/readme.md" || ref.ArchivePass != "p" || ref.Offset != 10 || ref.Length != 20 {
+		t.Fatalf("unexpected ref: %+v", ref)
+	}
+	ref, err = parseResourceURI("openlist://docs/a.txt")
+	if err != nil || ref.Path != "/docs/a.txt" {
+		t.Fatalf("unexpected ref: %+v, %v", ref, err)
+	}
+	for _, uri := range []string{"file:///etc/passwd", "openlist:///a?offset=-1", "openlist:///a?length=0"} {
+		if _, err := parseResourceURI(uri); err == nil {
+			t.Errorf("expected error for %q", uri)
+		}
+	}
+	if uri := resourceURI("/docs/a b.txt"); uri != "openlist:///docs/a%20b.txt" {
+		t.Fatalf("unexpected uri: %s", uri)
+	}
+}
+
+func TestResourceRange(t *testing.T) {
+	offset, length := resourceRange(&resourceRef{Offset: 90}, 100)
+	if offset != 90 || length != 10 {
+		t.Fatalf("unexpected range: %d, %d", offset, length)
+	}
+	_, length = resourceRange(&resourceRef{}, 10*maxResourceTextSize)
+	if length != maxResourceTextSize {
+		t.Fatalf("expected length capped, got %d", length)
+	}
+	_, length = resourceRange(&resourceRef{Length: 5}, -1)
+	if length != 5 {
+		t.Fatalf("unexpected length for unknown size: %d", length)
+	}
+}
+
+func TestFileContents(t *testing.T) {
+	// "你好" cut in the middle of the second rune
+	data := []byte("hi 你好")[:7]
+	contents, err := fileContents("openlist:///a", "a.unknownext", data, 0, 9, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if contents.Text == nil || *contents.Text != "hi 你" || contents.Meta["truncated"] != true {
+		t.Fatalf("unexpected text contents: %+v", contents)
+	}
+
+	png := []byte{0x89, 'P', 'N', 'G', 0, 1, 2}
+	contents, err = fileContents("openlist:///a.png", "a.png", png, 0, int64(len(png)), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if contents.Text != nil || contents.Blob != base64.StdEncoding.EncodeToString(png) || contents.MimeType != "image/png" {
+		t.Fatalf("unexpected blob contents: %+v", contents)
+	}
+
+	// a truncated binary must be requested by range
+	if _, err := fileContents("openlist:///a.bin", "a.bin", []byte{0, 1}, 0, 100, false); err == nil {
+		t.Fatal("expected error for truncated binary")
+	}
+	big := []byte(strings.Repeat("\x00", maxResourceBlobSize+1))
+	if _, err := fileContents("openlist:///a.bin", "a.bin", big, 0, int64(len(big)), true); err == nil {
+		t.Fatal("expected error for large binary")
+	}
+}
@@ -0,0 +1,41 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/gin-gonic/gin"
+)
+
+func TestFSSearchWithoutIndex(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	settingCacheMu.Lock()
+	t.Cleanup(func() {
+		op.Cache.ClearAll()
+		settingCacheMu.Unlock()
+	})
+	srv := newTestServer(nil)
+	search := func() *rpcError {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "http://example.com/mcp", nil)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), conf.UserKey, &model.User{ID: 1, Role: model.ADMIN, BasePath: "/"}))
+		_, mcpErr := srv.callFSSearch(c, json.RawMessage(`{"keywords":"a"}`))
+		return mcpErr
+	}
+
+	op.Cache.SetSetting(conf.SearchIndex, &model.SettingItem{Key: conf.SearchIndex, Value: "none"})
+	if mcpErr := search(); mcpErr == nil || mcpErr.Message != errs.SearchNotAvailable.Error() {
+		t.Fatalf("unexpected error: %+v", mcpErr)
+	}
+	// the index failed to initialise
+	op.Cache.SetSetting(conf.SearchIndex, &model.SettingItem{Key: conf.SearchIndex, Value: "bleve"})
+	if mcpErr := search(); mcpErr == nil || mcpErr.Message != errs.SearchNotAvailable.Error() {
+		t.Fatalf("unexpected error: %+v", mcpErr)
+	}
+}
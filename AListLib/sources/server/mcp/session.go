@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
+	gocache "github.com/OpenListTeam/go-cache"
+)
+
+// sharedSession is the state of a session shared with the other instances
+// when the cache backend is distributed, so any instance serves the requests
+// of the session. The event streams and the subscriptions stay on the
+// instance serving them.
+type sharedSession struct {
+	UserID          uint      `json:"user_id"`
+	ProtocolVersion string    `json:"protocol_version"`
+	Initialized     bool      `json:"initialized"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+var sharedSessions = cache.NewSharedCache[sharedSession]("mcp_session")
+
+func shareSession(currentSession *session) {
+	if !cache.Distributed() {
+		return
+	}
+	sharedSessions.Set(currentSession.id, sharedSession{
+		UserID:          currentSession.userID,
+		ProtocolVersion: currentSession.protocolVersion,
+		Initialized:     currentSession.initialized,
+		CreatedAt:       currentSession.createdAt,
+	}, gocache.WithEx[sharedSession](sessionTTL))
+}
+
+func touchSharedSession(id string) {
+	if cache.Distributed() {
+		sharedSessions.Expire(id, sessionTTL)
+	}
+}
+
+func unshareSession(id string) {
+	if cache.Distributed() {
+		sharedSessions.Del(id)
+	}
+}
+
+// lookupSessionLocked returns the session not expired. The shared state is
+// the source of truth when the cache backend is distributed, the sessions
+// created by the other instances are adopted.
+func (s *Server) lookupSessionLocked(id string, now time.Time) (*session, bool) {
+	currentSession := s.sessions[id]
+	if cache.Distributed() {
+		shared, ok := sharedSessions.Get(id)
+		if !ok {
+			delete(s.sessions, id)
+			return nil, false
+		}
+		if currentSession == nil {
+			currentSession = &session{id: id, createdAt: shared.CreatedAt, lastUsedAt: now}
+			s.sessions[id] = currentSession
+		}
+		currentSession.userID = shared.UserID
+		currentSession.protocolVersion = shared.ProtocolVersion
+		currentSession.initialized = shared.Initialized
+		return currentSession, true
+	}
+	if currentSession == nil {
+		delete(s.sessions, id)
+		return nil, false
+	}
+	if sessionExpired(currentSession, now) {
+		delete(s.sessions, id)
+		return nil, false
+	}
+	return currentSession, true
+}
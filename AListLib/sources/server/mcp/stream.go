@@ -0,0 +1,371 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	sseKeepAliveInterval = 25 * time.Second
+	sseStreamBuffer      = 64
+	progressReporterKey  = "mcp_progress_reporter"
+)
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// sseStream is the GET stream of a session, messages are dropped if the client does not keep up
+type sseStream struct {
+	events    chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSSEStream() *sseStream {
+	return &sseStream{
+		events: make(chan []byte, sseStreamBuffer),
+		done:   make(chan struct{}),
+	}
+}
+
+func (st *sseStream) send(data []byte) bool {
+	select {
+	case <-st.done:
+		return false
+	default:
+	}
+	select {
+	case st.events <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+func (st *sseStream) close() {
+	st.closeOnce.Do(func() {
+		close(st.done)
+	})
+}
+
+func writeSSEEvent(w gin.ResponseWriter, data []byte) error {
+	if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", data); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
+
+func acceptsEventStream(accept string) bool {
+	for part := range strings.SplitSeq(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			quality, err := strconv.ParseFloat(q, 64)
+			if err == nil && quality == 0 {
+				continue
+			}
+		}
+		switch mediaType {
+		case "*/*", "text/*", "text/event-stream":
+			return true
+		}
+	}
+	return false
+}
+
+// handleGet opens the server-to-client SSE stream of a session,
+// a new stream of the same session replaces the previous one
+func (s *Server) handleGet(c *gin.Context) {
+	if !validateOrigin(c.Request) {
+		c.Status(http.StatusForbidden)
+		return
+	}
+	if !acceptsEventStream(c.GetHeader("Accept")) {
+		c.JSON(http.StatusNotAcceptable, response{
+			JSONRPC: "2.0",
+			Error:   &rpcError{Code: -32000, Message: "Not Acceptable: client must accept text/event-stream"},
+		})
+		return
+	}
+	sessionID := c.GetHeader(SessionHeader)
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, response{
+			JSONRPC: "2.0",
+			Error:   &rpcError{Code: -32000, Message: "missing MCP session"},
+		})
+		return
+	}
+	currentSession, ok := s.getSession(sessionID)
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !ok || currentSession.userID != user.ID {
+		c.JSON(http.StatusNotFound, response{
+			JSONRPC: "2.0",
+			Error:   &rpcError{Code: -32001, Message: "session not found"},
+		})
+		return
+	}
+	if !s.validateRequestProtocolVersion(c.GetHeader(ProtocolVersionHeader), currentSession.protocolVersion) {
+		c.JSON(http.StatusBadRequest, response{
+			JSONRPC: "2.0",
+			Error:   &rpcError{Code: -32000, Message: "missing or unsupported MCP protocol version"},
+		})
+		return
+	}
+
+	stream := s.openStream(sessionID)
+	defer s.closeStream(sessionID, stream)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(sseKeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-stream.done:
+			return
+		case data := <-stream.events:
+			if err := writeSSEEvent(c.Writer, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, ok := s.getSession(sessionID); !ok {
+				return
+			}
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func (s *Server) openStream(sessionID string) *sseStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.streams == nil {
+		s.streams = map[string]*sseStream{}
+	}
+	if old, ok := s.streams[sessionID]; ok {
+		old.close()
+	}
+	stream := newSSEStream()
+	s.streams[sessionID] = stream
+	return stream
+}
+
+func (s *Server) closeStream(sessionID string, stream *sseStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stream.close()
+	if s.streams[sessionID] == stream {
+		delete(s.streams, sessionID)
+	}
+}
+
+// notify sends a notification to the GET stream of a session, it reports false if there is none
+func (s *Server) notify(sessionID string, method string, params any) bool {
+	data, err := json.Marshal(notification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		log.Errorf("failed to encode MCP notification: %+v", err)
+		return false
+	}
+	s.mu.Lock()
+	stream, ok := s.streams[sessionID]
+	s.mu.Unlock()
+	if !ok {
+		return false
+	}
+	return stream.send(data)
+}
+
+// progressReporter delivers notifications/progress of a request, either on the
+// SSE response of the request itself or on the GET stream of the session
+type progressReporter struct {
+	mu        sync.Mutex
+	token     any
+	c         *gin.Context
+	server    *Server
+	sessionID string
+	// sse is set once the response of the request is switched to an event stream
+	sse       bool
+	acceptSSE bool
+}
+
+func (p *progressReporter) report(progress, total float64, message string) {
+	params := map[string]any{
+		"progressToken": p.token,
+		"progress":      progress,
+	}
+	if total > 0 {
+		params["total"] = total
+	}
+	if message != "" {
+		params["message"] = message
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.acceptSSE {
+		p.server.notify(p.sessionID, "notifications/progress", params)
+		return
+	}
+	data, err := json.Marshal(notification{JSONRPC: "2.0", Method: "notifications/progress", Params: params})
+	if err != nil {
+		return
+	}
+	if !p.sse {
+		p.sse = true
+		p.c.Header("Content-Type", "text/event-stream")
+		p.c.Header("Cache-Control", "no-cache")
+		p.c.Header("X-Accel-Buffering", "no")
+		p.c.Status(http.StatusOK)
+	}
+	_ = writeSSEEvent(p.c.Writer, data)
+}
+
+// finish writes the response of the request, as the last event if the response is an event stream
+func (p *progressReporter) finish(status int, resp response) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.sse {
+		p.c.JSON(status, resp)
+		return
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	_ = writeSSEEvent(p.c.Writer, data)
+}
+
+// reportProgress is a no-op unless the client asked for progress with a progress token
+func reportProgress(c *gin.Context, progress, total float64, message string) {
+	if v, ok := c.Get(progressReporterKey); ok {
+		v.(*progressReporter).report(progress, total, message)
+	}
+}
+
+type resourceSubscribeParams struct {
+	URI string `json:"uri"`
+}
+
+func (s *Server) handleResourcesSubscribe(c *gin.Context, req request, sessionID string, subscribe bool) response {
+	var params resourceSubscribeParams
+	if len(req.Params) == 0 || json.Unmarshal(req.Params, &params) != nil || params.URI == "" {
+		return response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &rpcError{Code: -32602, Message: "invalid " + req.Method + " params"},
+		}
+	}
+	if !subscribe {
+		s.mu.Lock()
+		delete(s.subscriptions[sessionID], params.URI)
+		s.mu.Unlock()
+		return response{JSONRPC: "2.0", ID: req.ID, Result: map[string]any{}}
+	}
+	ref, err := parseResourceURI(params.URI)
+	if err != nil {
+		return response{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{Code: -32602, Message: err.Error()}}
+	}
+	user, mcpErr := currentUser(c)
+	if mcpErr != nil {
+		return response{JSONRPC: "2.0", ID: req.ID, Error: mcpErr}
+	}
+	reqPath, err := user.JoinPath(ref.Path)
+	if err != nil {
+		return response{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{Code: -32003, Message: err.Error()}}
+	}
+	meta, mcpErr := nearestMeta(reqPath)
+	if mcpErr != nil {
+		return response{JSONRPC: "2.0", ID: req.ID, Error: mcpErr}
+	}
+	if !common.CanAccess(user, meta, reqPath, ref.Password) {
+		return response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &rpcError{Code: -32003, Message: "password is incorrect or you have no permission"},
+		}
+	}
+	s.mu.Lock()
+	if s.subscriptions == nil {
+		s.subscriptions = map[string]map[string]string{}
+	}
+	if s.subscriptions[sessionID] == nil {
+		s.subscriptions[sessionID] = map[string]string{}
+	}
+	s.subscriptions[sessionID][params.URI] = reqPath
+	s.mu.Unlock()
+	return response{JSONRPC: "2.0", ID: req.ID, Result: map[string]any{}}
+}
+
+// handleObjsUpdate is registered as an op.ObjsUpdateHook, a subscribed path is
+// updated when it is the updated directory itself or a direct child of it
+func (s *Server) handleObjsUpdate(_ context.Context, parent string, _ []model.Obj) {
+	s.notifyUpdated(func(p string) bool {
+		return p == parent || stdpath.Dir(p) == parent
+	})
+}
+
+// handleObjWrite is registered as an op.ObjWriteHook, so the writes are notified
+// without waiting for the next listing. A subscribed path is updated when it is
+// the written path, its parent or under it
+func (s *Server) handleObjWrite(storage driver.Driver, path string) {
+	full := utils.GetFullPath(storage.GetStorage().MountPath, path)
+	s.notifyUpdated(func(p string) bool {
+		return p == full || p == stdpath.Dir(full) || strings.HasPrefix(p, strings.TrimSuffix(full, "/")+"/")
+	})
+}
+
+// notifyUpdated notifies the subscriptions whose path matches of the update
+func (s *Server) notifyUpdated(match func(p string) bool) {
+	type update struct {
+		sessionID string
+		uri       string
+	}
+	var updates []update
+	s.mu.Lock()
+	for sessionID, subs := range s.subscriptions {
+		if _, ok := s.sessions[sessionID]; !ok {
+			delete(s.subscriptions, sessionID)
+			continue
+		}
+		for uri, p := range subs {
+			if match(p) {
+				updates = append(updates, update{sessionID: sessionID, uri: uri})
+			}
+		}
+	}
+	s.mu.Unlock()
+	for _, u := range updates {
+		s.notify(u.sessionID, "notifications/resources/updated", map[string]any{"uri": u.uri})
+	}
+}
@@ -0,0 +1,149 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func newStreamTestServer(t *testing.T, srv *Server) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/mcp", func(c *gin.Context) {
+		common.GinAppendValues(c, conf.UserKey, &model.User{ID: 1, Role: model.ADMIN, BasePath: "/"})
+		srv.handleGet(c)
+	})
+	r.POST("/mcp", func(c *gin.Context) {
+		common.GinAppendValues(c, conf.UserKey, &model.User{ID: 1, Role: model.ADMIN, BasePath: "/"})
+		srv.handlePost(c)
+	})
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// readEvent reads the data of the next SSE message event
+func readEvent(t *testing.T, r *bufio.Reader) notification {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: "); ok {
+			var n notification
+			if err := json.Unmarshal([]byte(data), &n); err != nil {
+				t.Fatalf("failed to decode event %q: %v", data, err)
+			}
+			return n
+		}
+	}
+}
+
+func TestGetStreamDeliversResourceUpdates(t *testing.T) {
+	srv := newTestServer(map[string]*session{
+		"s1": {id: "s1", userID: 1, initialized: true},
+	})
+	srv.subscriptions = map[string]map[string]string{
+		"s1": {"openlist:///docs/a.txt": "/docs/a.txt", "openlist:///other": "/other"},
+	}
+	ts := newStreamTestServer(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/mcp", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(SessionHeader, "s1")
+	req.Header.Set(ProtocolVersionHeader, ProtocolVersion)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response: %d %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+
+	// wait until the stream is registered
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		srv.mu.Lock()
+		_, ok := srv.streams["s1"]
+		srv.mu.Unlock()
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stream not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	body := bufio.NewReader(res.Body)
+	srv.handleObjsUpdate(context.Background(), "/docs", nil)
+	n := readEvent(t, body)
+	params, _ := n.Params.(map[string]any)
+	if n.Method != "notifications/resources/updated" || params["uri"] != "openlist:///docs/a.txt" {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+
+	// the writes are notified without a listing
+	srv.handleObjWrite(&fsLinkTestDriver{storage: model.Storage{MountPath: "/docs"}}, "/a.txt")
+	n = readEvent(t, body)
+	params, _ = n.Params.(map[string]any)
+	if n.Method != "notifications/resources/updated" || params["uri"] != "openlist:///docs/a.txt" {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+
+	srv.deleteSession("s1")
+	if srv.notify("s1", "notifications/message", nil) {
+		t.Fatal("expected stream to be closed with the session")
+	}
+}
+
+func TestToolsCallStreamsProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := newTestServer(map[string]*session{
+		"s2": {id: "s2", userID: 1, initialized: true},
+	})
+
+	r := gin.New()
+	r.POST("/mcp", func(c *gin.Context) {
+		common.GinAppendValues(c, conf.UserKey, &model.User{ID: 1, Role: model.ADMIN})
+		reporter := srv.newProgressReporter(c, request{Params: json.RawMessage(`{"name":"x","_meta":{"progressToken":"p1"}}`)}, "s2")
+		if reporter == nil {
+			t.Fatal("expected progress reporter")
+		}
+		c.Set(progressReporterKey, reporter)
+		reportProgress(c, 50, 100, "half")
+		reporter.finish(http.StatusOK, response{JSONRPC: "2.0", ID: 1, Result: map[string]any{}})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/mcp", nil)
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+	body := bufio.NewReader(strings.NewReader(w.Body.String()))
+	n := readEvent(t, body)
+	params, _ := n.Params.(map[string]any)
+	if n.Method != "notifications/progress" || params["progressToken"] != "p1" || params["progress"] != float64(50) {
+		t.Fatalf("unexpected progress notification: %+v", n)
+	}
+	if last := readEvent(t, body); last.Method != "" {
+		t.Fatalf("expected the response as the last event, got %+v", last)
+	}
+}
@@ -0,0 +1,137 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	offlineTool "github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/server/handles"
+	"github.com/OpenListTeam/tache"
+	"github.com/gin-gonic/gin"
+)
+
+// taskTypes are the same as the groups of /api/task
+var taskTypes = []string{
+	"upload",
+	"copy",
+	"move",
+	"offline_download",
+	"offline_download_transfer",
+	"decompress",
+	"decompress_upload",
+}
+
+type taskArgs struct {
+	Type string `json:"type"`
+	Tid  string `json:"tid"`
+}
+
+func (s *Server) callTaskStatus(c *gin.Context, raw json.RawMessage) (any, *rpcError) {
+	return s.taskAction(c, raw, "openlist.task.status", false)
+}
+
+func (s *Server) callTaskCancel(c *gin.Context, raw json.RawMessage) (any, *rpcError) {
+	return s.taskAction(c, raw, "openlist.task.cancel", true)
+}
+
+func (s *Server) taskAction(c *gin.Context, raw json.RawMessage, name string, cancel bool) (any, *rpcError) {
+	var args taskArgs
+	if mcpErr := parseArgs(raw, name, &args); mcpErr != nil {
+		return nil, mcpErr
+	}
+	if args.Type == "" || args.Tid == "" {
+		return nil, &rpcError{Code: -32602, Message: "type and tid are required"}
+	}
+	user, mcpErr := currentUser(c)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	var (
+		info handles.TaskInfo
+		ok   bool
+	)
+	switch args.Type {
+	case "upload":
+		info, ok = getTask(fs.UploadTaskManager, user, args.Tid, cancel)
+	case "copy":
+		info, ok = getTask(fs.CopyTaskManager, user, args.Tid, cancel)
+	case "move":
+		info, ok = getTask(fs.MoveTaskManager, user, args.Tid, cancel)
+	case "offline_download":
+		info, ok = getTask(offlineTool.DownloadTaskManager, user, args.Tid, cancel)
+	case "offline_download_transfer":
+		info, ok = getTask(offlineTool.TransferTaskManager, user, args.Tid, cancel)
+	case "decompress":
+		info, ok = getTask(fs.ArchiveDownloadTaskManager, user, args.Tid, cancel)
+	case "decompress_upload":
+		info, ok = getTask(fs.ArchiveContentUploadTaskManager, user, args.Tid, cancel)
+	default:
+		return nil, &rpcError{Code: -32602, Message: "unknown task type"}
+	}
+	if !ok {
+		return nil, &rpcError{Code: -32602, Message: "task not found"}
+	}
+	return info, nil
+}
+
+// getTask hides tasks of other users as not found, the same as /api/task
+func getTask[T task.TaskExtensionInfo](manager task.Manager[T], user *model.User, tid string, cancel bool) (handles.TaskInfo, bool) {
+	if manager == nil {
+		return handles.TaskInfo{}, false
+	}
+	t, ok := manager.GetByID(tid)
+	if !ok {
+		return handles.TaskInfo{}, false
+	}
+	if !user.IsAdmin() && (t.GetCreator() == nil || t.GetCreator().ID != user.ID) {
+		return handles.TaskInfo{}, false
+	}
+	if cancel {
+		manager.Cancel(tid)
+	}
+	return handles.GetTaskInfo(t), true
+}
+
+const taskPollInterval = time.Second
+
+// waitTasks blocks until the tasks are done or the request is canceled,
+// the average progress is reported to the client meanwhile
+func waitTasks[T task.TaskExtensionInfo](c *gin.Context, manager task.Manager[T], infos []handles.TaskInfo) []handles.TaskInfo {
+	ticker := time.NewTicker(taskPollInterval)
+	defer ticker.Stop()
+	for {
+		var (
+			sum  float64
+			done int
+		)
+		for i := range infos {
+			if t, ok := manager.GetByID(infos[i].ID); ok {
+				infos[i] = handles.GetTaskInfo(t)
+			}
+			if isTaskDone(infos[i].State) {
+				done++
+				sum += 100
+			} else {
+				sum += infos[i].Progress
+			}
+		}
+		if len(infos) == 0 || done == len(infos) {
+			reportProgress(c, 100, 100, fmt.Sprintf("%d/%d tasks done", done, len(infos)))
+			return infos
+		}
+		reportProgress(c, sum/float64(len(infos)), 100, fmt.Sprintf("%d/%d tasks done", done, len(infos)))
+		select {
+		case <-c.Request.Context().Done():
+			return infos
+		case <-ticker.C:
+		}
+	}
+}
+
+func isTaskDone(state tache.State) bool {
+	return state == tache.StateSucceeded || state == tache.StateFailed || state == tache.StateCanceled
+}
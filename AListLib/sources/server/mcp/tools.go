@@ -1,12 +1,20 @@
 package mcp
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/gin-gonic/gin"
+)
 
 type tool struct {
 	Name        string          `json:"name"`
 	Title       string          `json:"title,omitempty"`
 	Description string          `json:"description,omitempty"`
 	InputSchema toolInputSchema `json:"inputSchema"`
+	// write tools are hidden and rejected for users whose MCP access is read-only
+	write bool
 }
 
 type toolInputSchema struct {
@@ -16,8 +24,10 @@ type toolInputSchema struct {
 }
 
 type schemaProperty struct {
-	Type        string `json:"type,omitempty"`
-	Description string `json:"description,omitempty"`
+	Type        string          `json:"type,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Items       *schemaProperty `json:"items,omitempty"`
+	Enum        []string        `json:"enum,omitempty"`
 }
 
 type toolsListParams struct {
@@ -98,9 +108,309 @@ var openListTools = []tool{
 			Required: []string{"path"},
 		},
 	},
+	{
+		Name:        "openlist.fs.search",
+		Title:       "OpenList FS Search",
+		Description: "Search the index for files and directories that the current user can access.",
+		InputSchema: toolInputSchema{
+			Type: "object",
+			Properties: map[string]schemaProperty{
+				"keywords": {
+					Type:        "string",
+					Description: "Keywords to search for.",
+				},
+				"parent": {
+					Type:        "string",
+					Description: "Mount path to search under, defaults to \"/\".",
+				},
+				"scope": {
+					Type:        "integer",
+					Description: "0 for all, 1 for directories only, 2 for files only.",
+				},
+				"password": {
+					Type:        "string",
+					Description: "Optional password for protected paths.",
+				},
+				"page": {
+					Type:        "integer",
+					Description: "1-based page number.",
+				},
+				"per_page": {
+					Type:        "integer",
+					Description: "Page size, defaults to 100.",
+				},
+			},
+			Required: []string{"keywords"},
+		},
+	},
+	{
+		Name:        "openlist.fs.mkdir",
+		Title:       "OpenList FS Mkdir",
+		Description: "Create a directory, missing parent directories are created as well.",
+		InputSchema: toolInputSchema{
+			Type: "object",
+			Properties: map[string]schemaProperty{
+				"path": {
+					Type:        "string",
+					Description: "Mount path of the new directory, for example \"/movies/2024\".",
+				},
+			},
+			Required: []string{"path"},
+		},
+		write: true,
+	},
+	{
+		Name:        "openlist.fs.rename",
+		Title:       "OpenList FS Rename",
+		Description: "Rename a file or directory in place.",
+		InputSchema: toolInputSchema{
+			Type: "object",
+			Properties: map[string]schemaProperty{
+				"path": {
+					Type:        "string",
+					Description: "Mount path of the object to rename.",
+				},
+				"name": {
+					Type:        "string",
+					Description: "New name without any path separator.",
+				},
+				"overwrite": {
+					Type:        "boolean",
+					Description: "Overwrite an existing object with the new name.",
+				},
+			},
+			Required: []string{"path", "name"},
+		},
+		write: true,
+	},
+	{
+		Name:        "openlist.fs.move",
+		Title:       "OpenList FS Move",
+		Description: "Move objects between directories, returns the created move tasks.",
+		InputSchema: toolInputSchema{
+			Type: "object",
+			Properties: map[string]schemaProperty{
+				"src_dir": {
+					Type:        "string",
+					Description: "Source directory mount path.",
+				},
+				"dst_dir": {
+					Type:        "string",
+					Description: "Destination directory mount path.",
+				},
+				"names": {
+					Type:        "array",
+					Description: "Names of the objects in src_dir to move.",
+					Items:       &schemaProperty{Type: "string"},
+				},
+				"overwrite": {
+					Type:        "boolean",
+					Description: "Overwrite existing objects in dst_dir.",
+				},
+				"skip_existing": {
+					Type:        "boolean",
+					Description: "Skip objects that already exist in dst_dir instead of failing.",
+				},
+				"wait": {
+					Type:        "boolean",
+					Description: "Wait until the tasks are done, progress is reported with notifications/progress if a progress token is given.",
+				},
+			},
+			Required: []string{"src_dir", "dst_dir", "names"},
+		},
+		write: true,
+	},
+	{
+		Name:        "openlist.fs.copy",
+		Title:       "OpenList FS Copy",
+		Description: "Copy objects between directories, returns the created copy tasks.",
+		InputSchema: toolInputSchema{
+			Type: "object",
+			Properties: map[string]schemaProperty{
+				"src_dir": {
+					Type:        "string",
+					Description: "Source directory mount path.",
+				},
+				"dst_dir": {
+					Type:        "string",
+					Description: "Destination directory mount path.",
+				},
+				"names": {
+					Type:        "array",
+					Description: "Names of the objects in src_dir to copy.",
+					Items:       &schemaProperty{Type: "string"},
+				},
+				"overwrite": {
+					Type:        "boolean",
+					Description: "Overwrite existing objects in dst_dir.",
+				},
+				"skip_existing": {
+					Type:        "boolean",
+					Description: "Skip objects that already exist in dst_dir instead of failing.",
+				},
+				"merge": {
+					Type:        "boolean",
+					Description: "Merge into existing directories in dst_dir.",
+				},
+				"wait": {
+					Type:        "boolean",
+					Description: "Wait until the tasks are done, progress is reported with notifications/progress if a progress token is given.",
+				},
+			},
+			Required: []string{"src_dir", "dst_dir", "names"},
+		},
+		write: true,
+	},
+	{
+		Name:        "openlist.fs.remove",
+		Title:       "OpenList FS Remove",
+		Description: "Remove objects from a directory.",
+		InputSchema: toolInputSchema{
+			Type: "object",
+			Properties: map[string]schemaProperty{
+				"dir": {
+					Type:        "string",
+					Description: "Directory mount path.",
+				},
+				"names": {
+					Type:        "array",
+					Description: "Names of the objects in dir to remove.",
+					Items:       &schemaProperty{Type: "string"},
+				},
+			},
+			Required: []string{"dir", "names"},
+		},
+		write: true,
+	},
+	{
+		Name:        "openlist.fs.put_text",
+		Title:       "OpenList FS Put Text",
+		Description: "Write a small text file, up to 512 KiB.",
+		InputSchema: toolInputSchema{
+			Type: "object",
+			Properties: map[string]schemaProperty{
+				"path": {
+					Type:        "string",
+					Description: "File mount path, for example \"/notes/todo.md\".",
+				},
+				"content": {
+					Type:        "string",
+					Description: "Text content of the file.",
+				},
+				"overwrite": {
+					Type:        "boolean",
+					Description: "Overwrite the file if it already exists.",
+				},
+			},
+			Required: []string{"path", "content"},
+		},
+		write: true,
+	},
+	{
+		Name:        "openlist.offline_download.add",
+		Title:       "OpenList Offline Download Add",
+		Description: "Add offline download tasks that save the urls into a directory.",
+		InputSchema: toolInputSchema{
+			Type: "object",
+			Properties: map[string]schemaProperty{
+				"urls": {
+					Type:        "array",
+					Description: "Urls to download.",
+					Items:       &schemaProperty{Type: "string"},
+				},
+				"path": {
+					Type:        "string",
+					Description: "Destination directory mount path.",
+				},
+				"tool": {
+					Type:        "string",
+					Description: "Offline download tool, for example \"SimpleHttp\" or \"aria2\".",
+				},
+				"delete_policy": {
+					Type:        "string",
+					Description: "What to do with the temporary files after transfer.",
+					Enum:        []string{"delete_on_upload_succeed", "delete_on_upload_failed", "delete_never", "delete_always", "upload_download_stream"},
+				},
+				"checksum": {
+					Type:        "string",
+					Description: "Optional expected checksum of a single url, for example \"sha256:<hex>\".",
+				},
+				"wait": {
+					Type:        "boolean",
+					Description: "Wait until the tasks are done, progress is reported with notifications/progress if a progress token is given.",
+				},
+			},
+			Required: []string{"urls", "path", "tool"},
+		},
+		write: true,
+	},
+	{
+		Name:        "openlist.task.status",
+		Title:       "OpenList Task Status",
+		Description: "Get the status and progress of a task created by the current user.",
+		InputSchema: toolInputSchema{
+			Type: "object",
+			Properties: map[string]schemaProperty{
+				"type": {
+					Type:        "string",
+					Description: "Task type.",
+					Enum:        taskTypes,
+				},
+				"tid": {
+					Type:        "string",
+					Description: "Task ID.",
+				},
+			},
+			Required: []string{"type", "tid"},
+		},
+	},
+	{
+		Name:        "openlist.task.cancel",
+		Title:       "OpenList Task Cancel",
+		Description: "Cancel a task created by the current user.",
+		InputSchema: toolInputSchema{
+			Type: "object",
+			Properties: map[string]schemaProperty{
+				"type": {
+					Type:        "string",
+					Description: "Task type.",
+					Enum:        taskTypes,
+				},
+				"tid": {
+					Type:        "string",
+					Description: "Task ID.",
+				},
+			},
+			Required: []string{"type", "tid"},
+		},
+		write: true,
+	},
+}
+
+func findTool(name string) (tool, bool) {
+	for _, t := range openListTools {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return tool{}, false
+}
+
+// visibleTools hides write tools from users whose MCP access is read-only
+func visibleTools(user *model.User) []tool {
+	if user == nil || !user.IsMCPReadOnly() {
+		return openListTools
+	}
+	tools := make([]tool, 0, len(openListTools))
+	for _, t := range openListTools {
+		if !t.write {
+			tools = append(tools, t)
+		}
+	}
+	return tools
 }
 
-func (s *Server) handleToolsList(req request) response {
+func (s *Server) handleToolsList(c *gin.Context, req request) response {
 	var params toolsListParams
 	if len(req.Params) > 0 {
 		if err := json.Unmarshal(req.Params, &params); err != nil {
@@ -112,11 +422,12 @@ func (s *Server) handleToolsList(req request) response {
 		}
 	}
 
+	user, _ := c.Request.Context().Value(conf.UserKey).(*model.User)
 	return response{
 		JSONRPC: "2.0",
 		ID:      req.ID,
 		Result: map[string]any{
-			"tools": openListTools,
+			"tools": visibleTools(user),
 		},
 	}
 }
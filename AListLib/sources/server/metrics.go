@@ -0,0 +1,37 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics serves the prometheus metrics to the scrapers presenting the token,
+// they are served without it on the separate listener only.
+func Metrics(g *gin.RouterGroup) {
+	if !conf.Conf.Metrics.Enable || conf.Conf.Metrics.Token == "" {
+		return
+	}
+	token := []byte(conf.Conf.Metrics.Token)
+	handler := metrics.Handler()
+	g.GET("/metrics", func(c *gin.Context) {
+		auth, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(auth), token) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	})
+}
+
+// NewMetricsServer returns the server of the separate metrics listener
+func NewMetricsServer() *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	return &http.Server{Addr: conf.Conf.Metrics.Listen, Handler: mux}
+}
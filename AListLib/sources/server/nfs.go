@@ -0,0 +1,32 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/server/nfs"
+)
+
+// NewNFSServer creates the NFS server with the export rules of the config
+func NewNFSServer() (*nfs.Server, error) {
+	exports := make([]nfs.Export, 0, len(conf.Conf.NFS.Exports))
+	for _, e := range conf.Conf.NFS.Exports {
+		cidr := e.CIDR
+		if !strings.Contains(cidr, "/") {
+			// a single address
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nfs export cidr [%s]: %w", e.CIDR, err)
+		}
+		exports = append(exports, nfs.Export{Net: ipNet, User: e.User, ReadOnly: e.ReadOnly})
+	}
+	return nfs.NewServer(exports), nil
+}
@@ -0,0 +1,211 @@
+package nfs
+
+import (
+	"context"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/pkg/errors"
+)
+
+var errReadOnly = errors.New("read-only export")
+
+func nearestMeta(p string) (*model.Meta, error) {
+	meta, err := op.GetNearestMeta(p)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// readCtx checks that the user can read p and returns the context of the fs calls
+func readCtx(sess *session, p string) (context.Context, error) {
+	if !utils.IsSubPath(sess.user.BasePath, p) {
+		return nil, errs.PermissionDenied
+	}
+	meta, err := nearestMeta(p)
+	if err != nil {
+		return nil, err
+	}
+	if !common.CanAccess(sess.user, meta, p, "") {
+		return nil, errs.PermissionDenied
+	}
+	return context.WithValue(sess.ctx, conf.MetaKey, meta), nil
+}
+
+// checkWriteIn checks that the user can create objects in dir
+func checkWriteIn(sess *session, dir string) error {
+	if sess.readOnly {
+		return errReadOnly
+	}
+	if !utils.IsSubPath(sess.user.BasePath, dir) {
+		return errs.PermissionDenied
+	}
+	meta, err := nearestMeta(dir)
+	if err != nil {
+		return err
+	}
+	if !sess.user.CanWriteContent() && !common.CanWriteContentBypassUserPerms(meta, dir) {
+		return errs.PermissionDenied
+	}
+	if !common.CanWrite(sess.user, meta, dir) {
+		return errs.PermissionDenied
+	}
+	return nil
+}
+
+// checkWrite checks that the user can modify the content of the file at p
+func checkWrite(sess *session, p string) error {
+	return checkWriteIn(sess, stdpath.Dir(p))
+}
+
+func checkRemove(sess *session, p string) error {
+	if sess.readOnly {
+		return errReadOnly
+	}
+	if !sess.user.CanRemove() || !utils.IsSubPath(sess.user.BasePath, p) || p == sess.user.BasePath {
+		return errs.PermissionDenied
+	}
+	meta, err := nearestMeta(p)
+	if err != nil {
+		return err
+	}
+	if !common.CanWrite(sess.user, meta, p) {
+		return errs.PermissionDenied
+	}
+	return nil
+}
+
+func checkRename(sess *session, src, dst string) error {
+	if sess.readOnly {
+		return errReadOnly
+	}
+	if !utils.IsSubPath(sess.user.BasePath, src) || !utils.IsSubPath(sess.user.BasePath, dst) || src == sess.user.BasePath {
+		return errs.PermissionDenied
+	}
+	srcDir, srcBase := stdpath.Split(src)
+	dstDir, dstBase := stdpath.Split(dst)
+	dstMeta, err := nearestMeta(dstDir)
+	if err != nil {
+		return err
+	}
+	if !common.CanWrite(sess.user, dstMeta, dstDir) {
+		return errs.PermissionDenied
+	}
+	if srcDir == dstDir {
+		if !sess.user.CanRename() {
+			return errs.PermissionDenied
+		}
+		return nil
+	}
+	srcMeta, err := nearestMeta(srcDir)
+	if err != nil {
+		return err
+	}
+	if !sess.user.CanMove() || (srcBase != dstBase && !sess.user.CanRename()) || !common.CanWrite(sess.user, srcMeta, srcDir) {
+		return errs.PermissionDenied
+	}
+	return nil
+}
+
+// stat returns the attributes of p, the pending writes are taken into account
+func (s *Server) stat(sess *session, p string) (attr, model.Obj, error) {
+	ctx, err := readCtx(sess, p)
+	if err != nil {
+		return attr{}, nil, err
+	}
+	if sp := s.spools.get(p); sp != nil {
+		return sp.attr(), nil, nil
+	}
+	obj, err := fs.Get(ctx, p, &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return attr{}, nil, err
+	}
+	return attrOf(obj), obj, nil
+}
+
+type dirEntry struct {
+	name string
+	attr attr
+}
+
+// list returns the entries of the directory p, including the files that are being written
+func (s *Server) list(sess *session, p string) ([]dirEntry, error) {
+	ctx, err := readCtx(sess, p)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := fs.List(ctx, p, &fs.ListArgs{NoLog: true})
+	if err != nil {
+		return nil, err
+	}
+	pending := s.spools.list(p)
+	entries := make([]dirEntry, 0, len(objs)+len(pending))
+	for _, obj := range objs {
+		a := attrOf(obj)
+		if sp, ok := pending[obj.GetName()]; ok {
+			a = sp.attr()
+			delete(pending, obj.GetName())
+		}
+		entries = append(entries, dirEntry{name: obj.GetName(), attr: a})
+	}
+	for name, sp := range pending {
+		entries = append(entries, dirEntry{name: name, attr: sp.attr()})
+	}
+	return entries, nil
+}
+
+func (s *Server) rename(sess *session, src, dst string) error {
+	srcDir, srcBase := stdpath.Split(src)
+	dstDir, dstBase := stdpath.Split(dst)
+	if sp := s.spools.get(src); sp != nil {
+		// the file may not be uploaded yet
+		if err := s.spools.flush(sp); err != nil {
+			return err
+		}
+	}
+	// the move across the storages is done before the reply, not as a task
+	moveCtx := context.WithValue(sess.ctx, conf.NoTaskKey, struct{}{})
+	if srcDir == dstDir {
+		if err := fs.Rename(sess.ctx, src, dstBase); err != nil {
+			return err
+		}
+	} else if srcBase == dstBase {
+		if _, err := fs.Move(moveCtx, src, dstDir); err != nil {
+			return err
+		}
+	} else if !s.exists(sess, stdpath.Join(srcDir, dstBase)) {
+		// renamed in the source first, so the file with the old name in the destination is kept
+		if err := fs.Rename(sess.ctx, src, dstBase, true); err != nil {
+			return err
+		}
+		if _, err := fs.Move(moveCtx, stdpath.Join(srcDir, dstBase), dstDir); err != nil {
+			return err
+		}
+	} else if !s.exists(sess, stdpath.Join(dstDir, srcBase)) {
+		// the new name is taken in the source, moved first and renamed in the destination
+		if _, err := fs.Move(moveCtx, src, dstDir); err != nil {
+			return err
+		}
+		if err := fs.Rename(sess.ctx, stdpath.Join(dstDir, srcBase), dstBase, true); err != nil {
+			return err
+		}
+	} else {
+		return errs.ObjectAlreadyExists
+	}
+	s.spools.rename(src, dst)
+	s.readers.drop(src)
+	return nil
+}
+
+// exists reports whether p exists, including the files that are being written
+func (s *Server) exists(sess *session, p string) bool {
+	_, _, err := s.stat(sess, p)
+	return err == nil
+}
@@ -0,0 +1,145 @@
+package nfs
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+const handleSize = 16
+
+// handleTable maps the file handles to the virtual paths, a handle is derived
+// from the path so the handle of a path is stable, but the paths are only
+// known after a lookup, clients look up again when a handle turns stale
+type handleTable struct {
+	mu    sync.RWMutex
+	paths map[[handleSize]byte]string
+}
+
+func newHandleTable() *handleTable {
+	return &handleTable{paths: map[[handleSize]byte]string{}}
+}
+
+func handleOf(p string) [handleSize]byte {
+	sum := sha256.Sum256([]byte(p))
+	var fh [handleSize]byte
+	copy(fh[:], sum[:])
+	return fh
+}
+
+func (t *handleTable) handle(p string) []byte {
+	fh := handleOf(p)
+	t.mu.RLock()
+	_, ok := t.paths[fh]
+	t.mu.RUnlock()
+	if !ok {
+		t.mu.Lock()
+		t.paths[fh] = p
+		t.mu.Unlock()
+	}
+	return fh[:]
+}
+
+func (t *handleTable) path(fh []byte) (string, bool) {
+	if len(fh) != handleSize {
+		return "", false
+	}
+	var key [handleSize]byte
+	copy(key[:], fh)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	p, ok := t.paths[key]
+	return p, ok
+}
+
+func fileID(p string) uint64 {
+	fh := handleOf(p)
+	return binary.BigEndian.Uint64(fh[:8])
+}
+
+// ftype3
+const (
+	nf3Reg = 1
+	nf3Dir = 2
+)
+
+// attr is the subset of fattr3 that comes from the virtual file system
+type attr struct {
+	isDir    bool
+	size     int64
+	modified time.Time
+	created  time.Time
+}
+
+func attrOf(obj model.Obj) attr {
+	a := attr{isDir: obj.IsDir(), size: obj.GetSize(), modified: obj.ModTime(), created: obj.CreateTime()}
+	if a.isDir {
+		a.size = 4096
+	}
+	return a
+}
+
+func writeTime(w *xdrWriter, t time.Time) {
+	if t.IsZero() || t.Unix() < 0 {
+		w.uint32(0)
+		w.uint32(0)
+		return
+	}
+	w.uint32(uint32(t.Unix()))
+	w.uint32(uint32(t.Nanosecond()))
+}
+
+// writeFattr writes the fattr3 of the object at path p
+func writeFattr(w *xdrWriter, sess *session, p string, a attr) {
+	mode := os.FileMode(0o644)
+	typ := uint32(nf3Reg)
+	if a.isDir {
+		mode = 0o755
+		typ = nf3Dir
+	}
+	if sess.readOnly {
+		mode &^= 0o222
+	}
+	w.uint32(typ)
+	w.uint32(uint32(mode))
+	if a.isDir {
+		w.uint32(2)
+	} else {
+		w.uint32(1)
+	}
+	// the objects are owned by the caller so that the permission checks of the client pass
+	w.uint32(sess.uid)
+	w.uint32(sess.gid)
+	w.uint64(uint64(a.size))
+	w.uint64(uint64(a.size))
+	w.uint32(0) // rdev
+	w.uint32(0)
+	w.uint64(1) // fsid
+	w.uint64(fileID(p))
+	writeTime(w, a.modified)
+	writeTime(w, a.modified)
+	if a.created.IsZero() {
+		writeTime(w, a.modified)
+	} else {
+		writeTime(w, a.created)
+	}
+}
+
+func writePostOpAttr(w *xdrWriter, sess *session, p string, a *attr) {
+	if a == nil {
+		w.bool(false)
+		return
+	}
+	w.bool(true)
+	writeFattr(w, sess, p, *a)
+}
+
+// writeWcc writes wcc_data without the attributes before the operation
+func writeWcc(w *xdrWriter, sess *session, p string, a *attr) {
+	w.bool(false)
+	writePostOpAttr(w, sess, p, a)
+}
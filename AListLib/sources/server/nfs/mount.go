@@ -0,0 +1,69 @@
+package nfs
+
+import "github.com/OpenListTeam/OpenList/v4/pkg/utils"
+
+// MOUNT procedures
+const (
+	mountProcNull    = 0
+	mountProcMnt     = 1
+	mountProcDump    = 2
+	mountProcUmnt    = 3
+	mountProcUmntAll = 4
+	mountProcExport  = 5
+)
+
+const maxPathLen = 1024
+
+func (s *Server) mountProc(c *rpcCall, w *xdrWriter) uint32 {
+	switch c.proc {
+	case mountProcNull, mountProcUmnt, mountProcUmntAll:
+		return rpcSuccess
+	case mountProcDump:
+		w.bool(false)
+		return rpcSuccess
+	case mountProcExport:
+		// a single export that is open to the clients allowed by the rules
+		w.bool(true)
+		w.string("/")
+		w.bool(false)
+		w.bool(false)
+		return rpcSuccess
+	case mountProcMnt:
+		dirPath := c.args.string(maxPathLen)
+		if c.args.err != nil {
+			return rpcGarbageArgs
+		}
+		status, fh := s.mount(c, dirPath)
+		w.uint32(status)
+		if status == nfs3OK {
+			w.opaque(fh)
+			w.uint32(1)
+			w.uint32(authUnix)
+		}
+		return rpcSuccess
+	default:
+		return rpcProcUnavail
+	}
+}
+
+// mount returns the handle of dirPath, which is relative to the base path of the user
+func (s *Server) mount(c *rpcCall, dirPath string) (uint32, []byte) {
+	sess, ok := s.authorize(c)
+	if !ok {
+		utils.Log.Warnf("[nfs] mount request of %s is rejected", c.remote)
+		return nfs3ErrAcces, nil
+	}
+	p, err := sess.user.JoinPath(dirPath)
+	if err != nil {
+		return nfs3ErrAcces, nil
+	}
+	a, _, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err), nil
+	}
+	if !a.isDir {
+		return nfs3ErrNotDir, nil
+	}
+	utils.Log.Infof("[nfs] %s mounted %s as %s", c.remote, dirPath, sess.user.Username)
+	return nfs3OK, s.handles.handle(p)
+}
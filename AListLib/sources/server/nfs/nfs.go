@@ -0,0 +1,232 @@
+package nfs
+
+import (
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/pkg/errors"
+)
+
+// NFS procedures
+const (
+	nfsProcNull        = 0
+	nfsProcGetattr     = 1
+	nfsProcSetattr     = 2
+	nfsProcLookup      = 3
+	nfsProcAccess      = 4
+	nfsProcReadlink    = 5
+	nfsProcRead        = 6
+	nfsProcWrite       = 7
+	nfsProcCreate      = 8
+	nfsProcMkdir       = 9
+	nfsProcSymlink     = 10
+	nfsProcMknod       = 11
+	nfsProcRemove      = 12
+	nfsProcRmdir       = 13
+	nfsProcRename      = 14
+	nfsProcLink        = 15
+	nfsProcReaddir     = 16
+	nfsProcReaddirplus = 17
+	nfsProcFsstat      = 18
+	nfsProcFsinfo      = 19
+	nfsProcPathconf    = 20
+	nfsProcCommit      = 21
+)
+
+// nfsstat3
+const (
+	nfs3OK             = 0
+	nfs3ErrPerm        = 1
+	nfs3ErrNoEnt       = 2
+	nfs3ErrIO          = 5
+	nfs3ErrAcces       = 13
+	nfs3ErrExist       = 17
+	nfs3ErrNotDir      = 20
+	nfs3ErrIsDir       = 21
+	nfs3ErrInval       = 22
+	nfs3ErrRofs        = 30
+	nfs3ErrNameTooLong = 63
+	nfs3ErrNotEmpty    = 66
+	nfs3ErrStale       = 70
+	nfs3ErrBadHandle   = 10001
+	nfs3ErrNotSupp     = 10004
+	nfs3ErrTooSmall    = 10005
+
+	// statGarbage is returned by the procedures when the arguments can not be decoded
+	statGarbage = ^uint32(0)
+)
+
+const (
+	maxReadSize  = 1024 * 1024
+	maxWriteSize = 1024 * 1024
+	maxNameLen   = 255
+)
+
+type nfsHandler func(s *Server, sess *session, args *xdrReader, w *xdrWriter) uint32
+
+var nfsHandlers = map[uint32]nfsHandler{
+	nfsProcGetattr:     (*Server).getattr,
+	nfsProcSetattr:     (*Server).setattr,
+	nfsProcLookup:      (*Server).lookup,
+	nfsProcAccess:      (*Server).access,
+	nfsProcRead:        (*Server).read,
+	nfsProcWrite:       (*Server).write,
+	nfsProcCreate:      (*Server).create,
+	nfsProcMkdir:       (*Server).mkdir,
+	nfsProcRemove:      (*Server).remove,
+	nfsProcRmdir:       (*Server).rmdir,
+	nfsProcRename:      (*Server).renameProc,
+	nfsProcReaddir:     (*Server).readdir,
+	nfsProcReaddirplus: (*Server).readdirplus,
+	nfsProcFsstat:      (*Server).fsstat,
+	nfsProcFsinfo:      (*Server).fsinfo,
+	nfsProcPathconf:    (*Server).pathconf,
+	nfsProcCommit:      (*Server).commit,
+}
+
+// failWords is the size in words of the results of a failed procedure, the
+// attributes in them are always left out, so all the words are zero
+var failWords = map[uint32]int{
+	nfsProcLookup:      1,
+	nfsProcAccess:      1,
+	nfsProcReadlink:    1,
+	nfsProcRead:        1,
+	nfsProcReaddir:     1,
+	nfsProcReaddirplus: 1,
+	nfsProcFsstat:      1,
+	nfsProcFsinfo:      1,
+	nfsProcPathconf:    1,
+	nfsProcSetattr:     2,
+	nfsProcWrite:       2,
+	nfsProcCreate:      2,
+	nfsProcMkdir:       2,
+	nfsProcSymlink:     2,
+	nfsProcMknod:       2,
+	nfsProcRemove:      2,
+	nfsProcRmdir:       2,
+	nfsProcCommit:      2,
+	nfsProcLink:        3,
+	nfsProcRename:      4,
+}
+
+func (s *Server) nfsProc(c *rpcCall, w *xdrWriter) uint32 {
+	if c.proc == nfsProcNull {
+		return rpcSuccess
+	}
+	if c.proc > nfsProcCommit {
+		return rpcProcUnavail
+	}
+	var res xdrWriter
+	status := uint32(nfs3ErrNotSupp)
+	if h, ok := nfsHandlers[c.proc]; ok {
+		if sess, ok := s.authorize(c); ok {
+			status = h(s, sess, c.args, &res)
+		} else {
+			status = nfs3ErrAcces
+		}
+	}
+	if status == statGarbage {
+		return rpcGarbageArgs
+	}
+	w.uint32(status)
+	if status == nfs3OK {
+		w.Write(res.Bytes())
+		return rpcSuccess
+	}
+	for i := 0; i < failWords[c.proc]; i++ {
+		w.uint32(0)
+	}
+	return rpcSuccess
+}
+
+func statusOf(err error) uint32 {
+	switch {
+	case err == nil:
+		return nfs3OK
+	case errors.Is(err, errReadOnly):
+		return nfs3ErrRofs
+	case errors.Is(errors.Cause(err), errs.PermissionDenied):
+		return nfs3ErrAcces
+	case errs.IsNotFoundError(err):
+		return nfs3ErrNoEnt
+	case errs.IsObjectAlreadyExists(err):
+		return nfs3ErrExist
+	case errors.Is(errors.Cause(err), errs.NotFolder):
+		return nfs3ErrNotDir
+	case errs.IsNotSupportError(err), errs.IsNotImplementError(err), errors.Is(errors.Cause(err), errs.UploadNotSupported):
+		return nfs3ErrNotSupp
+	default:
+		return nfs3ErrIO
+	}
+}
+
+// pathOf returns the path of the file handle
+func (s *Server) pathOf(args *xdrReader) (string, uint32) {
+	fh := args.opaque(64)
+	if args.err != nil {
+		return "", statGarbage
+	}
+	if len(fh) != handleSize {
+		return "", nfs3ErrBadHandle
+	}
+	p, ok := s.handles.path(fh)
+	if !ok {
+		return "", nfs3ErrStale
+	}
+	return p, nfs3OK
+}
+
+// childOf returns the path of name in the directory of the handle
+func (s *Server) childOf(sess *session, args *xdrReader) (string, string, uint32) {
+	dir, status := s.pathOf(args)
+	name := args.string(maxPathLen)
+	if args.err != nil {
+		return "", "", statGarbage
+	}
+	if status != nfs3OK {
+		return "", "", status
+	}
+	if len(name) > maxNameLen {
+		return "", "", nfs3ErrNameTooLong
+	}
+	switch name {
+	case ".":
+		return dir, dir, nfs3OK
+	case "..":
+		if dir == sess.user.BasePath {
+			return dir, dir, nfs3OK
+		}
+		return dir, stdpath.Dir(dir), nfs3OK
+	}
+	if name == "" || stdpath.Base(name) != name {
+		return "", "", nfs3ErrInval
+	}
+	return dir, stdpath.Join(dir, name), nfs3OK
+}
+
+// sattr is the part of sattr3 that can be applied, the mode, the owner and
+// the times are accepted and ignored
+type sattr struct {
+	setSize bool
+	size    uint64
+}
+
+func readSattr(args *xdrReader) sattr {
+	var sa sattr
+	for i := 0; i < 3; i++ {
+		// mode, uid, gid
+		if args.bool() {
+			args.uint32()
+		}
+	}
+	if sa.setSize = args.bool(); sa.setSize {
+		sa.size = args.uint64()
+	}
+	for i := 0; i < 2; i++ {
+		// atime, mtime
+		if args.uint32() == 2 {
+			args.uint64()
+		}
+	}
+	return sa
+}
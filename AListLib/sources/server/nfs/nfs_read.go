@@ -0,0 +1,309 @@
+package nfs
+
+import (
+	"errors"
+	"io"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+)
+
+// ACCESS bits
+const (
+	accessRead    = 0x01
+	accessLookup  = 0x02
+	accessModify  = 0x04
+	accessExtend  = 0x08
+	accessDelete  = 0x10
+	accessExecute = 0x20
+)
+
+func (s *Server) getattr(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	p, status := s.pathOf(args)
+	if status != nfs3OK {
+		return status
+	}
+	a, _, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	writeFattr(w, sess, p, a)
+	return nfs3OK
+}
+
+func (s *Server) lookup(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	dir, p, status := s.childOf(sess, args)
+	if status != nfs3OK {
+		return status
+	}
+	a, _, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	w.opaque(s.handles.handle(p))
+	writePostOpAttr(w, sess, p, &a)
+	writePostOpAttr(w, sess, dir, nil)
+	return nfs3OK
+}
+
+func (s *Server) access(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	p, status := s.pathOf(args)
+	mask := args.uint32()
+	if args.err != nil {
+		return statGarbage
+	}
+	if status != nfs3OK {
+		return status
+	}
+	a, _, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	granted := uint32(accessRead | accessLookup | accessExecute)
+	if a.isDir {
+		if checkWriteIn(sess, p) == nil {
+			granted |= accessModify | accessExtend | accessDelete
+		}
+	} else {
+		if checkWrite(sess, p) == nil {
+			granted |= accessModify | accessExtend
+		}
+		if checkRemove(sess, p) == nil {
+			granted |= accessDelete
+		}
+	}
+	writePostOpAttr(w, sess, p, &a)
+	w.uint32(mask & granted)
+	return nfs3OK
+}
+
+func (s *Server) read(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	p, status := s.pathOf(args)
+	offset := int64(args.uint64())
+	count := args.uint32()
+	if args.err != nil || offset < 0 {
+		return statGarbage
+	}
+	if status != nfs3OK {
+		return status
+	}
+	a, _, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	if a.isDir {
+		return nfs3ErrIsDir
+	}
+	buf := make([]byte, min(count, maxReadSize))
+	var n int
+	if sp := s.spools.get(p); sp != nil {
+		n, err = sp.readAt(buf, offset)
+	} else if offset < a.size {
+		ctx, cerr := readCtx(sess, p)
+		if cerr != nil {
+			return statusOf(cerr)
+		}
+		n, err = s.readers.readAt(ctx, p, buf, offset)
+	}
+	eof := offset+int64(n) >= a.size
+	if errors.Is(err, io.EOF) {
+		eof, err = true, nil
+	}
+	if err != nil {
+		return statusOf(err)
+	}
+	writePostOpAttr(w, sess, p, &a)
+	w.uint32(uint32(n))
+	w.bool(eof)
+	w.opaque(buf[:n])
+	return nfs3OK
+}
+
+// entries returns the entries of the directory p with . and .. first
+func (s *Server) entries(sess *session, p string) ([]dirEntry, attr, error) {
+	a, _, err := s.stat(sess, p)
+	if err != nil {
+		return nil, a, err
+	}
+	if !a.isDir {
+		return nil, a, errs.NotFolder
+	}
+	list, err := s.list(sess, p)
+	if err != nil {
+		return nil, a, err
+	}
+	entries := append([]dirEntry{{name: ".", attr: a}, {name: "..", attr: attr{isDir: true, size: 4096}}}, list...)
+	return entries, a, nil
+}
+
+// entryPath returns the path of the entry in the directory p
+func entryPath(sess *session, p, name string) string {
+	switch name {
+	case ".":
+		return p
+	case "..":
+		if p == sess.user.BasePath {
+			return p
+		}
+		return stdpath.Dir(p)
+	}
+	return stdpath.Join(p, name)
+}
+
+func xdrLen(s string) int {
+	return 4 + (len(s)+3)/4*4
+}
+
+func (s *Server) readdir(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	p, status := s.pathOf(args)
+	cookie := args.uint64()
+	args.fixed(8)
+	count := int(args.uint32())
+	if args.err != nil {
+		return statGarbage
+	}
+	if status != nfs3OK {
+		return status
+	}
+	entries, a, err := s.entries(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	writePostOpAttr(w, sess, p, &a)
+	w.fixed(make([]byte, 8))
+	// status, attributes, verifier and the end of the list
+	size := 4 + 88 + 8 + 8
+	i := int(min(cookie, uint64(len(entries))))
+	for ; i < len(entries); i++ {
+		e := entries[i]
+		n := 4 + 8 + xdrLen(e.name) + 8
+		if size+n > count {
+			break
+		}
+		size += n
+		w.bool(true)
+		w.uint64(fileID(entryPath(sess, p, e.name)))
+		w.string(e.name)
+		w.uint64(uint64(i + 1))
+	}
+	if i < len(entries) && uint64(i) == cookie {
+		return nfs3ErrTooSmall
+	}
+	w.bool(false)
+	w.bool(i == len(entries))
+	return nfs3OK
+}
+
+func (s *Server) readdirplus(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	p, status := s.pathOf(args)
+	cookie := args.uint64()
+	args.fixed(8)
+	dirCount := int(args.uint32())
+	maxCount := int(args.uint32())
+	if args.err != nil {
+		return statGarbage
+	}
+	if status != nfs3OK {
+		return status
+	}
+	entries, a, err := s.entries(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	writePostOpAttr(w, sess, p, &a)
+	w.fixed(make([]byte, 8))
+	size, dirSize := 4+88+8+8, 0
+	i := int(min(cookie, uint64(len(entries))))
+	for ; i < len(entries); i++ {
+		e := entries[i]
+		n := 8 + xdrLen(e.name) + 8
+		// the entry, the attributes and the handle
+		full := 4 + n + 88 + 4 + 4 + handleSize
+		if size+full > maxCount || dirSize+n > dirCount {
+			break
+		}
+		size += full
+		dirSize += n
+		ep := entryPath(sess, p, e.name)
+		w.bool(true)
+		w.uint64(fileID(ep))
+		w.string(e.name)
+		w.uint64(uint64(i + 1))
+		writePostOpAttr(w, sess, ep, &e.attr)
+		w.bool(true)
+		w.opaque(s.handles.handle(ep))
+	}
+	if i < len(entries) && uint64(i) == cookie {
+		return nfs3ErrTooSmall
+	}
+	w.bool(false)
+	w.bool(i == len(entries))
+	return nfs3OK
+}
+
+func (s *Server) fsstat(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	p, status := s.pathOf(args)
+	if status != nfs3OK {
+		return status
+	}
+	a, _, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	writePostOpAttr(w, sess, p, &a)
+	// the space of the storages is unknown here, report plenty of it
+	const space, files = 1 << 50, 1 << 40
+	w.uint64(space)
+	w.uint64(space)
+	w.uint64(space)
+	w.uint64(files)
+	w.uint64(files)
+	w.uint64(files)
+	w.uint32(0)
+	return nfs3OK
+}
+
+func (s *Server) fsinfo(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	p, status := s.pathOf(args)
+	if status != nfs3OK {
+		return status
+	}
+	a, _, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	writePostOpAttr(w, sess, p, &a)
+	w.uint32(maxReadSize)
+	w.uint32(maxReadSize)
+	w.uint32(4096)
+	w.uint32(maxWriteSize)
+	w.uint32(maxWriteSize)
+	w.uint32(4096)
+	w.uint32(64 * 1024)
+	w.uint64(1 << 62)
+	// time_delta
+	w.uint32(0)
+	w.uint32(1)
+	// FSF3_HOMOGENEOUS | FSF3_CANSETTIME
+	w.uint32(0x08 | 0x10)
+	return nfs3OK
+}
+
+func (s *Server) pathconf(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	p, status := s.pathOf(args)
+	if status != nfs3OK {
+		return status
+	}
+	a, _, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	writePostOpAttr(w, sess, p, &a)
+	w.uint32(1)
+	w.uint32(maxNameLen)
+	w.bool(true)  // no_trunc
+	w.bool(true)  // chown_restricted
+	w.bool(false) // case_insensitive
+	w.bool(true)  // case_preserving
+	return nfs3OK
+}
@@ -0,0 +1,414 @@
+package nfs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+// testClient is a minimal NFSv3 client
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	xid  uint32
+}
+
+func dial(t *testing.T, addr string) *testClient {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *testClient) call(prog, proc uint32, args func(w *xdrWriter)) *xdrReader {
+	c.t.Helper()
+	c.xid++
+	w := &xdrWriter{}
+	w.uint32(c.xid)
+	w.uint32(0)
+	w.uint32(2)
+	w.uint32(prog)
+	w.uint32(3)
+	w.uint32(proc)
+	var cred xdrWriter
+	cred.uint32(0)
+	cred.string("test")
+	cred.uint32(1000)
+	cred.uint32(1000)
+	cred.uint32(0)
+	w.uint32(authUnix)
+	w.opaque(cred.Bytes())
+	w.uint32(authNone)
+	w.uint32(0)
+	if args != nil {
+		args(w)
+	}
+	if err := writeRecord(c.conn, w.Bytes()); err != nil {
+		c.t.Fatal(err)
+	}
+	record, err := readRecord(c.r)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	r := &xdrReader{buf: record}
+	if xid := r.uint32(); xid != c.xid {
+		c.t.Fatalf("unexpected xid %d", xid)
+	}
+	if r.uint32() != 1 || r.uint32() != 0 {
+		c.t.Fatal("call is not accepted")
+	}
+	r.uint32()
+	r.opaque(400)
+	if stat := r.uint32(); stat != rpcSuccess {
+		c.t.Fatalf("unexpected accept stat %d", stat)
+	}
+	return r
+}
+
+func (c *testClient) mount(p string) (uint32, []byte) {
+	r := c.call(progMount, mountProcMnt, func(w *xdrWriter) { w.string(p) })
+	if status := r.uint32(); status != nfs3OK {
+		return status, nil
+	}
+	return nfs3OK, r.opaque(64)
+}
+
+// readFattr returns the type and the size of a fattr3
+func readFattr(r *xdrReader) (uint32, uint64) {
+	typ := r.uint32()
+	r.next(16)
+	size := r.uint64()
+	r.next(84 - 28)
+	return typ, size
+}
+
+func skipPostOpAttr(r *xdrReader) {
+	if r.bool() {
+		readFattr(r)
+	}
+}
+
+func (c *testClient) lookup(dir []byte, name string) (uint32, []byte) {
+	r := c.call(progNFS, nfsProcLookup, func(w *xdrWriter) {
+		w.opaque(dir)
+		w.string(name)
+	})
+	if status := r.uint32(); status != nfs3OK {
+		return status, nil
+	}
+	return nfs3OK, r.opaque(64)
+}
+
+func (c *testClient) getattr(fh []byte) (uint32, uint64) {
+	r := c.call(progNFS, nfsProcGetattr, func(w *xdrWriter) { w.opaque(fh) })
+	if status := r.uint32(); status != nfs3OK {
+		c.t.Fatalf("getattr failed: %d", status)
+	}
+	return readFattr(r)
+}
+
+func (c *testClient) read(fh []byte, offset uint64, count uint32) ([]byte, bool) {
+	r := c.call(progNFS, nfsProcRead, func(w *xdrWriter) {
+		w.opaque(fh)
+		w.uint64(offset)
+		w.uint32(count)
+	})
+	if status := r.uint32(); status != nfs3OK {
+		c.t.Fatalf("read failed: %d", status)
+	}
+	skipPostOpAttr(r)
+	r.uint32()
+	eof := r.bool()
+	return r.opaque(maxReadSize), eof
+}
+
+func (c *testClient) write(fh []byte, offset uint64, data string) uint32 {
+	r := c.call(progNFS, nfsProcWrite, func(w *xdrWriter) {
+		w.opaque(fh)
+		w.uint64(offset)
+		w.uint32(uint32(len(data)))
+		w.uint32(unstable)
+		w.string(data)
+	})
+	return r.uint32()
+}
+
+func (c *testClient) commit(fh []byte) uint32 {
+	r := c.call(progNFS, nfsProcCommit, func(w *xdrWriter) {
+		w.opaque(fh)
+		w.uint64(0)
+		w.uint32(0)
+	})
+	return r.uint32()
+}
+
+func (c *testClient) create(dir []byte, name string) (uint32, []byte) {
+	r := c.call(progNFS, nfsProcCreate, func(w *xdrWriter) {
+		w.opaque(dir)
+		w.string(name)
+		w.uint32(createGuarded)
+		for i := 0; i < 6; i++ {
+			w.uint32(0)
+		}
+	})
+	if status := r.uint32(); status != nfs3OK {
+		return status, nil
+	}
+	r.bool()
+	return nfs3OK, r.opaque(64)
+}
+
+func (c *testClient) mkdir(dir []byte, name string) uint32 {
+	r := c.call(progNFS, nfsProcMkdir, func(w *xdrWriter) {
+		w.opaque(dir)
+		w.string(name)
+		for i := 0; i < 6; i++ {
+			w.uint32(0)
+		}
+	})
+	return r.uint32()
+}
+
+func (c *testClient) rename(fromDir []byte, fromName string, toDir []byte, toName string) uint32 {
+	r := c.call(progNFS, nfsProcRename, func(w *xdrWriter) {
+		w.opaque(fromDir)
+		w.string(fromName)
+		w.opaque(toDir)
+		w.string(toName)
+	})
+	return r.uint32()
+}
+
+func (c *testClient) remove(proc uint32, dir []byte, name string) uint32 {
+	r := c.call(progNFS, proc, func(w *xdrWriter) {
+		w.opaque(dir)
+		w.string(name)
+	})
+	return r.uint32()
+}
+
+func (c *testClient) readdirplus(dir []byte) map[string]uint64 {
+	entries := map[string]uint64{}
+	var cookie uint64
+	for {
+		r := c.call(progNFS, nfsProcReaddirplus, func(w *xdrWriter) {
+			w.opaque(dir)
+			w.uint64(cookie)
+			w.fixed(make([]byte, 8))
+			w.uint32(512)
+			w.uint32(1024)
+		})
+		if status := r.uint32(); status != nfs3OK {
+			c.t.Fatalf("readdirplus failed: %d", status)
+		}
+		skipPostOpAttr(r)
+		r.fixed(8)
+		for r.bool() {
+			r.uint64()
+			name := r.string(maxNameLen)
+			cookie = r.uint64()
+			var size uint64
+			if r.bool() {
+				_, size = readFattr(r)
+			}
+			if r.bool() {
+				r.opaque(64)
+			}
+			entries[name] = size
+		}
+		if r.bool() || r.err != nil {
+			return entries
+		}
+	}
+}
+
+func serve(t *testing.T, exports ...Export) (*Server, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(exports)
+	go func() { _ = s.Serve(ln) }()
+	t.Cleanup(func() { _ = s.Close() })
+	return s, ln.Addr().String()
+}
+
+func loopback(user string, readOnly bool) Export {
+	_, ipNet, _ := net.ParseCIDR("127.0.0.0/8")
+	return Export{Net: ipNet, User: user, ReadOnly: readOnly}
+}
+
+func TestNFS(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello world"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateUser(&model.User{Username: "nfs", Role: model.GENERAL, BasePath: "/", Permission: 0xFFFF}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/local",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, dir),
+	}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+
+	_, addr := serve(t, loopback("nfs", false))
+	c := dial(t, addr)
+	status, root := c.mount("/")
+	if status != nfs3OK {
+		t.Fatalf("mount failed: %d", status)
+	}
+	status, local := c.lookup(root, "local")
+	if status != nfs3OK {
+		t.Fatalf("lookup failed: %d", status)
+	}
+	if status, _ := c.lookup(local, "missing.txt"); status != nfs3ErrNoEnt {
+		t.Fatalf("expected NOENT, got %d", status)
+	}
+	_, hello := c.lookup(local, "hello.txt")
+	if typ, size := c.getattr(hello); typ != nf3Reg || size != 11 {
+		t.Fatalf("unexpected attributes: %d %d", typ, size)
+	}
+	if data, eof := c.read(hello, 6, 100); string(data) != "world" || !eof {
+		t.Fatalf("unexpected read: %q %v", data, eof)
+	}
+
+	// a new file is kept in the spool until it is committed
+	status, fh := c.create(local, "new.txt")
+	if status != nfs3OK {
+		t.Fatalf("create failed: %d", status)
+	}
+	if status, _ := c.create(local, "new.txt"); status != nfs3ErrExist {
+		t.Fatalf("guarded create of an existing file should fail, got %d", status)
+	}
+	if c.write(fh, 3, "def") != nfs3OK || c.write(fh, 0, "abc") != nfs3OK {
+		t.Fatal("write failed")
+	}
+	if data, _ := c.read(fh, 0, 100); string(data) != "abcdef" {
+		t.Fatalf("unexpected read of the spool: %q", data)
+	}
+	if c.commit(fh) != nfs3OK {
+		t.Fatal("commit failed")
+	}
+	assertFile(t, filepath.Join(dir, "new.txt"), "abcdef")
+
+	// existing files are written in place
+	if c.write(hello, 0, "HELLO") != nfs3OK || c.commit(hello) != nfs3OK {
+		t.Fatal("write of an existing file failed")
+	}
+	assertFile(t, filepath.Join(dir, "hello.txt"), "HELLO world")
+
+	if status := c.mkdir(local, "sub"); status != nfs3OK {
+		t.Fatalf("mkdir failed: %d", status)
+	}
+	_, sub := c.lookup(local, "sub")
+	// the file taking the new name in the source is kept
+	if err := os.WriteFile(filepath.Join(dir, "moved.txt"), []byte("kept"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if status := c.rename(local, "new.txt", sub, "moved.txt"); status != nfs3OK {
+		t.Fatalf("rename failed: %d", status)
+	}
+	assertFile(t, filepath.Join(dir, "sub", "moved.txt"), "abcdef")
+	assertFile(t, filepath.Join(dir, "moved.txt"), "kept")
+	if err := os.Remove(filepath.Join(dir, "moved.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := c.readdirplus(local)
+	if len(entries) != 4 || entries["hello.txt"] != 11 {
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+	if status := c.remove(nfsProcRmdir, local, "sub"); status != nfs3ErrNotEmpty {
+		t.Fatalf("expected NOTEMPTY, got %d", status)
+	}
+	if status := c.remove(nfsProcRemove, sub, "moved.txt"); status != nfs3OK {
+		t.Fatalf("remove failed: %d", status)
+	}
+	if status := c.remove(nfsProcRmdir, local, "sub"); status != nfs3OK {
+		t.Fatalf("rmdir failed: %d", status)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub")); !os.IsNotExist(err) {
+		t.Fatalf("sub should be removed: %v", err)
+	}
+
+	// the rename across the storages is done before the reply
+	otherDir := t.TempDir()
+	if _, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/other",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, otherDir),
+	}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	for name, data := range map[string]string{"cross.txt": "cross", "renamed.txt": "kept"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, otherRoot := c.lookup(root, "other")
+	if status := c.rename(local, "cross.txt", otherRoot, "renamed.txt"); status != nfs3OK {
+		t.Fatalf("rename across the storages failed: %d", status)
+	}
+	assertFile(t, filepath.Join(otherDir, "renamed.txt"), "cross")
+	assertFile(t, filepath.Join(dir, "renamed.txt"), "kept")
+	if _, err := os.Stat(filepath.Join(dir, "cross.txt")); !os.IsNotExist(err) {
+		t.Fatalf("the renamed file is kept: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "renamed.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	// a read-only export
+	_, addr = serve(t, loopback("nfs", true))
+	c = dial(t, addr)
+	_, root = c.mount("/local")
+	if status, _ := c.create(root, "ro.txt"); status != nfs3ErrRofs {
+		t.Fatalf("expected ROFS, got %d", status)
+	}
+
+	// no rule matches the client
+	_, other, _ := net.ParseCIDR("10.0.0.0/8")
+	_, addr = serve(t, Export{Net: other, User: "nfs"})
+	c = dial(t, addr)
+	if status, _ := c.mount("/"); status != nfs3ErrAcces {
+		t.Fatalf("expected ACCES, got %d", status)
+	}
+}
+
+func assertFile(t *testing.T, p, expected string) {
+	t.Helper()
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expected {
+		t.Fatalf("unexpected content of %s: %q", p, data)
+	}
+}
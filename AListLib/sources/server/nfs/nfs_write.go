@@ -0,0 +1,340 @@
+package nfs
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+)
+
+// stable_how
+const (
+	unstable = 0
+	fileSync = 2
+)
+
+// createhow3
+const (
+	createGuarded   = 1
+	createExclusive = 2
+)
+
+func (s *Server) setattr(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	p, status := s.pathOf(args)
+	sa := readSattr(args)
+	if args.bool() {
+		args.uint64()
+	}
+	if args.err != nil {
+		return statGarbage
+	}
+	if status != nfs3OK {
+		return status
+	}
+	a, obj, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	if sa.setSize {
+		if a.isDir {
+			return nfs3ErrIsDir
+		}
+		if err := checkWrite(sess, p); err != nil {
+			return statusOf(err)
+		}
+		if int64(sa.size) != a.size || s.spools.get(p) != nil {
+			sp, err := s.spools.open(sess.ctx, p, obj)
+			if err != nil {
+				return statusOf(err)
+			}
+			if err := sp.truncate(int64(sa.size)); err != nil {
+				return statusOf(err)
+			}
+			a = sp.attr()
+		}
+	}
+	writeWcc(w, sess, p, &a)
+	return nfs3OK
+}
+
+func (s *Server) write(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	p, status := s.pathOf(args)
+	offset := int64(args.uint64())
+	args.uint32() // count, the length of data is used
+	stable := args.uint32()
+	data := args.opaque(maxWriteSize)
+	if args.err != nil || offset < 0 {
+		return statGarbage
+	}
+	if status != nfs3OK {
+		return status
+	}
+	a, obj, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	if a.isDir {
+		return nfs3ErrIsDir
+	}
+	if err := checkWrite(sess, p); err != nil {
+		return statusOf(err)
+	}
+	sp, err := s.spools.open(sess.ctx, p, obj)
+	if err != nil {
+		return statusOf(err)
+	}
+	if err := sp.writeAt(data, offset); err != nil {
+		return statusOf(err)
+	}
+	committed := uint32(unstable)
+	if stable != unstable {
+		if err := s.spools.flush(sp); err != nil {
+			return statusOf(err)
+		}
+		committed = fileSync
+	}
+	a = sp.attr()
+	writeWcc(w, sess, p, &a)
+	w.uint32(uint32(len(data)))
+	w.uint32(committed)
+	w.fixed(s.writeVerf[:])
+	return nfs3OK
+}
+
+func (s *Server) commit(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	p, status := s.pathOf(args)
+	args.uint64()
+	args.uint32()
+	if args.err != nil {
+		return statGarbage
+	}
+	if status != nfs3OK {
+		return status
+	}
+	if sp := s.spools.get(p); sp != nil {
+		if err := s.spools.flush(sp); err != nil {
+			return statusOf(err)
+		}
+	}
+	a, _, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	writeWcc(w, sess, p, &a)
+	w.fixed(s.writeVerf[:])
+	return nfs3OK
+}
+
+// writeCreated writes the results of CREATE and MKDIR
+func (s *Server) writeCreated(sess *session, w *xdrWriter, p string, a attr) {
+	w.bool(true)
+	w.opaque(s.handles.handle(p))
+	writePostOpAttr(w, sess, p, &a)
+	writeWcc(w, sess, p, nil)
+}
+
+func (s *Server) create(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	dir, p, status := s.childOf(sess, args)
+	how := args.uint32()
+	var sa sattr
+	if how == createExclusive {
+		args.fixed(8)
+	} else {
+		sa = readSattr(args)
+	}
+	if args.err != nil {
+		return statGarbage
+	}
+	if status != nfs3OK {
+		return status
+	}
+	if p == dir {
+		return nfs3ErrExist
+	}
+	if err := checkWriteIn(sess, dir); err != nil {
+		return statusOf(err)
+	}
+	a, obj, err := s.stat(sess, p)
+	switch {
+	case err == nil:
+		if how == createGuarded || how == createExclusive {
+			return nfs3ErrExist
+		}
+		if a.isDir {
+			return nfs3ErrIsDir
+		}
+		if sa.setSize && int64(sa.size) != a.size {
+			sp, err := s.spools.open(sess.ctx, p, obj)
+			if err != nil {
+				return statusOf(err)
+			}
+			if err := sp.truncate(int64(sa.size)); err != nil {
+				return statusOf(err)
+			}
+			a = sp.attr()
+		}
+	case errs.IsNotFoundError(err):
+		// the file is uploaded when the client commits or stops writing
+		sp, err := s.spools.create(sess.ctx, p)
+		if err != nil {
+			return statusOf(err)
+		}
+		a = sp.attr()
+	default:
+		return statusOf(err)
+	}
+	s.writeCreated(sess, w, p, a)
+	return nfs3OK
+}
+
+func (s *Server) mkdir(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	dir, p, status := s.childOf(sess, args)
+	readSattr(args)
+	if args.err != nil {
+		return statGarbage
+	}
+	if status != nfs3OK {
+		return status
+	}
+	if err := checkWriteIn(sess, dir); err != nil {
+		return statusOf(err)
+	}
+	if _, _, err := s.stat(sess, p); err == nil {
+		return nfs3ErrExist
+	}
+	if err := fs.MakeDir(sess.ctx, p); err != nil {
+		return statusOf(err)
+	}
+	a, _, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	s.writeCreated(sess, w, p, a)
+	return nfs3OK
+}
+
+func (s *Server) remove(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	_, p, status := s.childOf(sess, args)
+	if args.err != nil {
+		return statGarbage
+	}
+	if status != nfs3OK {
+		return status
+	}
+	if err := checkRemove(sess, p); err != nil {
+		return statusOf(err)
+	}
+	a, _, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	if a.isDir {
+		return nfs3ErrIsDir
+	}
+	if err := s.removePath(sess, p); err != nil {
+		return statusOf(err)
+	}
+	writeWcc(w, sess, p, nil)
+	return nfs3OK
+}
+
+func (s *Server) rmdir(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	_, p, status := s.childOf(sess, args)
+	if args.err != nil {
+		return statGarbage
+	}
+	if status != nfs3OK {
+		return status
+	}
+	if err := checkRemove(sess, p); err != nil {
+		return statusOf(err)
+	}
+	if status := s.checkEmptyDir(sess, p); status != nfs3OK {
+		return status
+	}
+	if err := s.removePath(sess, p); err != nil {
+		return statusOf(err)
+	}
+	writeWcc(w, sess, p, nil)
+	return nfs3OK
+}
+
+func (s *Server) checkEmptyDir(sess *session, p string) uint32 {
+	a, _, err := s.stat(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	if !a.isDir {
+		return nfs3ErrNotDir
+	}
+	entries, err := s.list(sess, p)
+	if err != nil {
+		return statusOf(err)
+	}
+	if len(entries) > 0 {
+		return nfs3ErrNotEmpty
+	}
+	return nfs3OK
+}
+
+func (s *Server) removePath(sess *session, p string) error {
+	pending := s.spools.get(p) != nil
+	s.spools.remove(p)
+	s.readers.drop(p)
+	err := fs.Remove(sess.ctx, p)
+	if pending && errs.IsNotFoundError(err) {
+		// the file has never been uploaded
+		return nil
+	}
+	return err
+}
+
+func (s *Server) renameProc(sess *session, args *xdrReader, w *xdrWriter) uint32 {
+	_, src, status := s.childOf(sess, args)
+	_, dst, dstStatus := s.childOf(sess, args)
+	if args.err != nil {
+		return statGarbage
+	}
+	if status != nfs3OK {
+		return status
+	}
+	if dstStatus != nfs3OK {
+		return dstStatus
+	}
+	if src == dst {
+		writeWcc(w, sess, src, nil)
+		writeWcc(w, sess, dst, nil)
+		return nfs3OK
+	}
+	if err := checkRename(sess, src, dst); err != nil {
+		return statusOf(err)
+	}
+	a, _, err := s.stat(sess, src)
+	if err != nil {
+		return statusOf(err)
+	}
+	// the target is replaced
+	if dstAttr, _, err := s.stat(sess, dst); err == nil {
+		if err := checkRemove(sess, dst); err != nil {
+			return statusOf(err)
+		}
+		if a.isDir != dstAttr.isDir {
+			if a.isDir {
+				return nfs3ErrNotDir
+			}
+			return nfs3ErrIsDir
+		}
+		if dstAttr.isDir {
+			if status := s.checkEmptyDir(sess, dst); status != nfs3OK {
+				return status
+			}
+		}
+		if err := s.removePath(sess, dst); err != nil {
+			return statusOf(err)
+		}
+	}
+	if err := s.rename(sess, src, dst); err != nil {
+		return statusOf(err)
+	}
+	writeWcc(w, sess, src, nil)
+	writeWcc(w, sess, dst, nil)
+	return nfs3OK
+}
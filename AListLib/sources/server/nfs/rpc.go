@@ -0,0 +1,323 @@
+// Package nfs implements an NFSv3 (RFC 1813) server over TCP that exports the
+// virtual file system. The MOUNT and NFS programs are served on the same port,
+// there is no portmapper, so clients mount with the port and mountport options:
+//
+//	mount -t nfs -o vers=3,proto=tcp,port=2049,mountport=2049,nolock host:/ /mnt
+package nfs
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+const (
+	progMount    = 100005
+	progNFS      = 100003
+	mountVersion = 3
+	nfsVersion   = 3
+
+	// the largest WRITE is maxWriteSize, the rest is room for the headers
+	maxRecordSize = maxWriteSize + 64*1024
+)
+
+// accept_stat of a reply
+const (
+	rpcSuccess      = 0
+	rpcProgUnavail  = 1
+	rpcProgMismatch = 2
+	rpcProcUnavail  = 3
+	rpcGarbageArgs  = 4
+)
+
+const (
+	authNone = 0
+	authUnix = 1
+)
+
+// Export authorises the clients in Net as User
+type Export struct {
+	Net *net.IPNet
+	// User the clients act as, the guest is used if it is empty
+	User     string
+	ReadOnly bool
+}
+
+type Server struct {
+	Exports []Export
+
+	handles *handleTable
+	spools  *spoolTable
+	readers *readerTable
+	// writeVerf changes with every start so that clients resend the uncommitted writes
+	writeVerf [8]byte
+
+	mu     sync.Mutex
+	ln     net.Listener
+	conns  map[net.Conn]struct{}
+	closed bool
+	done   chan struct{}
+}
+
+func NewServer(exports []Export) *Server {
+	s := &Server{
+		Exports: exports,
+		handles: newHandleTable(),
+		readers: newReaderTable(),
+		conns:   map[net.Conn]struct{}{},
+		done:    make(chan struct{}),
+	}
+	s.spools = newSpoolTable(s.readers)
+	_, _ = rand.Read(s.writeVerf[:])
+	return s
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = ln.Close()
+		return net.ErrClosed
+	}
+	s.ln = ln
+	s.mu.Unlock()
+	go s.spools.run(s.done)
+	go s.readers.run(s.done)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops serving and uploads the pending writes
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	var err error
+	if s.ln != nil {
+		err = s.ln.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.spools.flushAll()
+	s.readers.closeAll()
+	return err
+}
+
+type rpcCall struct {
+	xid    uint32
+	prog   uint32
+	vers   uint32
+	proc   uint32
+	uid    uint32
+	gid    uint32
+	remote net.IP
+	args   *xdrReader
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+	var remote net.IP
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		remote = addr.IP
+	}
+	r := bufio.NewReader(conn)
+	var writeMu sync.Mutex
+	for {
+		record, err := readRecord(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				utils.Log.Debugf("[nfs] failed to read from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		// calls are answered concurrently, the client matches the replies by xid
+		go func() {
+			reply := s.handleRecord(record, remote)
+			if reply == nil {
+				return
+			}
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if err := writeRecord(conn, reply); err != nil {
+				utils.Log.Debugf("[nfs] failed to reply to %s: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// readRecord reads a record of the record marking standard (RFC 5531 section 11)
+func readRecord(r io.Reader) ([]byte, error) {
+	var record []byte
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, err
+		}
+		h := binary.BigEndian.Uint32(header[:])
+		size := int(h & 0x7fffffff)
+		if len(record)+size > maxRecordSize {
+			return nil, errors.New("record is too large")
+		}
+		frag := make([]byte, size)
+		if _, err := io.ReadFull(r, frag); err != nil {
+			return nil, err
+		}
+		record = append(record, frag...)
+		if h&0x80000000 != 0 {
+			return record, nil
+		}
+	}
+}
+
+func writeRecord(w io.Writer, data []byte) error {
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data))|0x80000000)
+	copy(buf[4:], data)
+	_, err := w.Write(buf)
+	return err
+}
+
+func (s *Server) handleRecord(record []byte, remote net.IP) []byte {
+	args := &xdrReader{buf: record}
+	c := &rpcCall{xid: args.uint32(), remote: remote, args: args}
+	if msgType := args.uint32(); args.err != nil || msgType != 0 {
+		return nil
+	}
+	rpcVersion := args.uint32()
+	c.prog, c.vers, c.proc = args.uint32(), args.uint32(), args.uint32()
+	credFlavor := args.uint32()
+	cred := args.opaque(400)
+	args.uint32()
+	args.opaque(400)
+	if args.err != nil {
+		return nil
+	}
+	w := &xdrWriter{}
+	w.uint32(c.xid)
+	w.uint32(1) // REPLY
+	if rpcVersion != 2 {
+		// MSG_DENIED, RPC_MISMATCH
+		w.uint32(1)
+		w.uint32(0)
+		w.uint32(2)
+		w.uint32(2)
+		return w.Bytes()
+	}
+	if credFlavor == authUnix {
+		cr := &xdrReader{buf: cred}
+		cr.uint32()    // stamp
+		cr.string(255) // machine name
+		c.uid, c.gid = cr.uint32(), cr.uint32()
+	}
+	w.uint32(0) // MSG_ACCEPTED
+	w.uint32(authNone)
+	w.uint32(0)
+
+	var res xdrWriter
+	var stat uint32
+	switch c.prog {
+	case progMount:
+		stat = s.dispatch(c, mountVersion, s.mountProc, &res)
+	case progNFS:
+		stat = s.dispatch(c, nfsVersion, s.nfsProc, &res)
+	default:
+		stat = rpcProgUnavail
+	}
+	w.uint32(stat)
+	switch stat {
+	case rpcSuccess:
+		w.Write(res.Bytes())
+	case rpcProgMismatch:
+		w.uint32(c.vers)
+		w.uint32(c.vers)
+	}
+	return w.Bytes()
+}
+
+func (s *Server) dispatch(c *rpcCall, version uint32, f func(*rpcCall, *xdrWriter) uint32, res *xdrWriter) uint32 {
+	if c.vers != version {
+		c.vers = version
+		return rpcProgMismatch
+	}
+	return f(c, res)
+}
+
+// session is the user a call is authorised as
+type session struct {
+	ctx      context.Context
+	user     *model.User
+	readOnly bool
+	uid      uint32
+	gid      uint32
+}
+
+// authorize matches the client with the export rules
+func (s *Server) authorize(c *rpcCall) (*session, bool) {
+	for _, e := range s.Exports {
+		if e.Net == nil || !e.Net.Contains(c.remote) {
+			continue
+		}
+		var user *model.User
+		var err error
+		if e.User == "" {
+			user, err = op.GetGuest()
+		} else {
+			user, err = op.GetUserByName(e.User)
+		}
+		if err != nil || user.Disabled || !user.CanFTPAccess() {
+			return nil, false
+		}
+		ctx := context.WithValue(context.Background(), conf.UserKey, user)
+		ctx = context.WithValue(ctx, conf.ClientIPKey, c.remote.String())
+		return &session{
+			ctx:      ctx,
+			user:     user,
+			readOnly: e.ReadOnly || !user.CanFTPManage(),
+			uid:      c.uid,
+			gid:      c.gid,
+		}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,440 @@
+package nfs
+
+import (
+	"context"
+	"io"
+	stdpath "path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/hybrid_cache"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+const (
+	spoolBlockSize  = 4 * utils.MB
+	spoolMemorySize = 64 * utils.MB
+	// dirty spools are uploaded when they are not written for flushDelay
+	flushDelay = 5 * time.Second
+	// clean spools and open files are dropped when they are not used for idleTimeout
+	idleTimeout = 30 * time.Second
+)
+
+// spool keeps the content of a file that is written by a client, NFS writes
+// are random and unstable, so the file is uploaded as a whole by fs.PutDirectly
+// on COMMIT, on a stable write or when the client stops writing for a while
+type spool struct {
+	mu       sync.Mutex
+	path     string
+	ctx      context.Context
+	hc       *hybrid_cache.HybridCache
+	size     int64
+	modified time.Time
+	created  time.Time
+	dirty    bool
+	lastUsed time.Time
+}
+
+func newSpool(ctx context.Context, p string) (*spool, error) {
+	hc, err := hybrid_cache.NewHybridCache(spoolBlockSize, spoolMemorySize)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &spool{path: p, ctx: ctx, hc: hc, modified: now, created: now, lastUsed: now}, nil
+}
+
+func (sp *spool) attr() attr {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return attr{size: sp.size, modified: sp.modified, created: sp.created}
+}
+
+// grow makes the cache hold at least size bytes, the bytes after the end of the file are zeroed
+func (sp *spool) grow(size int64) error {
+	if n := size - sp.hc.Size(); n > 0 {
+		if _, err := sp.hc.AllocBlock(uint64(n)); err != nil {
+			return err
+		}
+	}
+	// the bytes after a truncation are stale
+	zeros := make([]byte, 32*1024)
+	for off := sp.size; off < size; {
+		n := min(int64(len(zeros)), size-off)
+		if _, err := sp.hc.WriteAt(zeros[:n], off); err != nil {
+			return err
+		}
+		off += n
+	}
+	return nil
+}
+
+func (sp *spool) writeAt(p []byte, off int64) error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	end := off + int64(len(p))
+	if err := sp.grow(max(off, sp.size)); err != nil {
+		return err
+	}
+	if n := end - sp.hc.Size(); n > 0 {
+		if _, err := sp.hc.AllocBlock(uint64(n)); err != nil {
+			return err
+		}
+	}
+	if _, err := sp.hc.WriteAt(p, off); err != nil {
+		return err
+	}
+	sp.size = max(sp.size, end)
+	sp.dirty = true
+	sp.modified = time.Now()
+	sp.lastUsed = sp.modified
+	return nil
+}
+
+func (sp *spool) truncate(size int64) error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if size > sp.size {
+		if err := sp.grow(size); err != nil {
+			return err
+		}
+	}
+	sp.size = size
+	sp.dirty = true
+	sp.modified = time.Now()
+	sp.lastUsed = sp.modified
+	return nil
+}
+
+func (sp *spool) readAt(p []byte, off int64) (int, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.lastUsed = time.Now()
+	if off >= sp.size {
+		return 0, io.EOF
+	}
+	n := min(int64(len(p)), sp.size-off)
+	return sp.hc.ReadAt(p[:n], off)
+}
+
+// flush uploads the file if it has been written since the last upload
+func (sp *spool) flush() error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if !sp.dirty {
+		return nil
+	}
+	dir, name := stdpath.Split(sp.path)
+	s := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     name,
+			Size:     sp.size,
+			Modified: sp.modified,
+			Ctime:    sp.created,
+		},
+		Mimetype: utils.GetMimeType(name),
+		Reader:   io.NewSectionReader(sp.hc, 0, sp.size),
+	}
+	if err := fs.PutDirectly(sp.ctx, dir, s); err != nil {
+		return err
+	}
+	sp.dirty = false
+	return nil
+}
+
+func (sp *spool) close() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	_ = sp.hc.Close()
+}
+
+type spoolTable struct {
+	mu      sync.Mutex
+	spools  map[string]*spool
+	readers *readerTable
+}
+
+func newSpoolTable(readers *readerTable) *spoolTable {
+	return &spoolTable{spools: map[string]*spool{}, readers: readers}
+}
+
+func (t *spoolTable) get(p string) *spool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.spools[p]
+}
+
+// create starts an empty file at p
+func (t *spoolTable) create(ctx context.Context, p string) (*spool, error) {
+	sp, err := newSpool(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	sp.dirty = true
+	t.mu.Lock()
+	old := t.spools[p]
+	t.spools[p] = sp
+	t.mu.Unlock()
+	if old != nil {
+		old.close()
+	}
+	return sp, nil
+}
+
+// open returns the spool of the existing file obj, the content is downloaded the first time
+func (t *spoolTable) open(ctx context.Context, p string, obj model.Obj) (*spool, error) {
+	if sp := t.get(p); sp != nil {
+		return sp, nil
+	}
+	sp, err := newSpool(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	sp.modified, sp.created = obj.ModTime(), obj.CreateTime()
+	if obj.GetSize() > 0 {
+		f, err := t.readers.open(ctx, p)
+		if err != nil {
+			sp.close()
+			return nil, err
+		}
+		f.mu.Lock()
+		_, err = sp.hc.CopyFromN(io.NewSectionReader(f.r, 0, obj.GetSize()), obj.GetSize())
+		f.mu.Unlock()
+		if err != nil {
+			sp.close()
+			return nil, err
+		}
+		sp.size = obj.GetSize()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if exist := t.spools[p]; exist != nil {
+		sp.close()
+		return exist, nil
+	}
+	t.spools[p] = sp
+	return sp, nil
+}
+
+// list returns the spools in dir
+func (t *spoolTable) list(dir string) map[string]*spool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	res := map[string]*spool{}
+	for p, sp := range t.spools {
+		if stdpath.Dir(p) == dir {
+			res[stdpath.Base(p)] = sp
+		}
+	}
+	return res
+}
+
+// remove drops the spools of p and the files under p
+func (t *spoolTable) remove(p string) {
+	t.mu.Lock()
+	var removed []*spool
+	for key, sp := range t.spools {
+		if key == p || strings.HasPrefix(key, p+"/") {
+			removed = append(removed, sp)
+			delete(t.spools, key)
+		}
+	}
+	t.mu.Unlock()
+	for _, sp := range removed {
+		sp.close()
+	}
+}
+
+// rename moves the spools of src and the files under src to dst
+func (t *spoolTable) rename(src, dst string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, sp := range t.spools {
+		if key != src && !strings.HasPrefix(key, src+"/") {
+			continue
+		}
+		delete(t.spools, key)
+		p := dst + strings.TrimPrefix(key, src)
+		sp.mu.Lock()
+		sp.path = p
+		sp.mu.Unlock()
+		t.spools[p] = sp
+	}
+}
+
+func (t *spoolTable) flush(sp *spool) error {
+	err := sp.flush()
+	if err == nil {
+		// the content of the storage has changed
+		t.readers.drop(sp.path)
+	}
+	return err
+}
+
+func (t *spoolTable) run(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		t.mu.Lock()
+		var dirty []*spool
+		for key, sp := range t.spools {
+			sp.mu.Lock()
+			idle := time.Since(sp.lastUsed)
+			isDirty := sp.dirty
+			sp.mu.Unlock()
+			if isDirty && idle > flushDelay {
+				dirty = append(dirty, sp)
+			} else if !isDirty && idle > idleTimeout {
+				delete(t.spools, key)
+				go sp.close()
+			}
+		}
+		t.mu.Unlock()
+		for _, sp := range dirty {
+			if err := t.flush(sp); err != nil {
+				utils.Log.Errorf("[nfs] failed to upload %s: %+v", sp.path, err)
+				// try again later
+				sp.mu.Lock()
+				sp.lastUsed = time.Now()
+				sp.mu.Unlock()
+			}
+		}
+	}
+}
+
+func (t *spoolTable) flushAll() {
+	t.mu.Lock()
+	spools := t.spools
+	t.spools = map[string]*spool{}
+	t.mu.Unlock()
+	for _, sp := range spools {
+		if err := sp.flush(); err != nil {
+			utils.Log.Errorf("[nfs] failed to upload %s: %+v", sp.path, err)
+		}
+		sp.close()
+	}
+}
+
+// openFile is a file of the storage opened for reading
+type openFile struct {
+	mu       sync.Mutex
+	ss       *stream.SeekableStream
+	r        model.File
+	lastUsed time.Time
+}
+
+type readerTable struct {
+	mu    sync.Mutex
+	files map[string]*openFile
+}
+
+func newReaderTable() *readerTable {
+	return &readerTable{files: map[string]*openFile{}}
+}
+
+func (t *readerTable) open(ctx context.Context, p string) (*openFile, error) {
+	t.mu.Lock()
+	f := t.files[p]
+	t.mu.Unlock()
+	if f != nil {
+		return f, nil
+	}
+	link, obj, err := fs.Link(ctx, p, model.LinkArgs{})
+	if err != nil {
+		return nil, err
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{Obj: obj, Ctx: ctx}, link)
+	if err != nil {
+		_ = link.Close()
+		return nil, err
+	}
+	r, err := stream.NewReadAtSeeker(ss, 0)
+	if err != nil {
+		_ = ss.Close()
+		return nil, err
+	}
+	f = &openFile{ss: ss, r: r, lastUsed: time.Now()}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if exist := t.files[p]; exist != nil {
+		_ = ss.Close()
+		return exist, nil
+	}
+	t.files[p] = f
+	return f, nil
+}
+
+func (t *readerTable) readAt(ctx context.Context, p string, buf []byte, off int64) (int, error) {
+	f, err := t.open(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastUsed = time.Now()
+	return f.r.ReadAt(buf, off)
+}
+
+// drop closes the open files of p and the files under p
+func (t *readerTable) drop(p string) {
+	t.mu.Lock()
+	var dropped []*openFile
+	for key, f := range t.files {
+		if key == p || strings.HasPrefix(key, p+"/") {
+			dropped = append(dropped, f)
+			delete(t.files, key)
+		}
+	}
+	t.mu.Unlock()
+	for _, f := range dropped {
+		f.close()
+	}
+}
+
+func (f *openFile) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_ = f.ss.Close()
+}
+
+func (t *readerTable) run(done <-chan struct{}) {
+	ticker := time.NewTicker(idleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		t.mu.Lock()
+		for key, f := range t.files {
+			f.mu.Lock()
+			idle := time.Since(f.lastUsed)
+			f.mu.Unlock()
+			if idle > idleTimeout {
+				delete(t.files, key)
+				go f.close()
+			}
+		}
+		t.mu.Unlock()
+	}
+}
+
+func (t *readerTable) closeAll() {
+	t.mu.Lock()
+	files := t.files
+	t.files = map[string]*openFile{}
+	t.mu.Unlock()
+	for _, f := range files {
+		f.close()
+	}
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/opds"
+	"github.com/gin-gonic/gin"
+)
+
+func OPDS(g *gin.RouterGroup) {
+	if !conf.Conf.OPDS.Enable {
+		g.Any("/opds/*path", func(c *gin.Context) {
+			common.ErrorStrResp(c, "OPDS catalog is not enabled", 403)
+		})
+		return
+	}
+	opds.Register(g)
+}
@@ -0,0 +1,324 @@
+// Package opds serves the ebooks under the configured roots as OPDS 1.2
+// (Atom) and OPDS 2.0 (JSON) catalogs under /opds
+package opds
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	stdpath "path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/ebook"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/search"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const perPage = 50
+
+func Register(g *gin.RouterGroup) {
+	o := g.Group("/opds", auth)
+	o.GET("", v1Root)
+	o.GET("/v1", v1Root)
+	o.GET("/v1/browse/*path", v1Browse)
+	o.GET("/v1/opensearch.xml", v1OpenSearch)
+	o.GET("/v1/search", middlewares.SearchIndex, v1Search)
+	o.GET("/v2", v2Root)
+	o.GET("/v2/browse/*path", v2Browse)
+	o.GET("/v2/search", middlewares.SearchIndex, v2Search)
+	o.GET("/cover/*path", cover)
+}
+
+// auth logs in with the basic authentication, the guest is used if the
+// request has no credentials
+func auth(c *gin.Context) {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		guest, err := op.GetGuest()
+		if err != nil || guest.Disabled {
+			unauthorized(c)
+			return
+		}
+		common.GinAppendValues(c, conf.UserKey, guest)
+		c.Next()
+		return
+	}
+	ip := c.ClientIP()
+	count, cok := model.LoginCache.Get(ip)
+	if cok && count >= model.DefaultMaxAuthRetries {
+		model.LoginCache.Expire(ip, model.DefaultLockDuration)
+		common.ErrorPage(c, errors.New(model.TooManyAttempts), http.StatusTooManyRequests)
+		return
+	}
+	user, err := op.GetUserByName(username)
+	if err == nil {
+		err = user.ValidateRawPassword(password)
+		if err != nil && setting.GetBool(conf.LdapLoginEnabled) && user.AllowLdap {
+			err = common.HandleLdapLogin(username, password)
+		}
+	}
+	if err != nil || user.Disabled {
+		model.LoginCache.Set(ip, count+1)
+		unauthorized(c)
+		return
+	}
+	model.LoginCache.Del(ip)
+	common.GinAppendValues(c, conf.UserKey, user)
+	c.Next()
+}
+
+func unauthorized(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Basic realm="openlist opds"`)
+	c.Status(http.StatusUnauthorized)
+	c.Abort()
+}
+
+func getUser(c *gin.Context) *model.User {
+	return c.Request.Context().Value(conf.UserKey).(*model.User)
+}
+
+// roots returns the catalog roots the user can see, a root above the base
+// path of the user is narrowed to the base path
+func roots(user *model.User) []string {
+	base := utils.FixAndCleanPath(user.BasePath)
+	var res []string
+	for _, root := range ebook.Roots() {
+		p := root
+		if !utils.IsSubPath(base, root) {
+			if !utils.IsSubPath(root, base) {
+				continue
+			}
+			p = base
+		}
+		if canAccess(user, p) && !utils.SliceContains(res, p) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// canAccess checks the base path of the user and the metas of p, the folders
+// with passwords can not be accessed as the password can not be provided
+func canAccess(user *model.User, p string) bool {
+	if !utils.IsSubPath(user.BasePath, p) {
+		return false
+	}
+	meta, err := op.GetNearestMeta(p)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return false
+	}
+	return common.CanAccess(user, meta, p, "")
+}
+
+// visible reports whether p is under one of the roots of the user and can be accessed
+func visible(user *model.User, p string) bool {
+	for _, root := range roots(user) {
+		if utils.IsSubPath(root, p) {
+			return canAccess(user, p)
+		}
+	}
+	return false
+}
+
+// item is a folder or an ebook of a catalog page
+type item struct {
+	Path string
+	// Name is the title of a folder, the title of an ebook is in Meta
+	Name string
+	Obj  model.Obj
+	Mime string
+	Meta *model.EbookMeta
+}
+
+// page is the format independent content of a catalog feed
+type page struct {
+	Title string
+	// Path is the browsed folder, empty for the root catalog and the searches
+	Path  string
+	Up    string
+	Items []item
+	Total int
+	Page  int
+}
+
+func (p *page) hasNext() bool {
+	return p.Page*perPage < p.Total
+}
+
+func pageParam(c *gin.Context) int {
+	i, err := strconv.Atoi(c.Query("page"))
+	if err != nil || i < 1 {
+		return 1
+	}
+	return i
+}
+
+func rootPage(user *model.User) *page {
+	pg := &page{Title: setting.GetStr(conf.SiteTitle), Page: 1}
+	for _, root := range roots(user) {
+		name := utils.GetNoneEmpty(stdpath.Base(root), "/")
+		if name == "/" {
+			name = pg.Title
+		}
+		pg.Items = append(pg.Items, item{Path: root, Name: name, Obj: &model.Object{Name: name, IsFolder: true}})
+	}
+	pg.Total = len(pg.Items)
+	return pg
+}
+
+// browsePage lists the folders and the ebooks of the folder at p
+func browsePage(c *gin.Context, user *model.User, p string) (*page, error) {
+	p = utils.FixAndCleanPath(p)
+	if !visible(user, p) {
+		return nil, errs.PermissionDenied
+	}
+	objs, err := fs.List(c.Request.Context(), p, &fs.ListArgs{})
+	if err != nil {
+		return nil, err
+	}
+	var items []item
+	for _, obj := range objs {
+		fp := stdpath.Join(p, obj.GetName())
+		mime := ebook.MimeType(obj.GetName())
+		if (!obj.IsDir() && mime == "") || !canAccess(user, fp) {
+			continue
+		}
+		items = append(items, item{Path: fp, Name: obj.GetName(), Obj: obj, Mime: mime})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Obj.IsDir() != items[j].Obj.IsDir() {
+			return items[i].Obj.IsDir()
+		}
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
+	pg := &page{Title: utils.GetNoneEmpty(stdpath.Base(p), "/"), Path: p, Total: len(items), Page: pageParam(c)}
+	if parent := stdpath.Dir(p); p != "/" && visible(user, parent) {
+		pg.Up = parent
+	}
+	from := (pg.Page - 1) * perPage
+	if from < len(items) {
+		pg.Items = items[from:min(from+perPage, len(items))]
+	}
+	for i, it := range pg.Items {
+		if !it.Obj.IsDir() {
+			pg.Items[i].Meta = ebook.GetMeta(c.Request.Context(), it.Path, it.Obj)
+		}
+	}
+	return pg, nil
+}
+
+// searchPage searches the ebooks under the roots of the user
+func searchPage(c *gin.Context, user *model.User, keywords string) (*page, error) {
+	pg := &page{Title: keywords, Page: pageParam(c)}
+	if keywords == "" {
+		return pg, nil
+	}
+	userRoots := roots(user)
+	nodes, total, err := search.SearchFiltered(c.Request.Context(), model.SearchReq{
+		Parent:   utils.FixAndCleanPath(user.BasePath),
+		Keywords: keywords,
+		Scope:    2,
+		PageReq:  model.PageReq{Page: pg.Page, PerPage: perPage},
+	}, func(node model.SearchNode) bool {
+		p := stdpath.Join(node.Parent, node.Name)
+		if ebook.MimeType(node.Name) == "" {
+			return false
+		}
+		for _, root := range userRoots {
+			if utils.IsSubPath(root, p) {
+				return canAccess(user, p)
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	pg.Total = int(total)
+	for _, node := range nodes {
+		p := stdpath.Join(node.Parent, node.Name)
+		obj, err := fs.Get(c.Request.Context(), p, &fs.GetArgs{NoLog: true})
+		if err != nil {
+			// the index may be out of date
+			continue
+		}
+		pg.Items = append(pg.Items, item{
+			Path: p,
+			Name: obj.GetName(),
+			Obj:  obj,
+			Mime: ebook.MimeType(obj.GetName()),
+			Meta: ebook.GetMeta(c.Request.Context(), p, obj),
+		})
+	}
+	return pg, nil
+}
+
+func failed(c *gin.Context, err error) {
+	if errs.IsNotFoundError(err) {
+		common.ErrorPage(c, err, http.StatusNotFound)
+	} else if errors.Is(errors.Cause(err), errs.PermissionDenied) {
+		common.ErrorPage(c, err, http.StatusForbidden)
+	} else {
+		common.ErrorPage(c, err, http.StatusInternalServerError)
+	}
+}
+
+func apiUrl(c *gin.Context) string {
+	return common.GetApiUrl(c.Request.Context())
+}
+
+// downloadUrl returns the signed /d url of the file at p
+func downloadUrl(c *gin.Context, p string) string {
+	return fmt.Sprintf("%s/d%s?sign=%s", apiUrl(c), utils.EncodePath(p, true), sign.Sign(p))
+}
+
+func coverUrl(c *gin.Context, p string) string {
+	return fmt.Sprintf("%s/opds/cover%s", apiUrl(c), utils.EncodePath(p, true))
+}
+
+// cover streams the cover image in the ebook archive
+func cover(c *gin.Context) {
+	user := getUser(c)
+	p := utils.FixAndCleanPath(c.Param("path"))
+	if !visible(user, p) {
+		common.ErrorPage(c, errs.PermissionDenied, http.StatusForbidden)
+		return
+	}
+	obj, err := fs.Get(c.Request.Context(), p, &fs.GetArgs{})
+	if err != nil {
+		failed(c, err)
+		return
+	}
+	m := ebook.GetMeta(c.Request.Context(), p, obj)
+	if m.Cover == "" {
+		common.ErrorPage(c, errors.New("the ebook has no cover"), http.StatusNotFound)
+		return
+	}
+	rc, size, err := ebook.OpenInner(c.Request.Context(), p, m.Cover)
+	if err != nil {
+		failed(c, err)
+		return
+	}
+	defer rc.Close()
+	c.Header("Cache-Control", "max-age=86400")
+	c.Header("Content-Type", utils.GetMimeType(m.Cover))
+	c.Header("Content-Length", strconv.FormatInt(size, 10))
+	c.Status(http.StatusOK)
+	if _, err = io.Copy(c.Writer, rc); err != nil {
+		log.Warnf("failed write the cover of [%s]: %+v", p, err)
+	}
+}
@@ -0,0 +1,240 @@
+package opds
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/search"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	conf.SlicesMap[conf.ImageTypes] = []string{"jpg", "png"}
+}
+
+const testOPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>The Test Book</dc:title>
+    <dc:creator>Jane Doe</dc:creator>
+    <dc:creator>John Roe</dc:creator>
+    <dc:language>en</dc:language>
+    <dc:identifier>urn:isbn:9780000000000</dc:identifier>
+    <dc:description>A book for the tests.</dc:description>
+  </metadata>
+  <manifest>
+    <item id="chapter" href="chapter.xhtml" media-type="application/xhtml+xml"/>
+    <item id="cover" href="images/cover%20art.jpg" media-type="image/jpeg" properties="cover-image"/>
+  </manifest>
+</package>`
+
+func testEPUB(t *testing.T, cover []byte) []byte {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{"mimetype", []byte("application/epub+zip")},
+		{"META-INF/container.xml", []byte(`<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`)},
+		{"OEBPS/content.opf", []byte(testOPF)},
+		{"OEBPS/chapter.xhtml", []byte("<html><body>chapter</body></html>")},
+		{"OEBPS/images/cover art.jpg", cover},
+	} {
+		fw, err := w.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = fw.Write(f.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func writeFile(t *testing.T, p string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func request(r http.Handler, target string, auth bool) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if auth {
+		req.SetBasicAuth("reader", "secret")
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func feed(t *testing.T, r http.Handler, target string) Feed {
+	t.Helper()
+	w := request(r, target, true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: status %d %s", target, w.Code, w.Body.String())
+	}
+	// the prefixed elements can not be decoded by the prefixed names
+	var f Feed
+	if err := xml.Unmarshal(w.Body.Bytes(), &f); err != nil {
+		t.Fatalf("failed decode %s: %v", target, err)
+	}
+	return f
+}
+
+func link(links []Link, rel string) string {
+	for _, l := range links {
+		if l.Rel == rel {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+func TestOPDS(t *testing.T) {
+	var cover bytes.Buffer
+	if err := jpeg.Encode(&cover, image.NewRGBA(image.Rect(0, 0, 60, 80)), nil); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "books", "Novels", "book.epub"), testEPUB(t, cover.Bytes()))
+	writeFile(t, filepath.Join(dir, "books", "manual.pdf"), []byte("%PDF-1.4"))
+	writeFile(t, filepath.Join(dir, "books", "notes.txt"), []byte("not a book"))
+	writeFile(t, filepath.Join(dir, "private", "secret.epub"), []byte("not listed"))
+	for _, s := range []model.Storage{
+		{Driver: "Local", MountPath: "/books", Addition: fmt.Sprintf(`{"root_folder_path":%q}`, filepath.Join(dir, "books"))},
+		{Driver: "Local", MountPath: "/private", Addition: fmt.Sprintf(`{"root_folder_path":%q}`, filepath.Join(dir, "private"))},
+	} {
+		if _, err := op.CreateStorage(context.Background(), s); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+	}
+	user := &model.User{Username: "reader", Role: model.GENERAL, BasePath: "/"}
+	if err := db.CreateUser(user.SetPassword("secret")); err != nil {
+		t.Fatal(err)
+	}
+	conf.Conf.OPDS.Roots = []string{"/books"}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	Register(r.Group(""))
+
+	// there is no guest, the credentials are required
+	if w := request(r, "/opds/v1", false); w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("expect 401 with the challenge, got %d", w.Code)
+	}
+
+	root := feed(t, r, "/opds")
+	if len(root.Entries) != 1 || root.Entries[0].Title != "books" || link(root.Entries[0].Links, "subsection") != "/opds/v1/browse/books" {
+		t.Fatalf("unexpected root feed: %+v", root.Entries)
+	}
+	books := feed(t, r, "/opds/v1/browse/books")
+	if len(books.Entries) != 2 || books.Entries[0].Title != "Novels" || books.Entries[1].Title != "manual" {
+		t.Fatalf("unexpected books feed: %+v", books.Entries)
+	}
+	if link(books.Links, "up") != "/opds/v1" || link(books.Links, "search") != "/opds/v1/opensearch.xml" {
+		t.Fatalf("unexpected feed links: %+v", books.Links)
+	}
+	if href := link(books.Entries[1].Links, relAcquisition); href != "/d/books/manual.pdf?sign="+sign.Sign("/books/manual.pdf") {
+		t.Fatalf("unexpected acquisition link: %s", href)
+	}
+	if w := request(r, "/opds/v1/browse/private", true); w.Code != http.StatusForbidden {
+		t.Fatalf("expect the path out of the roots to be forbidden, got %d", w.Code)
+	}
+
+	// epub metadata
+	novels := feed(t, r, "/opds/v1/browse/books/Novels")
+	if len(novels.Entries) != 1 {
+		t.Fatalf("unexpected novels feed: %+v", novels.Entries)
+	}
+	e := novels.Entries[0]
+	if e.Title != "The Test Book" || len(e.Authors) != 2 || e.Authors[1].Name != "John Roe" ||
+		e.Summary == nil || e.Summary.Text != "A book for the tests." {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	coverHref := link(e.Links, relImage)
+	if coverHref != "/opds/cover/books/Novels/book.epub" {
+		t.Fatalf("unexpected cover link: %s", coverHref)
+	}
+	w := request(r, coverHref, true)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/jpeg" || !bytes.Equal(w.Body.Bytes(), cover.Bytes()) {
+		t.Fatalf("unexpected cover response: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	// the cached metadata is used until the file changes
+	m, err := db.GetEbookMeta("/books/Novels", "book.epub")
+	if err != nil || m.Cover != "OEBPS/images/cover art.jpg" || m.Identifier != "urn:isbn:9780000000000" {
+		t.Fatalf("unexpected cached metadata: %+v, %v", m, err)
+	}
+	m.Title = "Cached Title"
+	if err = db.SaveEbookMeta(m); err != nil {
+		t.Fatal(err)
+	}
+
+	// opds 2.0
+	w = request(r, "/opds/v2/browse/books/Novels", true)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), opdsJSON) {
+		t.Fatalf("unexpected v2 response: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	var cat Catalog
+	if err = json.Unmarshal(w.Body.Bytes(), &cat); err != nil {
+		t.Fatal(err)
+	}
+	if len(cat.Publications) != 1 || cat.Publications[0].Metadata.Title != "Cached Title" ||
+		len(cat.Publications[0].Images) != 1 || cat.Publications[0].Links[0].Type != "application/epub+zip" {
+		t.Fatalf("unexpected v2 catalog: %+v", cat)
+	}
+
+	// opensearch
+	if err = search.Init("database"); err != nil {
+		t.Fatalf("failed init search: %+v", err)
+	}
+	for _, node := range []model.SearchNode{
+		{Parent: "/books/Novels", Name: "book.epub", Size: 1},
+		{Parent: "/books", Name: "notes.txt", Size: 1},
+		{Parent: "/private", Name: "secret.epub", Size: 1},
+	} {
+		if err = db.CreateSearchNode(&node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	found := feed(t, r, "/opds/v1/search?q=e")
+	if len(found.Entries) != 1 || found.Entries[0].Title != "Cached Title" {
+		t.Fatalf("unexpected search result: %+v", found.Entries)
+	}
+}
@@ -0,0 +1,227 @@
+package opds
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	atomNavigation  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	atomAcquisition = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	openSearchType  = "application/opensearchdescription+xml"
+
+	relAcquisition = "http://opds-spec.org/acquisition"
+	relImage       = "http://opds-spec.org/image"
+	relThumbnail   = "http://opds-spec.org/image/thumbnail"
+)
+
+type Feed struct {
+	XMLName      xml.Name `xml:"feed"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	XmlnsDC      string   `xml:"xmlns:dc,attr"`
+	XmlnsOS      string   `xml:"xmlns:opensearch,attr"`
+	ID           string   `xml:"id"`
+	Title        string   `xml:"title"`
+	Updated      string   `xml:"updated"`
+	TotalResults int      `xml:"opensearch:totalResults"`
+	ItemsPerPage int      `xml:"opensearch:itemsPerPage"`
+	StartIndex   int      `xml:"opensearch:startIndex"`
+	Links        []Link   `xml:"link"`
+	Entries      []Entry  `xml:"entry"`
+}
+
+type Link struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+type Author struct {
+	Name string `xml:"name"`
+}
+
+type Content struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type Entry struct {
+	ID         string   `xml:"id"`
+	Title      string   `xml:"title"`
+	Updated    string   `xml:"updated"`
+	Authors    []Author `xml:"author"`
+	Identifier string   `xml:"dc:identifier,omitempty"`
+	Language   string   `xml:"dc:language,omitempty"`
+	Publisher  string   `xml:"dc:publisher,omitempty"`
+	Issued     string   `xml:"dc:issued,omitempty"`
+	Summary    *Content `xml:"summary,omitempty"`
+	Links      []Link   `xml:"link"`
+}
+
+type OpenSearchDescription struct {
+	XMLName        xml.Name `xml:"OpenSearchDescription"`
+	Xmlns          string   `xml:"xmlns,attr"`
+	ShortName      string   `xml:"ShortName"`
+	Description    string   `xml:"Description"`
+	InputEncoding  string   `xml:"InputEncoding"`
+	OutputEncoding string   `xml:"OutputEncoding"`
+	Url            struct {
+		Type     string `xml:"type,attr"`
+		Template string `xml:"template,attr"`
+	} `xml:"Url"`
+}
+
+func v1Url(c *gin.Context, route, p string) string {
+	return apiUrl(c) + "/opds/v1" + route + utils.EncodePath(p, true)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func v1Feed(c *gin.Context, pg *page, id, self string) *Feed {
+	start := apiUrl(c) + "/opds/v1"
+	feed := &Feed{
+		Xmlns:        "http://www.w3.org/2005/Atom",
+		XmlnsDC:      "http://purl.org/dc/terms/",
+		XmlnsOS:      "http://a9.com/-/spec/opensearch/1.1/",
+		ID:           id,
+		Title:        pg.Title,
+		Updated:      formatTime(time.Time{}),
+		TotalResults: pg.Total,
+		ItemsPerPage: perPage,
+		StartIndex:   (pg.Page-1)*perPage + 1,
+		Links: []Link{
+			{Rel: "start", Href: start, Type: atomNavigation, Title: setting.GetStr(conf.SiteTitle)},
+			{Rel: "search", Href: start + "/opensearch.xml", Type: openSearchType},
+		},
+	}
+	kind := atomNavigation
+	for _, it := range pg.Items {
+		if !it.Obj.IsDir() {
+			kind = atomAcquisition
+			break
+		}
+	}
+	feed.Links = append(feed.Links, Link{Rel: "self", Href: pageUrl(self, pg.Page), Type: kind})
+	if pg.Up != "" {
+		feed.Links = append(feed.Links, Link{Rel: "up", Href: v1Url(c, "/browse", pg.Up), Type: atomNavigation})
+	} else if pg.Path != "" {
+		feed.Links = append(feed.Links, Link{Rel: "up", Href: start, Type: atomNavigation})
+	}
+	if pg.Page > 1 {
+		feed.Links = append(feed.Links, Link{Rel: "previous", Href: pageUrl(self, pg.Page-1), Type: kind})
+	}
+	if pg.hasNext() {
+		feed.Links = append(feed.Links, Link{Rel: "next", Href: pageUrl(self, pg.Page+1), Type: kind})
+	}
+	for _, it := range pg.Items {
+		feed.Entries = append(feed.Entries, v1Entry(c, it))
+	}
+	return feed
+}
+
+// pageUrl sets the page parameter of u
+func pageUrl(u string, page int) string {
+	if page <= 1 {
+		return u
+	}
+	sep := "?"
+	if strings.Contains(u, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%spage=%d", u, sep, page)
+}
+
+func v1Entry(c *gin.Context, it item) Entry {
+	e := Entry{
+		ID:      "urn:openlist:" + it.Path,
+		Title:   it.Name,
+		Updated: formatTime(it.Obj.ModTime()),
+	}
+	if it.Obj.IsDir() {
+		e.Links = []Link{{Rel: "subsection", Href: v1Url(c, "/browse", it.Path), Type: atomNavigation}}
+		return e
+	}
+	m := it.Meta
+	e.Title = m.Title
+	if m.Author != "" {
+		for _, name := range strings.Split(m.Author, ", ") {
+			e.Authors = append(e.Authors, Author{Name: name})
+		}
+	}
+	e.Identifier, e.Language, e.Publisher, e.Issued = m.Identifier, m.Language, m.Publisher, m.Published
+	if m.Description != "" {
+		e.Summary = &Content{Type: "text", Text: m.Description}
+	}
+	if m.Cover != "" {
+		cover := coverUrl(c, it.Path)
+		mime := utils.GetMimeType(m.Cover)
+		e.Links = append(e.Links,
+			Link{Rel: relImage, Href: cover, Type: mime},
+			Link{Rel: relThumbnail, Href: cover, Type: mime})
+	}
+	e.Links = append(e.Links, Link{Rel: relAcquisition, Href: downloadUrl(c, it.Path), Type: it.Mime, Title: it.Name})
+	return e
+}
+
+func writeXML(c *gin.Context, contentType string, v any) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		failed(c, err)
+		return
+	}
+	c.Data(http.StatusOK, contentType+"; charset=utf-8", append([]byte(xml.Header), data...))
+}
+
+func v1Root(c *gin.Context) {
+	pg := rootPage(getUser(c))
+	writeXML(c, atomNavigation, v1Feed(c, pg, "urn:openlist:opds", apiUrl(c)+"/opds/v1"))
+}
+
+func v1Browse(c *gin.Context) {
+	pg, err := browsePage(c, getUser(c), c.Param("path"))
+	if err != nil {
+		failed(c, err)
+		return
+	}
+	writeXML(c, atomAcquisition, v1Feed(c, pg, "urn:openlist:"+pg.Path, v1Url(c, "/browse", pg.Path)))
+}
+
+func v1OpenSearch(c *gin.Context) {
+	title := setting.GetStr(conf.SiteTitle)
+	desc := &OpenSearchDescription{
+		Xmlns:          "http://a9.com/-/spec/opensearch/1.1/",
+		ShortName:      title,
+		Description:    "Search the ebooks of " + title,
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+	}
+	desc.Url.Type = atomAcquisition
+	desc.Url.Template = apiUrl(c) + "/opds/v1/search?q={searchTerms}"
+	writeXML(c, openSearchType, desc)
+}
+
+func v1Search(c *gin.Context) {
+	q := c.Query("q")
+	pg, err := searchPage(c, getUser(c), q)
+	if err != nil {
+		failed(c, err)
+		return
+	}
+	self := apiUrl(c) + "/opds/v1/search?q=" + url.QueryEscape(q)
+	writeXML(c, atomAcquisition, v1Feed(c, pg, "urn:openlist:search:"+q, self))
+}
@@ -0,0 +1,157 @@
+package opds
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const opdsJSON = "application/opds+json"
+
+type Catalog struct {
+	Metadata     CatalogMetadata `json:"metadata"`
+	Links        []JSONLink      `json:"links"`
+	Navigation   []JSONLink      `json:"navigation,omitempty"`
+	Publications []Publication   `json:"publications,omitempty"`
+}
+
+type CatalogMetadata struct {
+	Title         string `json:"title"`
+	NumberOfItems int    `json:"numberOfItems"`
+	ItemsPerPage  int    `json:"itemsPerPage"`
+	CurrentPage   int    `json:"currentPage"`
+}
+
+type JSONLink struct {
+	Rel       string `json:"rel,omitempty"`
+	Href      string `json:"href"`
+	Type      string `json:"type,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Templated bool   `json:"templated,omitempty"`
+}
+
+type Contributor struct {
+	Name string `json:"name"`
+}
+
+type PublicationMetadata struct {
+	Type        string        `json:"@type"`
+	Title       string        `json:"title"`
+	Author      []Contributor `json:"author,omitempty"`
+	Identifier  string        `json:"identifier,omitempty"`
+	Language    string        `json:"language,omitempty"`
+	Publisher   string        `json:"publisher,omitempty"`
+	Published   string        `json:"published,omitempty"`
+	Modified    string        `json:"modified,omitempty"`
+	Description string        `json:"description,omitempty"`
+}
+
+type Publication struct {
+	Metadata PublicationMetadata `json:"metadata"`
+	Links    []JSONLink          `json:"links"`
+	Images   []JSONLink          `json:"images,omitempty"`
+}
+
+func v2Url(c *gin.Context, route, p string) string {
+	return apiUrl(c) + "/opds/v2" + route + utils.EncodePath(p, true)
+}
+
+func v2Catalog(c *gin.Context, pg *page, self string) *Catalog {
+	start := apiUrl(c) + "/opds/v2"
+	cat := &Catalog{
+		Metadata: CatalogMetadata{
+			Title:         pg.Title,
+			NumberOfItems: pg.Total,
+			ItemsPerPage:  perPage,
+			CurrentPage:   pg.Page,
+		},
+		Links: []JSONLink{
+			{Rel: "self", Href: pageUrl(self, pg.Page), Type: opdsJSON},
+			{Rel: "start", Href: start, Type: opdsJSON, Title: setting.GetStr(conf.SiteTitle)},
+			{Rel: "search", Href: start + "/search{?query}", Type: opdsJSON, Templated: true},
+		},
+	}
+	if pg.Up != "" {
+		cat.Links = append(cat.Links, JSONLink{Rel: "up", Href: v2Url(c, "/browse", pg.Up), Type: opdsJSON})
+	} else if pg.Path != "" {
+		cat.Links = append(cat.Links, JSONLink{Rel: "up", Href: start, Type: opdsJSON})
+	}
+	if pg.Page > 1 {
+		cat.Links = append(cat.Links, JSONLink{Rel: "previous", Href: pageUrl(self, pg.Page-1), Type: opdsJSON})
+	}
+	if pg.hasNext() {
+		cat.Links = append(cat.Links, JSONLink{Rel: "next", Href: pageUrl(self, pg.Page+1), Type: opdsJSON})
+	}
+	for _, it := range pg.Items {
+		if it.Obj.IsDir() {
+			cat.Navigation = append(cat.Navigation, JSONLink{
+				Rel:   "subsection",
+				Href:  v2Url(c, "/browse", it.Path),
+				Type:  opdsJSON,
+				Title: it.Name,
+			})
+			continue
+		}
+		cat.Publications = append(cat.Publications, v2Publication(c, it))
+	}
+	return cat
+}
+
+func v2Publication(c *gin.Context, it item) Publication {
+	m := it.Meta
+	pub := Publication{
+		Metadata: PublicationMetadata{
+			Type:        "http://schema.org/Book",
+			Title:       m.Title,
+			Identifier:  m.Identifier,
+			Language:    m.Language,
+			Publisher:   m.Publisher,
+			Published:   m.Published,
+			Modified:    formatTime(it.Obj.ModTime()),
+			Description: m.Description,
+		},
+		Links: []JSONLink{{Rel: relAcquisition, Href: downloadUrl(c, it.Path), Type: it.Mime, Title: it.Name}},
+	}
+	if m.Author != "" {
+		for _, name := range strings.Split(m.Author, ", ") {
+			pub.Metadata.Author = append(pub.Metadata.Author, Contributor{Name: name})
+		}
+	}
+	if m.Cover != "" {
+		pub.Images = []JSONLink{{Href: coverUrl(c, it.Path), Type: utils.GetMimeType(m.Cover)}}
+	}
+	return pub
+}
+
+func writeJSON(c *gin.Context, v any) {
+	c.Header("Content-Type", opdsJSON)
+	c.JSON(http.StatusOK, v)
+}
+
+func v2Root(c *gin.Context) {
+	writeJSON(c, v2Catalog(c, rootPage(getUser(c)), apiUrl(c)+"/opds/v2"))
+}
+
+func v2Browse(c *gin.Context) {
+	pg, err := browsePage(c, getUser(c), c.Param("path"))
+	if err != nil {
+		failed(c, err)
+		return
+	}
+	writeJSON(c, v2Catalog(c, pg, v2Url(c, "/browse", pg.Path)))
+}
+
+func v2Search(c *gin.Context) {
+	query := c.Query("query")
+	pg, err := searchPage(c, getUser(c), query)
+	if err != nil {
+		failed(c, err)
+		return
+	}
+	writeJSON(c, v2Catalog(c, pg, apiUrl(c)+"/opds/v2/search?query="+url.QueryEscape(query)))
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/restic"
+	"github.com/gin-gonic/gin"
+)
+
+func Restic(g *gin.RouterGroup) {
+	if !conf.Conf.Restic.Enable {
+		g.Any("/restic/*path", func(c *gin.Context) {
+			common.ErrorStrResp(c, "restic REST server is not enabled", 403)
+		})
+		return
+	}
+	restic.Register(g)
+}
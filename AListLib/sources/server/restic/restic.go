@@ -0,0 +1,364 @@
+// Package restic implements the REST backend protocol of restic under
+// /restic, the repositories are stored in the storages under the restic path
+// of the user
+package restic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	mimeV1 = "application/vnd.x.restic.rest.v1"
+	mimeV2 = "application/vnd.x.restic.rest.v2"
+)
+
+// objectTypes are the folders of a repository, the data files are stored in
+// sub folders named by the first two characters of the names
+var objectTypes = []string{"data", "keys", "locks", "snapshots", "index"}
+
+func Register(g *gin.RouterGroup) {
+	g.Any("/restic/*path", auth, serve)
+}
+
+// auth logs in with the basic authentication, the guest can not access the repositories
+func auth(c *gin.Context) {
+	ip := c.ClientIP()
+	count, cok := model.LoginCache.Get(ip)
+	if cok && count >= model.DefaultMaxAuthRetries {
+		model.LoginCache.Expire(ip, model.DefaultLockDuration)
+		c.AbortWithStatus(http.StatusTooManyRequests)
+		return
+	}
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		c.Header("WWW-Authenticate", `Basic realm="openlist restic"`)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	user, err := op.GetUserByName(username)
+	if err == nil {
+		err = user.ValidateRawPassword(password)
+		if err != nil && setting.GetBool(conf.LdapLoginEnabled) && user.AllowLdap {
+			err = common.HandleLdapLogin(username, password)
+		}
+	}
+	if err != nil || user.Disabled || user.IsGuest() {
+		model.LoginCache.Set(ip, count+1)
+		c.Header("WWW-Authenticate", `Basic realm="openlist restic"`)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	model.LoginCache.Del(ip)
+	common.GinAppendValues(c, conf.UserKey, user)
+	c.Next()
+}
+
+// request is a parsed request path, Type is empty for the repository itself
+// and is "config" for the config file
+type request struct {
+	Repo string
+	Type string
+	Name string
+}
+
+func isObjectType(s string) bool {
+	return utils.SliceContains(objectTypes, s)
+}
+
+// parse splits the path into the repository, the type and the name, the
+// repository can be nested in folders like the rest-server does
+func parse(p string) (*request, bool) {
+	var segs []string
+	if p = strings.Trim(p, "/"); p != "" {
+		segs = strings.Split(p, "/")
+	}
+	for _, seg := range segs {
+		if seg == "" || seg == "." || seg == ".." {
+			return nil, false
+		}
+	}
+	n := len(segs)
+	switch {
+	case n >= 1 && segs[n-1] == "config":
+		return &request{Repo: "/" + strings.Join(segs[:n-1], "/"), Type: "config"}, true
+	case n >= 1 && isObjectType(segs[n-1]):
+		return &request{Repo: "/" + strings.Join(segs[:n-1], "/"), Type: segs[n-1]}, true
+	case n >= 2 && isObjectType(segs[n-2]):
+		return &request{Repo: "/" + strings.Join(segs[:n-2], "/"), Type: segs[n-2], Name: segs[n-1]}, true
+	}
+	return &request{Repo: "/" + strings.Join(segs, "/")}, true
+}
+
+type handler struct {
+	c    *gin.Context
+	user *model.User
+	// repo is the path of the repository in the storages
+	repo string
+	req  *request
+}
+
+func serve(c *gin.Context) {
+	req, ok := parse(c.Param("path"))
+	if !ok {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	repo, err := user.JoinPath(stdpath.Join(utils.FixAndCleanPath(conf.Conf.Restic.Path), req.Repo))
+	if err != nil {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	h := &handler{c: c, user: user, repo: repo, req: req}
+	method := c.Request.Method
+	switch {
+	case req.Type == "" && method == http.MethodPost:
+		h.createRepo()
+	case req.Type == "" && method == http.MethodDelete:
+		// removing the whole repository is too dangerous for a shared storage
+		c.Status(http.StatusNotImplemented)
+	case req.Type != "config" && req.Name == "" && req.Type != "" && method == http.MethodGet:
+		h.list()
+	case (req.Type == "config" || req.Name != "") && (method == http.MethodGet || method == http.MethodHead):
+		h.get()
+	case (req.Type == "config" || req.Name != "") && method == http.MethodPost:
+		h.save()
+	case (req.Type == "config" || req.Name != "") && method == http.MethodDelete:
+		h.remove()
+	default:
+		c.Status(http.StatusMethodNotAllowed)
+	}
+}
+
+// filePath returns the path of the requested file in the storages
+func (h *handler) filePath() string {
+	if h.req.Type == "config" {
+		return stdpath.Join(h.repo, "config")
+	}
+	return stdpath.Join(h.typeDir(), h.req.Name)
+}
+
+func (h *handler) typeDir() string {
+	if h.req.Type == "data" && len(h.req.Name) >= 2 {
+		return stdpath.Join(h.repo, "data", h.req.Name[:2])
+	}
+	return stdpath.Join(h.repo, h.req.Type)
+}
+
+// canRead checks the metas of p, the folders with passwords can not be
+// accessed as the password can not be provided
+func (h *handler) canRead(p string) bool {
+	meta, err := op.GetNearestMeta(p)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return false
+	}
+	return common.CanAccess(h.user, meta, p, "")
+}
+
+func (h *handler) canWrite(dir string) bool {
+	meta, err := op.GetNearestMeta(dir)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return false
+	}
+	if !h.user.CanWriteContent() && !common.CanWriteContentBypassUserPerms(meta, dir) {
+		return false
+	}
+	return common.CanWrite(h.user, meta, dir)
+}
+
+func (h *handler) failed(err error) {
+	if errs.IsNotFoundError(err) {
+		h.c.Status(http.StatusNotFound)
+		return
+	}
+	if errors.Is(errors.Cause(err), errs.PermissionDenied) {
+		h.c.Status(http.StatusForbidden)
+		return
+	}
+	log.Errorf("restic %s %s: %+v", h.c.Request.Method, h.c.Request.URL.Path, err)
+	h.c.Status(http.StatusInternalServerError)
+}
+
+// createRepo creates the folders of a new repository, the sub folders of
+// the data are created by the uploads
+func (h *handler) createRepo() {
+	if h.c.Query("create") != "true" {
+		h.c.Status(http.StatusBadRequest)
+		return
+	}
+	if !h.canWrite(h.repo) {
+		h.c.Status(http.StatusForbidden)
+		return
+	}
+	for _, t := range objectTypes {
+		if err := fs.MakeDir(h.c.Request.Context(), stdpath.Join(h.repo, t)); err != nil {
+			h.failed(err)
+			return
+		}
+	}
+	h.c.Status(http.StatusOK)
+}
+
+type listEntry struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+func (h *handler) list() {
+	dir := h.typeDir()
+	if !h.canRead(dir) {
+		h.c.Status(http.StatusForbidden)
+		return
+	}
+	entries := make([]listEntry, 0)
+	objs, err := fs.List(h.c.Request.Context(), dir, &fs.ListArgs{NoLog: true})
+	if err != nil && !errs.IsNotFoundError(err) {
+		h.failed(err)
+		return
+	}
+	for _, obj := range objs {
+		if !obj.IsDir() {
+			entries = append(entries, listEntry{Name: obj.GetName(), Size: obj.GetSize()})
+			continue
+		}
+		if h.req.Type != "data" {
+			continue
+		}
+		sub, err := fs.List(h.c.Request.Context(), stdpath.Join(dir, obj.GetName()), &fs.ListArgs{NoLog: true})
+		if err != nil {
+			h.failed(err)
+			return
+		}
+		for _, o := range sub {
+			if !o.IsDir() {
+				entries = append(entries, listEntry{Name: o.GetName(), Size: o.GetSize()})
+			}
+		}
+	}
+	var body any = entries
+	contentType := mimeV2
+	if !strings.Contains(h.c.GetHeader("Accept"), mimeV2) {
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		body, contentType = names, mimeV1
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		h.failed(err)
+		return
+	}
+	h.c.Data(http.StatusOK, contentType, data)
+}
+
+func (h *handler) get() {
+	p := h.filePath()
+	if !h.canRead(p) {
+		h.c.Status(http.StatusForbidden)
+		return
+	}
+	ctx := h.c.Request.Context()
+	if h.c.Request.Method == http.MethodHead {
+		obj, err := fs.Get(ctx, p, &fs.GetArgs{NoLog: true})
+		if err != nil {
+			h.failed(err)
+			return
+		}
+		h.c.Header("Content-Length", strconv.FormatInt(obj.GetSize(), 10))
+		h.c.Status(http.StatusOK)
+		return
+	}
+	link, obj, err := fs.Link(ctx, p, model.LinkArgs{Header: h.c.Request.Header})
+	if err != nil {
+		h.failed(err)
+		return
+	}
+	defer link.Close()
+	w := &common.WrittenResponseWriter{ResponseWriter: h.c.Writer}
+	if err = common.Proxy(w, h.c.Request, link, obj); err != nil {
+		if w.IsWritten() {
+			log.Errorf("restic proxy %s error: %+v", p, err)
+			return
+		}
+		h.failed(err)
+	}
+}
+
+// save uploads the body, the names of the files except the config are the
+// SHA-256 of the contents, which is verified after the upload
+func (h *handler) save() {
+	body := h.c.Request.Body
+	defer func() {
+		_, _ = utils.CopyWithBuffer(io.Discard, body)
+		_ = body.Close()
+	}()
+	p := h.filePath()
+	dir, name := stdpath.Split(p)
+	if !h.canWrite(dir) {
+		h.c.Status(http.StatusForbidden)
+		return
+	}
+	ctx := h.c.Request.Context()
+	if obj, _ := fs.Get(ctx, p, &fs.GetArgs{NoLog: true}); obj != nil {
+		// restic never overwrites a file
+		h.c.Status(http.StatusForbidden)
+		return
+	}
+	hash := sha256.New()
+	s := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     name,
+			Size:     h.c.Request.ContentLength,
+			Modified: time.Now(),
+		},
+		Reader:   io.TeeReader(body, hash),
+		Mimetype: "application/octet-stream",
+	}
+	if err := fs.PutDirectly(ctx, dir, s); err != nil {
+		h.failed(err)
+		return
+	}
+	if h.req.Type != "config" && hex.EncodeToString(hash.Sum(nil)) != h.req.Name {
+		if err := fs.Remove(ctx, p); err != nil {
+			log.Errorf("failed remove the corrupted restic file %s: %+v", p, err)
+		}
+		h.c.Status(http.StatusBadRequest)
+		return
+	}
+	h.c.Status(http.StatusOK)
+}
+
+func (h *handler) remove() {
+	p := h.filePath()
+	if !h.user.CanRemove() || !h.canWrite(stdpath.Dir(p)) {
+		h.c.Status(http.StatusForbidden)
+		return
+	}
+	if err := fs.Remove(h.c.Request.Context(), p); err != nil {
+		h.failed(err)
+		return
+	}
+	h.c.Status(http.StatusOK)
+}
@@ -0,0 +1,173 @@
+package restic
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+// client speaks the REST backend protocol like restic does
+type client struct {
+	t        *testing.T
+	url      string
+	username string
+}
+
+func (c *client) do(method, p string, body []byte, header ...string) *http.Response {
+	c.t.Helper()
+	req, err := http.NewRequest(method, c.url+p, bytes.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	req.SetBasicAuth(c.username, "secret")
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func (c *client) expect(resp *http.Response, code int) []byte {
+	c.t.Helper()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != code {
+		c.t.Fatalf("%s %s: expect %d, got %d %s", resp.Request.Method, resp.Request.URL.Path, code, resp.StatusCode, data)
+	}
+	return data
+}
+
+// save uploads data with its SHA-256 as the name
+func (c *client) save(typ string, data []byte) string {
+	c.t.Helper()
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:])
+	c.expect(c.do(http.MethodPost, "/"+typ+"/"+name, data), http.StatusOK)
+	return name
+}
+
+func TestRestic(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/backup",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, dir),
+	}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	for _, u := range []*model.User{
+		{Username: "alice", Role: model.GENERAL, BasePath: "/backup/alice", Permission: 0xffff},
+		{Username: "bob", Role: model.GENERAL, BasePath: "/backup/bob"},
+	} {
+		if err := db.CreateUser(u.SetPassword("secret")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conf.Conf.Restic.Path = "/restic"
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	Register(r.Group(""))
+	server := httptest.NewServer(r)
+	defer server.Close()
+	c := &client{t: t, url: server.URL + "/restic/host", username: "alice"}
+
+	c.expect(c.do(http.MethodGet, "/config", nil), http.StatusNotFound)
+	c.expect(c.do(http.MethodPost, "/?create=true", nil), http.StatusOK)
+	for _, typ := range objectTypes {
+		if _, err := os.Stat(filepath.Join(dir, "alice", "restic", "host", typ)); err != nil {
+			t.Fatalf("the %s folder is not created: %v", typ, err)
+		}
+	}
+	config := []byte("encrypted config")
+	c.expect(c.do(http.MethodPost, "/config", config), http.StatusOK)
+	// the existing files are never overwritten
+	c.expect(c.do(http.MethodPost, "/config", config), http.StatusForbidden)
+	if resp := c.do(http.MethodHead, "/config", nil); resp.ContentLength != int64(len(config)) {
+		t.Fatalf("unexpected config length: %d", resp.ContentLength)
+	}
+	if data := c.expect(c.do(http.MethodGet, "/config", nil), http.StatusOK); !bytes.Equal(data, config) {
+		t.Fatalf("unexpected config: %q", data)
+	}
+
+	key := c.save("keys", []byte("key"))
+	pack := []byte("0123456789abcdef pack file")
+	packName := c.save("data", pack)
+	if _, err := os.Stat(filepath.Join(dir, "alice", "restic", "host", "data", packName[:2], packName)); err != nil {
+		t.Fatalf("the pack is not stored in the sub folder: %v", err)
+	}
+	data := c.expect(c.do(http.MethodGet, "/data/"+packName, nil, "Range", "bytes=4-9"), http.StatusPartialContent)
+	if string(data) != "456789" {
+		t.Fatalf("unexpected range: %q", data)
+	}
+
+	// the names are verified
+	c.expect(c.do(http.MethodPost, "/snapshots/"+packName, []byte("other")), http.StatusBadRequest)
+	if _, err := os.Stat(filepath.Join(dir, "alice", "restic", "host", "snapshots", packName)); !os.IsNotExist(err) {
+		t.Fatalf("the corrupted file is kept: %v", err)
+	}
+
+	var names []string
+	if err := json.Unmarshal(c.expect(c.do(http.MethodGet, "/keys/", nil), http.StatusOK), &names); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != key {
+		t.Fatalf("unexpected keys: %v", names)
+	}
+	resp := c.do(http.MethodGet, "/data/", nil, "Accept", mimeV2)
+	if resp.Header.Get("Content-Type") != mimeV2 {
+		t.Fatalf("unexpected content type: %s", resp.Header.Get("Content-Type"))
+	}
+	var entries []listEntry
+	if err := json.Unmarshal(c.expect(resp, http.StatusOK), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name != packName || entries[0].Size != int64(len(pack)) {
+		t.Fatalf("unexpected data: %+v", entries)
+	}
+
+	lock := c.save("locks", []byte("lock"))
+	c.expect(c.do(http.MethodDelete, "/locks/"+lock, nil), http.StatusOK)
+	c.expect(c.do(http.MethodGet, "/locks/"+lock, nil), http.StatusNotFound)
+	if err := json.Unmarshal(c.expect(c.do(http.MethodGet, "/locks/", nil), http.StatusOK), &names); err != nil || len(names) != 0 {
+		t.Fatalf("unexpected locks: %v %v", names, err)
+	}
+
+	// the repositories are under the base paths of the users
+	bob := &client{t: t, url: server.URL + "/restic/host", username: "bob"}
+	bob.expect(bob.do(http.MethodGet, "/config", nil), http.StatusNotFound)
+	bob.expect(bob.do(http.MethodPost, "/?create=true", nil), http.StatusForbidden)
+	bob.expect(bob.do(http.MethodGet, "/../../alice/restic/host/config", nil), http.StatusBadRequest)
+	bob.username = "nobody"
+	bob.expect(bob.do(http.MethodGet, "/config", nil), http.StatusUnauthorized)
+}
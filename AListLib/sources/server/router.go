@@ -4,8 +4,10 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/message"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/internal/sign"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/OpenListTeam/OpenList/v4/server/handles"
@@ -23,6 +25,12 @@ func Init(e *gin.Engine) {
 		})
 	}
 	Cors(e)
+	if conf.Conf.Metrics.Enable {
+		e.Use(metrics.HTTP(conf.URL.Path, ""))
+	}
+	if conf.Conf.Tracing.Enable {
+		e.Use(tracing.HTTP())
+	}
 	g := e.Group(conf.URL.Path)
 	if conf.Conf.Scheme.HttpPort != -1 && conf.Conf.Scheme.HttpsPort != -1 && conf.Conf.Scheme.ForceHttps {
 		e.Use(middlewares.ForceHttps)
@@ -34,6 +42,7 @@ func Init(e *gin.Engine) {
 	g.GET("/robots.txt", handles.Robots)
 	g.GET("/manifest.json", static.ManifestJSON)
 	g.GET("/i/:link_name", handles.Plist)
+	Metrics(g)
 	common.SecretKey = []byte(conf.Conf.JwtSecret)
 	g.Use(middlewares.StoragesLoaded)
 	if conf.Conf.MaxConnections > 0 {
@@ -42,6 +51,9 @@ func Init(e *gin.Engine) {
 	WebDav(g.Group("/dav"))
 	S3(g.Group("/s3"))
 	MCP(g)
+	Subsonic(g)
+	OPDS(g)
+	Restic(g)
 
 	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
 	signCheck := middlewares.Down(sign.Verify)
@@ -143,6 +155,11 @@ func admin(g *gin.RouterGroup) {
 	storage.POST("/enable", handles.EnableStorage)
 	storage.POST("/disable", handles.DisableStorage)
 	storage.POST("/load_all", handles.LoadAllStorages)
+	storage.GET("/health", handles.GetStoragesHealth)
+
+	blockCache := g.Group("/block_cache")
+	blockCache.GET("/stats", handles.GetBlockCacheStats)
+	blockCache.POST("/clear", handles.ClearBlockCache)
 
 	driver := g.Group("/driver")
 	driver.GET("/list", handles.ListDriverInfo)
@@ -159,6 +176,7 @@ func admin(g *gin.RouterGroup) {
 	setting.POST("/set_aria2", handles.SetAria2)
 	setting.POST("/set_qbit", handles.SetQbittorrent)
 	setting.POST("/set_transmission", handles.SetTransmission)
+	setting.POST("/set_bittorrent", handles.SetBitTorrent)
 	setting.POST("/set_115", handles.Set115)
 	setting.POST("/set_115_open", handles.Set115Open)
 	setting.POST("/set_123_pan", handles.Set123Pan)
@@ -168,6 +186,29 @@ func admin(g *gin.RouterGroup) {
 	setting.POST("/set_thunderx", handles.SetThunderX)
 	setting.POST("/set_thunder_browser", handles.SetThunderBrowser)
 
+	feed := g.Group("/feed")
+	feed.GET("/list", handles.ListFeeds)
+	feed.GET("/get", handles.GetFeed)
+	feed.POST("/create", handles.CreateFeed)
+	feed.POST("/update", handles.UpdateFeed)
+	feed.POST("/delete", handles.DeleteFeed)
+	feed.POST("/check", handles.CheckFeed)
+	feed.GET("/history", handles.ListFeedHistory)
+
+	lc := g.Group("/lifecycle")
+	lc.GET("/list", handles.ListLifecycleRules)
+	lc.GET("/get", handles.GetLifecycleRule)
+	lc.POST("/create", handles.CreateLifecycleRule)
+	lc.POST("/update", handles.UpdateLifecycleRule)
+	lc.POST("/delete", handles.DeleteLifecycleRule)
+	lc.POST("/run", handles.RunLifecycleRule)
+	lc.GET("/history", handles.ListLifecycleHistory)
+	lc.GET("/items", handles.ListLifecycleItems)
+
+	bk := g.Group("/backup")
+	bk.POST("/export", handles.ExportBackup)
+	bk.POST("/import", handles.ImportBackup)
+
 	// retain /admin/task API to ensure compatibility with legacy automation scripts
 	_task(g.Group("/task"))
 
@@ -191,6 +232,7 @@ func admin(g *gin.RouterGroup) {
 func fsAndShare(g *gin.RouterGroup) {
 	g.Any("/list", handles.FsListSplit)
 	g.Any("/get", handles.FsGetSplit)
+	g.GET("/playlist", handles.FsPlaylist)
 	a := g.Group("/archive")
 	a.Any("/meta", handles.FsArchiveMetaSplit)
 	a.Any("/list", handles.FsArchiveListSplit)
@@ -209,13 +251,23 @@ func _fs(g *gin.RouterGroup) {
 	g.POST("/copy", handles.FsCopy)
 	g.POST("/remove", handles.FsRemove)
 	g.POST("/remove_empty_directory", handles.FsRemoveEmptyDirectory)
+	g.GET("/duplicates", middlewares.AuthAdmin, handles.FsDuplicates)
+	g.POST("/duplicates", middlewares.AuthAdmin, handles.FsDuplicatesScan)
+	g.POST("/duplicates/resolve", middlewares.AuthAdmin, handles.FsDuplicatesResolve)
 	uploadLimiter := middlewares.UploadRateLimiter(stream.ClientUploadLimit)
 	g.PUT("/put", middlewares.FsUp, uploadLimiter, handles.FsStream)
 	g.PUT("/form", middlewares.FsUp, uploadLimiter, handles.FsForm)
+	tus := g.Group("/tus", handles.TusResumable)
+	tus.OPTIONS("", handles.TusOptions)
+	tus.POST("", middlewares.FsUp, uploadLimiter, handles.TusCreate)
+	tus.HEAD("/:id", handles.TusHead)
+	tus.PATCH("/:id", uploadLimiter, handles.TusPatch)
+	tus.DELETE("/:id", handles.TusDelete)
 	g.POST("/link", middlewares.AuthAdmin, handles.Link)
 	// g.POST("/add_aria2", handles.AddOfflineDownload)
 	// g.POST("/add_qbit", handles.AddQbittorrent)
 	// g.POST("/add_transmission", handles.SetTransmission)
+	g.POST("/rapid_upload", handles.FsRapidUpload)
 	g.POST("/add_offline_download", handles.AddOfflineDownload)
 	g.POST("/archive/decompress", handles.FsArchiveDecompress)
 	// Torrent 相关接口
@@ -252,5 +304,11 @@ func Cors(r *gin.Engine) {
 
 func InitS3(e *gin.Engine) {
 	Cors(e)
+	if conf.Conf.Metrics.Enable {
+		e.Use(metrics.HTTP("", "s3"))
+	}
+	if conf.Conf.Tracing.Enable {
+		e.Use(tracing.HTTP())
+	}
 	S3Server(e.Group("/"))
 }
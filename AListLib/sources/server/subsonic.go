@@ -0,0 +1,18 @@
+package server
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/subsonic"
+	"github.com/gin-gonic/gin"
+)
+
+func Subsonic(g *gin.RouterGroup) {
+	if !conf.Conf.Subsonic.Enable {
+		g.Any("/rest/*path", func(c *gin.Context) {
+			common.ErrorStrResp(c, "Subsonic API is not enabled", 403)
+		})
+		return
+	}
+	subsonic.Register(g)
+}
@@ -0,0 +1,451 @@
+package subsonic
+
+import (
+	stdpath "path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/music"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// the ids of the API are the ids of the index with the prefix of the kind
+const (
+	artistPrefix = "ar-"
+	albumPrefix  = "al-"
+	trackPrefix  = "tr-"
+)
+
+const maxListSize = 500
+
+func artistID(id uint) string {
+	return artistPrefix + strconv.FormatUint(uint64(id), 10)
+}
+
+func albumID(id uint) string {
+	return albumPrefix + strconv.FormatUint(uint64(id), 10)
+}
+
+func trackID(id uint) string {
+	return trackPrefix + strconv.FormatUint(uint64(id), 10)
+}
+
+func parseID(id, prefix string) (uint, bool) {
+	if !strings.HasPrefix(id, prefix) {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(strings.TrimPrefix(id, prefix), 10, 64)
+	return uint(v), err == nil
+}
+
+func sizeParam(c *gin.Context, name string, def int) int {
+	return min(max(intParam(c, name, def), 0), maxListSize)
+}
+
+func toArtist(a *model.MusicArtist) Artist {
+	return Artist{ID: artistID(a.ID), Name: a.Name, CoverArt: artistID(a.ID), AlbumCount: a.AlbumCount}
+}
+
+func toAlbum(a *model.MusicAlbum) Album {
+	album := Album{
+		ID:        albumID(a.ID),
+		Name:      a.Name,
+		Artist:    a.Artist,
+		ArtistID:  artistID(a.ArtistID),
+		SongCount: a.SongCount,
+		Duration:  a.Duration,
+		Year:      a.Year,
+		Genre:     a.Genre,
+		Created:   a.Created,
+	}
+	if a.CoverPath != "" {
+		album.CoverArt = album.ID
+	}
+	return album
+}
+
+// albumChild is the album as a directory of the file structure based browsing
+func albumChild(a *model.MusicAlbum) Child {
+	album := toAlbum(a)
+	return Child{
+		ID:       album.ID,
+		Parent:   album.ArtistID,
+		IsDir:    true,
+		Title:    a.Name,
+		Album:    a.Name,
+		Artist:   a.Artist,
+		Year:     a.Year,
+		Genre:    a.Genre,
+		CoverArt: album.CoverArt,
+		Created:  a.Created,
+	}
+}
+
+func trackChild(user *model.User, t *model.MusicTrack) Child {
+	child := Child{
+		ID:          trackID(t.ID),
+		Parent:      albumID(t.AlbumID),
+		Title:       t.Title,
+		Album:       utils.GetNoneEmpty(t.Album, stdpath.Base(t.Dir)),
+		Artist:      utils.GetNoneEmpty(t.Artist, t.AlbumArtist, music.UnknownArtist),
+		Track:       t.Track,
+		Year:        t.Year,
+		Genre:       t.Genre,
+		CoverArt:    albumID(t.AlbumID),
+		Size:        t.Size,
+		ContentType: utils.GetMimeType(t.Name),
+		Suffix:      strings.TrimPrefix(stdpath.Ext(t.Name), "."),
+		Duration:    t.Duration,
+		Path:        strings.TrimPrefix(strings.TrimPrefix(t.GetPath(), utils.FixAndCleanPath(user.BasePath)), "/"),
+		DiscNumber:  t.Disc,
+		AlbumID:     albumID(t.AlbumID),
+		ArtistID:    artistID(t.ArtistID),
+		Type:        "music",
+		Created:     t.Created,
+	}
+	if t.HasCover {
+		child.CoverArt = child.ID
+	}
+	return child
+}
+
+func albumChildren(user *model.User, albums []model.MusicAlbum) []Child {
+	children := []Child{}
+	for i := range albums {
+		if canAccess(user, albums[i].Dir) {
+			children = append(children, albumChild(&albums[i]))
+		}
+	}
+	return children
+}
+
+func albumEntries(user *model.User, albums []model.MusicAlbum) []Album {
+	entries := []Album{}
+	for i := range albums {
+		if canAccess(user, albums[i].Dir) {
+			entries = append(entries, toAlbum(&albums[i]))
+		}
+	}
+	return entries
+}
+
+func trackChildren(user *model.User, tracks []model.MusicTrack) []Child {
+	children := []Child{}
+	for i := range tracks {
+		if canAccess(user, tracks[i].GetPath()) {
+			children = append(children, trackChild(user, &tracks[i]))
+		}
+	}
+	return children
+}
+
+func indexName(name string) string {
+	for _, r := range name {
+		if unicode.IsLetter(r) {
+			return string(unicode.ToUpper(r))
+		}
+		break
+	}
+	return "#"
+}
+
+// artistIndexes groups the artists of the scope by the first letters of their names
+func artistIndexes(c *gin.Context, user *model.User) ([]Index, *Response) {
+	indexes := []Index{}
+	dir, ok := scope(c, user)
+	if !ok {
+		return indexes, nil
+	}
+	artists, err := db.GetMusicArtists(dir)
+	if err != nil {
+		return nil, failedErr(err)
+	}
+	sort.SliceStable(artists, func(i, j int) bool {
+		ni, nj := indexName(artists[i].Name), indexName(artists[j].Name)
+		if ni != nj {
+			// the names that do not start with a letter are listed at last
+			return nj == "#" || ni != "#" && ni < nj
+		}
+		return strings.ToLower(artists[i].Name) < strings.ToLower(artists[j].Name)
+	})
+	for i := range artists {
+		name := indexName(artists[i].Name)
+		if n := len(indexes); n == 0 || indexes[n-1].Name != name {
+			indexes = append(indexes, Index{Name: name})
+		}
+		index := &indexes[len(indexes)-1]
+		index.Artist = append(index.Artist, toArtist(&artists[i]))
+	}
+	return indexes, nil
+}
+
+func getIndexes(c *gin.Context, user *model.User) *Response {
+	indexes, resp := artistIndexes(c, user)
+	if resp != nil {
+		return resp
+	}
+	resp = newResponse()
+	resp.Indexes = &Indexes{LastModified: music.GetStatus().LastScan.UnixMilli(), Index: indexes}
+	return resp
+}
+
+func getArtists(c *gin.Context, user *model.User) *Response {
+	indexes, resp := artistIndexes(c, user)
+	if resp != nil {
+		return resp
+	}
+	resp = newResponse()
+	resp.Artists = &Artists{Index: indexes}
+	return resp
+}
+
+// artistAlbums returns the artist of id with its albums that the user can access
+func artistAlbums(user *model.User, id uint) (*model.MusicArtist, []model.MusicAlbum, *Response) {
+	artist, err := db.GetMusicArtistById(id)
+	if err != nil {
+		return nil, nil, failed(codeNotFound, "artist not found")
+	}
+	albums, err := db.GetMusicAlbumsByArtist(id, utils.FixAndCleanPath(user.BasePath))
+	if err != nil {
+		return nil, nil, failedErr(err)
+	}
+	if len(albums) == 0 {
+		return nil, nil, failed(codeNotFound, "artist not found")
+	}
+	return artist, albums, nil
+}
+
+// albumTracks returns the album of id with its tracks that the user can access
+func albumTracks(user *model.User, id uint) (*model.MusicAlbum, []model.MusicTrack, *Response) {
+	album, err := db.GetMusicAlbumById(id)
+	if err != nil || !canAccess(user, album.Dir) {
+		return nil, nil, failed(codeNotFound, "album not found")
+	}
+	tracks, err := db.GetMusicTracksByAlbum(id, utils.FixAndCleanPath(user.BasePath))
+	if err != nil {
+		return nil, nil, failedErr(err)
+	}
+	return album, tracks, nil
+}
+
+func getMusicDirectory(c *gin.Context, user *model.User) *Response {
+	id := param(c, "id")
+	if id == "" {
+		return missing("id")
+	}
+	if aid, ok := parseID(id, artistPrefix); ok {
+		artist, albums, resp := artistAlbums(user, aid)
+		if resp != nil {
+			return resp
+		}
+		resp = newResponse()
+		resp.Directory = &Directory{ID: id, Name: artist.Name, Child: albumChildren(user, albums)}
+		return resp
+	}
+	if aid, ok := parseID(id, albumPrefix); ok {
+		album, tracks, resp := albumTracks(user, aid)
+		if resp != nil {
+			return resp
+		}
+		resp = newResponse()
+		resp.Directory = &Directory{ID: id, Parent: artistID(album.ArtistID), Name: album.Name, Child: trackChildren(user, tracks)}
+		return resp
+	}
+	return failed(codeNotFound, "directory not found")
+}
+
+func getArtist(c *gin.Context, user *model.User) *Response {
+	id, ok := parseID(param(c, "id"), artistPrefix)
+	if !ok {
+		return failed(codeNotFound, "artist not found")
+	}
+	artist, albums, resp := artistAlbums(user, id)
+	if resp != nil {
+		return resp
+	}
+	resp = newResponse()
+	resp.Artist = &ArtistWithAlbums{Artist: toArtist(artist), Album: albumEntries(user, albums)}
+	return resp
+}
+
+func getAlbum(c *gin.Context, user *model.User) *Response {
+	id, ok := parseID(param(c, "id"), albumPrefix)
+	if !ok {
+		return failed(codeNotFound, "album not found")
+	}
+	album, tracks, resp := albumTracks(user, id)
+	if resp != nil {
+		return resp
+	}
+	resp = newResponse()
+	resp.Album = &AlbumWithSongs{Album: toAlbum(album), Song: trackChildren(user, tracks)}
+	return resp
+}
+
+// track returns the track of the id parameter if the user can access it
+func track(c *gin.Context, user *model.User) (*model.MusicTrack, *Response) {
+	id, ok := parseID(param(c, "id"), trackPrefix)
+	if !ok {
+		return nil, failed(codeNotFound, "song not found")
+	}
+	t, err := db.GetMusicTrackById(id)
+	if err != nil || !canAccess(user, t.GetPath()) {
+		return nil, failed(codeNotFound, "song not found")
+	}
+	return t, nil
+}
+
+func getSong(c *gin.Context, user *model.User) *Response {
+	t, resp := track(c, user)
+	if resp != nil {
+		return resp
+	}
+	child := trackChild(user, t)
+	resp = newResponse()
+	resp.Song = &child
+	return resp
+}
+
+// albumList returns the albums of the getAlbumList parameters, the types that
+// need the play history of the users are empty as it is not recorded
+func albumList(c *gin.Context, user *model.User) ([]model.MusicAlbum, *Response) {
+	req := db.MusicAlbumListReq{
+		Type:     param(c, "type"),
+		Genre:    param(c, "genre"),
+		FromYear: intParam(c, "fromYear", 0),
+		ToYear:   intParam(c, "toYear", 9999),
+		Offset:   max(intParam(c, "offset", 0), 0),
+		Size:     sizeParam(c, "size", 10),
+	}
+	switch req.Type {
+	case "":
+		return nil, missing("type")
+	case "random", "newest", "alphabeticalByName", "alphabeticalByArtist", "byYear", "byGenre":
+	default:
+		return nil, nil
+	}
+	dir, ok := scope(c, user)
+	if !ok {
+		return nil, nil
+	}
+	albums, err := db.GetMusicAlbums(dir, req)
+	if err != nil {
+		return nil, failedErr(err)
+	}
+	return albums, nil
+}
+
+func getAlbumList(c *gin.Context, user *model.User) *Response {
+	albums, resp := albumList(c, user)
+	if resp != nil {
+		return resp
+	}
+	resp = newResponse()
+	resp.AlbumList = &AlbumList{Album: albumChildren(user, albums)}
+	return resp
+}
+
+func getAlbumList2(c *gin.Context, user *model.User) *Response {
+	albums, resp := albumList(c, user)
+	if resp != nil {
+		return resp
+	}
+	resp = newResponse()
+	resp.AlbumList2 = &AlbumList2{Album: albumEntries(user, albums)}
+	return resp
+}
+
+func getRandomSongs(c *gin.Context, user *model.User) *Response {
+	resp := newResponse()
+	resp.RandomSongs = &Songs{Song: []Child{}}
+	dir, ok := scope(c, user)
+	if !ok {
+		return resp
+	}
+	tracks, err := db.GetRandomMusicTracks(dir, param(c, "genre"), sizeParam(c, "size", 10))
+	if err != nil {
+		return failedErr(err)
+	}
+	resp.RandomSongs.Song = trackChildren(user, tracks)
+	return resp
+}
+
+type searchResult struct {
+	artists []model.MusicArtist
+	albums  []model.MusicAlbum
+	tracks  []model.MusicTrack
+}
+
+func search(c *gin.Context, user *model.User) (*searchResult, *Response) {
+	// an empty query matches everything, some clients sync the whole library with it
+	query := strings.Trim(strings.TrimSpace(param(c, "query")), `"*`)
+	res := &searchResult{}
+	dir, ok := scope(c, user)
+	if !ok {
+		return res, nil
+	}
+	var err error
+	if res.artists, err = db.SearchMusicArtists(dir, db.MusicSearchReq{
+		Query:  query,
+		Offset: max(intParam(c, "artistOffset", 0), 0),
+		Size:   sizeParam(c, "artistCount", 20),
+	}); err != nil {
+		return nil, failedErr(err)
+	}
+	if res.albums, err = db.SearchMusicAlbums(dir, db.MusicSearchReq{
+		Query:  query,
+		Offset: max(intParam(c, "albumOffset", 0), 0),
+		Size:   sizeParam(c, "albumCount", 20),
+	}); err != nil {
+		return nil, failedErr(err)
+	}
+	if res.tracks, err = db.SearchMusicTracks(dir, db.MusicSearchReq{
+		Query:  query,
+		Offset: max(intParam(c, "songOffset", 0), 0),
+		Size:   sizeParam(c, "songCount", 20),
+	}); err != nil {
+		return nil, failedErr(err)
+	}
+	return res, nil
+}
+
+func (r *searchResult) artistEntries() []Artist {
+	artists := make([]Artist, 0, len(r.artists))
+	for i := range r.artists {
+		artists = append(artists, toArtist(&r.artists[i]))
+	}
+	return artists
+}
+
+func search2(c *gin.Context, user *model.User) *Response {
+	res, resp := search(c, user)
+	if resp != nil {
+		return resp
+	}
+	resp = newResponse()
+	resp.SearchResult2 = &SearchResult2{
+		Artist: res.artistEntries(),
+		Album:  albumChildren(user, res.albums),
+		Song:   trackChildren(user, res.tracks),
+	}
+	return resp
+}
+
+func search3(c *gin.Context, user *model.User) *Response {
+	res, resp := search(c, user)
+	if resp != nil {
+		return resp
+	}
+	resp = newResponse()
+	resp.SearchResult3 = &SearchResult3{
+		Artist: res.artistEntries(),
+		Album:  albumEntries(user, res.albums),
+		Song:   trackChildren(user, res.tracks),
+	}
+	return resp
+}
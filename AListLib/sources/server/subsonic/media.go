@@ -0,0 +1,125 @@
+package subsonic
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/music"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/disintegration/imaging"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// stream serves the original file of the track through the proxy, the
+// transcoding parameters are ignored
+func stream(c *gin.Context, user *model.User) *Response {
+	t, resp := track(c, user)
+	if resp != nil {
+		return resp
+	}
+	return proxy(c, t.GetPath())
+}
+
+// proxy serves the file at p like /p does, the download proxy url of the
+// storage is used if it is set
+func proxy(c *gin.Context, p string) *Response {
+	storage, err := fs.GetStorage(p, &fs.GetStoragesArgs{})
+	if err != nil {
+		return failedErr(err)
+	}
+	if url := common.GenerateDownProxyURL(storage.GetStorage(), p); url != "" {
+		c.Redirect(http.StatusFound, url)
+		return nil
+	}
+	link, file, err := fs.Link(c.Request.Context(), p, model.LinkArgs{Header: c.Request.Header})
+	if err != nil {
+		return failedErr(err)
+	}
+	defer link.Close()
+	if storage.GetStorage().ProxyRange {
+		link = common.ProxyRange(c, link, file.GetSize())
+	}
+	w := &common.WrittenResponseWriter{ResponseWriter: c.Writer}
+	if err = common.Proxy(w, c.Request, link, file); err != nil {
+		if w.IsWritten() {
+			log.Errorf("%s %s subsonic proxy error: %+v", c.Request.Method, c.Request.URL.Path, err)
+			return nil
+		}
+		return failedErr(err)
+	}
+	return nil
+}
+
+// cover returns the cover of the id of getCoverArt, which is the cover of an
+// album, the embedded picture of a track or the cover of the first album of an artist
+func cover(c *gin.Context, user *model.User) (path string, embedded bool, resp *Response) {
+	id := param(c, "id")
+	if id == "" {
+		return "", false, missing("id")
+	}
+	notFound := failed(codeNotFound, "cover art not found")
+	var album *model.MusicAlbum
+	if aid, ok := parseID(id, artistPrefix); ok {
+		_, albums, resp := artistAlbums(user, aid)
+		if resp != nil {
+			return "", false, resp
+		}
+		for i := range albums {
+			if albums[i].CoverPath != "" && canAccess(user, albums[i].Dir) {
+				album = &albums[i]
+				break
+			}
+		}
+	} else if aid, ok := parseID(id, albumPrefix); ok {
+		a, err := db.GetMusicAlbumById(aid)
+		if err == nil && canAccess(user, a.Dir) {
+			album = a
+		}
+	} else if _, ok := parseID(id, trackPrefix); ok {
+		t, resp := track(c, user)
+		if resp != nil {
+			return "", false, resp
+		}
+		if t.HasCover {
+			return t.GetPath(), true, nil
+		}
+		if album, _ = db.GetMusicAlbumById(t.AlbumID); album == nil {
+			return "", false, notFound
+		}
+	}
+	if album == nil || album.CoverPath == "" {
+		return "", false, notFound
+	}
+	return album.CoverPath, album.CoverEmbedded, nil
+}
+
+func getCoverArt(c *gin.Context, user *model.User) *Response {
+	p, embedded, resp := cover(c, user)
+	if resp != nil {
+		return resp
+	}
+	size := intParam(c, "size", 0)
+	if !embedded && size <= 0 {
+		return proxy(c, p)
+	}
+	data, mimeType, err := music.ReadCover(c.Request.Context(), p, embedded)
+	if err != nil {
+		return failedErr(err)
+	}
+	if size > 0 {
+		if img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true)); err == nil &&
+			(img.Bounds().Dx() > size || img.Bounds().Dy() > size) {
+			var buf bytes.Buffer
+			if err = imaging.Encode(&buf, imaging.Fit(img, size, size, imaging.Lanczos), imaging.JPEG); err == nil {
+				data, mimeType = buf.Bytes(), "image/jpeg"
+			}
+		}
+	}
+	c.Header("Cache-Control", "max-age=86400")
+	c.Data(http.StatusOK, mimeType, data)
+	return nil
+}
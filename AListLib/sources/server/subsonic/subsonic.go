@@ -0,0 +1,307 @@
+// Package subsonic serves the music library with the Subsonic / OpenSubsonic
+// API under /rest, the library is indexed by internal/music
+package subsonic
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/music"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+const apiVersion = "1.16.1"
+
+// error codes of the Subsonic API
+const (
+	codeGeneric         = 0
+	codeMissingParam    = 10
+	codeWrongAuth       = 40
+	codeTokenAuth       = 41
+	codeMultipleAuthArg = 43
+	codeInvalidAPIKey   = 44
+	codeNotAuthorized   = 50
+	codeNotFound        = 70
+)
+
+type handler func(c *gin.Context, user *model.User) *Response
+
+var handlers = map[string]handler{
+	"ping":                      ping,
+	"getLicense":                getLicense,
+	"getOpenSubsonicExtensions": getOpenSubsonicExtensions,
+	"getMusicFolders":           getMusicFolders,
+	"getIndexes":                getIndexes,
+	"getMusicDirectory":         getMusicDirectory,
+	"getArtists":                getArtists,
+	"getArtist":                 getArtist,
+	"getAlbum":                  getAlbum,
+	"getSong":                   getSong,
+	"getAlbumList":              getAlbumList,
+	"getAlbumList2":             getAlbumList2,
+	"getRandomSongs":            getRandomSongs,
+	"search2":                   search2,
+	"search3":                   search3,
+	"startScan":                 startScan,
+	"getScanStatus":             getScanStatus,
+	"stream":                    stream,
+	"download":                  stream,
+	"getCoverArt":               getCoverArt,
+}
+
+func Register(g *gin.RouterGroup) {
+	g.Any("/rest/:method", serve)
+}
+
+func serve(c *gin.Context) {
+	method := strings.TrimSuffix(c.Param("method"), ".view")
+	h, ok := handlers[method]
+	if !ok {
+		c.Status(http.StatusNotFound)
+		write(c, failed(codeGeneric, "unknown method: "+method))
+		return
+	}
+	var user *model.User
+	// the extensions are listed before the authentication as required by OpenSubsonic
+	if method != "getOpenSubsonicExtensions" {
+		var resp *Response
+		if user, resp = authenticate(c); resp != nil {
+			write(c, resp)
+			return
+		}
+		common.GinAppendValues(c, conf.UserKey, user)
+	}
+	if resp := h(c, user); resp != nil {
+		write(c, resp)
+	}
+}
+
+func newResponse() *Response {
+	return &Response{
+		Status:        "ok",
+		Version:       apiVersion,
+		Type:          "openlist",
+		ServerVersion: conf.Version,
+		OpenSubsonic:  true,
+	}
+}
+
+func failed(code int, message string) *Response {
+	resp := newResponse()
+	resp.Status = "failed"
+	resp.Error = &Error{Code: code, Message: message}
+	return resp
+}
+
+func failedErr(err error) *Response {
+	if errs.IsNotFoundError(err) || errors.Is(errors.Cause(err), errs.PermissionDenied) {
+		return failed(codeNotFound, "the requested data was not found")
+	}
+	return failed(codeGeneric, err.Error())
+}
+
+func missing(name string) *Response {
+	return failed(codeMissingParam, "required parameter is missing: "+name)
+}
+
+// jsonpCallback is a javascript identifier, the callback is not escaped in
+// the response
+var jsonpCallback = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$.]*$`)
+
+// write encodes resp in the format of the f parameter, XML by default
+func write(c *gin.Context, resp *Response) {
+	switch param(c, "f") {
+	case "json":
+		c.JSON(http.StatusOK, gin.H{"subsonic-response": resp})
+	case "jsonp":
+		callback := param(c, "callback")
+		if callback == "" {
+			c.JSON(http.StatusOK, gin.H{"subsonic-response": missing("callback")})
+			return
+		}
+		if !jsonpCallback.MatchString(callback) {
+			c.JSON(http.StatusOK, gin.H{"subsonic-response": failed(codeGeneric, "invalid callback")})
+			return
+		}
+		data, err := json.Marshal(gin.H{"subsonic-response": resp})
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Data(http.StatusOK, "application/javascript; charset=utf-8",
+			[]byte(callback+"("+string(data)+");"))
+	default:
+		data, err := xml.Marshal(resp)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Data(http.StatusOK, "application/xml; charset=utf-8", append([]byte(xml.Header), data...))
+	}
+}
+
+// param returns the parameter from the query or the form body
+func param(c *gin.Context, name string) string {
+	return c.Request.FormValue(name)
+}
+
+func intParam(c *gin.Context, name string, def int) int {
+	v, err := strconv.Atoi(param(c, name))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// authenticate maps the credentials of the request to an OpenList user. The
+// passwords are only stored as hashes, so the token authentication of
+// Subsonic is not supported, an OpenList token can be used as the api key.
+func authenticate(c *gin.Context) (*model.User, *Response) {
+	username, password, apiKey := param(c, "u"), param(c, "p"), param(c, "apiKey")
+	if apiKey != "" {
+		if username != "" {
+			return nil, failed(codeMultipleAuthArg, "multiple conflicting authentication mechanisms provided")
+		}
+		claims, err := common.ParseToken(apiKey)
+		if err != nil {
+			return nil, failed(codeInvalidAPIKey, "invalid api key")
+		}
+		user, err := op.GetUserByName(claims.Username)
+		if err != nil || claims.PwdTS != user.PwdTS || user.Disabled {
+			return nil, failed(codeInvalidAPIKey, "invalid api key")
+		}
+		return user, nil
+	}
+	if username == "" {
+		return nil, missing("u")
+	}
+	if password == "" {
+		if param(c, "t") != "" {
+			return nil, failed(codeTokenAuth, "token authentication is not supported, use the password or an api key")
+		}
+		return nil, missing("p")
+	}
+	if strings.HasPrefix(password, "enc:") {
+		data, err := hex.DecodeString(strings.TrimPrefix(password, "enc:"))
+		if err != nil {
+			return nil, failed(codeWrongAuth, "wrong username or password")
+		}
+		password = string(data)
+	}
+	ip := c.ClientIP()
+	count, ok := model.LoginCache.Get(ip)
+	if ok && count >= model.DefaultMaxAuthRetries {
+		model.LoginCache.Expire(ip, model.DefaultLockDuration)
+		return nil, failed(codeWrongAuth, model.TooManyAttempts)
+	}
+	user, err := op.GetUserByName(username)
+	if err == nil {
+		err = user.ValidateRawPassword(password)
+		if err != nil && setting.GetBool(conf.LdapLoginEnabled) && user.AllowLdap {
+			err = common.HandleLdapLogin(username, password)
+		}
+	}
+	if err != nil || user.Disabled {
+		model.LoginCache.Set(ip, count+1)
+		return nil, failed(codeWrongAuth, "wrong username or password")
+	}
+	model.LoginCache.Del(ip)
+	return user, nil
+}
+
+// scope returns the directory the request can see, which is the base path of
+// the user narrowed to the music folder of the request
+func scope(c *gin.Context, user *model.User) (string, bool) {
+	dir := utils.FixAndCleanPath(user.BasePath)
+	id := param(c, "musicFolderId")
+	if id == "" {
+		return dir, true
+	}
+	roots := music.Roots()
+	i, err := strconv.Atoi(id)
+	if err != nil || i < 1 || i > len(roots) {
+		return "", false
+	}
+	root := roots[i-1]
+	switch {
+	case utils.IsSubPath(dir, root):
+		return root, true
+	case utils.IsSubPath(root, dir):
+		return dir, true
+	}
+	return "", false
+}
+
+// canAccess checks the base path of the user and the metas of p, the folders
+// with passwords can not be accessed as the password can not be provided
+func canAccess(user *model.User, p string) bool {
+	if !utils.IsSubPath(user.BasePath, p) {
+		return false
+	}
+	meta, err := op.GetNearestMeta(p)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return false
+	}
+	return common.CanAccess(user, meta, p, "")
+}
+
+func ping(c *gin.Context, user *model.User) *Response {
+	return newResponse()
+}
+
+func getLicense(c *gin.Context, user *model.User) *Response {
+	resp := newResponse()
+	resp.License = &License{Valid: true}
+	return resp
+}
+
+func getOpenSubsonicExtensions(c *gin.Context, user *model.User) *Response {
+	resp := newResponse()
+	resp.OpenSubsonicExtensions = &[]Extension{
+		{Name: "apiKeyAuthentication", Versions: []int{1}},
+		{Name: "formPost", Versions: []int{1}},
+	}
+	return resp
+}
+
+func getMusicFolders(c *gin.Context, user *model.User) *Response {
+	resp := newResponse()
+	resp.MusicFolders = &MusicFolders{MusicFolder: []MusicFolder{}}
+	base := utils.FixAndCleanPath(user.BasePath)
+	for i, root := range music.Roots() {
+		if !utils.IsSubPath(base, root) && !utils.IsSubPath(root, base) {
+			continue
+		}
+		name := utils.GetNoneEmpty(strings.TrimPrefix(root[strings.LastIndex(root, "/"):], "/"), "Music")
+		resp.MusicFolders.MusicFolder = append(resp.MusicFolders.MusicFolder, MusicFolder{ID: i + 1, Name: name})
+	}
+	return resp
+}
+
+func startScan(c *gin.Context, user *model.User) *Response {
+	if !user.IsAdmin() {
+		return failed(codeNotAuthorized, "only the admin can scan the music library")
+	}
+	music.StartScan()
+	return getScanStatus(c, user)
+}
+
+func getScanStatus(c *gin.Context, user *model.User) *Response {
+	status := music.GetStatus()
+	resp := newResponse()
+	resp.ScanStatus = &ScanStatus{Scanning: status.Scanning, Count: status.Count}
+	return resp
+}
@@ -0,0 +1,315 @@
+package subsonic
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/music"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	conf.SlicesMap[conf.AudioTypes] = []string{"mp3", "flac"}
+	conf.SlicesMap[conf.ImageTypes] = []string{"jpg", "png"}
+}
+
+func id3Frame(id, text string) []byte {
+	var b bytes.Buffer
+	b.WriteString(id)
+	_ = binary.Write(&b, binary.BigEndian, uint32(len(text)+1))
+	b.Write([]byte{0, 0, 0})
+	b.WriteString(text)
+	return b.Bytes()
+}
+
+// testMP3 is an ID3v2.3 tag followed by a frame with a Xing header of 1000 frames
+func testMP3(title, artist, album, track string) []byte {
+	var frames bytes.Buffer
+	frames.Write(id3Frame("TIT2", title))
+	frames.Write(id3Frame("TPE1", artist))
+	frames.Write(id3Frame("TALB", album))
+	frames.Write(id3Frame("TRCK", track))
+	size := frames.Len()
+	var b bytes.Buffer
+	b.WriteString("ID3")
+	b.Write([]byte{3, 0, 0, byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)})
+	b.Write(frames.Bytes())
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x40})
+	copy(frame[36:], "Xing")
+	binary.BigEndian.PutUint32(frame[40:], 1)
+	binary.BigEndian.PutUint32(frame[44:], 1000)
+	b.Write(frame)
+	return b.Bytes()
+}
+
+// testFLAC is a FLAC of 10 seconds with a vorbis comment block
+func testFLAC(comments ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("fLaC")
+	b.Write([]byte{0, 0, 0, 34})
+	info := make([]byte, 34)
+	info[10], info[11], info[12], info[13] = 0x0a, 0xc4, 0x42, 0xf0
+	binary.BigEndian.PutUint32(info[14:], 441000)
+	b.Write(info)
+	var vorbis bytes.Buffer
+	_ = binary.Write(&vorbis, binary.LittleEndian, uint32(4))
+	vorbis.WriteString("test")
+	_ = binary.Write(&vorbis, binary.LittleEndian, uint32(len(comments)))
+	for _, c := range comments {
+		_ = binary.Write(&vorbis, binary.LittleEndian, uint32(len(c)))
+		vorbis.WriteString(c)
+	}
+	n := vorbis.Len()
+	b.Write([]byte{0x84, byte(n >> 16), byte(n >> 8), byte(n)})
+	b.Write(vorbis.Bytes())
+	return b.Bytes()
+}
+
+func testJPEG(t *testing.T) []byte {
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, image.NewRGBA(image.Rect(0, 0, 300, 300)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func writeFile(t *testing.T, p string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type testResponse struct {
+	Response Response `json:"subsonic-response"`
+}
+
+func request(t *testing.T, r http.Handler, method string, params url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rest/"+method+"?"+params.Encode(), nil))
+	return w
+}
+
+func call(t *testing.T, r http.Handler, method string, params url.Values) Response {
+	t.Helper()
+	params.Set("f", "json")
+	w := request(t, r, method, params)
+	var resp testResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed decode %s response %q: %v", method, w.Body.String(), err)
+	}
+	return resp.Response
+}
+
+func auth(username, password string) url.Values {
+	return url.Values{"u": {username}, "p": {password}, "v": {"1.16.1"}, "c": {"test"}}
+}
+
+func TestSubsonic(t *testing.T) {
+	dir := t.TempDir()
+	mp3 := testMP3("Song One", "Artist A", "Album X", "1")
+	writeFile(t, filepath.Join(dir, "Artist A", "Album X", "01.mp3"), mp3)
+	writeFile(t, filepath.Join(dir, "Artist A", "Album X", "02.flac"),
+		testFLAC("TITLE=Song Two", "ARTIST=Artist A", "ALBUM=Album X", "TRACKNUMBER=2"))
+	writeFile(t, filepath.Join(dir, "Artist A", "Album X", "cover.jpg"), testJPEG(t))
+	writeFile(t, filepath.Join(dir, "Other", "untagged.mp3"), []byte("not an mp3"))
+	if _, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/music",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, dir),
+	}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	for _, u := range []*model.User{
+		{Username: "listener", Role: model.GENERAL, BasePath: "/"},
+		{Username: "other", Role: model.GENERAL, BasePath: "/music/Other"},
+	} {
+		if err := db.CreateUser(u.SetPassword("secret")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conf.Conf.Subsonic.MusicRoots = []string{"/music"}
+	if err := music.Scan(context.Background()); err != nil {
+		t.Fatalf("failed scan: %+v", err)
+	}
+	if status := music.GetStatus(); status.Count != 3 {
+		t.Fatalf("scan found %d tracks, expect 3", status.Count)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	Register(r.Group(""))
+
+	// authentication
+	if resp := call(t, r, "ping", auth("listener", "secret")); resp.Status != "ok" {
+		t.Fatalf("ping failed: %+v", resp.Error)
+	}
+	if resp := call(t, r, "ping.view", auth("listener", "enc:"+fmt.Sprintf("%x", "secret"))); resp.Status != "ok" {
+		t.Fatalf("ping with the encoded password failed: %+v", resp.Error)
+	}
+	if resp := call(t, r, "ping", auth("listener", "wrong")); resp.Error == nil || resp.Error.Code != codeWrongAuth {
+		t.Fatalf("expect wrong password error, got %+v", resp)
+	}
+	// the failed attempts are cleared by a successful login
+	if resp := call(t, r, "ping", auth("listener", "secret")); resp.Status != "ok" {
+		t.Fatalf("ping failed: %+v", resp.Error)
+	}
+	if count, ok := model.LoginCache.Get("192.0.2.1"); ok {
+		t.Errorf("expect the failed attempts to be cleared, got %d", count)
+	}
+	token := url.Values{"u": {"listener"}, "t": {"26719a1196d2a940705a59634eb18eab"}, "s": {"c19b2d"}}
+	if resp := call(t, r, "ping", token); resp.Error == nil || resp.Error.Code != codeTokenAuth {
+		t.Fatalf("expect token authentication error, got %+v", resp)
+	}
+
+	// browsing
+	resp := call(t, r, "getArtists", auth("listener", "secret"))
+	// the untagged track is indexed with the unknown artist
+	if resp.Artists == nil || len(resp.Artists.Index) != 2 || resp.Artists.Index[1].Artist[0].Name != music.UnknownArtist {
+		t.Fatalf("unexpected artists: %+v", resp.Artists)
+	}
+	artist := resp.Artists.Index[0].Artist[0]
+	if artist.Name != "Artist A" || artist.AlbumCount != 1 {
+		t.Fatalf("unexpected artist: %+v", artist)
+	}
+	resp = call(t, r, "getArtist", url.Values{"id": {artist.ID}, "u": {"listener"}, "p": {"secret"}})
+	if resp.Artist == nil || len(resp.Artist.Album) != 1 {
+		t.Fatalf("unexpected artist: %+v", resp)
+	}
+	album := resp.Artist.Album[0]
+	if album.Name != "Album X" || album.SongCount != 2 || album.Duration != 36 || album.CoverArt == "" {
+		t.Fatalf("unexpected album: %+v", album)
+	}
+	resp = call(t, r, "getAlbum", url.Values{"id": {album.ID}, "u": {"listener"}, "p": {"secret"}})
+	if resp.Album == nil || len(resp.Album.Song) != 2 {
+		t.Fatalf("unexpected album: %+v", resp)
+	}
+	songs := resp.Album.Song
+	if songs[0].Title != "Song One" || songs[0].Track != 1 || songs[0].Duration != 26 || songs[0].Path != "music/Artist A/Album X/01.mp3" ||
+		songs[1].Title != "Song Two" || songs[1].Track != 2 || songs[1].Duration != 10 || songs[1].Suffix != "flac" {
+		t.Fatalf("unexpected songs: %+v", songs)
+	}
+	resp = call(t, r, "search3", url.Values{"query": {"two"}, "u": {"listener"}, "p": {"secret"}})
+	if resp.SearchResult3 == nil || len(resp.SearchResult3.Song) != 1 || resp.SearchResult3.Song[0].ID != songs[1].ID {
+		t.Fatalf("unexpected search result: %+v", resp.SearchResult3)
+	}
+	resp = call(t, r, "getAlbumList2", url.Values{"type": {"alphabeticalByName"}, "u": {"listener"}, "p": {"secret"}})
+	if resp.AlbumList2 == nil || len(resp.AlbumList2.Album) != 2 {
+		t.Fatalf("unexpected album list: %+v", resp.AlbumList2)
+	}
+
+	// media
+	w := request(t, r, "stream", url.Values{"id": {songs[0].ID}, "u": {"listener"}, "p": {"secret"}})
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), mp3) {
+		t.Fatalf("unexpected stream: %d %d bytes", w.Code, w.Body.Len())
+	}
+	req := httptest.NewRequest(http.MethodGet, "/rest/download?"+url.Values{"id": {songs[0].ID}, "u": {"listener"}, "p": {"secret"}}.Encode(), nil)
+	req.Header.Set("Range", "bytes=0-2")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "ID3" {
+		t.Fatalf("unexpected range download: %d %q", w.Code, w.Body.String())
+	}
+	w = request(t, r, "getCoverArt", url.Values{"id": {album.CoverArt}, "size": {"100"}, "u": {"listener"}, "p": {"secret"}})
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("unexpected cover: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if img, err := jpeg.Decode(w.Body); err != nil || img.Bounds().Dx() != 100 {
+		t.Fatalf("unexpected resized cover: %v", err)
+	}
+
+	// the users only see the tracks under their base paths
+	resp = call(t, r, "getAlbum", url.Values{"id": {album.ID}, "u": {"other"}, "p": {"secret"}})
+	if resp.Error == nil || resp.Error.Code != codeNotFound {
+		t.Fatalf("expect album not found, got %+v", resp)
+	}
+	resp = call(t, r, "getRandomSongs", url.Values{"size": {"10"}, "u": {"other"}, "p": {"secret"}})
+	if resp.RandomSongs == nil || len(resp.RandomSongs.Song) != 1 || resp.RandomSongs.Song[0].Title != "untagged" ||
+		resp.RandomSongs.Song[0].Path != "untagged.mp3" {
+		t.Fatalf("unexpected random songs: %+v", resp.RandomSongs)
+	}
+
+	// XML is the default format
+	w = request(t, r, "getMusicFolders", auth("listener", "secret"))
+	var folders struct {
+		XMLName xml.Name `xml:"subsonic-response"`
+		Status  string   `xml:"status,attr"`
+		Folders []struct {
+			ID   int    `xml:"id,attr"`
+			Name string `xml:"name,attr"`
+		} `xml:"musicFolders>musicFolder"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &folders); err != nil {
+		t.Fatal(err)
+	}
+	if folders.Status != "ok" || len(folders.Folders) != 1 || folders.Folders[0].Name != "music" {
+		t.Fatalf("unexpected music folders: %s", w.Body.String())
+	}
+
+	// the removed tracks are dropped from the index
+	if err := os.Remove(filepath.Join(dir, "Other", "untagged.mp3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := music.Scan(context.Background()); err != nil {
+		t.Fatalf("failed scan: %+v", err)
+	}
+	resp = call(t, r, "getArtists", auth("listener", "secret"))
+	if len(resp.Artists.Index) != 1 || resp.Artists.Index[0].Artist[0].ID != artist.ID {
+		t.Fatalf("unexpected artists after the removal: %+v", resp.Artists)
+	}
+}
+
+func TestJSONP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	Register(r.Group(""))
+	params := url.Values{"f": {"jsonp"}, "callback": {"app.onResponse"}}
+	if w := request(t, r, "getOpenSubsonicExtensions", params); !strings.HasPrefix(w.Body.String(), "app.onResponse({") {
+		t.Fatalf("unexpected jsonp response: %s", w.Body.String())
+	}
+	for callback, code := range map[string]int{
+		"":                       codeMissingParam,
+		"alert(document.cookie)": codeGeneric,
+		"1cb":                    codeGeneric,
+	} {
+		params.Set("callback", callback)
+		w := request(t, r, "getOpenSubsonicExtensions", params)
+		var resp testResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("expect the callback %q to be refused, got %s", callback, w.Body.String())
+		}
+		if resp.Response.Error == nil || resp.Response.Error.Code != code {
+			t.Errorf("expect the callback %q to fail with %d, got %+v", callback, code, resp.Response)
+		}
+	}
+}
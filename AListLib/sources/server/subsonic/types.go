@@ -0,0 +1,163 @@
+package subsonic
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Response is the subsonic-response envelope, it is encoded as XML or JSON
+// with the same field names
+type Response struct {
+	XMLName       xml.Name `xml:"http://subsonic.org/restapi subsonic-response" json:"-"`
+	Status        string   `xml:"status,attr" json:"status"`
+	Version       string   `xml:"version,attr" json:"version"`
+	Type          string   `xml:"type,attr" json:"type"`
+	ServerVersion string   `xml:"serverVersion,attr" json:"serverVersion"`
+	OpenSubsonic  bool     `xml:"openSubsonic,attr" json:"openSubsonic"`
+
+	Error                  *Error            `xml:"error,omitempty" json:"error,omitempty"`
+	License                *License          `xml:"license,omitempty" json:"license,omitempty"`
+	OpenSubsonicExtensions *[]Extension      `xml:"openSubsonicExtensions,omitempty" json:"openSubsonicExtensions,omitempty"`
+	MusicFolders           *MusicFolders     `xml:"musicFolders,omitempty" json:"musicFolders,omitempty"`
+	Indexes                *Indexes          `xml:"indexes,omitempty" json:"indexes,omitempty"`
+	Directory              *Directory        `xml:"directory,omitempty" json:"directory,omitempty"`
+	Artists                *Artists          `xml:"artists,omitempty" json:"artists,omitempty"`
+	Artist                 *ArtistWithAlbums `xml:"artist,omitempty" json:"artist,omitempty"`
+	Album                  *AlbumWithSongs   `xml:"album,omitempty" json:"album,omitempty"`
+	Song                   *Child            `xml:"song,omitempty" json:"song,omitempty"`
+	AlbumList              *AlbumList        `xml:"albumList,omitempty" json:"albumList,omitempty"`
+	AlbumList2             *AlbumList2       `xml:"albumList2,omitempty" json:"albumList2,omitempty"`
+	RandomSongs            *Songs            `xml:"randomSongs,omitempty" json:"randomSongs,omitempty"`
+	SearchResult2          *SearchResult2    `xml:"searchResult2,omitempty" json:"searchResult2,omitempty"`
+	SearchResult3          *SearchResult3    `xml:"searchResult3,omitempty" json:"searchResult3,omitempty"`
+	ScanStatus             *ScanStatus       `xml:"scanStatus,omitempty" json:"scanStatus,omitempty"`
+}
+
+type Error struct {
+	Code    int    `xml:"code,attr" json:"code"`
+	Message string `xml:"message,attr" json:"message"`
+}
+
+type License struct {
+	Valid bool `xml:"valid,attr" json:"valid"`
+}
+
+type Extension struct {
+	Name     string `xml:"name,attr" json:"name"`
+	Versions []int  `xml:"versions" json:"versions"`
+}
+
+type MusicFolders struct {
+	MusicFolder []MusicFolder `xml:"musicFolder" json:"musicFolder"`
+}
+
+type MusicFolder struct {
+	ID   int    `xml:"id,attr" json:"id"`
+	Name string `xml:"name,attr" json:"name"`
+}
+
+type Indexes struct {
+	LastModified    int64   `xml:"lastModified,attr" json:"lastModified"`
+	IgnoredArticles string  `xml:"ignoredArticles,attr" json:"ignoredArticles"`
+	Index           []Index `xml:"index" json:"index"`
+}
+
+type Artists struct {
+	IgnoredArticles string  `xml:"ignoredArticles,attr" json:"ignoredArticles"`
+	Index           []Index `xml:"index" json:"index"`
+}
+
+type Index struct {
+	Name   string   `xml:"name,attr" json:"name"`
+	Artist []Artist `xml:"artist" json:"artist"`
+}
+
+type Artist struct {
+	ID         string `xml:"id,attr" json:"id"`
+	Name       string `xml:"name,attr" json:"name"`
+	CoverArt   string `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+	AlbumCount int    `xml:"albumCount,attr" json:"albumCount"`
+}
+
+type ArtistWithAlbums struct {
+	Artist
+	Album []Album `xml:"album" json:"album"`
+}
+
+type Album struct {
+	ID        string    `xml:"id,attr" json:"id"`
+	Name      string    `xml:"name,attr" json:"name"`
+	Artist    string    `xml:"artist,attr,omitempty" json:"artist,omitempty"`
+	ArtistID  string    `xml:"artistId,attr,omitempty" json:"artistId,omitempty"`
+	CoverArt  string    `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+	SongCount int       `xml:"songCount,attr" json:"songCount"`
+	Duration  int       `xml:"duration,attr" json:"duration"`
+	Year      int       `xml:"year,attr,omitempty" json:"year,omitempty"`
+	Genre     string    `xml:"genre,attr,omitempty" json:"genre,omitempty"`
+	Created   time.Time `xml:"created,attr" json:"created"`
+}
+
+type AlbumWithSongs struct {
+	Album
+	Song []Child `xml:"song" json:"song"`
+}
+
+// Child is a song or a directory in the file structure based browsing
+type Child struct {
+	ID          string    `xml:"id,attr" json:"id"`
+	Parent      string    `xml:"parent,attr,omitempty" json:"parent,omitempty"`
+	IsDir       bool      `xml:"isDir,attr" json:"isDir"`
+	Title       string    `xml:"title,attr" json:"title"`
+	Album       string    `xml:"album,attr,omitempty" json:"album,omitempty"`
+	Artist      string    `xml:"artist,attr,omitempty" json:"artist,omitempty"`
+	Track       int       `xml:"track,attr,omitempty" json:"track,omitempty"`
+	Year        int       `xml:"year,attr,omitempty" json:"year,omitempty"`
+	Genre       string    `xml:"genre,attr,omitempty" json:"genre,omitempty"`
+	CoverArt    string    `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+	Size        int64     `xml:"size,attr,omitempty" json:"size,omitempty"`
+	ContentType string    `xml:"contentType,attr,omitempty" json:"contentType,omitempty"`
+	Suffix      string    `xml:"suffix,attr,omitempty" json:"suffix,omitempty"`
+	Duration    int       `xml:"duration,attr,omitempty" json:"duration,omitempty"`
+	Path        string    `xml:"path,attr,omitempty" json:"path,omitempty"`
+	DiscNumber  int       `xml:"discNumber,attr,omitempty" json:"discNumber,omitempty"`
+	AlbumID     string    `xml:"albumId,attr,omitempty" json:"albumId,omitempty"`
+	ArtistID    string    `xml:"artistId,attr,omitempty" json:"artistId,omitempty"`
+	Type        string    `xml:"type,attr,omitempty" json:"type,omitempty"`
+	Created     time.Time `xml:"created,attr" json:"created"`
+}
+
+type Directory struct {
+	ID     string  `xml:"id,attr" json:"id"`
+	Parent string  `xml:"parent,attr,omitempty" json:"parent,omitempty"`
+	Name   string  `xml:"name,attr" json:"name"`
+	Child  []Child `xml:"child" json:"child"`
+}
+
+type AlbumList struct {
+	Album []Child `xml:"album" json:"album"`
+}
+
+type AlbumList2 struct {
+	Album []Album `xml:"album" json:"album"`
+}
+
+type Songs struct {
+	Song []Child `xml:"song" json:"song"`
+}
+
+type SearchResult2 struct {
+	Artist []Artist `xml:"artist" json:"artist"`
+	Album  []Child  `xml:"album" json:"album"`
+	Song   []Child  `xml:"song" json:"song"`
+}
+
+type SearchResult3 struct {
+	Artist []Artist `xml:"artist" json:"artist"`
+	Album  []Album  `xml:"album" json:"album"`
+	Song   []Child  `xml:"song" json:"song"`
+}
+
+type ScanStatus struct {
+	Scanning bool  `xml:"scanning,attr" json:"scanning"`
+	Count    int64 `xml:"count,attr" json:"count"`
+}
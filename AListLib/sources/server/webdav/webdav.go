@@ -355,14 +355,24 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request) (status int,
 		return status, err
 	}
 	defer release()
-	// TODO(rost): Support the If-Match, If-None-Match headers? See bradfitz'
-	// comments in http.checkEtag.
 	ctx := r.Context()
 	user := ctx.Value(conf.UserKey).(*model.User)
 	reqPath, err = user.JoinPath(reqPath)
 	if err != nil {
 		return http.StatusForbidden, err
 	}
+	if r.Header.Get("If-Match") != "" || r.Header.Get("If-Unmodified-Since") != "" {
+		etag, modtime := "", time.Time{}
+		if fi, err := fs.Get(ctx, reqPath, &fs.GetArgs{NoLog: true}); err == nil {
+			if etag, err = findETag(ctx, h.LockSystem, reqPath, fi); err != nil {
+				return http.StatusInternalServerError, err
+			}
+			modtime = fi.ModTime()
+		}
+		if !net.CheckWritePreconditions(r.Header, etag, modtime) {
+			return http.StatusPreconditionFailed, nil
+		}
+	}
 	size := r.ContentLength
 	if size < 0 {
 		sizeStr := r.Header.Get("X-File-Size")